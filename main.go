@@ -3,15 +3,25 @@ package main
 import (
 	"github.com/joho/godotenv"
 	"tigaputera-backend/sdk/cryptolib"
+	"tigaputera-backend/sdk/imaging"
 	"tigaputera-backend/sdk/jwt"
 	"tigaputera-backend/sdk/log"
 	"tigaputera-backend/sdk/password"
+	"tigaputera-backend/sdk/signedurl"
 	"tigaputera-backend/sdk/storage"
+	"tigaputera-backend/sdk/totp"
 	"tigaputera-backend/sdk/validator"
 	"tigaputera-backend/src/controller"
 	"tigaputera-backend/src/database"
+	"tigaputera-backend/src/model"
+	"tigaputera-backend/src/repository"
+	"tigaputera-backend/src/service"
 
+	"context"
 	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"fmt"
 	"os"
 )
 
@@ -53,31 +63,79 @@ func getSAPrivateKey() string {
 	return cryptolib.Decrypt(string(base16PrivateKey))
 }
 
+// getStorageConfig reads the settings of the storage driver, the default driver is gcs
+func getStorageConfig() storage.Config {
+	config := storage.Config{
+		Driver:     storage.Driver(os.Getenv("STORAGE_DRIVER")),
+		BucketName: os.Getenv("STORAGE_BUCKET_NAME"),
+	}
+
+	switch config.Driver {
+	case "", storage.GCS:
+		config.Driver = storage.GCS
+		config.GCPServiceAccount = storage.GCPServiceAccount{
+			Type:                    os.Getenv("SA_TYPE"),
+			ProjectID:               os.Getenv("SA_PROJECT_ID"),
+			PrivateKeyID:            os.Getenv("SA_PRIVATE_KEY_ID"),
+			PrivateKey:              getSAPrivateKey(),
+			ClientEmail:             os.Getenv("SA_CLIENT_EMAIL"),
+			ClientID:                os.Getenv("SA_CLIENT_ID"),
+			AuthURI:                 os.Getenv("SA_AUTH_URI"),
+			TokenURI:                os.Getenv("SA_TOKEN_URI"),
+			AuthProviderX509CertURL: os.Getenv("SA_AUTH_PROVIDER_X509_CERT_URL"),
+			ClientX509CertURL:       os.Getenv("SA_CLIENT_X509_CERT_URL"),
+			UniverseDomain:          os.Getenv("SA_UNIVERSE_DOMAIN"),
+		}
+	case storage.Local:
+		config.Local = storage.LocalConfig{
+			Dir: os.Getenv("STORAGE_LOCAL_DIR"),
+		}
+	case storage.S3:
+		config.S3 = storage.S3Config{
+			Endpoint:        os.Getenv("S3_ENDPOINT"),
+			AccessKeyID:     os.Getenv("S3_ACCESS_KEY_ID"),
+			SecretAccessKey: os.Getenv("S3_SECRET_ACCESS_KEY"),
+			Region:          os.Getenv("S3_REGION"),
+			UseSSL:          os.Getenv("S3_USE_SSL") == "true",
+		}
+	}
+
+	return config
+}
+
 func initialize() {
 	logger := log.Init()
 
+	repo := initRepository(logger)
+
+	// the maintenance commands only need the database, so they run without the storage and http setup
+	if len(os.Args) > 1 {
+		runCommand(service.NewLedgerService(repo, nil, nil, nil), os.Args[1:])
+		return
+	}
+
 	validator := validator.Init()
 
 	password := password.Init()
 
 	jwt := jwt.Init()
 
-	gcpServiceAccount := storage.GCPServiceAccount{
-		Type:                    os.Getenv("SA_TYPE"),
-		ProjectID:               os.Getenv("SA_PROJECT_ID"),
-		PrivateKeyID:            os.Getenv("SA_PRIVATE_KEY_ID"),
-		PrivateKey:              getSAPrivateKey(),
-		ClientEmail:             os.Getenv("SA_CLIENT_EMAIL"),
-		ClientID:                os.Getenv("SA_CLIENT_ID"),
-		AuthURI:                 os.Getenv("SA_AUTH_URI"),
-		TokenURI:                os.Getenv("SA_TOKEN_URI"),
-		AuthProviderX509CertURL: os.Getenv("SA_AUTH_PROVIDER_X509_CERT_URL"),
-		ClientX509CertURL:       os.Getenv("SA_CLIENT_X509_CERT_URL"),
-		UniverseDomain:          os.Getenv("SA_UNIVERSE_DOMAIN"),
-	}
+	storage := storage.Init(getStorageConfig())
+
+	signedURL := signedurl.Init()
+
+	imaging := imaging.Init()
+
+	totp := totp.Init(cryptolib.Init(os.Getenv("CRYPTO_SECRET_KEY")))
+
+	svc := service.Init(repo, jwt, password, storage, signedURL, imaging, totp)
 
-	storage := storage.Init(gcpServiceAccount, os.Getenv("STORAGE_BUCKET_NAME"))
+	r := controller.Init(logger, jwt, validator, svc)
 
+	r.Run()
+}
+
+func initRepository(logger log.LogInterface) repository.Interface {
 	db, err := database.Init(logger)
 	if err != nil {
 		panic(err)
@@ -91,6 +149,46 @@ func initialize() {
 		panic(err)
 	}
 
-	r := controller.Init(logger, db, jwt, password, validator, storage)
-	r.Run()
+	if err := db.SeedRolePermissions(); err != nil {
+		panic(err)
+	}
+
+	if err := db.RegisterAuditCallbacks(); err != nil {
+		panic(err)
+	}
+
+	return repository.Init(db)
+}
+
+type command interface {
+	CheckLedgerIntegrity(ctx context.Context, fix bool) (model.LedgerIntegrityReport, error)
+}
+
+// runCommand runs a maintenance subcommand instead of the server, e.g. ./app ledger-check -fix
+func runCommand(cmd command, args []string) {
+	switch args[0] {
+	case "ledger-check":
+		flags := flag.NewFlagSet("ledger-check", flag.ExitOnError)
+		fix := flags.Bool("fix", false, "rewrite the running balances and totals after checking")
+		if err := flags.Parse(args[1:]); err != nil {
+			panic(err)
+		}
+
+		report, err := cmd.CheckLedgerIntegrity(context.Background(), *fix)
+		if err != nil {
+			panic(err)
+		}
+
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(report); err != nil {
+			panic(err)
+		}
+
+		if !report.IsFixed && !report.IsConsistent() {
+			os.Exit(1)
+		}
+	default:
+		panic(fmt.Sprintf("unknown command %s", args[0]))
+	}
 }
@@ -12,6 +12,7 @@ const (
 	requestId        contextKey = "RequestId"
 	serviceVersion   contextKey = "ServiceVersion"
 	userAgent        contextKey = "UserAgent"
+	clientIP         contextKey = "ClientIP"
 	requestStartTime contextKey = "RequestStartTime"
 	deviceType       contextKey = "DeviceType"
 
@@ -20,6 +21,9 @@ const (
 	HeaderCacheControl = "cache-control"
 	HeaderUserAgent    = "user-agent"
 	HeaderDeviceType   = "x-device-type"
+
+	HeaderIdempotencyKey     = "idempotency-key"
+	HeaderIdempotentReplayed = "idempotent-replayed"
 )
 
 func SetRequestId(ctx context.Context, rid string) context.Context {
@@ -61,6 +65,19 @@ func GetUserAgent(ctx context.Context) string {
 	return ua
 }
 
+func SetClientIP(ctx context.Context, ip string) context.Context {
+	return context.WithValue(ctx, clientIP, ip)
+}
+
+func GetClientIP(ctx context.Context) string {
+	ip, ok := ctx.Value(clientIP).(string)
+	if !ok {
+		return ""
+	}
+
+	return ip
+}
+
 func SetRequestStartTime(ctx context.Context, t time.Time) context.Context {
 	return context.WithValue(ctx, requestStartTime, t)
 }
@@ -16,6 +16,20 @@ type User struct {
 	Name         string `json:"name"`
 	IsFirstLogin bool   `json:"isFirstLogin"`
 	Role         string `json:"role"`
+	SessionID    int64  `json:"sessionId"`
+	// Permissions aren't in the token, they are read from the role of the user on every request
+	Permissions []string `json:"-"`
+}
+
+// HasPermission reports whether the role of the user grants the permission
+func (u User) HasPermission(permission string) bool {
+	for _, p := range u.Permissions {
+		if p == permission {
+			return true
+		}
+	}
+
+	return false
 }
 
 func GetUserID(ctx context.Context) int64 {
@@ -44,5 +58,15 @@ func SetUser(ctx context.Context, user map[string]interface{}) context.Context {
 		IsFirstLogin: user["isFirstLogin"].(bool),
 		Role:         user["role"].(string),
 	}
+	// the tokens issued before the sessions have none, they are rejected as a revoked session
+	if sessionID, ok := user["sessionId"].(float64); ok {
+		userObj.SessionID = int64(sessionID)
+	}
 	return context.WithValue(ctx, userAuthInfo, userObj)
 }
+
+func SetPermissions(ctx context.Context, permissions []string) context.Context {
+	user := GetUser(ctx)
+	user.Permissions = permissions
+	return context.WithValue(ctx, userAuthInfo, user)
+}
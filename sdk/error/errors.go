@@ -16,6 +16,10 @@ const (
 	InternalServerErrorType = "HTTPStatusInternalServerError"
 	BadRequestType          = "HTTPStatusBadRequest"
 	UnauthorizedType        = "HTTPStatusUnauthorized"
+	ConflictType            = "HTTPStatusConflict"
+	ForbiddenType           = "HTTPStatusForbidden"
+	TooManyRequestsType     = "HTTPStatusTooManyRequests"
+	UnprocessableEntityType = "HTTPStatusUnprocessableEntity"
 )
 
 func (e *Errors) Error() string {
@@ -48,6 +52,22 @@ func Unauthorized(message string) error {
 	return NewWithCode(http.StatusUnauthorized, message, UnauthorizedType)
 }
 
+func Conflict(message string) error {
+	return NewWithCode(http.StatusConflict, message, ConflictType)
+}
+
+func Forbidden(message string) error {
+	return NewWithCode(http.StatusForbidden, message, ForbiddenType)
+}
+
+func TooManyRequests(message string) error {
+	return NewWithCode(http.StatusTooManyRequests, message, TooManyRequestsType)
+}
+
+func UnprocessableEntity(message string) error {
+	return NewWithCode(http.StatusUnprocessableEntity, message, UnprocessableEntityType)
+}
+
 func GetType(err error) string {
 	if err == nil {
 		return "HTTPStatusOK"
@@ -1,12 +1,18 @@
 package file
 
 import (
+	"errors"
+	"io"
 	"mime/multipart"
+	"net/http"
 	"strings"
 
 	"github.com/gin-gonic/gin"
 )
 
+// ErrTooManyFiles is returned by InitMultiple when the form field has more files than allowed
+var ErrTooManyFiles = errors.New("too many files")
+
 type File struct {
 	Content multipart.File
 	Meta    *multipart.FileHeader
@@ -26,14 +32,74 @@ func Init(ctx *gin.Context, key string) (*File, error) {
 	return file, nil
 }
 
+// InitMultiple opens every file sent in the form field, it returns http.ErrMissingFile when there is none
+// and ErrTooManyFiles when there are more than maxFiles, before opening any of them.
+// The opened files must be closed with CloseAll.
+func InitMultiple(ctx *gin.Context, key string, maxFiles int) ([]*File, error) {
+	form, err := ctx.MultipartForm()
+	if err != nil {
+		return nil, err
+	}
+
+	metas := form.File[key]
+	if len(metas) == 0 {
+		return nil, http.ErrMissingFile
+	} else if len(metas) > maxFiles {
+		return nil, ErrTooManyFiles
+	}
+
+	files := []*File{}
+	for _, meta := range metas {
+		content, err := meta.Open()
+		if err != nil {
+			CloseAll(files)
+			return nil, err
+		}
+
+		files = append(files, &File{
+			Content: content,
+			Meta:    meta,
+		})
+	}
+
+	return files, nil
+}
+
+// CloseAll closes the files opened by InitMultiple
+func CloseAll(files []*File) {
+	for _, file := range files {
+		file.Content.Close()
+	}
+}
+
 func (f *File) SetFileName(newName string) {
 	fileName := strings.Split(f.Meta.Filename, ".")
 	fileName[0] = newName
 	f.Meta.Filename = strings.Join(fileName, ".")
 }
 
+// ContentType sniffs the magic bytes of the content, the Content-Type sent by the client isn't trusted
+func (f *File) ContentType() string {
+	header := make([]byte, 512)
+	n, err := io.ReadFull(f.Content, header)
+	if err != nil && err != io.ErrUnexpectedEOF {
+		return ""
+	}
+
+	if _, err := f.Content.Seek(0, io.SeekStart); err != nil {
+		return ""
+	}
+
+	return http.DetectContentType(header[:n])
+}
+
 func (f *File) IsImage() bool {
-	return strings.Contains(f.Meta.Header.Get("Content-Type"), "image")
+	contentType := f.ContentType()
+	return contentType == "image/png" || contentType == "image/jpeg"
+}
+
+func (f *File) IsPDF() bool {
+	return f.ContentType() == "application/pdf"
 }
 
 func GetFileNameFromURL(url string) string {
@@ -0,0 +1,269 @@
+package imaging
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"image"
+	"image/color"
+	"image/jpeg"
+	_ "image/png"
+	"io"
+	"math/bits"
+	"net/http"
+
+	"github.com/rwcarlsen/goexif/exif"
+	"golang.org/x/image/draw"
+)
+
+const (
+	// MaxFileSize is the largest upload accepted, a phone photo is usually below 10 MB
+	MaxFileSize = 15 << 20
+	// the decoded image is held in memory, larger resolutions are rejected before decoding
+	maxPixels          = 50_000_000
+	maxDimension       = 1920
+	thumbnailDimension = 320
+	jpegQuality        = 85
+	// the difference hash compares each pixel of a 9x8 grayscale image with its right neighbour
+	hashWidth  = 9
+	hashHeight = 8
+)
+
+var (
+	ErrFileTooLarge       = errors.New("file too large")
+	ErrUnsupportedFormat  = errors.New("unsupported image format")
+	ErrResolutionTooLarge = errors.New("image resolution too large")
+	ErrInvalidImage       = errors.New("invalid image")
+	supportedContentTypes = []string{"image/jpeg", "image/png"}
+	background            = image.NewUniform(color.White)
+)
+
+// Image is an uploaded image re-encoded as jpeg, only the capture time and the location are kept from its metadata
+type Image struct {
+	Content    []byte
+	Thumbnail  []byte
+	CapturedAt *int64
+	Latitude   *float64
+	Longitude  *float64
+	// ContentHash is the sha256 of the uploaded file, before it is re-encoded
+	ContentHash string
+	// PerceptualHash stays close for a resized, recompressed, or re-photographed image, see Distance
+	PerceptualHash uint64
+}
+
+type Interface interface {
+	// Process sniffs the content by its magic bytes, then resizes and re-encodes it without its metadata
+	Process(content io.Reader) (Image, error)
+}
+
+type imagingLib struct{}
+
+func Init() Interface {
+	return &imagingLib{}
+}
+
+// IsSupported sniffs the magic bytes of the content, the Content-Type sent by the client isn't trusted
+func IsSupported(header []byte) bool {
+	contentType := http.DetectContentType(header)
+	for _, supportedContentType := range supportedContentTypes {
+		if contentType == supportedContentType {
+			return true
+		}
+	}
+
+	return false
+}
+
+// HashContent returns the hex encoded sha256 of the content
+func HashContent(content []byte) string {
+	hash := sha256.Sum256(content)
+	return hex.EncodeToString(hash[:])
+}
+
+// Distance is the number of different bits of two perceptual hashes, near-identical images are within 10
+func Distance(a uint64, b uint64) int {
+	return bits.OnesCount64(a ^ b)
+}
+
+func (i *imagingLib) Process(content io.Reader) (Image, error) {
+	var res Image
+
+	raw, err := io.ReadAll(io.LimitReader(content, MaxFileSize+1))
+	if err != nil {
+		return res, err
+	} else if len(raw) > MaxFileSize {
+		return res, ErrFileTooLarge
+	}
+
+	if !IsSupported(raw) {
+		return res, ErrUnsupportedFormat
+	}
+
+	config, _, err := image.DecodeConfig(bytes.NewReader(raw))
+	if err != nil {
+		return res, ErrInvalidImage
+	} else if config.Width*config.Height > maxPixels {
+		return res, ErrResolutionTooLarge
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(raw))
+	if err != nil {
+		return res, ErrInvalidImage
+	}
+
+	// a png has no exif, a jpeg without it is processed as is
+	orientation := 1
+	if metadata, err := exif.Decode(bytes.NewReader(raw)); err == nil {
+		orientation = getOrientation(metadata)
+		res.CapturedAt = getCapturedAt(metadata)
+		res.Latitude, res.Longitude = getLocation(metadata)
+	}
+
+	// orienting the scaled image is cheaper, the longest side stays the longest whatever the orientation
+	img = orient(scale(img, maxDimension), orientation)
+	res.ContentHash = HashContent(raw)
+	res.PerceptualHash = hash(img)
+
+	if res.Content, err = encode(img); err != nil {
+		return res, err
+	}
+
+	if res.Thumbnail, err = encode(scale(img, thumbnailDimension)); err != nil {
+		return res, err
+	}
+
+	return res, nil
+}
+
+// scale fits the image in the dimension, the transparent pixels of a png are painted white
+func scale(img image.Image, dimension int) image.Image {
+	width, height := fit(img.Bounds().Dx(), img.Bounds().Dy(), dimension)
+
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	draw.Draw(dst, dst.Bounds(), background, image.Point{}, draw.Src)
+	draw.CatmullRom.Scale(dst, dst.Bounds(), img, img.Bounds(), draw.Over, nil)
+
+	return dst
+}
+
+// hash computes the difference hash of the image, each bit tells whether a pixel is brighter than its right neighbour
+func hash(img image.Image) uint64 {
+	gray := image.NewGray(image.Rect(0, 0, hashWidth, hashHeight))
+	draw.ApproxBiLinear.Scale(gray, gray.Bounds(), img, img.Bounds(), draw.Src, nil)
+
+	var res uint64
+	for y := 0; y < hashHeight; y++ {
+		for x := 0; x < hashWidth-1; x++ {
+			res <<= 1
+			if gray.GrayAt(x, y).Y > gray.GrayAt(x+1, y).Y {
+				res |= 1
+			}
+		}
+	}
+
+	return res
+}
+
+func encode(img image.Image) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: jpegQuality}); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// fit keeps the aspect ratio while the longest side is at most the dimension, smaller images aren't enlarged
+func fit(width int, height int, dimension int) (int, int) {
+	if width <= dimension && height <= dimension {
+		return width, height
+	}
+
+	if width >= height {
+		return dimension, max(1, height*dimension/width)
+	}
+
+	return max(1, width*dimension/height), dimension
+}
+
+func max(a int, b int) int {
+	if a > b {
+		return a
+	}
+
+	return b
+}
+
+func getOrientation(metadata *exif.Exif) int {
+	tag, err := metadata.Get(exif.Orientation)
+	if err != nil {
+		return 1
+	}
+
+	orientation, err := tag.Int(0)
+	if err != nil {
+		return 1
+	}
+
+	return orientation
+}
+
+func getCapturedAt(metadata *exif.Exif) *int64 {
+	capturedAt, err := metadata.DateTime()
+	if err != nil {
+		return nil
+	}
+
+	unix := capturedAt.Unix()
+	return &unix
+}
+
+func getLocation(metadata *exif.Exif) (*float64, *float64) {
+	latitude, longitude, err := metadata.LatLong()
+	if err != nil {
+		return nil, nil
+	}
+
+	return &latitude, &longitude
+}
+
+// orient applies the exif orientation, the pixels are stored as the camera sensor read them
+func orient(img image.Image, orientation int) image.Image {
+	if orientation < 2 || orientation > 8 {
+		return img
+	}
+
+	bounds := img.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+
+	// the orientations from 5 to 8 swap the width and the height
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	if orientation >= 5 {
+		dst = image.NewRGBA(image.Rect(0, 0, height, width))
+	}
+
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			pixel := img.At(bounds.Min.X+x, bounds.Min.Y+y)
+			switch orientation {
+			case 2: // mirrored horizontally
+				dst.Set(width-1-x, y, pixel)
+			case 3: // rotated 180
+				dst.Set(width-1-x, height-1-y, pixel)
+			case 4: // mirrored vertically
+				dst.Set(x, height-1-y, pixel)
+			case 5: // transposed
+				dst.Set(y, x, pixel)
+			case 6: // rotated 90 clockwise
+				dst.Set(height-1-y, x, pixel)
+			case 7: // transversed
+				dst.Set(height-1-y, width-1-x, pixel)
+			case 8: // rotated 90 counterclockwise
+				dst.Set(y, width-1-x, pixel)
+			}
+		}
+	}
+
+	return dst
+}
@@ -1,6 +1,11 @@
 package jwt
 
 import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
 	"os"
 	"strconv"
 	"time"
@@ -9,34 +14,66 @@ import (
 	"tigaputera-backend/sdk/error"
 )
 
-type jwtLib struct{}
+// a refresh token is valid for 30 days unless JWT_REFRESH_EXPIRED_TIME_SEC is set
+const defaultRefreshExpiredTimeSec = 30 * 24 * 60 * 60
+
+type jwtLib struct {
+	expiredTime int64
+	signingKey  signingKey
+	// verificationKeys are the public keys by their id, the keys being rotated out stay here until their tokens expire
+	verificationKeys map[string]verificationKey
+}
+
+// RefreshToken is an opaque token exchanged for a new access token, only its hash is stored
+type RefreshToken struct {
+	Token     string
+	Hash      string
+	ExpiresAt int64
+}
 
 type Interface interface {
+	// GenerateToken signs a short-lived access token, it expires after JWT_EXPIRED_TIME_SEC
 	GenerateToken(interface{}) (string, error)
+	// DecodeToken only accepts the tokens signed with RS256 or EdDSA by one of the verification keys
 	DecodeToken(string) (map[string]interface{}, error)
+	GenerateRefreshToken() (RefreshToken, error)
+	// JWKS returns the verification keys, the other services verify the access tokens with them
+	JWKS() JWKS
 }
 
+// Init loads the keys from JWT_KEYS_DIR, the tokens are signed with the key named by JWT_SIGNING_KEY_ID
 func Init() Interface {
-	return &jwtLib{}
-}
-
-func (j *jwtLib) GenerateToken(data interface{}) (string, error) {
 	expTime, err := strconv.ParseInt(os.Getenv("JWT_EXPIRED_TIME_SEC"), 10, 64)
 	if err != nil {
-		return "", err
+		panic(err)
 	}
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+
+	signingKey, verificationKeys, err := loadKeys(os.Getenv("JWT_KEYS_DIR"), os.Getenv("JWT_SIGNING_KEY_ID"))
+	if err != nil {
+		panic(err)
+	}
+
+	return &jwtLib{
+		expiredTime:      expTime,
+		signingKey:       signingKey,
+		verificationKeys: verificationKeys,
+	}
+}
+
+func (j *jwtLib) GenerateToken(data interface{}) (string, error) {
+	now := time.Now().Unix()
+	token := jwt.NewWithClaims(j.signingKey.method, jwt.MapClaims{
 		"data": data,
-		"exp":  expTime + time.Now().Unix(),
+		"iat":  now,
+		"exp":  j.expiredTime + now,
 	})
+	token.Header["kid"] = j.signingKey.id
 
-	return token.SignedString([]byte(os.Getenv("JWT_SECRET_KEY")))
+	return token.SignedString(j.signingKey.key)
 }
 
 func (j *jwtLib) DecodeToken(token string) (map[string]interface{}, error) {
-	decoded, err := jwt.Parse(token, func(t *jwt.Token) (interface{}, error) {
-		return []byte(os.Getenv("JWT_SECRET_KEY")), nil
-	})
+	decoded, err := jwt.Parse(token, j.getVerificationKey, jwt.WithValidMethods(validMethods))
 	if err != nil {
 		return nil, errors.Unauthorized("Invalid token")
 	}
@@ -52,3 +89,47 @@ func (j *jwtLib) DecodeToken(token string) (map[string]interface{}, error) {
 
 	return claims, nil
 }
+
+// getVerificationKey picks the key by the kid header, the algorithm must be the one of the key
+func (j *jwtLib) getVerificationKey(t *jwt.Token) (interface{}, error) {
+	keyID, _ := t.Header["kid"].(string)
+	key, ok := j.verificationKeys[keyID]
+	if !ok {
+		return nil, fmt.Errorf("unknown key id %q", keyID)
+	}
+
+	if t.Method.Alg() != key.method.Alg() {
+		return nil, fmt.Errorf("unexpected signing method %s for key %q", t.Method.Alg(), keyID)
+	}
+
+	return key.key, nil
+}
+
+func (j *jwtLib) GenerateRefreshToken() (RefreshToken, error) {
+	var res RefreshToken
+
+	expTime := int64(defaultRefreshExpiredTimeSec)
+	if refreshExpTime := os.Getenv("JWT_REFRESH_EXPIRED_TIME_SEC"); refreshExpTime != "" {
+		var err error
+		if expTime, err = strconv.ParseInt(refreshExpTime, 10, 64); err != nil {
+			return res, err
+		}
+	}
+
+	token := make([]byte, 32)
+	if _, err := rand.Read(token); err != nil {
+		return res, err
+	}
+
+	res.Token = base64.RawURLEncoding.EncodeToString(token)
+	res.Hash = HashRefreshToken(res.Token)
+	res.ExpiresAt = time.Now().Unix() + expTime
+
+	return res, nil
+}
+
+// HashRefreshToken returns the hash the refresh token is looked up by
+func HashRefreshToken(token string) string {
+	hash := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(hash[:])
+}
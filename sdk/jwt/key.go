@@ -0,0 +1,186 @@
+package jwt
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+const (
+	// a private key is stored as <kid>.pem, its public key is used for the verification as well
+	privateKeyExt = ".pem"
+	// a public key is stored as <kid>.pub, a key keeps verifying its tokens after its private key is removed
+	publicKeyExt = ".pub"
+)
+
+// validMethods are the only algorithms accepted, the hmac and none tokens are rejected
+var validMethods = []string{jwt.SigningMethodRS256.Alg(), jwt.SigningMethodEdDSA.Alg()}
+
+type signingKey struct {
+	id     string
+	method jwt.SigningMethod
+	key    crypto.PrivateKey
+}
+
+type verificationKey struct {
+	method jwt.SigningMethod
+	key    crypto.PublicKey
+}
+
+// JWK is a public key in the json web key format of RFC 7517
+type JWK struct {
+	Kty string `json:"kty"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	Kid string `json:"kid"`
+	// N and E are the modulus and the exponent of a RSA key
+	N string `json:"n,omitempty"`
+	E string `json:"e,omitempty"`
+	// Crv and X are the curve and the public key of an Ed25519 key
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+}
+
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+// loadKeys reads the keys in the directory, every public key of the directory verifies the tokens
+func loadKeys(dir string, signingKeyID string) (signingKey, map[string]verificationKey, error) {
+	var signing signingKey
+	verificationKeys := map[string]verificationKey{}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return signing, nil, fmt.Errorf("failed to read the jwt keys: %w", err)
+	}
+
+	for _, entry := range entries {
+		ext := filepath.Ext(entry.Name())
+		if entry.IsDir() || (ext != privateKeyExt && ext != publicKeyExt) {
+			continue
+		}
+
+		keyID := strings.TrimSuffix(entry.Name(), ext)
+		raw, err := os.ReadFile(filepath.Join(dir, entry.Name()))
+		if err != nil {
+			return signing, nil, err
+		}
+
+		var publicKey crypto.PublicKey
+		if ext == privateKeyExt {
+			privateKey, err := parsePrivateKey(raw)
+			if err != nil {
+				return signing, nil, fmt.Errorf("jwt key %s: %w", entry.Name(), err)
+			}
+
+			if keyID == signingKeyID {
+				signing = signingKey{id: keyID, method: getSigningMethod(privateKey.Public()), key: privateKey}
+			}
+			publicKey = privateKey.Public()
+		} else if publicKey, err = parsePublicKey(raw); err != nil {
+			return signing, nil, fmt.Errorf("jwt key %s: %w", entry.Name(), err)
+		}
+
+		verificationKeys[keyID] = verificationKey{method: getSigningMethod(publicKey), key: publicKey}
+	}
+
+	if signing.key == nil {
+		return signing, nil, fmt.Errorf("jwt signing key %q not found in %s", signingKeyID, dir)
+	}
+
+	return signing, verificationKeys, nil
+}
+
+type privateKey interface {
+	Public() crypto.PublicKey
+}
+
+func parsePrivateKey(raw []byte) (privateKey, error) {
+	block, _ := pem.Decode(raw)
+	if block == nil {
+		return nil, fmt.Errorf("invalid pem")
+	}
+
+	// openssl genrsa before 3.0 writes the rsa keys as pkcs1
+	if block.Type == "RSA PRIVATE KEY" {
+		return x509.ParsePKCS1PrivateKey(block.Bytes)
+	}
+
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+
+	switch key := key.(type) {
+	case *rsa.PrivateKey:
+		return key, nil
+	case ed25519.PrivateKey:
+		return key, nil
+	}
+
+	return nil, fmt.Errorf("only rsa and ed25519 keys are supported")
+}
+
+func parsePublicKey(raw []byte) (crypto.PublicKey, error) {
+	block, _ := pem.Decode(raw)
+	if block == nil {
+		return nil, fmt.Errorf("invalid pem")
+	}
+
+	key, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+
+	switch key.(type) {
+	case *rsa.PublicKey, ed25519.PublicKey:
+		return key, nil
+	}
+
+	return nil, fmt.Errorf("only rsa and ed25519 keys are supported")
+}
+
+// getSigningMethod returns RS256 for a rsa key and EdDSA for an ed25519 key, the parsed keys are one of them
+func getSigningMethod(key crypto.PublicKey) jwt.SigningMethod {
+	if _, ok := key.(ed25519.PublicKey); ok {
+		return jwt.SigningMethodEdDSA
+	}
+
+	return jwt.SigningMethodRS256
+}
+
+func (j *jwtLib) JWKS() JWKS {
+	jwks := JWKS{Keys: []JWK{}}
+	for keyID, key := range j.verificationKeys {
+		jwk := JWK{Use: "sig", Alg: key.method.Alg(), Kid: keyID}
+		switch publicKey := key.key.(type) {
+		case *rsa.PublicKey:
+			jwk.Kty = "RSA"
+			jwk.N = base64.RawURLEncoding.EncodeToString(publicKey.N.Bytes())
+			jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(publicKey.E)).Bytes())
+		case ed25519.PublicKey:
+			jwk.Kty = "OKP"
+			jwk.Crv = "Ed25519"
+			jwk.X = base64.RawURLEncoding.EncodeToString(publicKey)
+		}
+		jwks.Keys = append(jwks.Keys, jwk)
+	}
+
+	sort.Slice(jwks.Keys, func(i, k int) bool {
+		return jwks.Keys[i].Kid < jwks.Keys[k].Kid
+	})
+
+	return jwks
+}
@@ -3,19 +3,45 @@ package password
 import (
 	"os"
 	"strconv"
+	"time"
 
 	"golang.org/x/crypto/bcrypt"
 )
 
-type passwordLib struct{}
+const (
+	defaultMinLength      = 8
+	defaultHistoryCount   = 5
+	defaultExpiredTimeDay = 90
+)
+
+type passwordLib struct {
+	policy Policy
+}
+
+// Policy is enforced whenever a password is set, a zero HistoryCount or ExpiredTime disables its check
+type Policy struct {
+	MinLength int
+	// HistoryCount is how many of the latest passwords of a user can't be used again
+	HistoryCount int
+	// ExpiredTime is how long a password can be used before it must be changed
+	ExpiredTime time.Duration
+}
 
 type Interface interface {
 	Hash(string) (string, error)
 	Compare(string, string) bool
+	Policy() Policy
 }
 
+// Init reads the policy from PASSWORD_MIN_LENGTH, PASSWORD_HISTORY_COUNT, and PASSWORD_EXPIRED_TIME_DAY
 func Init() Interface {
-	return &passwordLib{}
+	return &passwordLib{
+		policy: Policy{
+			MinLength:    getEnvInt("PASSWORD_MIN_LENGTH", defaultMinLength),
+			HistoryCount: getEnvInt("PASSWORD_HISTORY_COUNT", defaultHistoryCount),
+			ExpiredTime:  time.Duration(getEnvInt("PASSWORD_EXPIRED_TIME_DAY", defaultExpiredTimeDay)) * 24 * time.Hour,
+		},
+	}
 }
 
 func (p *passwordLib) Hash(password string) (string, error) {
@@ -34,3 +60,21 @@ func (p *passwordLib) Hash(password string) (string, error) {
 func (p *passwordLib) Compare(hashedPassword, password string) bool {
 	return bcrypt.CompareHashAndPassword([]byte(hashedPassword), []byte(password)) == nil
 }
+
+func (p *passwordLib) Policy() Policy {
+	return p.policy
+}
+
+func getEnvInt(key string, defaultValue int) int {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+
+	res, err := strconv.Atoi(value)
+	if err != nil {
+		panic(err)
+	}
+
+	return res
+}
@@ -0,0 +1,84 @@
+package signedurl
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// how long a signed url stays valid when STORAGE_URL_EXPIRED_TIME_SEC is not set
+const defaultExpiredTime = 15 * time.Minute
+
+// the signatures are hmac-sha256, a shorter key would make them easier to forge
+const minSecretKeyLength = 32
+
+type signedURLLib struct {
+	baseURL     string
+	secretKey   []byte
+	expiredTime time.Duration
+}
+
+// Interface signs the urls of the private storage objects, they are served by the storage route of the api
+type Interface interface {
+	// Sign returns the url of the object, an empty object key has no url
+	Sign(objectKey string) string
+	// Verify reports whether the signature belongs to the object and hasn't expired
+	Verify(objectKey string, expires int64, signature string) bool
+}
+
+func Init() Interface {
+	secretKey := os.Getenv("STORAGE_URL_SECRET_KEY")
+	if len(secretKey) < minSecretKeyLength {
+		panic(fmt.Sprintf("STORAGE_URL_SECRET_KEY must be at least %d bytes", minSecretKeyLength))
+	}
+
+	expiredTime := defaultExpiredTime
+	if expiredTimeSec := os.Getenv("STORAGE_URL_EXPIRED_TIME_SEC"); expiredTimeSec != "" {
+		sec, err := strconv.ParseInt(expiredTimeSec, 10, 64)
+		if err != nil {
+			panic(err)
+		}
+		expiredTime = time.Duration(sec) * time.Second
+	}
+
+	return &signedURLLib{
+		baseURL:     strings.TrimSuffix(os.Getenv("APP_BASE_URL"), "/"),
+		secretKey:   []byte(secretKey),
+		expiredTime: expiredTime,
+	}
+}
+
+func (s *signedURLLib) Sign(objectKey string) string {
+	if objectKey == "" {
+		return ""
+	}
+
+	expires := time.Now().Add(s.expiredTime).Unix()
+
+	query := url.Values{}
+	query.Set("expires", strconv.FormatInt(expires, 10))
+	query.Set("signature", s.getSignature(objectKey, expires))
+
+	return fmt.Sprintf("%s/v1/storage/%s?%s", s.baseURL, objectKey, query.Encode())
+}
+
+func (s *signedURLLib) Verify(objectKey string, expires int64, signature string) bool {
+	if expires < time.Now().Unix() {
+		return false
+	}
+
+	return hmac.Equal([]byte(signature), []byte(s.getSignature(objectKey, expires)))
+}
+
+func (s *signedURLLib) getSignature(objectKey string, expires int64) string {
+	mac := hmac.New(sha256.New, s.secretKey)
+	mac.Write([]byte(fmt.Sprintf("%s:%d", objectKey, expires)))
+
+	return hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,140 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+
+	"tigaputera-backend/sdk/file"
+)
+
+type LocalConfig struct {
+	// Dir is the directory the objects are written to
+	Dir string
+}
+
+type localStorage struct {
+	dir string
+}
+
+func initLocal(config LocalConfig) Interface {
+	if err := os.MkdirAll(config.Dir, 0o750); err != nil {
+		panic(err)
+	}
+
+	return &localStorage{dir: config.Dir}
+}
+
+// getFilePath keeps the object inside the storage directory whatever the path and file name are
+func (s *localStorage) getFilePath(fileName string, path string) string {
+	return filepath.Join(s.dir, filepath.Clean("/"+getObjectPath(fileName, path)))
+}
+
+func (s *localStorage) Upload(
+	ctx context.Context,
+	file *file.File,
+	path string,
+) (string, error) {
+	return s.upload(file.Content, file.Meta.Filename, path)
+}
+
+func (s *localStorage) UploadFromBytes(
+	ctx context.Context,
+	file *bytes.Reader,
+	fileName string,
+	path string,
+) (string, error) {
+	return s.upload(file, fileName, path)
+}
+
+func (s *localStorage) UploadFromReader(
+	ctx context.Context,
+	content io.Reader,
+	fileName string,
+	path string,
+) (string, error) {
+	return s.upload(content, fileName, path)
+}
+
+func (s *localStorage) upload(content io.Reader, fileName string, path string) (string, error) {
+	filePath := s.getFilePath(fileName, path)
+	if err := os.MkdirAll(filepath.Dir(filePath), 0o750); err != nil {
+		return "", err
+	}
+
+	localFile, err := os.Create(filePath)
+	if err != nil {
+		return "", err
+	}
+
+	if _, err := io.Copy(localFile, content); err != nil {
+		localFile.Close()
+		return "", err
+	}
+
+	if err := localFile.Close(); err != nil {
+		return "", err
+	}
+
+	return getObjectPath(fileName, path), nil
+}
+
+func (s *localStorage) Download(
+	ctx context.Context,
+	fileName string,
+	path string,
+) (io.ReadCloser, error) {
+	localFile, err := os.Open(s.getFilePath(fileName, path))
+	if os.IsNotExist(err) {
+		return nil, ErrObjectNotFound
+	} else if err != nil {
+		return nil, err
+	}
+
+	return localFile, nil
+}
+
+func (s *localStorage) Delete(
+	ctx context.Context,
+	fileName string,
+	path string,
+) error {
+	err := os.Remove(s.getFilePath(fileName, path))
+	if os.IsNotExist(err) {
+		return ErrObjectNotFound
+	}
+
+	return err
+}
+
+func (s *localStorage) List(ctx context.Context, path string) ([]Object, error) {
+	objects := []Object{}
+
+	entries, err := os.ReadDir(filepath.Join(s.dir, filepath.Clean("/"+path)))
+	if os.IsNotExist(err) {
+		return objects, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+
+		info, err := entry.Info()
+		if err != nil {
+			return nil, err
+		}
+
+		objects = append(objects, Object{
+			FileName:  entry.Name(),
+			Path:      path,
+			UpdatedAt: info.ModTime(),
+		})
+	}
+
+	return objects, nil
+}
@@ -0,0 +1,153 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"strings"
+
+	"tigaputera-backend/sdk/file"
+
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
+)
+
+// the part size of the uploads of an unknown size, the smallest part S3 accepts is 5 MiB
+const s3StreamPartSize = 16 << 20
+
+type S3Config struct {
+	Endpoint        string
+	AccessKeyID     string
+	SecretAccessKey string
+	Region          string
+	UseSSL          bool
+}
+
+type s3Storage struct {
+	client     *minio.Client
+	bucketName string
+}
+
+func initS3(config S3Config, bucketName string) Interface {
+	client, err := minio.New(config.Endpoint, &minio.Options{
+		Creds:  credentials.NewStaticV4(config.AccessKeyID, config.SecretAccessKey, ""),
+		Secure: config.UseSSL,
+		Region: config.Region,
+	})
+	if err != nil {
+		panic(err)
+	}
+
+	return &s3Storage{
+		client:     client,
+		bucketName: bucketName,
+	}
+}
+
+func (s *s3Storage) Upload(
+	ctx context.Context,
+	file *file.File,
+	path string,
+) (string, error) {
+	return s.upload(
+		ctx,
+		file.Content,
+		file.Meta.Size,
+		file.Meta.Header.Get("Content-Type"),
+		file.Meta.Filename,
+		path,
+	)
+}
+
+func (s *s3Storage) UploadFromBytes(
+	ctx context.Context,
+	file *bytes.Reader,
+	fileName string,
+	path string,
+) (string, error) {
+	return s.upload(ctx, file, file.Size(), "", fileName, path)
+}
+
+func (s *s3Storage) UploadFromReader(
+	ctx context.Context,
+	content io.Reader,
+	fileName string,
+	path string,
+) (string, error) {
+	return s.upload(ctx, content, -1, "", fileName, path)
+}
+
+func (s *s3Storage) upload(
+	ctx context.Context,
+	content io.Reader,
+	size int64,
+	contentType string,
+	fileName string,
+	path string,
+) (string, error) {
+	options := minio.PutObjectOptions{ContentType: contentType}
+	if size < 0 {
+		// an unknown size is sent in parts, each one is buffered
+		options.PartSize = s3StreamPartSize
+	}
+
+	objectPath := getObjectPath(fileName, path)
+	if _, err := s.client.PutObject(ctx, s.bucketName, objectPath, content, size, options); err != nil {
+		return "", err
+	}
+
+	return objectPath, nil
+}
+
+func (s *s3Storage) Download(
+	ctx context.Context,
+	fileName string,
+	path string,
+) (io.ReadCloser, error) {
+	objectPath := getObjectPath(fileName, path)
+
+	// GetObject is lazy, stat the object so a missing one is reported here
+	if _, err := s.client.StatObject(ctx, s.bucketName, objectPath, minio.StatObjectOptions{}); err != nil {
+		if minio.ToErrorResponse(err).Code == "NoSuchKey" {
+			return nil, ErrObjectNotFound
+		}
+		return nil, err
+	}
+
+	return s.client.GetObject(ctx, s.bucketName, objectPath, minio.GetObjectOptions{})
+}
+
+func (s *s3Storage) Delete(
+	ctx context.Context,
+	fileName string,
+	path string,
+) error {
+	return s.client.RemoveObject(ctx, s.bucketName, getObjectPath(fileName, path), minio.RemoveObjectOptions{})
+}
+
+func (s *s3Storage) List(ctx context.Context, path string) ([]Object, error) {
+	objects := []Object{}
+
+	// the listing stops once its context is canceled
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	// without Recursive the sub folders are listed as keys ending with a slash
+	for info := range s.client.ListObjects(ctx, s.bucketName, minio.ListObjectsOptions{Prefix: path + "/"}) {
+		if info.Err != nil {
+			return nil, info.Err
+		}
+
+		if strings.HasSuffix(info.Key, "/") {
+			continue
+		}
+
+		objects = append(objects, Object{
+			FileName:  strings.TrimPrefix(info.Key, path+"/"),
+			Path:      path,
+			UpdatedAt: info.LastModified,
+		})
+	}
+
+	return objects, nil
+}
@@ -0,0 +1,159 @@
+package totp
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/base32"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"tigaputera-backend/sdk/cryptolib"
+)
+
+const (
+	// the authenticator apps only agree on sha1, 6 digits, and 30 seconds
+	digits     = 6
+	modulo     = 1000000
+	period     = 30
+	secretSize = 20
+	// a code of the step before or after the current one is accepted for the clock drift of the phone
+	skewSteps = 1
+
+	defaultIssuer = "Tigaputera"
+)
+
+var encoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+type totpLib struct {
+	issuer    string
+	cryptolib cryptolib.Interface
+}
+
+// Secret is a new secret, the plain secret is shown once for the enrollment and only the encrypted one is stored
+type Secret struct {
+	Plain     string
+	Encrypted string
+}
+
+// Interface implements the time-based one-time passwords of RFC 6238
+type Interface interface {
+	GenerateSecret() (Secret, error)
+	// GetProvisioningURI returns the otpauth uri the authenticator apps scan as a qr code
+	GetProvisioningURI(secret string, accountName string) string
+	// Verify returns the time step of the code, a step can only be used once so the caller keeps the last one
+	Verify(encryptedSecret string, code string) (int64, bool)
+}
+
+// Init encrypts the secrets with cryptolib, the issuer shown by the authenticator apps is TOTP_ISSUER
+func Init(cryptolib cryptolib.Interface) Interface {
+	issuer := os.Getenv("TOTP_ISSUER")
+	if issuer == "" {
+		issuer = defaultIssuer
+	}
+
+	return &totpLib{
+		issuer:    issuer,
+		cryptolib: cryptolib,
+	}
+}
+
+func (t *totpLib) GenerateSecret() (Secret, error) {
+	raw := make([]byte, secretSize)
+	if _, err := rand.Read(raw); err != nil {
+		return Secret{}, err
+	}
+
+	secret := encoding.EncodeToString(raw)
+
+	return Secret{
+		Plain:     secret,
+		Encrypted: hex.EncodeToString([]byte(t.cryptolib.Encrypt(secret))),
+	}, nil
+}
+
+func (t *totpLib) GetProvisioningURI(secret string, accountName string) string {
+	query := url.Values{}
+	query.Set("secret", secret)
+	query.Set("issuer", t.issuer)
+	query.Set("algorithm", "SHA1")
+	query.Set("digits", fmt.Sprint(digits))
+	query.Set("period", fmt.Sprint(period))
+
+	label := url.PathEscape(t.issuer + ":" + accountName)
+
+	return fmt.Sprintf("otpauth://totp/%s?%s", label, query.Encode())
+}
+
+func (t *totpLib) Verify(encryptedSecret string, code string) (int64, bool) {
+	cipherText, err := hex.DecodeString(encryptedSecret)
+	if err != nil {
+		return 0, false
+	}
+
+	secret := t.cryptolib.Decrypt(string(cipherText))
+	step := time.Now().Unix() / period
+	for i := -skewSteps; i <= skewSteps; i++ {
+		expected, err := getCode(secret, step+int64(i))
+		if err != nil {
+			return 0, false
+		}
+
+		if hmac.Equal([]byte(expected), []byte(code)) {
+			return step + int64(i), true
+		}
+	}
+
+	return 0, false
+}
+
+// GenerateCode returns the code of the secret at the time, the same code an authenticator app shows
+func GenerateCode(secret string, at time.Time) (string, error) {
+	return getCode(secret, at.Unix()/period)
+}
+
+func getCode(secret string, step int64) (string, error) {
+	key, err := encoding.DecodeString(strings.ToUpper(secret))
+	if err != nil {
+		return "", err
+	}
+
+	counter := make([]byte, 8)
+	binary.BigEndian.PutUint64(counter, uint64(step))
+
+	mac := hmac.New(sha1.New, key)
+	mac.Write(counter)
+	sum := mac.Sum(nil)
+
+	// the dynamic truncation of RFC 4226
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+
+	return fmt.Sprintf("%0*d", digits, value%modulo), nil
+}
+
+// GenerateRecoveryCode returns a single-use code for a lost authenticator, e.g. k7f2q-x9m4d
+func GenerateRecoveryCode() (string, error) {
+	raw := make([]byte, 7)
+	if _, err := rand.Read(raw); err != nil {
+		return "", err
+	}
+
+	code := strings.ToLower(encoding.EncodeToString(raw))[:10]
+
+	return code[:5] + "-" + code[5:], nil
+}
+
+// HashRecoveryCode returns the hash the recovery code is stored as, the case and the dashes are ignored
+func HashRecoveryCode(code string) string {
+	normalized := strings.ToLower(strings.NewReplacer("-", "", " ", "").Replace(code))
+	hash := sha256.Sum256([]byte(normalized))
+
+	return hex.EncodeToString(hash[:])
+}
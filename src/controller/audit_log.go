@@ -0,0 +1,41 @@
+package controller
+
+import (
+	"tigaputera-backend/src/model"
+
+	"github.com/gin-gonic/gin"
+)
+
+// @Summary Get list audit log
+// @Description Get the audit logs of the writes, filtered by the entity, the user and the date range
+// @Tags Audit
+// @Produce json
+// @Security BearerAuth
+// @param entity query string false "table name, e.g. projects"
+// @param entity_id query int false "entity_id"
+// @param user_id query int false "user_id"
+// @param from query int false "unix timestamp"
+// @param to query int false "unix timestamp"
+// @param limit query int false "limit"
+// @param page query int false "page"
+// @Success 200 {object} model.HTTPResponse{data=[]model.AuditLog}
+// @Failure 400 {object} model.HTTPResponse{}
+// @Failure 401 {object} model.HTTPResponse{}
+// @Failure 500 {object} model.HTTPResponse{}
+// @Router /v1/audit-log [GET]
+func (r *rest) GetListAuditLog(c *gin.Context) {
+	ctx := c.Request.Context()
+	var param model.AuditLogParam
+	if err := r.BindParam(c, &param); err != nil {
+		r.ErrorResponse(c, err)
+		return
+	}
+
+	logs, err := r.svc.Audit.GetListAuditLog(ctx, &param)
+	if err != nil {
+		r.ErrorResponse(c, err)
+		return
+	}
+
+	r.SuccessResponse(c, "Berhasil menampilkan log audit", logs, &param.PaginationParam)
+}
@@ -0,0 +1,201 @@
+package controller
+
+import (
+	"tigaputera-backend/sdk/auth"
+	errors "tigaputera-backend/sdk/error"
+	"tigaputera-backend/src/model"
+
+	"github.com/gin-gonic/gin"
+)
+
+// @Summary Get List Expenditure Approval
+// @Description Get the expenditure transactions of a project by approval status, inspectors only see their own
+// @Tags Expenditure Approval
+// @Produce json
+// @Security BearerAuth
+// @Param project_id path int true "project_id"
+// @Param status query string false "status"
+// @Param page query int false "page"
+// @Param limit query int false "limit"
+// @Success 200 {object} model.HTTPResponse{data=[]model.ExpenditureApprovalResponse}
+// @Failure 400 {object} model.HTTPResponse{}
+// @Failure 401 {object} model.HTTPResponse{}
+// @Failure 500 {object} model.HTTPResponse{}
+// @Router /v1/project/{project_id}/expenditure/approval [GET]
+func (r *rest) GetListExpenditureApproval(c *gin.Context) {
+	ctx := c.Request.Context()
+	var param model.ExpenditureApprovalParam
+
+	if err := r.BindParam(c, &param); err != nil {
+		r.ErrorResponse(c, err)
+		return
+	}
+
+	approvals, err := r.svc.Ledger.GetListExpenditureApproval(ctx, auth.GetUser(ctx), &param)
+	if err != nil {
+		r.ErrorResponse(c, err)
+		return
+	}
+
+	r.SuccessResponse(c, "Berhasil mendapatkan list persetujuan pengeluaran", approvals, &param.PaginationParam)
+}
+
+// @Summary Approve Expenditure Transaction
+// @Description Approve a pending expenditure transaction and post it to the ledger
+// @Tags Expenditure Approval
+// @Produce json
+// @Security BearerAuth
+// @Param Idempotency-Key header string false "Idempotency-Key"
+// @Param project_id path int true "project_id"
+// @Param expenditure_id path int true "expenditure_id"
+// @Param transaction_id path int true "transaction_id"
+// @Success 200 {object} model.HTTPResponse{}
+// @Failure 400 {object} model.HTTPResponse{}
+// @Failure 401 {object} model.HTTPResponse{}
+// @Failure 404 {object} model.HTTPResponse{}
+// @Failure 409 {object} model.HTTPResponse{}
+// @Failure 422 {object} model.HTTPResponse{}
+// @Failure 500 {object} model.HTTPResponse{}
+// @Router /v1/project/{project_id}/expenditure/{expenditure_id}/transaction/{transaction_id}/approve [PATCH]
+func (r *rest) ApproveExpenditureTransaction(c *gin.Context) {
+	ctx := c.Request.Context()
+	var param model.ExpenditureApprovalParam
+
+	if err := r.BindParam(c, &param); err != nil {
+		r.ErrorResponse(c, err)
+		return
+	}
+
+	if err := r.svc.Ledger.ApproveExpenditureTransaction(ctx, auth.GetUser(ctx), param); err != nil {
+		r.ErrorResponse(c, err)
+		return
+	}
+
+	r.SuccessResponse(c, "Berhasil menyetujui pengeluaran proyek", nil, nil)
+}
+
+// @Summary Reject Expenditure Transaction
+// @Description Reject a pending expenditure transaction with a reason for the inspector
+// @Tags Expenditure Approval
+// @Produce json
+// @Security BearerAuth
+// @Param project_id path int true "project_id"
+// @Param expenditure_id path int true "expenditure_id"
+// @Param transaction_id path int true "transaction_id"
+// @Param rejectExpenditureBody body model.RejectExpenditureBody true "body"
+// @Success 200 {object} model.HTTPResponse{}
+// @Failure 400 {object} model.HTTPResponse{}
+// @Failure 401 {object} model.HTTPResponse{}
+// @Failure 404 {object} model.HTTPResponse{}
+// @Failure 500 {object} model.HTTPResponse{}
+// @Router /v1/project/{project_id}/expenditure/{expenditure_id}/transaction/{transaction_id}/reject [PATCH]
+func (r *rest) RejectExpenditureTransaction(c *gin.Context) {
+	ctx := c.Request.Context()
+	var param model.ExpenditureApprovalParam
+	var body model.RejectExpenditureBody
+
+	if err := r.BindParam(c, &param); err != nil {
+		r.ErrorResponse(c, err)
+		return
+	}
+
+	if err := r.BindBody(c, &body); err != nil {
+		r.ErrorResponse(c, err)
+		return
+	}
+
+	if err := r.validator.ValidateStruct(body); err != nil {
+		r.ErrorResponse(c, errors.BadRequest(err.Error()))
+		return
+	}
+
+	if err := r.svc.Ledger.RejectExpenditureTransaction(ctx, auth.GetUser(ctx), param, body); err != nil {
+		r.ErrorResponse(c, err)
+		return
+	}
+
+	r.SuccessResponse(c, "Berhasil menolak pengeluaran proyek", nil, nil)
+}
+
+// @Summary Update Project Approval Threshold
+// @Description Expenditure transactions above the threshold wait for a director approval, an empty threshold turns the approval off
+// @Tags Expenditure Approval
+// @Produce json
+// @Security BearerAuth
+// @Param project_id path int true "project_id"
+// @Param updateApprovalThresholdBody body model.UpdateApprovalThresholdBody true "body"
+// @Success 200 {object} model.HTTPResponse{}
+// @Failure 400 {object} model.HTTPResponse{}
+// @Failure 401 {object} model.HTTPResponse{}
+// @Failure 404 {object} model.HTTPResponse{}
+// @Failure 500 {object} model.HTTPResponse{}
+// @Router /v1/project/{project_id}/approval-threshold [PATCH]
+func (r *rest) UpdateProjectApprovalThreshold(c *gin.Context) {
+	ctx := c.Request.Context()
+	var param model.ProjectParam
+	var body model.UpdateApprovalThresholdBody
+
+	if err := r.BindParam(c, &param); err != nil {
+		r.ErrorResponse(c, err)
+		return
+	}
+
+	if err := r.BindBody(c, &body); err != nil {
+		r.ErrorResponse(c, err)
+		return
+	}
+
+	if err := r.validator.ValidateStruct(body); err != nil {
+		r.ErrorResponse(c, errors.BadRequest(err.Error()))
+		return
+	}
+
+	if err := r.svc.Project.UpdateProjectApprovalThreshold(ctx, auth.GetUser(ctx), param.ID, body); err != nil {
+		r.ErrorResponse(c, err)
+		return
+	}
+
+	r.SuccessResponse(c, "Berhasil mengubah batas persetujuan proyek", nil, nil)
+}
+
+// @Summary Update Expenditure Approval Threshold
+// @Description Override the approval threshold of the project for an expenditure, an empty threshold uses the project threshold
+// @Tags Expenditure Approval
+// @Produce json
+// @Security BearerAuth
+// @Param project_id path int true "project_id"
+// @Param expenditure_id path int true "expenditure_id"
+// @Param updateApprovalThresholdBody body model.UpdateApprovalThresholdBody true "body"
+// @Success 200 {object} model.HTTPResponse{}
+// @Failure 400 {object} model.HTTPResponse{}
+// @Failure 401 {object} model.HTTPResponse{}
+// @Failure 404 {object} model.HTTPResponse{}
+// @Failure 500 {object} model.HTTPResponse{}
+// @Router /v1/project/{project_id}/expenditure/{expenditure_id}/approval-threshold [PATCH]
+func (r *rest) UpdateExpenditureApprovalThreshold(c *gin.Context) {
+	ctx := c.Request.Context()
+	var param model.ExpenditureDetailParam
+	var body model.UpdateApprovalThresholdBody
+
+	if err := r.BindParam(c, &param); err != nil {
+		r.ErrorResponse(c, err)
+		return
+	}
+
+	if err := r.BindBody(c, &body); err != nil {
+		r.ErrorResponse(c, err)
+		return
+	}
+
+	if err := r.validator.ValidateStruct(body); err != nil {
+		r.ErrorResponse(c, errors.BadRequest(err.Error()))
+		return
+	}
+
+	if err := r.svc.Project.UpdateExpenditureApprovalThreshold(ctx, auth.GetUser(ctx), param, body); err != nil {
+		r.ErrorResponse(c, err)
+		return
+	}
+
+	r.SuccessResponse(c, "Berhasil mengubah batas persetujuan pengeluaran proyek", nil, nil)
+}
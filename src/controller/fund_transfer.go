@@ -0,0 +1,156 @@
+package controller
+
+import (
+	"tigaputera-backend/sdk/auth"
+	errors "tigaputera-backend/sdk/error"
+	"tigaputera-backend/sdk/file"
+	"tigaputera-backend/src/model"
+
+	"github.com/gin-gonic/gin"
+)
+
+// @Summary Create Fund Transfer
+// @Description Send funds to the inspector of a project, the funds are posted after the inspector confirms the receipt
+// @Tags Fund Transfer
+// @Produce json
+// @Security BearerAuth
+// @Param Idempotency-Key header string false "Idempotency-Key"
+// @Param project_id path int true "project_id"
+// @Param createFundTransferBody body model.CreateFundTransferBody true "body"
+// @Success 201 {object} model.HTTPResponse{}
+// @Failure 400 {object} model.HTTPResponse{}
+// @Failure 401 {object} model.HTTPResponse{}
+// @Failure 404 {object} model.HTTPResponse{}
+// @Failure 422 {object} model.HTTPResponse{}
+// @Failure 500 {object} model.HTTPResponse{}
+// @Router /v1/project/{project_id}/transfer [POST]
+func (r *rest) CreateFundTransfer(c *gin.Context) {
+	ctx := c.Request.Context()
+	var param model.FundTransferParam
+	var body model.CreateFundTransferBody
+
+	if err := r.BindParam(c, &param); err != nil {
+		r.ErrorResponse(c, err)
+		return
+	}
+
+	if err := r.BindBody(c, &body); err != nil {
+		r.ErrorResponse(c, err)
+		return
+	}
+
+	if err := r.validator.ValidateStruct(body); err != nil {
+		r.ErrorResponse(c, errors.BadRequest(err.Error()))
+		return
+	}
+
+	if err := r.svc.Ledger.CreateFundTransfer(ctx, auth.GetUser(ctx), param, body); err != nil {
+		r.ErrorResponse(c, err)
+		return
+	}
+
+	r.CreatedResponse(c, "Berhasil mengirim dana ke pengawas", nil)
+}
+
+// @Summary Get List Fund Transfer
+// @Description Get list fund transfer of a project
+// @Tags Fund Transfer
+// @Produce json
+// @Security BearerAuth
+// @Param project_id path int true "project_id"
+// @Param page query int false "page"
+// @Param limit query int false "limit"
+// @Success 200 {object} model.HTTPResponse{data=[]model.FundTransferResponse}
+// @Failure 401 {object} model.HTTPResponse{}
+// @Failure 500 {object} model.HTTPResponse{}
+// @Router /v1/project/{project_id}/transfer [GET]
+func (r *rest) GetListFundTransfer(c *gin.Context) {
+	ctx := c.Request.Context()
+	var param model.FundTransferParam
+
+	if err := r.BindParam(c, &param); err != nil {
+		r.ErrorResponse(c, err)
+		return
+	}
+
+	transferResponses, err := r.svc.Ledger.GetListFundTransfer(ctx, auth.GetUser(ctx), &param)
+	if err != nil {
+		r.ErrorResponse(c, err)
+		return
+	}
+
+	r.SuccessResponse(c, "Berhasil mendapatkan list transfer dana", transferResponses, &param.PaginationParam)
+}
+
+// @Summary Confirm Fund Transfer
+// @Description Confirm the receipt of a fund transfer, both legs of the transfer are posted to the ledger
+// @Tags Fund Transfer
+// @Produce json
+// @Security BearerAuth
+// @Param Idempotency-Key header string false "Idempotency-Key"
+// @Param project_id path int true "project_id"
+// @Param transfer_id path int true "transfer_id"
+// @Param receiptImage formData file true "receiptImage, up to 5 png, jpg, jpeg, or pdf files"
+// @Accept multipart/form-data
+// @Success 200 {object} model.HTTPResponse{}
+// @Failure 400 {object} model.HTTPResponse{}
+// @Failure 401 {object} model.HTTPResponse{}
+// @Failure 404 {object} model.HTTPResponse{}
+// @Failure 409 {object} model.HTTPResponse{}
+// @Failure 422 {object} model.HTTPResponse{}
+// @Failure 500 {object} model.HTTPResponse{}
+// @Router /v1/project/{project_id}/transfer/{transfer_id}/confirm [POST]
+func (r *rest) ConfirmFundTransfer(c *gin.Context) {
+	ctx := c.Request.Context()
+	var param model.FundTransferParam
+
+	if err := r.BindParam(c, &param); err != nil {
+		r.ErrorResponse(c, err)
+		return
+	}
+
+	attachments, err := r.getAttachments(c)
+	if err != nil {
+		r.ErrorResponse(c, err)
+		return
+	}
+	defer file.CloseAll(attachments)
+
+	if err := r.svc.Ledger.ConfirmFundTransfer(ctx, auth.GetUser(ctx), param, attachments); err != nil {
+		r.ErrorResponse(c, err)
+		return
+	}
+
+	r.SuccessResponse(c, "Berhasil mengonfirmasi penerimaan dana", nil, nil)
+}
+
+// @Summary Cancel Fund Transfer
+// @Description Cancel a fund transfer that isn't confirmed yet
+// @Tags Fund Transfer
+// @Produce json
+// @Security BearerAuth
+// @Param Idempotency-Key header string false "Idempotency-Key"
+// @Param project_id path int true "project_id"
+// @Param transfer_id path int true "transfer_id"
+// @Success 200 {object} model.HTTPResponse{}
+// @Failure 401 {object} model.HTTPResponse{}
+// @Failure 404 {object} model.HTTPResponse{}
+// @Failure 422 {object} model.HTTPResponse{}
+// @Failure 500 {object} model.HTTPResponse{}
+// @Router /v1/project/{project_id}/transfer/{transfer_id} [DELETE]
+func (r *rest) CancelFundTransfer(c *gin.Context) {
+	ctx := c.Request.Context()
+	var param model.FundTransferParam
+
+	if err := r.BindParam(c, &param); err != nil {
+		r.ErrorResponse(c, err)
+		return
+	}
+
+	if err := r.svc.Ledger.CancelFundTransfer(ctx, auth.GetUser(ctx), param); err != nil {
+		r.ErrorResponse(c, err)
+		return
+	}
+
+	r.SuccessResponse(c, "Berhasil membatalkan transfer dana", nil, nil)
+}
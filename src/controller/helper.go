@@ -1,9 +1,10 @@
 package controller
 
 import (
+	"crypto/subtle"
 	"fmt"
+	"os"
 	"time"
-	"strings"
 
 	"github.com/gin-gonic/gin"
 	"github.com/gin-gonic/gin/binding"
@@ -76,16 +77,12 @@ func getRequestMetadata(ctx *gin.Context) model.Meta {
 	return meta
 }
 
-func (r *rest) isUniqueKeyViolation(err error) bool {
-	if err == nil {
+// isSchedulerKeyValid checks the scheduler-key header of the scheduled jobs, they are refused when SCHEDULER_KEY isn't set
+func isSchedulerKeyValid(ctx *gin.Context) bool {
+	schedulerKey := os.Getenv("SCHEDULER_KEY")
+	if schedulerKey == "" {
 		return false
 	}
-	return strings.Contains(err.Error(), "duplicate key value violates unique constraint")
-}
 
-func (r *rest) isNoRecordFound(err error) bool {
-	if err == nil {
-		return false
-	}
-	return strings.Contains(err.Error(), "record not found")
-}
\ No newline at end of file
+	return subtle.ConstantTimeCompare([]byte(ctx.Request.Header.Get("scheduler-key")), []byte(schedulerKey)) == 1
+}
@@ -0,0 +1,134 @@
+package controller
+
+import (
+	"tigaputera-backend/sdk/auth"
+	"tigaputera-backend/sdk/file"
+	"tigaputera-backend/src/model"
+
+	"github.com/gin-gonic/gin"
+)
+
+// @Summary Get Project Transaction Attachments
+// @Description Get the receipt files of a project transaction
+// @Tags Project Ledger
+// @Produce json
+// @Security BearerAuth
+// @Param project_id path int true "project_id"
+// @Param transaction_id path int true "transaction_id"
+// @Success 200 {object} model.HTTPResponse{data=[]model.LedgerAttachmentResponse}
+// @Failure 401 {object} model.HTTPResponse{}
+// @Failure 404 {object} model.HTTPResponse{}
+// @Failure 500 {object} model.HTTPResponse{}
+// @Router /v1/project/{project_id}/ledger/{transaction_id}/attachment [GET]
+func (r *rest) GetLedgerAttachments(c *gin.Context) {
+	ctx := c.Request.Context()
+	var param model.LedgerAttachmentParam
+
+	if err := r.BindParam(c, &param); err != nil {
+		r.ErrorResponse(c, err)
+		return
+	}
+
+	attachments, err := r.svc.Ledger.GetLedgerAttachments(ctx, auth.GetUser(ctx), param)
+	if err != nil {
+		r.ErrorResponse(c, err)
+		return
+	}
+
+	r.SuccessResponse(c, "Berhasil mendapatkan bukti transaksi", attachments, nil)
+}
+
+// @Summary Add Project Transaction Attachments
+// @Description Add receipt files to an income or expenditure transaction
+// @Tags Project Ledger
+// @Produce json
+// @Security BearerAuth
+// @Param Idempotency-Key header string false "Idempotency-Key"
+// @Param project_id path int true "project_id"
+// @Param transaction_id path int true "transaction_id"
+// @Param receiptImage formData file true "receiptImage, up to 5 png, jpg, jpeg, or pdf files"
+// @Accept multipart/form-data
+// @Success 201 {object} model.HTTPResponse{}
+// @Failure 400 {object} model.HTTPResponse{}
+// @Failure 401 {object} model.HTTPResponse{}
+// @Failure 404 {object} model.HTTPResponse{}
+// @Failure 409 {object} model.HTTPResponse{}
+// @Failure 422 {object} model.HTTPResponse{}
+// @Failure 500 {object} model.HTTPResponse{}
+// @Router /v1/project/{project_id}/ledger/{transaction_id}/attachment [POST]
+func (r *rest) AddLedgerAttachments(c *gin.Context) {
+	ctx := c.Request.Context()
+	var param model.LedgerAttachmentParam
+
+	if err := r.BindParam(c, &param); err != nil {
+		r.ErrorResponse(c, err)
+		return
+	}
+
+	attachments, err := r.getAttachments(c)
+	if err != nil {
+		r.ErrorResponse(c, err)
+		return
+	}
+	defer file.CloseAll(attachments)
+
+	if err := r.svc.Ledger.AddLedgerAttachments(ctx, auth.GetUser(ctx), param, attachments); err != nil {
+		r.ErrorResponse(c, err)
+		return
+	}
+
+	r.CreatedResponse(c, "Berhasil menambahkan bukti transaksi", nil)
+}
+
+// @Summary Delete Project Transaction Attachment
+// @Description Delete a receipt file of a transaction, a transaction keeps at least one receipt
+// @Tags Project Ledger
+// @Produce json
+// @Security BearerAuth
+// @Param Idempotency-Key header string false "Idempotency-Key"
+// @Param project_id path int true "project_id"
+// @Param transaction_id path int true "transaction_id"
+// @Param attachment_id path int true "attachment_id"
+// @Success 200 {object} model.HTTPResponse{}
+// @Failure 400 {object} model.HTTPResponse{}
+// @Failure 401 {object} model.HTTPResponse{}
+// @Failure 404 {object} model.HTTPResponse{}
+// @Failure 409 {object} model.HTTPResponse{}
+// @Failure 422 {object} model.HTTPResponse{}
+// @Failure 500 {object} model.HTTPResponse{}
+// @Router /v1/project/{project_id}/ledger/{transaction_id}/attachment/{attachment_id} [DELETE]
+func (r *rest) DeleteLedgerAttachment(c *gin.Context) {
+	ctx := c.Request.Context()
+	var param model.LedgerAttachmentParam
+
+	if err := r.BindParam(c, &param); err != nil {
+		r.ErrorResponse(c, err)
+		return
+	}
+
+	if err := r.svc.Ledger.DeleteLedgerAttachment(ctx, auth.GetUser(ctx), param); err != nil {
+		r.ErrorResponse(c, err)
+		return
+	}
+
+	r.SuccessResponse(c, "Berhasil menghapus bukti transaksi", nil, nil)
+}
+
+// @Summary Get Duplicate Receipt Report
+// @Description List the receipts near-identical to the receipt of another transaction across the projects
+// @Tags Ledger
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} model.HTTPResponse{data=[]model.DuplicateReceiptResponse}
+// @Failure 401 {object} model.HTTPResponse{}
+// @Failure 500 {object} model.HTTPResponse{}
+// @Router /v1/ledger/duplicate-receipt [GET]
+func (r *rest) GetDuplicateReceiptReport(c *gin.Context) {
+	report, err := r.svc.Ledger.GetDuplicateReceiptReport(c.Request.Context())
+	if err != nil {
+		r.ErrorResponse(c, err)
+		return
+	}
+
+	r.SuccessResponse(c, "Berhasil mendapatkan laporan bukti transaksi ganda", report, nil)
+}
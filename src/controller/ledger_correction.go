@@ -0,0 +1,87 @@
+package controller
+
+import (
+	"tigaputera-backend/sdk/auth"
+	errors "tigaputera-backend/sdk/error"
+	"tigaputera-backend/src/model"
+
+	"github.com/gin-gonic/gin"
+)
+
+// @Summary Cancel Project Income
+// @Description Cancel a mistyped project income by posting its reversal
+// @Tags Project Ledger
+// @Produce json
+// @Security BearerAuth
+// @Param Idempotency-Key header string false "Idempotency-Key"
+// @Param project_id path int true "project_id"
+// @Param transaction_id path int true "transaction_id"
+// @Success 200 {object} model.HTTPResponse{}
+// @Failure 400 {object} model.HTTPResponse{}
+// @Failure 401 {object} model.HTTPResponse{}
+// @Failure 404 {object} model.HTTPResponse{}
+// @Failure 409 {object} model.HTTPResponse{}
+// @Failure 422 {object} model.HTTPResponse{}
+// @Failure 500 {object} model.HTTPResponse{}
+// @Router /v1/project/{project_id}/income/{transaction_id} [DELETE]
+func (r *rest) DeleteIncomeTransaction(c *gin.Context) {
+	ctx := c.Request.Context()
+	var param model.LedgerTransactionParam
+
+	if err := r.BindParam(c, &param); err != nil {
+		r.ErrorResponse(c, err)
+		return
+	}
+
+	if err := r.svc.Ledger.CancelIncomeTransaction(ctx, auth.GetUser(ctx), param); err != nil {
+		r.ErrorResponse(c, err)
+		return
+	}
+
+	r.SuccessResponse(c, "Berhasil membatalkan pemasukan proyek", nil, nil)
+}
+
+// @Summary Correct Project Transaction
+// @Description Replace a posted income or expenditure transaction, the reversal and the replacement are posted together
+// @Tags Project Ledger
+// @Produce json
+// @Security BearerAuth
+// @Param Idempotency-Key header string false "Idempotency-Key"
+// @Param project_id path int true "project_id"
+// @Param transaction_id path int true "transaction_id"
+// @Param correctLedgerBody body model.CorrectLedgerBody true "body"
+// @Success 201 {object} model.HTTPResponse{}
+// @Failure 400 {object} model.HTTPResponse{}
+// @Failure 401 {object} model.HTTPResponse{}
+// @Failure 404 {object} model.HTTPResponse{}
+// @Failure 409 {object} model.HTTPResponse{}
+// @Failure 422 {object} model.HTTPResponse{}
+// @Failure 500 {object} model.HTTPResponse{}
+// @Router /v1/project/{project_id}/ledger/{transaction_id}/correction [POST]
+func (r *rest) CorrectTransaction(c *gin.Context) {
+	ctx := c.Request.Context()
+	var param model.LedgerTransactionParam
+	var body model.CorrectLedgerBody
+
+	if err := r.BindParam(c, &param); err != nil {
+		r.ErrorResponse(c, err)
+		return
+	}
+
+	if err := r.BindBody(c, &body); err != nil {
+		r.ErrorResponse(c, err)
+		return
+	}
+
+	if err := r.validator.ValidateStruct(body); err != nil {
+		r.ErrorResponse(c, errors.BadRequest(err.Error()))
+		return
+	}
+
+	if err := r.svc.Ledger.CorrectTransaction(ctx, auth.GetUser(ctx), param, body); err != nil {
+		r.ErrorResponse(c, err)
+		return
+	}
+
+	r.CreatedResponse(c, "Berhasil mengoreksi transaksi proyek", nil)
+}
@@ -0,0 +1,43 @@
+package controller
+
+import (
+	"github.com/gin-gonic/gin"
+)
+
+// @Summary Check Ledger Integrity
+// @Description Walk the ledger balance chains and report every break without changing anything
+// @Tags Ledger
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} model.HTTPResponse{data=model.LedgerIntegrityReport}
+// @Failure 401 {object} model.HTTPResponse{}
+// @Failure 500 {object} model.HTTPResponse{}
+// @Router /v1/ledger/integrity [GET]
+func (r *rest) GetLedgerIntegrity(c *gin.Context) {
+	report, err := r.svc.Ledger.CheckLedgerIntegrity(c.Request.Context(), false)
+	if err != nil {
+		r.ErrorResponse(c, err)
+		return
+	}
+
+	r.SuccessResponse(c, "Berhasil memeriksa integritas buku kas", report, nil)
+}
+
+// @Summary Rebuild Ledger Balance
+// @Description Rewrite the running balances, project incomes and expenditure totals from the ledger
+// @Tags Ledger
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} model.HTTPResponse{data=model.LedgerIntegrityReport}
+// @Failure 401 {object} model.HTTPResponse{}
+// @Failure 500 {object} model.HTTPResponse{}
+// @Router /v1/ledger/integrity/rebuild [POST]
+func (r *rest) RebuildLedgerBalance(c *gin.Context) {
+	report, err := r.svc.Ledger.CheckLedgerIntegrity(c.Request.Context(), true)
+	if err != nil {
+		r.ErrorResponse(c, err)
+		return
+	}
+
+	r.SuccessResponse(c, "Berhasil membangun ulang saldo buku kas", report, nil)
+}
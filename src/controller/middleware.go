@@ -1,7 +1,14 @@
 package controller
 
 import (
+	"bytes"
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
 	"time"
 
 	"tigaputera-backend/sdk/appcontext"
@@ -33,6 +40,7 @@ func (r *rest) AddFieldsToContext(ctx *gin.Context) {
 	c := ctx.Request.Context()
 	c = appcontext.SetRequestId(c, requestID)
 	c = appcontext.SetUserAgent(c, ctx.Request.Header.Get(appcontext.HeaderUserAgent))
+	c = appcontext.SetClientIP(c, ctx.ClientIP())
 	c = appcontext.SetDeviceType(c, ctx.Request.Header.Get(appcontext.HeaderDeviceType))
 	ctx.Request = ctx.Request.WithContext(c)
 
@@ -43,7 +51,7 @@ func (r *rest) CorsMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		c.Writer.Header().Set("Access-Control-Allow-Origin", "*")
 		c.Writer.Header().Set("Access-Control-Allow-Credentials", "true")
-		c.Writer.Header().Set("Access-Control-Allow-Headers", "Content-Type, Content-Length, Accept-Encoding, X-CSRF-Token, Authorization, accept, origin, Cache-Control, X-Requested-With")
+		c.Writer.Header().Set("Access-Control-Allow-Headers", "Content-Type, Content-Length, Accept-Encoding, X-CSRF-Token, Authorization, accept, origin, Cache-Control, X-Requested-With, Idempotency-Key")
 		c.Writer.Header().Set("Access-Control-Allow-Methods", "POST, OPTIONS, GET, PUT, PATCH, DELETE")
 
 		if c.Request.Method == "OPTIONS" {
@@ -59,6 +67,21 @@ func (r *rest) Authorization() gin.HandlerFunc {
 	return r.checkToken
 }
 
+// passwordChangeRoutes are the only routes of a user who must change the password first
+var passwordChangeRoutes = map[string]bool{
+	"/v1/user/profile":        true,
+	"/v1/user/reset-password": true,
+	"/v1/auth/logout":         true,
+}
+
+// totpEnrollmentRoutes are the only routes of a director who hasn't enabled an authenticator
+var totpEnrollmentRoutes = map[string]bool{
+	"/v1/user/profile":     true,
+	"/v1/user/totp":        true,
+	"/v1/user/totp/verify": true,
+	"/v1/auth/logout":      true,
+}
+
 func (r *rest) checkToken(ctx *gin.Context) {
 	header := ctx.Request.Header.Get("Authorization")
 	if header == "" {
@@ -77,20 +100,190 @@ func (r *rest) checkToken(ctx *gin.Context) {
 
 	c := ctx.Request.Context()
 	c = auth.SetUser(c, tokenClaims["data"].(map[string]interface{}))
+	user, err := r.svc.User.CheckSession(c, auth.GetUser(c))
+	if err != nil {
+		r.ErrorResponse(ctx, err)
+		ctx.Abort()
+		return
+	}
+
+	if user.MustChangePassword() && !passwordChangeRoutes[ctx.FullPath()] {
+		r.ErrorResponse(ctx, errors.Forbidden("Silakan ganti password anda terlebih dahulu"))
+		ctx.Abort()
+		return
+	}
+
+	if !user.MustChangePassword() && user.MustEnrollTotp() && !totpEnrollmentRoutes[ctx.FullPath()] {
+		r.ErrorResponse(ctx, errors.Forbidden("Silakan aktifkan autentikasi dua langkah terlebih dahulu"))
+		ctx.Abort()
+		return
+	}
+
+	permissions := []string{}
+	for _, permission := range user.Permissions {
+		permissions = append(permissions, string(permission))
+	}
+	c = auth.SetPermissions(c, permissions)
 	ctx.Request = ctx.Request.WithContext(c)
 
 	ctx.Next()
 }
 
-func (r *rest) AuthorizeRole(roleName model.Role) gin.HandlerFunc {
+// AuthorizePermission allows the users whose role grants any of the permissions
+func (r *rest) AuthorizePermission(permissions ...model.Permission) gin.HandlerFunc {
 	return func(ctx *gin.Context) {
-		userRole := auth.GetUser(ctx.Request.Context()).Role
-		if userRole != string(roleName) {
-			r.ErrorResponse(ctx, errors.Unauthorized("Anda tidak memiliki akses untuk melakukan aksi ini"))
-			ctx.Abort()
-			return
+		user := auth.GetUser(ctx.Request.Context())
+		for _, permission := range permissions {
+			if user.HasPermission(string(permission)) {
+				ctx.Next()
+				return
+			}
 		}
 
+		r.ErrorResponse(ctx, errors.Unauthorized("Anda tidak memiliki akses untuk melakukan aksi ini"))
+		ctx.Abort()
+	}
+}
+
+type idempotencyResponseWriter struct {
+	gin.ResponseWriter
+	body *bytes.Buffer
+}
+
+func (w *idempotencyResponseWriter) Write(b []byte) (int, error) {
+	w.body.Write(b)
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *idempotencyResponseWriter) WriteString(s string) (int, error) {
+	w.body.WriteString(s)
+	return w.ResponseWriter.WriteString(s)
+}
+
+// Idempotent replays the stored response of a request retried with the same Idempotency-Key header,
+// a retry sent while the first request is still processed gets a conflict
+func (r *rest) Idempotent(ctx *gin.Context) {
+	key := ctx.Request.Header.Get(appcontext.HeaderIdempotencyKey)
+	if key == "" {
 		ctx.Next()
+		return
 	}
+
+	if len(key) > 255 {
+		r.ErrorResponse(ctx, errors.BadRequest("Idempotency-Key maksimal 255 karakter"))
+		ctx.Abort()
+		return
+	}
+
+	requestHash, err := getRequestHash(ctx)
+	if err != nil {
+		r.ErrorResponse(ctx, errors.BadRequest(err.Error()))
+		ctx.Abort()
+		return
+	}
+
+	c := ctx.Request.Context()
+	idempotencyKey := model.IdempotencyKey{
+		UserID:      auth.GetUser(c).ID,
+		Key:         key,
+		Method:      ctx.Request.Method,
+		Path:        ctx.Request.URL.Path,
+		RequestHash: requestHash,
+	}
+
+	storedKey, err := r.svc.Idempotency.Acquire(c, &idempotencyKey)
+	if err != nil {
+		r.ErrorResponse(ctx, err)
+		ctx.Abort()
+		return
+	} else if storedKey != nil {
+		ctx.Header(appcontext.HeaderIdempotentReplayed, "true")
+		ctx.Data(storedKey.StatusCode, "application/json; charset=utf-8", storedKey.ResponseBody)
+		ctx.Abort()
+		return
+	}
+
+	writer := &idempotencyResponseWriter{ResponseWriter: ctx.Writer, body: &bytes.Buffer{}}
+	ctx.Writer = writer
+
+	isHandled := false
+	defer func() {
+		// the request context could be done already, the key must not stay locked
+		background := context.Background()
+
+		// panics, server errors and lock conflicts didn't change anything, so the retry has to be processed
+		statusCode := writer.Status()
+		if !isHandled || statusCode >= 500 || statusCode == 409 {
+			if err := r.svc.Idempotency.Release(background, idempotencyKey); err != nil {
+				r.log.Error(c, err.Error())
+			}
+			return
+		}
+
+		idempotencyKey.StatusCode = statusCode
+		idempotencyKey.ResponseBody = writer.body.Bytes()
+		if err := r.svc.Idempotency.Complete(background, idempotencyKey); err != nil {
+			r.log.Error(c, err.Error())
+		}
+	}()
+
+	ctx.Next()
+	isHandled = true
+}
+
+// getRequestHash hashes the body of the request, a retry with the same key must send the same body.
+// The multipart boundary changes between the retries, so the form fields and files are hashed instead.
+func getRequestHash(ctx *gin.Context) (string, error) {
+	hash := sha256.New()
+
+	if !strings.HasPrefix(ctx.ContentType(), "multipart/form-data") {
+		body, err := io.ReadAll(ctx.Request.Body)
+		if err != nil {
+			return "", err
+		}
+		ctx.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+		hash.Write(body)
+		return hex.EncodeToString(hash.Sum(nil)), nil
+	}
+
+	// the parsed form is kept in the request for the handler
+	form, err := ctx.MultipartForm()
+	if err != nil {
+		return "", err
+	}
+
+	for _, name := range getSortedKeys(form.Value) {
+		fmt.Fprintf(hash, "%q=%q\n", name, form.Value[name])
+	}
+
+	for _, name := range getSortedKeys(form.File) {
+		for _, fileHeader := range form.File[name] {
+			fmt.Fprintf(hash, "%q=%q:", name, fileHeader.Filename)
+
+			file, err := fileHeader.Open()
+			if err != nil {
+				return "", err
+			}
+
+			_, err = io.Copy(hash, file)
+			file.Close()
+			if err != nil {
+				return "", err
+			}
+			hash.Write([]byte("\n"))
+		}
+	}
+
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+func getSortedKeys[T any](m map[string]T) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	return keys
 }
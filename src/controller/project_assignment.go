@@ -0,0 +1,82 @@
+package controller
+
+import (
+	"tigaputera-backend/sdk/auth"
+	errors "tigaputera-backend/sdk/error"
+	"tigaputera-backend/src/model"
+
+	"github.com/gin-gonic/gin"
+)
+
+// @Summary Reassign Project
+// @Description Move a project to another inspector, the project balance of the previous inspector is handed over
+// @Tags Project
+// @Produce json
+// @Security BearerAuth
+// @Param Idempotency-Key header string false "Idempotency-Key"
+// @Param project_id path int true "project_id"
+// @Param reassignProjectBody body model.ReassignProjectBody true "body"
+// @Success 200 {object} model.HTTPResponse{}
+// @Failure 400 {object} model.HTTPResponse{}
+// @Failure 401 {object} model.HTTPResponse{}
+// @Failure 404 {object} model.HTTPResponse{}
+// @Failure 409 {object} model.HTTPResponse{}
+// @Failure 422 {object} model.HTTPResponse{}
+// @Failure 500 {object} model.HTTPResponse{}
+// @Router /v1/project/{project_id}/inspector [PATCH]
+func (r *rest) ReassignProject(c *gin.Context) {
+	ctx := c.Request.Context()
+	var param model.ProjectParam
+	var body model.ReassignProjectBody
+
+	if err := r.BindParam(c, &param); err != nil {
+		r.ErrorResponse(c, err)
+		return
+	}
+
+	if err := r.BindBody(c, &body); err != nil {
+		r.ErrorResponse(c, err)
+		return
+	}
+
+	if err := r.validator.ValidateStruct(body); err != nil {
+		r.ErrorResponse(c, errors.BadRequest(err.Error()))
+		return
+	}
+
+	if err := r.svc.Ledger.ReassignProject(ctx, auth.GetUser(ctx), param, body); err != nil {
+		r.ErrorResponse(c, err)
+		return
+	}
+
+	r.SuccessResponse(c, "Berhasil memindahkan proyek ke pengawas", nil, nil)
+}
+
+// @Summary Get List Project Assignment
+// @Description Get the inspectors who held a project, the first one first
+// @Tags Project
+// @Produce json
+// @Security BearerAuth
+// @Param project_id path int true "project_id"
+// @Success 200 {object} model.HTTPResponse{data=[]model.ProjectAssignmentResponse}
+// @Failure 401 {object} model.HTTPResponse{}
+// @Failure 404 {object} model.HTTPResponse{}
+// @Failure 500 {object} model.HTTPResponse{}
+// @Router /v1/project/{project_id}/assignment [GET]
+func (r *rest) GetListProjectAssignment(c *gin.Context) {
+	ctx := c.Request.Context()
+	var param model.ProjectParam
+
+	if err := r.BindParam(c, &param); err != nil {
+		r.ErrorResponse(c, err)
+		return
+	}
+
+	assignments, err := r.svc.Ledger.GetListProjectAssignment(ctx, auth.GetUser(ctx), param)
+	if err != nil {
+		r.ErrorResponse(c, err)
+		return
+	}
+
+	r.SuccessResponse(c, "Berhasil mendapatkan riwayat pengawas proyek", assignments, nil)
+}
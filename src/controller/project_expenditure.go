@@ -39,31 +39,8 @@ func (r *rest) CreateProjectExpenditure(c *gin.Context) {
 		return
 	}
 
-	// Get latest sequence
-	var latestProjectExpenditure model.ProjectExpenditure
-	err := r.db.WithContext(ctx).
-		Where("project_id = ?", param.ProjectID).
-		Order("sequence desc").
-		Take(&latestProjectExpenditure).Error
-
-	if r.isNoRecordFound(err) {
-		r.ErrorResponse(c, errors.NotFound("Proyek tidak ditemukan"))
-		return
-	} else if err != nil {
-		r.ErrorResponse(c, errors.InternalServerError(err.Error()))
-	}
-
-	projectExpenditure := model.ProjectExpenditure{
-		ProjectID:   param.ProjectID,
-		Sequence:    latestProjectExpenditure.Sequence + 1,
-		Name:        body.Name,
-		IsFixedCost: body.IsFixedCost,
-	}
-
-	if err := r.db.WithContext(ctx).
-		Model(&model.ProjectExpenditure{}).
-		Create(&projectExpenditure).Error; err != nil {
-		r.ErrorResponse(c, errors.InternalServerError(err.Error()))
+	if err := r.svc.Project.CreateProjectExpenditure(ctx, param.ProjectID, body); err != nil {
+		r.ErrorResponse(c, err)
 		return
 	}
 
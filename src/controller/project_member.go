@@ -0,0 +1,112 @@
+package controller
+
+import (
+	"tigaputera-backend/sdk/auth"
+	errors "tigaputera-backend/sdk/error"
+	"tigaputera-backend/src/model"
+
+	"github.com/gin-gonic/gin"
+)
+
+// @Summary Get List Project Member
+// @Description Get the inspectors working on a project with their project balance, the lead first
+// @Tags Project
+// @Produce json
+// @Security BearerAuth
+// @Param project_id path int true "project_id"
+// @Success 200 {object} model.HTTPResponse{data=[]model.ProjectMemberResponse}
+// @Failure 401 {object} model.HTTPResponse{}
+// @Failure 404 {object} model.HTTPResponse{}
+// @Failure 500 {object} model.HTTPResponse{}
+// @Router /v1/project/{project_id}/member [GET]
+func (r *rest) GetListProjectMember(c *gin.Context) {
+	ctx := c.Request.Context()
+	var param model.ProjectParam
+
+	if err := r.BindParam(c, &param); err != nil {
+		r.ErrorResponse(c, err)
+		return
+	}
+
+	members, err := r.svc.Ledger.GetListProjectMember(ctx, auth.GetUser(ctx), param)
+	if err != nil {
+		r.ErrorResponse(c, err)
+		return
+	}
+
+	r.SuccessResponse(c, "Berhasil mendapatkan anggota proyek", members, nil)
+}
+
+// @Summary Add Project Member
+// @Description Add an inspector to a project as an assistant of the lead
+// @Tags Project
+// @Produce json
+// @Security BearerAuth
+// @Param project_id path int true "project_id"
+// @Param addProjectMemberBody body model.AddProjectMemberBody true "body"
+// @Success 201 {object} model.HTTPResponse{}
+// @Failure 400 {object} model.HTTPResponse{}
+// @Failure 401 {object} model.HTTPResponse{}
+// @Failure 404 {object} model.HTTPResponse{}
+// @Failure 500 {object} model.HTTPResponse{}
+// @Router /v1/project/{project_id}/member [POST]
+func (r *rest) AddProjectMember(c *gin.Context) {
+	ctx := c.Request.Context()
+	var param model.ProjectParam
+	var body model.AddProjectMemberBody
+
+	if err := r.BindParam(c, &param); err != nil {
+		r.ErrorResponse(c, err)
+		return
+	}
+
+	if err := r.BindBody(c, &body); err != nil {
+		r.ErrorResponse(c, err)
+		return
+	}
+
+	if err := r.validator.ValidateStruct(body); err != nil {
+		r.ErrorResponse(c, errors.BadRequest(err.Error()))
+		return
+	}
+
+	if err := r.svc.Ledger.AddProjectMember(ctx, auth.GetUser(ctx), param, body); err != nil {
+		r.ErrorResponse(c, err)
+		return
+	}
+
+	r.CreatedResponse(c, "Berhasil menambahkan anggota proyek", nil)
+}
+
+// @Summary Remove Project Member
+// @Description Remove an assistant from a project, their project balance is handed over to the lead
+// @Tags Project
+// @Produce json
+// @Security BearerAuth
+// @Param Idempotency-Key header string false "Idempotency-Key"
+// @Param project_id path int true "project_id"
+// @Param user_id path int true "user_id"
+// @Success 200 {object} model.HTTPResponse{}
+// @Failure 400 {object} model.HTTPResponse{}
+// @Failure 401 {object} model.HTTPResponse{}
+// @Failure 404 {object} model.HTTPResponse{}
+// @Failure 409 {object} model.HTTPResponse{}
+// @Failure 422 {object} model.HTTPResponse{}
+// @Failure 500 {object} model.HTTPResponse{}
+// @Router /v1/project/{project_id}/member/{user_id} [DELETE]
+func (r *rest) RemoveProjectMember(c *gin.Context) {
+	ctx := c.Request.Context()
+	var param model.ProjectMemberParam
+
+	if err := r.BindParam(c, &param); err != nil {
+		r.ErrorResponse(c, err)
+		return
+	}
+
+	if err := r.svc.Ledger.RemoveProjectMember(ctx, auth.GetUser(ctx), param); err != nil {
+		r.ErrorResponse(c, err)
+		return
+	}
+
+	r.SuccessResponse(c, "Berhasil menghapus anggota proyek", nil, nil)
+}
@@ -0,0 +1,108 @@
+package controller
+
+import (
+	errors "tigaputera-backend/sdk/error"
+	"tigaputera-backend/src/model"
+
+	"github.com/gin-gonic/gin"
+)
+
+// @Summary Get list role
+// @Description Get the roles with their permissions, the director always holds the same permissions
+// @Tags Role
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} model.HTTPResponse{data=[]model.RoleResponse}
+// @Failure 401 {object} model.HTTPResponse{}
+// @Failure 500 {object} model.HTTPResponse{}
+// @Router /v1/role [GET]
+func (r *rest) GetListRole(c *gin.Context) {
+	roles, err := r.svc.User.GetListRole(c.Request.Context())
+	if err != nil {
+		r.ErrorResponse(c, err)
+		return
+	}
+
+	r.SuccessResponse(c, "Berhasil menampilkan daftar peran", roles, nil)
+}
+
+// @Summary Update role
+// @Description Replace the permissions of a role, a new role is created with them
+// @Tags Role
+// @Produce json
+// @Security BearerAuth
+// @Param role path string true "role"
+// @Param updateRoleBody body model.UpdateRoleBody true "body"
+// @Success 200 {object} model.HTTPResponse{}
+// @Failure 400 {object} model.HTTPResponse{}
+// @Failure 401 {object} model.HTTPResponse{}
+// @Failure 500 {object} model.HTTPResponse{}
+// @Router /v1/role/{role} [PUT]
+func (r *rest) UpdateRole(c *gin.Context) {
+	ctx := c.Request.Context()
+	var param model.RoleParam
+	var body model.UpdateRoleBody
+
+	if err := r.BindParam(c, &param); err != nil {
+		r.ErrorResponse(c, err)
+		return
+	}
+
+	if err := r.BindBody(c, &body); err != nil {
+		r.ErrorResponse(c, err)
+		return
+	}
+
+	if err := r.validator.ValidateStruct(body); err != nil {
+		r.ErrorResponse(c, errors.BadRequest(err.Error()))
+		return
+	}
+
+	if err := r.svc.User.UpdateRole(ctx, param, body); err != nil {
+		r.ErrorResponse(c, err)
+		return
+	}
+
+	r.SuccessResponse(c, "Berhasil mengubah hak akses peran", nil, nil)
+}
+
+// @Summary Update user role
+// @Description Move a user to another role, the role of a director can't be given or taken
+// @Tags Role
+// @Produce json
+// @Security BearerAuth
+// @Param user_id path int true "user_id"
+// @Param updateUserRoleBody body model.UpdateUserRoleBody true "body"
+// @Success 200 {object} model.HTTPResponse{}
+// @Failure 400 {object} model.HTTPResponse{}
+// @Failure 401 {object} model.HTTPResponse{}
+// @Failure 404 {object} model.HTTPResponse{}
+// @Failure 500 {object} model.HTTPResponse{}
+// @Router /v1/user/{user_id}/role [PATCH]
+func (r *rest) UpdateUserRole(c *gin.Context) {
+	ctx := c.Request.Context()
+	var param model.UserParam
+	var body model.UpdateUserRoleBody
+
+	if err := r.BindParam(c, &param); err != nil {
+		r.ErrorResponse(c, err)
+		return
+	}
+
+	if err := r.BindBody(c, &body); err != nil {
+		r.ErrorResponse(c, err)
+		return
+	}
+
+	if err := r.validator.ValidateStruct(body); err != nil {
+		r.ErrorResponse(c, errors.BadRequest(err.Error()))
+		return
+	}
+
+	if err := r.svc.User.UpdateUserRole(ctx, param, body); err != nil {
+		r.ErrorResponse(c, err)
+		return
+	}
+
+	r.SuccessResponse(c, "Berhasil mengubah peran pengguna", nil, nil)
+}
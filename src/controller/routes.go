@@ -4,11 +4,9 @@ import (
 	swagger "tigaputera-backend/docs"
 	"tigaputera-backend/sdk/jwt"
 	"tigaputera-backend/sdk/log"
-	"tigaputera-backend/sdk/password"
-	"tigaputera-backend/sdk/storage"
 	"tigaputera-backend/sdk/validator"
-	"tigaputera-backend/src/database"
 	"tigaputera-backend/src/model"
+	"tigaputera-backend/src/service"
 
 	"github.com/gin-gonic/gin"
 	swaggerFiles "github.com/swaggo/files"
@@ -19,6 +17,7 @@ import (
 	"net/http"
 	"os"
 	"os/signal"
+	"strings"
 	"sync"
 	"syscall"
 	"time"
@@ -28,21 +27,17 @@ var once = sync.Once{}
 
 type rest struct {
 	http      *gin.Engine
-	db        *database.DB
 	log       log.LogInterface
 	jwt       jwt.Interface
-	password  password.Interface
 	validator validator.Interface
-	storage   storage.Interface
+	svc       *service.Service
 }
 
 func Init(
 	log log.LogInterface,
-	db *database.DB,
 	jwt jwt.Interface,
-	password password.Interface,
 	validator validator.Interface,
-	storage storage.Interface,
+	svc *service.Service,
 ) *rest {
 	r := &rest{}
 
@@ -51,12 +46,14 @@ func Init(
 		gin.SetMode(gin.ReleaseMode)
 
 		r.http = gin.New()
+		// the client ip limits the failed logins, so only the proxies in front of the api may set it
+		if err := r.http.SetTrustedProxies(getTrustedProxies()); err != nil {
+			panic(err)
+		}
 		r.log = log
-		r.db = db
 		r.jwt = jwt
-		r.password = password
 		r.validator = validator
-		r.storage = storage
+		r.svc = svc
 
 		r.RegisterMiddlewareAndRoutes()
 	})
@@ -64,6 +61,15 @@ func Init(
 	return r
 }
 
+// getTrustedProxies reads the comma separated TRUSTED_PROXIES, no proxy is trusted when it's empty
+func getTrustedProxies() []string {
+	if os.Getenv("TRUSTED_PROXIES") == "" {
+		return nil
+	}
+
+	return strings.Split(os.Getenv("TRUSTED_PROXIES"), ",")
+}
+
 func (r *rest) RegisterMiddlewareAndRoutes() {
 	// Global middleware
 	r.http.Use(r.CorsMiddleware())
@@ -75,80 +81,311 @@ func (r *rest) RegisterMiddlewareAndRoutes() {
 
 	// Global routes
 	r.http.GET("/ping", r.Ping)
+	r.http.GET("/.well-known/jwks.json", r.GetJWKS)
 	r.http.GET("/docs/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
 
 	// Auth routes
 	r.http.POST("/v1/auth/login", r.Login)
+	r.http.POST("/v1/auth/refresh", r.RefreshToken)
+	r.http.POST("/v1/auth/login/totp", r.VerifyLoginTotp)
 
 	// Protected Routes
 	r.http.PUT("/v1/user/statistics/refresh", r.RefreshStatistics)
 	r.http.POST("/v1/user/statistics/ledger-report", r.CreateLedgerReport)
+	r.http.POST("/v1/storage/garbage-collection", r.CollectStorageGarbage)
+
+	// Signed routes, the signature of the url authorizes the request
+	r.http.GET("/v1/storage/:path/:file_name", r.DownloadStorageObject)
+
 	v1 := r.http.Group("v1", r.Authorization())
 
+	v1.POST("auth/logout", r.Logout)
+
 	// User routes
 	v1.Group("user")
 	{
 		v1.GET("user/profile", r.GetUserProfile)
 		v1.PATCH("user/reset-password", r.ResetPassword)
+		v1.POST("user/totp", r.EnrollTotp)
+		v1.POST("user/totp/verify", r.EnableTotp)
+		v1.POST("user/totp/disable", r.DisableTotp)
 		v1.POST(
 			"user/inspector",
-			r.AuthorizeRole(model.Admin),
+			r.AuthorizePermission(model.UserWrite),
 			r.CreateInspector,
 		)
 		v1.GET(
 			"user/inspector",
-			r.AuthorizeRole(model.Admin),
+			r.AuthorizePermission(model.UserRead),
 			r.GetListInspector,
 		)
+		v1.PATCH(
+			"user/inspector/:user_id",
+			r.AuthorizePermission(model.UserWrite),
+			r.UpdateInspector,
+		)
+		v1.PATCH(
+			"user/inspector/:user_id/password",
+			r.AuthorizePermission(model.UserWrite),
+			r.ResetInspectorPassword,
+		)
 		v1.DELETE(
 			"user/inspector/:user_id",
-			r.AuthorizeRole(model.Admin),
+			r.AuthorizePermission(model.UserWrite),
 			r.DeactiveInspector,
 		)
+		v1.POST(
+			"user/inspector/:user_id/reactivate",
+			r.AuthorizePermission(model.UserWrite),
+			r.ReactivateInspector,
+		)
 		v1.GET(
 			"user/inspector/ledger",
+			r.AuthorizePermission(model.LedgerReadOwn, model.LedgerReadAll),
 			r.GetInspectorLedger,
 		)
-		v1.GET("user/statistics", r.GetUserStats)
-		v1.GET("user/statistics/detail", r.GetUserStatsDetail)
+		v1.POST(
+			"user/:user_id/unlock",
+			r.AuthorizePermission(model.UserWrite),
+			r.UnlockUser,
+		)
+		v1.PATCH(
+			"user/:user_id/role",
+			r.AuthorizePermission(model.RoleWrite),
+			r.UpdateUserRole,
+		)
+		v1.GET(
+			"user/statistics",
+			r.AuthorizePermission(model.LedgerReadOwn, model.LedgerReadAll),
+			r.GetUserStats,
+		)
+		v1.GET(
+			"user/statistics/detail",
+			r.AuthorizePermission(model.LedgerReadOwn, model.LedgerReadAll),
+			r.GetUserStatsDetail,
+		)
+		v1.GET(
+			"user/statistics/ledger-report",
+			r.AuthorizePermission(model.ReportRead),
+			r.GetLedgerReportURL,
+		)
+	}
+
+	// Role routes
+	v1.Group("role")
+	{
+		v1.GET("role", r.AuthorizePermission(model.RoleWrite), r.GetListRole)
+		v1.PUT("role/:role", r.AuthorizePermission(model.RoleWrite), r.UpdateRole)
 	}
 
 	// Project routes
 	v1.Group("project")
 	{
-		v1.POST("project", r.AuthorizeRole(model.Admin), r.CreateProject)
-		v1.GET("project", r.GetListProject)
-		v1.GET("project/name", r.GetListProjectName)
-		v1.GET("project/:project_id", r.GetProject)
-		v1.GET("project/:project_id/detail", r.GetProjectDetail)
-		v1.GET("project/:project_id/ledger", r.GetProjectLedger)
+		v1.POST("project", r.AuthorizePermission(model.ProjectWrite), r.CreateProject)
+		v1.GET(
+			"project",
+			r.AuthorizePermission(model.ProjectReadOwn, model.ProjectReadAll),
+			r.GetListProject,
+		)
+		v1.GET(
+			"project/name",
+			r.AuthorizePermission(model.ProjectReadOwn, model.ProjectReadAll),
+			r.GetListProjectName,
+		)
+		v1.GET(
+			"project/:project_id",
+			r.AuthorizePermission(model.ProjectReadOwn, model.ProjectReadAll),
+			r.GetProject,
+		)
+		v1.GET(
+			"project/:project_id/detail",
+			r.AuthorizePermission(model.ProjectReadOwn, model.ProjectReadAll),
+			r.GetProjectDetail,
+		)
+		v1.GET(
+			"project/:project_id/ledger",
+			r.AuthorizePermission(model.LedgerReadOwn, model.LedgerReadAll),
+			r.GetProjectLedger,
+		)
+		v1.PATCH(
+			"project/:project_id",
+			r.AuthorizePermission(model.ProjectWrite),
+			r.UpdateProject,
+		)
 		v1.PATCH(
 			"project/:project_id/budget",
-			r.AuthorizeRole(model.Admin),
+			r.AuthorizePermission(model.ProjectBudgetWrite),
 			r.UpdateProjectBudget,
 		)
 		v1.PATCH(
 			"project/:project_id/status",
-			r.AuthorizeRole(model.Admin),
+			r.AuthorizePermission(model.ProjectWrite),
 			r.UpdateProjectStatus,
 		)
+		v1.PATCH(
+			"project/:project_id/inspector",
+			r.AuthorizePermission(model.ProjectWrite),
+			r.Idempotent,
+			r.ReassignProject,
+		)
+		v1.GET(
+			"project/:project_id/assignment",
+			r.AuthorizePermission(model.ProjectReadOwn, model.ProjectReadAll),
+			r.GetListProjectAssignment,
+		)
+		v1.GET(
+			"project/:project_id/member",
+			r.AuthorizePermission(model.ProjectReadOwn, model.ProjectReadAll),
+			r.GetListProjectMember,
+		)
+		v1.POST(
+			"project/:project_id/member",
+			r.AuthorizePermission(model.ProjectWrite),
+			r.AddProjectMember,
+		)
+		v1.DELETE(
+			"project/:project_id/member/:user_id",
+			r.AuthorizePermission(model.ProjectWrite),
+			r.Idempotent,
+			r.RemoveProjectMember,
+		)
 		v1.POST(
 			"project/:project_id/income",
-			r.AuthorizeRole(model.Inspector),
+			r.AuthorizePermission(model.LedgerWrite),
+			r.Idempotent,
 			r.CreateIncomeTransaction,
 		)
+		v1.DELETE(
+			"project/:project_id/income/:transaction_id",
+			r.AuthorizePermission(model.LedgerWrite),
+			r.Idempotent,
+			r.DeleteIncomeTransaction,
+		)
+		v1.POST(
+			"project/:project_id/ledger/:transaction_id/correction",
+			r.AuthorizePermission(model.LedgerWrite),
+			r.Idempotent,
+			r.CorrectTransaction,
+		)
+		v1.GET(
+			"project/:project_id/ledger/:transaction_id/attachment",
+			r.AuthorizePermission(model.LedgerReadOwn, model.LedgerReadAll),
+			r.GetLedgerAttachments,
+		)
+		v1.POST(
+			"project/:project_id/ledger/:transaction_id/attachment",
+			r.AuthorizePermission(model.LedgerWrite),
+			r.Idempotent,
+			r.AddLedgerAttachments,
+		)
+		v1.DELETE(
+			"project/:project_id/ledger/:transaction_id/attachment/:attachment_id",
+			r.AuthorizePermission(model.LedgerWrite),
+			r.Idempotent,
+			r.DeleteLedgerAttachment,
+		)
 		v1.POST(
 			"project/:project_id/expenditure/:expenditure_id/transaction",
+			r.AuthorizePermission(model.LedgerWrite),
+			r.Idempotent,
 			r.CreateExpenditureTransaction,
 		)
 		v1.GET(
 			"project/:project_id/expenditure/:expenditure_id/transaction",
+			r.AuthorizePermission(model.LedgerReadOwn, model.LedgerReadAll),
 			r.GetExpenditureTransactionList,
 		)
 		v1.DELETE(
 			"project/:project_id/expenditure/:expenditure_id/transaction/:transaction_id",
+			r.AuthorizePermission(model.LedgerWrite),
+			r.Idempotent,
 			r.DeleteExpenditureTransaction,
 		)
+		v1.GET(
+			"project/:project_id/expenditure/approval",
+			r.AuthorizePermission(model.LedgerReadOwn, model.LedgerReadAll),
+			r.GetListExpenditureApproval,
+		)
+		v1.PATCH(
+			"project/:project_id/expenditure/:expenditure_id/transaction/:transaction_id/approve",
+			r.AuthorizePermission(model.LedgerApprove),
+			r.Idempotent,
+			r.ApproveExpenditureTransaction,
+		)
+		v1.PATCH(
+			"project/:project_id/expenditure/:expenditure_id/transaction/:transaction_id/reject",
+			r.AuthorizePermission(model.LedgerApprove),
+			r.RejectExpenditureTransaction,
+		)
+		v1.PATCH(
+			"project/:project_id/approval-threshold",
+			r.AuthorizePermission(model.ProjectBudgetWrite),
+			r.UpdateProjectApprovalThreshold,
+		)
+		v1.PATCH(
+			"project/:project_id/expenditure/:expenditure_id/approval-threshold",
+			r.AuthorizePermission(model.ProjectBudgetWrite),
+			r.UpdateExpenditureApprovalThreshold,
+		)
+		v1.POST(
+			"project/:project_id/transfer",
+			r.AuthorizePermission(model.TransferWrite),
+			r.Idempotent,
+			r.CreateFundTransfer,
+		)
+		v1.GET(
+			"project/:project_id/transfer",
+			r.AuthorizePermission(model.LedgerReadOwn, model.LedgerReadAll),
+			r.GetListFundTransfer,
+		)
+		v1.POST(
+			"project/:project_id/transfer/:transfer_id/confirm",
+			r.AuthorizePermission(model.TransferConfirm),
+			r.Idempotent,
+			r.ConfirmFundTransfer,
+		)
+		v1.DELETE(
+			"project/:project_id/transfer/:transfer_id",
+			r.AuthorizePermission(model.TransferWrite),
+			r.Idempotent,
+			r.CancelFundTransfer,
+		)
+	}
+
+	// Ledger routes
+	v1.Group("ledger")
+	{
+		v1.GET(
+			"ledger/integrity",
+			r.AuthorizePermission(model.LedgerIntegrity),
+			r.GetLedgerIntegrity,
+		)
+		v1.POST(
+			"ledger/integrity/rebuild",
+			r.AuthorizePermission(model.LedgerIntegrity),
+			r.RebuildLedgerBalance,
+		)
+		v1.GET(
+			"ledger/duplicate-receipt",
+			r.AuthorizePermission(model.ReportRead),
+			r.GetDuplicateReceiptReport,
+		)
+	}
+
+	// Audit routes
+	v1.Group("audit-log")
+	{
+		v1.GET("audit-log", r.AuthorizePermission(model.AuditRead), r.GetListAuditLog)
+	}
+
+	// Storage routes
+	v1.Group("storage")
+	{
+		v1.GET(
+			"storage/garbage-collection",
+			r.AuthorizePermission(model.StorageMaintenance),
+			r.GetStorageGarbageReport,
+		)
 	}
 }
 
@@ -212,3 +449,13 @@ func (r *rest) Run() {
 func (r *rest) Ping(c *gin.Context) {
 	r.SuccessResponse(c, "PONG!!", nil, nil)
 }
+
+// @Summary JSON Web Key Set
+// @Description Get the public keys the access tokens are verified with, the set is returned without the response envelope
+// @Tags Server
+// @Produce json
+// @Success 200 {object} jwt.JWKS
+// @Router /.well-known/jwks.json [GET]
+func (r *rest) GetJWKS(c *gin.Context) {
+	c.JSON(http.StatusOK, r.jwt.JWKS())
+}
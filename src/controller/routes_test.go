@@ -0,0 +1,4469 @@
+package controller
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"io"
+	mathrand "math/rand"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"tigaputera-backend/sdk/appcontext"
+	"tigaputera-backend/sdk/cryptolib"
+	"tigaputera-backend/sdk/file"
+	"tigaputera-backend/sdk/imaging"
+	"tigaputera-backend/sdk/jwt"
+	"tigaputera-backend/sdk/log"
+	"tigaputera-backend/sdk/password"
+	"tigaputera-backend/sdk/signedurl"
+	"tigaputera-backend/sdk/storage"
+	"tigaputera-backend/sdk/totp"
+	"tigaputera-backend/sdk/validator"
+	"tigaputera-backend/src/database"
+	"tigaputera-backend/src/model"
+	"tigaputera-backend/src/repository"
+	"tigaputera-backend/src/repository/memory"
+	"tigaputera-backend/src/service"
+
+	gojwt "github.com/golang-jwt/jwt/v4"
+)
+
+const (
+	testSchedulerKey = "test-scheduler-key"
+	testPassword     = "password123"
+)
+
+// fakeStorage keeps the uploaded objects in memory instead of sending them to the bucket
+type fakeStorage struct {
+	mu        sync.Mutex
+	objects   map[string][]byte
+	updatedAt map[string]time.Time
+}
+
+func (s *fakeStorage) Upload(ctx context.Context, file *file.File, path string) (string, error) {
+	return s.store(file.Content, file.Meta.Filename, path)
+}
+
+func (s *fakeStorage) UploadFromBytes(
+	ctx context.Context,
+	file *bytes.Reader,
+	fileName string,
+	path string,
+) (string, error) {
+	return s.store(file, fileName, path)
+}
+
+func (s *fakeStorage) UploadFromReader(
+	ctx context.Context,
+	content io.Reader,
+	fileName string,
+	path string,
+) (string, error) {
+	return s.store(content, fileName, path)
+}
+
+func (s *fakeStorage) Download(ctx context.Context, fileName string, path string) (io.ReadCloser, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	content, ok := s.objects[path+"/"+fileName]
+	if !ok {
+		return nil, storage.ErrObjectNotFound
+	}
+
+	return io.NopCloser(bytes.NewReader(content)), nil
+}
+
+func (s *fakeStorage) Delete(ctx context.Context, fileName string, path string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.objects, path+"/"+fileName)
+	delete(s.updatedAt, path+"/"+fileName)
+
+	return nil
+}
+
+func (s *fakeStorage) List(ctx context.Context, path string) ([]storage.Object, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	objects := []storage.Object{}
+	for key := range s.objects {
+		fileName := strings.TrimPrefix(key, path+"/")
+		if fileName == key || strings.Contains(fileName, "/") {
+			continue
+		}
+
+		objects = append(objects, storage.Object{FileName: fileName, Path: path, UpdatedAt: s.updatedAt[key]})
+	}
+
+	sort.Slice(objects, func(i, j int) bool {
+		return objects[i].FileName < objects[j].FileName
+	})
+
+	return objects, nil
+}
+
+// age moves the update time of the object back
+func (s *fakeStorage) age(key string, age time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.updatedAt[key] = s.updatedAt[key].Add(-age)
+}
+
+func (s *fakeStorage) store(content io.Reader, fileName string, path string) (string, error) {
+	raw, err := io.ReadAll(content)
+	if err != nil {
+		return "", err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.objects[path+"/"+fileName] = raw
+	s.updatedAt[path+"/"+fileName] = time.Now()
+
+	return path + "/" + fileName, nil
+}
+
+// fakeJWT hands out opaque tokens mapped to the claims they were generated from
+type fakeJWT struct {
+	mu            sync.Mutex
+	claims        map[string]map[string]interface{}
+	refreshTokens int64
+}
+
+func (j *fakeJWT) GenerateToken(data interface{}) (string, error) {
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return "", err
+	}
+
+	var claims map[string]interface{}
+	if err := json.Unmarshal(raw, &claims); err != nil {
+		return "", err
+	}
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	token := fmt.Sprintf("token-%d", len(j.claims)+1)
+	j.claims[token] = claims
+
+	return token, nil
+}
+
+func (j *fakeJWT) DecodeToken(token string) (map[string]interface{}, error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	claims, ok := j.claims[token]
+	if !ok {
+		return nil, fmt.Errorf("invalid token")
+	}
+
+	return map[string]interface{}{"data": claims}, nil
+}
+
+func (j *fakeJWT) JWKS() jwt.JWKS {
+	return jwt.JWKS{Keys: []jwt.JWK{}}
+}
+
+func (j *fakeJWT) GenerateRefreshToken() (jwt.RefreshToken, error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	j.refreshTokens++
+	token := fmt.Sprintf("refresh-token-%d", j.refreshTokens)
+
+	return jwt.RefreshToken{
+		Token:     token,
+		Hash:      jwt.HashRefreshToken(token),
+		ExpiresAt: time.Now().Add(time.Hour).Unix(),
+	}, nil
+}
+
+// fakeTOTP accepts the codes ok-<step>, so the tests choose the time step of every code
+type fakeTOTP struct{}
+
+func (f *fakeTOTP) GenerateSecret() (totp.Secret, error) {
+	return totp.Secret{Plain: "TESTSECRET", Encrypted: "encrypted-TESTSECRET"}, nil
+}
+
+func (f *fakeTOTP) GetProvisioningURI(secret string, accountName string) string {
+	return "otpauth://totp/Tigaputera:" + accountName + "?secret=" + secret
+}
+
+func (f *fakeTOTP) Verify(encryptedSecret string, code string) (int64, bool) {
+	var step int64
+	if encryptedSecret == "" || !strings.HasPrefix(code, "ok-") {
+		return 0, false
+	}
+	if _, err := fmt.Sscanf(code, "ok-%d", &step); err != nil {
+		return 0, false
+	}
+
+	return step, true
+}
+
+type testServer struct {
+	rest *rest
+	repo repository.Interface
+	// db is the postgres database of TEST_DATABASE=postgres, it is nil on the in-memory repositories
+	db        *database.DB
+	storage   *fakeStorage
+	signedURL signedurl.Interface
+	tokens    map[string]string
+	// refreshTokens are the refresh tokens of the last login of the users
+	refreshTokens map[string]string
+	// receipts counts the default receipts, each one is a different image
+	receipts int64
+	// totpSteps counts the authenticator codes of the logins, each one is of a later time step
+	totpSteps int64
+}
+
+// newTestServer boots the api on the in-memory repositories with a director already seeded,
+// TEST_DATABASE=postgres boots it on the database of the DB_* env instead
+func newTestServer(t *testing.T) *testServer {
+	t.Helper()
+	t.Setenv("BCRYPT_SALT_ROUND", "4")
+	t.Setenv("SCHEDULER_KEY", testSchedulerKey)
+	t.Setenv("STORAGE_URL_SECRET_KEY", "storage-url-secret-of-the-test-server")
+
+	repo, db := newTestRepository(t)
+	jwtLib := &fakeJWT{claims: map[string]map[string]interface{}{}}
+	storage := &fakeStorage{objects: map[string][]byte{}, updatedAt: map[string]time.Time{}}
+	password := password.Init()
+	signedURL := signedurl.Init()
+
+	hashedPassword, err := password.Hash(testPassword)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	totpEnabledAt := time.Now().Unix()
+	if err := repo.User().Create(context.Background(), &model.User{
+		Username:          "direktur",
+		Name:              "Direktur",
+		Password:          hashedPassword,
+		IsFirstLogin:      new(bool),
+		Role:              model.Admin,
+		PasswordChangedAt: time.Now().Unix(),
+		TotpSecret:        "encrypted-TESTSECRET",
+		TotpEnabledAt:     &totpEnabledAt,
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	// the roles are seeded with their default permissions like the database
+	for role, permissions := range model.DefaultRolePermissions {
+		rolePermissions := []model.RolePermission{}
+		for _, permission := range permissions {
+			rolePermissions = append(rolePermissions, model.RolePermission{Role: role, Permission: permission})
+		}
+
+		if err := repo.RolePermission().Replace(context.Background(), role, rolePermissions); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Init only builds the server once per process
+	once = sync.Once{}
+	svc := service.Init(repo, jwtLib, password, storage, signedURL, imaging.Init(), &fakeTOTP{})
+
+	return &testServer{
+		rest:          Init(log.Init(), jwtLib, validator.Init(), svc),
+		repo:          repo,
+		db:            db,
+		storage:       storage,
+		signedURL:     signedURL,
+		tokens:        map[string]string{},
+		refreshTokens: map[string]string{},
+	}
+}
+
+// newTestRepository migrates an empty postgres schema for each test when TEST_DATABASE=postgres,
+// the database is dropped so it must be one for the tests only
+func newTestRepository(t *testing.T) (repository.Interface, *database.DB) {
+	t.Helper()
+
+	if os.Getenv("TEST_DATABASE") != "postgres" {
+		return memory.Init(), nil
+	}
+
+	db, err := database.Init(log.Init())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sqlDB, err := db.DB.DB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { sqlDB.Close() })
+
+	// the audit logs are append-only, so the schema is recreated instead of truncating the tables
+	for _, query := range []string{"DROP SCHEMA public CASCADE", "CREATE SCHEMA public"} {
+		if err := db.Exec(query).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := db.Migrate(); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := db.RegisterAuditCallbacks(); err != nil {
+		t.Fatal(err)
+	}
+
+	return repository.Init(db), db
+}
+
+type testRequest struct {
+	method  string
+	path    string
+	user    string
+	headers map[string]string
+	body    interface{}
+	form    map[string]string
+	// receipt attaches a new image as the receiptImage form file
+	receipt bool
+	// receiptContent replaces the png attached by receipt
+	receiptContent []byte
+	// attachments are more receiptImage form files, attached after receipt
+	attachments []testAttachment
+}
+
+type testAttachment struct {
+	fileName string
+	content  []byte
+}
+
+type testResponse struct {
+	code    int
+	header  http.Header
+	body    model.HTTPResponse
+	rawData json.RawMessage
+}
+
+func (s *testServer) do(t *testing.T, req testRequest) testResponse {
+	t.Helper()
+
+	var body bytes.Buffer
+	contentType := ""
+	if req.body != nil {
+		if err := json.NewEncoder(&body).Encode(req.body); err != nil {
+			t.Fatal(err)
+		}
+		contentType = "application/json"
+	} else if req.form != nil || req.receipt || req.attachments != nil {
+		writer := multipart.NewWriter(&body)
+		for key, value := range req.form {
+			if err := writer.WriteField(key, value); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		attachments := req.attachments
+		if req.receipt {
+			content := req.receiptContent
+			if content == nil {
+				s.receipts++
+				content = testReceiptImage(t, s.receipts, 0)
+			}
+			attachments = append([]testAttachment{{fileName: "receipt.png", content: content}}, attachments...)
+		}
+
+		for _, attachment := range attachments {
+			part, err := writer.CreateFormFile("receiptImage", attachment.fileName)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err := part.Write(attachment.content); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		if err := writer.Close(); err != nil {
+			t.Fatal(err)
+		}
+		contentType = writer.FormDataContentType()
+	}
+
+	httpReq := httptest.NewRequest(req.method, req.path, &body)
+	if contentType != "" {
+		httpReq.Header.Set("Content-Type", contentType)
+	}
+	if req.user != "" {
+		httpReq.Header.Set("Authorization", "Bearer "+s.tokens[req.user])
+	}
+	for key, value := range req.headers {
+		httpReq.Header.Set(key, value)
+	}
+
+	recorder := httptest.NewRecorder()
+	s.rest.http.ServeHTTP(recorder, httpReq)
+
+	res := testResponse{code: recorder.Code, header: recorder.Header()}
+	var envelope struct {
+		model.HTTPResponse
+		Data json.RawMessage `json:"data"`
+	}
+	if err := json.Unmarshal(recorder.Body.Bytes(), &envelope); err != nil {
+		t.Fatalf("%s %s: invalid response body %q", req.method, req.path, recorder.Body.String())
+	}
+	res.body = envelope.HTTPResponse
+	res.rawData = envelope.Data
+
+	return res
+}
+
+// testReceiptImage encodes a small png of random blocks, the receipts are decoded and re-encoded by the api.
+// The same seed draws the same image, brightness lightens it like a second photo of the same nota.
+func testReceiptImage(t *testing.T, seed int64, brightness uint8) []byte {
+	t.Helper()
+
+	random := mathrand.New(mathrand.NewSource(seed))
+	img := image.NewRGBA(image.Rect(0, 0, 40, 30))
+	for y := 0; y < 30; y += 5 {
+		for x := 0; x < 40; x += 5 {
+			gray := uint8(random.Intn(200)) + brightness
+			block := image.NewUniform(color.RGBA{R: gray, G: gray, B: gray, A: 255})
+			draw.Draw(img, image.Rect(x, y, x+5, y+5), block, image.Point{}, draw.Src)
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+
+	return buf.Bytes()
+}
+
+// testReceiptPDF is a minimal pdf, the pdf receipts are stored as sent
+var testReceiptPDF = []byte("%PDF-1.4\n1 0 obj\n<< /Type /Catalog >>\nendobj\ntrailer\n<< /Root 1 0 R >>\n%%EOF\n")
+
+func (s *testServer) login(t *testing.T, user string, username string) {
+	t.Helper()
+
+	res := s.do(t, testRequest{
+		method: http.MethodPost,
+		path:   "/v1/auth/login",
+		body:   model.UserLoginBody{Username: username, Password: testPassword},
+	})
+	if res.code != http.StatusOK {
+		t.Fatalf("login %s: got status %d, %s", username, res.code, res.body.Message.Description)
+	}
+
+	var loginResponse model.UserLoginResponse
+	if err := json.Unmarshal(res.rawData, &loginResponse); err != nil {
+		t.Fatal(err)
+	}
+
+	if loginResponse.IsTotpRequired {
+		s.totpSteps++
+		res = s.do(t, testRequest{
+			method: http.MethodPost,
+			path:   "/v1/auth/login/totp",
+			body: model.TotpLoginBody{
+				ChallengeToken: loginResponse.ChallengeToken,
+				Code:           fmt.Sprintf("ok-%d", s.totpSteps),
+			},
+		})
+		if res.code != http.StatusOK {
+			t.Fatalf("login %s with totp: got status %d, %s", username, res.code, res.body.Message.Description)
+		}
+
+		loginResponse = model.UserLoginResponse{}
+		if err := json.Unmarshal(res.rawData, &loginResponse); err != nil {
+			t.Fatal(err)
+		}
+	}
+	s.tokens[user] = loginResponse.Token
+	s.refreshTokens[user] = loginResponse.RefreshToken
+
+	// the tests that don't cover the password change keep the password the director set
+	if *loginResponse.User.IsFirstLogin {
+		storedUser, err := s.repo.User().Get(context.Background(), loginResponse.User.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := s.repo.User().UpdatePassword(context.Background(), storedUser.ID, storedUser.Password); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+type testCase struct {
+	name        string
+	req         testRequest
+	wantCode    int
+	wantMessage string
+	// check asserts the data of the response
+	check func(t *testing.T, res testResponse)
+}
+
+func (s *testServer) run(t *testing.T, cases []testCase) {
+	for _, tc := range cases {
+		ok := t.Run(tc.name, func(t *testing.T) {
+			res := s.do(t, tc.req)
+
+			if res.code != tc.wantCode {
+				t.Fatalf("got status %d, want %d: %s", res.code, tc.wantCode, res.body.Message.Description)
+			}
+
+			wantSuccess := tc.wantCode < 300
+			if res.body.IsSuccess != wantSuccess {
+				t.Errorf("got isSuccess %v, want %v", res.body.IsSuccess, wantSuccess)
+			}
+
+			if res.body.Meta.RequestID == "" {
+				t.Error("got an empty request id")
+			}
+
+			if tc.wantMessage != "" && res.body.Message.Description != tc.wantMessage {
+				t.Errorf("got message %q, want %q", res.body.Message.Description, tc.wantMessage)
+			}
+
+			if tc.check != nil {
+				tc.check(t, res)
+			}
+		})
+
+		// the cases build on each other, so the rest can't pass once one fails
+		if !ok {
+			break
+		}
+	}
+}
+
+func decodeData(t *testing.T, res testResponse, data interface{}) {
+	t.Helper()
+
+	if err := json.Unmarshal(res.rawData, data); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func checkProjectBalance(want string, wantTransactions int) func(t *testing.T, res testResponse) {
+	return func(t *testing.T, res testResponse) {
+		var ledger model.ProjectLedgerResponse
+		decodeData(t, res, &ledger)
+
+		if ledger.Account.CurrentBalance != want {
+			t.Errorf("got project balance %q, want %q", ledger.Account.CurrentBalance, want)
+		}
+
+		if len(ledger.Transactions) != wantTransactions {
+			t.Errorf("got %d transactions, want %d", len(ledger.Transactions), wantTransactions)
+		}
+	}
+}
+
+func checkInspectorBalance(want string) func(t *testing.T, res testResponse) {
+	return func(t *testing.T, res testResponse) {
+		var ledger model.InspectorLedgerResponse
+		decodeData(t, res, &ledger)
+
+		if ledger.Account.CurrentBalance != want {
+			t.Errorf("got inspector balance %q, want %q", ledger.Account.CurrentBalance, want)
+		}
+	}
+}
+
+func TestAuthorization(t *testing.T) {
+	s := newTestServer(t)
+	s.tokens["invalid"] = "not-a-token"
+
+	s.run(t, []testCase{
+		{
+			name:     "ping",
+			req:      testRequest{method: http.MethodGet, path: "/ping"},
+			wantCode: http.StatusOK,
+		},
+		{
+			name: "login with a wrong password",
+			req: testRequest{
+				method: http.MethodPost,
+				path:   "/v1/auth/login",
+				body:   model.UserLoginBody{Username: "direktur", Password: "wrong-password"},
+			},
+			wantCode:    http.StatusUnauthorized,
+			wantMessage: "Username atau password salah",
+		},
+		{
+			name: "login with an unknown user",
+			req: testRequest{
+				method: http.MethodPost,
+				path:   "/v1/auth/login",
+				body:   model.UserLoginBody{Username: "unknown", Password: testPassword},
+			},
+			wantCode:    http.StatusUnauthorized,
+			wantMessage: "Username atau password salah",
+		},
+		{
+			name:     "profile without a token",
+			req:      testRequest{method: http.MethodGet, path: "/v1/user/profile"},
+			wantCode: http.StatusUnauthorized,
+		},
+		{
+			name:     "profile with an invalid token",
+			req:      testRequest{method: http.MethodGet, path: "/v1/user/profile", user: "invalid"},
+			wantCode: http.StatusUnauthorized,
+		},
+		{
+			name:     "download without a signature",
+			req:      testRequest{method: http.MethodGet, path: "/v1/storage/incomes/receipt.png"},
+			wantCode: http.StatusUnauthorized,
+		},
+		{
+			name: "download with a tampered signature",
+			req: testRequest{
+				method: http.MethodGet,
+				path:   "/v1/storage/incomes/receipt.png?expires=9999999999&signature=00",
+			},
+			wantCode: http.StatusUnauthorized,
+		},
+		{
+			name: "refresh statistics without the scheduler key",
+			req: testRequest{
+				method: http.MethodPut,
+				path:   "/v1/user/statistics/refresh",
+			},
+			wantCode: http.StatusUnauthorized,
+		},
+	})
+}
+
+func TestSession(t *testing.T) {
+	s := newTestServer(t)
+	s.login(t, "director", "direktur")
+
+	var inspectorID int64
+	var oldRefreshToken string
+
+	checkRefreshed := func(user string) func(t *testing.T, res testResponse) {
+		return func(t *testing.T, res testResponse) {
+			var loginResponse model.UserLoginResponse
+			decodeData(t, res, &loginResponse)
+
+			if loginResponse.RefreshToken == "" || loginResponse.RefreshToken == s.refreshTokens[user] {
+				t.Errorf("got refresh token %q, want a new one", loginResponse.RefreshToken)
+			}
+
+			oldRefreshToken = s.refreshTokens[user]
+			s.tokens[user] = loginResponse.Token
+			s.refreshTokens[user] = loginResponse.RefreshToken
+		}
+	}
+
+	s.run(t, []testCase{
+		{
+			name: "director creates an inspector",
+			req: testRequest{
+				method: http.MethodPost,
+				path:   "/v1/user/inspector",
+				user:   "director",
+				body: model.CreateInspectorBody{
+					Username: "pengawas1",
+					Name:     "Pengawas Satu",
+					Password: testPassword,
+				},
+			},
+			wantCode: http.StatusCreated,
+			check: func(t *testing.T, res testResponse) {
+				inspector, err := s.repo.User().GetByUsername(context.Background(), "pengawas1")
+				if err != nil {
+					t.Fatal(err)
+				}
+				inspectorID = inspector.ID
+				s.login(t, "inspector", "pengawas1")
+			},
+		},
+		{
+			name: "refresh with an unknown token",
+			req: testRequest{
+				method: http.MethodPost,
+				path:   "/v1/auth/refresh",
+				body:   model.RefreshTokenBody{RefreshToken: "unknown"},
+			},
+			wantCode: http.StatusUnauthorized,
+		},
+		{
+			name: "director refreshes the tokens",
+			req: testRequest{
+				method: http.MethodPost,
+				path:   "/v1/auth/refresh",
+				body:   model.RefreshTokenBody{RefreshToken: s.refreshTokens["director"]},
+			},
+			wantCode:    http.StatusOK,
+			wantMessage: "Berhasil memperbarui token",
+			check:       checkRefreshed("director"),
+		},
+		{
+			name:     "refreshed access token works",
+			req:      testRequest{method: http.MethodGet, path: "/v1/user/profile", user: "director"},
+			wantCode: http.StatusOK,
+		},
+	})
+
+	s.run(t, []testCase{
+		{
+			name: "reusing the rotated refresh token revokes the session",
+			req: testRequest{
+				method: http.MethodPost,
+				path:   "/v1/auth/refresh",
+				body:   model.RefreshTokenBody{RefreshToken: oldRefreshToken},
+			},
+			wantCode: http.StatusUnauthorized,
+		},
+		{
+			name:     "access token of the revoked session is rejected",
+			req:      testRequest{method: http.MethodGet, path: "/v1/user/profile", user: "director"},
+			wantCode: http.StatusUnauthorized,
+		},
+		{
+			name: "newest refresh token of the revoked session is rejected",
+			req: testRequest{
+				method: http.MethodPost,
+				path:   "/v1/auth/refresh",
+				body:   model.RefreshTokenBody{RefreshToken: s.refreshTokens["director"]},
+			},
+			wantCode: http.StatusUnauthorized,
+			check: func(t *testing.T, res testResponse) {
+				s.login(t, "director", "direktur")
+			},
+		},
+		{
+			name:        "director logs out",
+			req:         testRequest{method: http.MethodPost, path: "/v1/auth/logout", user: "director"},
+			wantCode:    http.StatusOK,
+			wantMessage: "Logout berhasil",
+		},
+		{
+			name:     "access token is rejected after the logout",
+			req:      testRequest{method: http.MethodGet, path: "/v1/user/profile", user: "director"},
+			wantCode: http.StatusUnauthorized,
+			check: func(t *testing.T, res testResponse) {
+				s.login(t, "director", "direktur")
+			},
+		},
+		{
+			name:     "inspector token works before the deactivation",
+			req:      testRequest{method: http.MethodGet, path: "/v1/user/profile", user: "inspector"},
+			wantCode: http.StatusOK,
+		},
+	})
+
+	s.run(t, []testCase{
+		{
+			name: "director deactivates the inspector",
+			req: testRequest{
+				method: http.MethodDelete,
+				path:   fmt.Sprintf("/v1/user/inspector/%d", inspectorID),
+				user:   "director",
+			},
+			wantCode: http.StatusOK,
+		},
+		{
+			name:     "access token of the deactivated inspector is rejected",
+			req:      testRequest{method: http.MethodGet, path: "/v1/user/profile", user: "inspector"},
+			wantCode: http.StatusUnauthorized,
+		},
+		{
+			name: "refresh token of the deactivated inspector is rejected",
+			req: testRequest{
+				method: http.MethodPost,
+				path:   "/v1/auth/refresh",
+				body:   model.RefreshTokenBody{RefreshToken: s.refreshTokens["inspector"]},
+			},
+			wantCode: http.StatusUnauthorized,
+		},
+	})
+}
+
+// writeTestKey writes the private key in pkcs8 and returns its public key in pkix
+func writeTestKey(t *testing.T, dir string, keyID string, key interface{}) []byte {
+	t.Helper()
+
+	raw, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(
+		filepath.Join(dir, keyID+".pem"),
+		pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: raw}),
+		0600,
+	); err != nil {
+		t.Fatal(err)
+	}
+
+	publicRaw, err := x509.MarshalPKIXPublicKey(key.(interface{ Public() crypto.PublicKey }).Public())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: publicRaw})
+}
+
+func TestJWTKeyRotation(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("JWT_KEYS_DIR", dir)
+	t.Setenv("JWT_EXPIRED_TIME_SEC", "900")
+
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rsaPublicKey := writeTestKey(t, dir, "2025-rsa", rsaKey)
+
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	writeTestKey(t, dir, "2026-ed", edKey)
+
+	data := map[string]interface{}{"id": float64(1)}
+
+	t.Setenv("JWT_SIGNING_KEY_ID", "2025-rsa")
+	oldToken, err := jwt.Init().GenerateToken(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the private key of the old key is removed, its public key keeps verifying the tokens it signed
+	if err := os.Remove(filepath.Join(dir, "2025-rsa.pem")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "2025-rsa.pub"), rsaPublicKey, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Setenv("JWT_SIGNING_KEY_ID", "2026-ed")
+	jwtLib := jwt.Init()
+
+	newToken, err := jwtLib.GenerateToken(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for name, token := range map[string]string{"old": oldToken, "new": newToken} {
+		claims, err := jwtLib.DecodeToken(token)
+		if err != nil {
+			t.Fatalf("%s token: %v", name, err)
+		}
+		if claims["data"].(map[string]interface{})["id"] != float64(1) {
+			t.Errorf("%s token: got claims %v", name, claims)
+		}
+	}
+
+	// an hmac token keyed with the public key is the classic algorithm confusion
+	hmacToken := gojwt.NewWithClaims(gojwt.SigningMethodHS256, gojwt.MapClaims{"data": data})
+	hmacToken.Header["kid"] = "2025-rsa"
+	forged, err := hmacToken.SignedString(rsaPublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// a token of the ed25519 key labelled with the rsa key id
+	mislabeledToken := gojwt.NewWithClaims(gojwt.SigningMethodEdDSA, gojwt.MapClaims{"data": data})
+	mislabeledToken.Header["kid"] = "2025-rsa"
+	mislabeled, err := mislabeledToken.SignedString(edKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	noneToken := gojwt.NewWithClaims(gojwt.SigningMethodNone, gojwt.MapClaims{"data": data})
+	noneToken.Header["kid"] = "2026-ed"
+	unsigned, err := noneToken.SignedString(gojwt.UnsafeAllowNoneSignatureType)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for name, token := range map[string]string{"hmac": forged, "mislabeled": mislabeled, "none": unsigned} {
+		if _, err := jwtLib.DecodeToken(token); err == nil {
+			t.Errorf("%s token: got no error", name)
+		}
+	}
+
+	s := newTestServer(t)
+	s.rest.jwt = jwtLib
+
+	recorder := httptest.NewRecorder()
+	s.rest.http.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/.well-known/jwks.json", nil))
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("got status %d", recorder.Code)
+	}
+
+	var jwks jwt.JWKS
+	if err := json.Unmarshal(recorder.Body.Bytes(), &jwks); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []jwt.JWK{{Kty: "RSA", Alg: "RS256", Kid: "2025-rsa"}, {Kty: "OKP", Alg: "EdDSA", Kid: "2026-ed"}}
+	if len(jwks.Keys) != len(want) {
+		t.Fatalf("got %d keys, want %d", len(jwks.Keys), len(want))
+	}
+	for i, key := range jwks.Keys {
+		if key.Kty != want[i].Kty || key.Alg != want[i].Alg || key.Kid != want[i].Kid || key.Use != "sig" {
+			t.Errorf("got key %+v, want %+v", key, want[i])
+		}
+	}
+}
+
+func TestPasswordPolicy(t *testing.T) {
+	s := newTestServer(t)
+	s.login(t, "director", "direktur")
+
+	// loginFirst logs in without changing the password, so the password change is still required
+	loginFirst := func(user string, username string, password string) func(t *testing.T, res testResponse) {
+		return func(t *testing.T, _ testResponse) {
+			res := s.do(t, testRequest{
+				method: http.MethodPost,
+				path:   "/v1/auth/login",
+				body:   model.UserLoginBody{Username: username, Password: password},
+			})
+
+			var loginResponse model.UserLoginResponse
+			decodeData(t, res, &loginResponse)
+			if !loginResponse.User.MustChangePassword() {
+				t.Errorf("got user %+v, want a required password change", loginResponse.User)
+			}
+			s.tokens[user] = loginResponse.Token
+		}
+	}
+
+	createInspector := func(password string) testRequest {
+		return testRequest{
+			method: http.MethodPost,
+			path:   "/v1/user/inspector",
+			user:   "director",
+			body: model.CreateInspectorBody{
+				Username: "pengawas1",
+				Name:     "Pengawas Satu",
+				Password: password,
+			},
+		}
+	}
+
+	resetPassword := func(currentPassword string, password string) testRequest {
+		return testRequest{
+			method: http.MethodPatch,
+			path:   "/v1/user/reset-password",
+			user:   "inspector",
+			body:   model.ResetPasswordBody{CurrentPassword: currentPassword, NewPassword: password},
+		}
+	}
+
+	s.run(t, []testCase{
+		{
+			name:        "inspector password is too short",
+			req:         createInspector("pass"),
+			wantCode:    http.StatusBadRequest,
+			wantMessage: "Password minimal 8 karakter",
+		},
+		{
+			name:        "inspector password is the username",
+			req:         createInspector("Pengawas1"),
+			wantCode:    http.StatusBadRequest,
+			wantMessage: "Password tidak boleh sama dengan username",
+		},
+		{
+			name:     "director creates an inspector",
+			req:      createInspector(testPassword),
+			wantCode: http.StatusCreated,
+			check:    loginFirst("inspector", "pengawas1", testPassword),
+		},
+		{
+			name:        "first login user can't list the projects",
+			req:         testRequest{method: http.MethodGet, path: "/v1/project", user: "inspector"},
+			wantCode:    http.StatusForbidden,
+			wantMessage: "Silakan ganti password anda terlebih dahulu",
+		},
+		{
+			name:     "first login user can see the profile",
+			req:      testRequest{method: http.MethodGet, path: "/v1/user/profile", user: "inspector"},
+			wantCode: http.StatusOK,
+		},
+		{
+			name:        "new password is the one the director set",
+			req:         resetPassword("", testPassword),
+			wantCode:    http.StatusBadRequest,
+			wantMessage: "Password tidak boleh sama dengan 5 password terakhir",
+		},
+		{
+			name:        "new password is the username",
+			req:         resetPassword("", "pengawas1"),
+			wantCode:    http.StatusBadRequest,
+			wantMessage: "Password tidak boleh sama dengan username",
+		},
+		{
+			name:     "first login user changes the password without the current one",
+			req:      resetPassword("", "rahasia-baru-1"),
+			wantCode: http.StatusOK,
+		},
+		{
+			name:     "inspector lists the projects after the password change",
+			req:      testRequest{method: http.MethodGet, path: "/v1/project", user: "inspector"},
+			wantCode: http.StatusOK,
+			check: func(t *testing.T, res testResponse) {
+				res = s.do(t, testRequest{
+					method: http.MethodPost,
+					path:   "/v1/auth/login",
+					body:   model.UserLoginBody{Username: "pengawas1", Password: "rahasia-baru-1"},
+				})
+
+				var loginResponse model.UserLoginResponse
+				decodeData(t, res, &loginResponse)
+				s.tokens["other-session"] = loginResponse.Token
+			},
+		},
+		{
+			name:        "inspector can't change the password without the current one",
+			req:         resetPassword("", "rahasia-baru-2"),
+			wantCode:    http.StatusBadRequest,
+			wantMessage: "Password lama salah",
+		},
+		{
+			name:        "inspector can't change the password with a wrong current one",
+			req:         resetPassword(testPassword, "rahasia-baru-2"),
+			wantCode:    http.StatusBadRequest,
+			wantMessage: "Password lama salah",
+		},
+		{
+			name:     "inspector changes the password again",
+			req:      resetPassword("rahasia-baru-1", "rahasia-baru-2"),
+			wantCode: http.StatusOK,
+		},
+		{
+			name:     "other session of the inspector is logged out by the password change",
+			req:      testRequest{method: http.MethodGet, path: "/v1/user/profile", user: "other-session"},
+			wantCode: http.StatusUnauthorized,
+		},
+		{
+			name:     "session changing the password is kept",
+			req:      testRequest{method: http.MethodGet, path: "/v1/user/profile", user: "inspector"},
+			wantCode: http.StatusOK,
+		},
+		{
+			name:        "inspector reuses a previous password",
+			req:         resetPassword("rahasia-baru-2", "rahasia-baru-1"),
+			wantCode:    http.StatusBadRequest,
+			wantMessage: "Password tidak boleh sama dengan 5 password terakhir",
+		},
+	})
+
+	hashedPassword, err := password.Init().Hash(testPassword)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := s.repo.User().Create(context.Background(), &model.User{
+		Username:          "pengawas2",
+		Name:              "Pengawas Dua",
+		Password:          hashedPassword,
+		IsFirstLogin:      new(bool),
+		Role:              model.Inspector,
+		PasswordChangedAt: time.Now().Add(-91 * 24 * time.Hour).Unix(),
+	}); err != nil {
+		t.Fatal(err)
+	}
+	loginFirst("inspector", "pengawas2", testPassword)(t, testResponse{})
+
+	s.run(t, []testCase{
+		{
+			name:     "expired password user can't list the projects",
+			req:      testRequest{method: http.MethodGet, path: "/v1/project", user: "inspector"},
+			wantCode: http.StatusForbidden,
+		},
+		{
+			name:     "expired password user changes the password",
+			req:      resetPassword("", "rahasia-baru-3"),
+			wantCode: http.StatusOK,
+		},
+		{
+			name:     "expired password user lists the projects after the password change",
+			req:      testRequest{method: http.MethodGet, path: "/v1/project", user: "inspector"},
+			wantCode: http.StatusOK,
+		},
+	})
+}
+
+func TestLoginThrottle(t *testing.T) {
+	s := newTestServer(t)
+	s.login(t, "director", "direktur")
+
+	login := func(username string, password string) testRequest {
+		return testRequest{
+			method: http.MethodPost,
+			path:   "/v1/auth/login",
+			body:   model.UserLoginBody{Username: username, Password: password},
+		}
+	}
+
+	cases := []testCase{
+		{
+			name: "director creates an inspector",
+			req: testRequest{
+				method: http.MethodPost,
+				path:   "/v1/user/inspector",
+				user:   "director",
+				body: model.CreateInspectorBody{
+					Username: "pengawas1",
+					Name:     "Pengawas Satu",
+					Password: testPassword,
+				},
+			},
+			wantCode: http.StatusCreated,
+			check: func(t *testing.T, res testResponse) {
+				s.login(t, "inspector", "pengawas1")
+			},
+		},
+	}
+	for i := 1; i <= 5; i++ {
+		cases = append(cases, testCase{
+			name:        fmt.Sprintf("wrong password %d", i),
+			req:         login("Pengawas1", "wrong-password"),
+			wantCode:    http.StatusUnauthorized,
+			wantMessage: "Username atau password salah",
+		})
+	}
+	cases = append(cases, []testCase{
+		{
+			name:        "locked username rejects the right password",
+			req:         login("pengawas1", testPassword),
+			wantCode:    http.StatusTooManyRequests,
+			wantMessage: "Terlalu banyak percobaan login, silakan coba lagi dalam 1 menit",
+		},
+		{
+			name:     "inspector can't unlock a user",
+			req:      testRequest{method: http.MethodPost, path: "/v1/user/2/unlock", user: "inspector"},
+			wantCode: http.StatusUnauthorized,
+		},
+		{
+			name:     "director unlocks an unknown user",
+			req:      testRequest{method: http.MethodPost, path: "/v1/user/99/unlock", user: "director"},
+			wantCode: http.StatusNotFound,
+		},
+		{
+			name:        "director unlocks the inspector",
+			req:         testRequest{method: http.MethodPost, path: "/v1/user/2/unlock", user: "director"},
+			wantCode:    http.StatusOK,
+			wantMessage: "Berhasil membuka kunci pengguna",
+		},
+		{
+			name:     "unlocked inspector logs in",
+			req:      login("pengawas1", testPassword),
+			wantCode: http.StatusOK,
+		},
+	}...)
+	// the ip address has 5 failures, the locked attempt isn't counted
+	for i := 6; i <= 20; i++ {
+		cases = append(cases, testCase{
+			name:        fmt.Sprintf("unknown username %d", i),
+			req:         login(fmt.Sprintf("unknown%d", i), testPassword),
+			wantCode:    http.StatusUnauthorized,
+			wantMessage: "Username atau password salah",
+		})
+	}
+	locked := login("direktur", testPassword)
+	locked.headers = map[string]string{"X-Forwarded-For": "203.0.113.7"}
+	cases = append(cases, testCase{
+		name:     "locked ip address can't be hidden behind a forwarded header",
+		req:      locked,
+		wantCode: http.StatusTooManyRequests,
+	})
+
+	s.run(t, cases)
+}
+
+func TestTotp(t *testing.T) {
+	s := newTestServer(t)
+	s.login(t, "director", "direktur")
+
+	hashedPassword, err := password.Init().Hash(testPassword)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.repo.User().Create(context.Background(), &model.User{
+		Username:          "direktur2",
+		Name:              "Direktur Dua",
+		Password:          hashedPassword,
+		IsFirstLogin:      new(bool),
+		Role:              model.Admin,
+		PasswordChangedAt: time.Now().Unix(),
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	var challengeToken string
+	var recoveryCodes []string
+
+	login := func(username string) testCase {
+		return testCase{
+			name: username + " logs in with the password",
+			req: testRequest{
+				method: http.MethodPost,
+				path:   "/v1/auth/login",
+				body:   model.UserLoginBody{Username: username, Password: testPassword},
+			},
+			wantCode: http.StatusOK,
+			check: func(t *testing.T, res testResponse) {
+				var loginResponse model.UserLoginResponse
+				decodeData(t, res, &loginResponse)
+				challengeToken = loginResponse.ChallengeToken
+				if loginResponse.IsTotpRequired {
+					return
+				}
+				s.tokens["director2"] = loginResponse.Token
+			},
+		}
+	}
+
+	loginTotp := func(code *string) testRequest {
+		return testRequest{
+			method: http.MethodPost,
+			path:   "/v1/auth/login/totp",
+			body:   map[string]string{"challengeToken": challengeToken, "code": *code},
+		}
+	}
+
+	code := func(value string) *string {
+		return &value
+	}
+
+	checkToken := func(t *testing.T, res testResponse) {
+		var loginResponse model.UserLoginResponse
+		decodeData(t, res, &loginResponse)
+		if loginResponse.Token == "" || loginResponse.RefreshToken == "" {
+			t.Errorf("got %+v, want the tokens", loginResponse)
+		}
+	}
+
+	s.run(t, []testCase{
+		{
+			name:        "director gets a challenge instead of the tokens",
+			req:         login("direktur").req,
+			wantCode:    http.StatusOK,
+			wantMessage: "Masukkan kode autentikator",
+			check: func(t *testing.T, res testResponse) {
+				var loginResponse model.UserLoginResponse
+				decodeData(t, res, &loginResponse)
+				if !loginResponse.IsTotpRequired || loginResponse.Token != "" || loginResponse.ChallengeToken == "" {
+					t.Errorf("got %+v, want only a challenge", loginResponse)
+				}
+				challengeToken = loginResponse.ChallengeToken
+			},
+		},
+	})
+
+	s.run(t, []testCase{
+		{
+			name:        "wrong authenticator code",
+			req:         loginTotp(code("123456")),
+			wantCode:    http.StatusUnauthorized,
+			wantMessage: "Kode autentikator salah",
+		},
+		{
+			name:     "the code of the last login can't be used again",
+			req:      loginTotp(code("ok-1")),
+			wantCode: http.StatusUnauthorized,
+		},
+		{
+			name:        "director finishes the login",
+			req:         loginTotp(code("ok-2")),
+			wantCode:    http.StatusOK,
+			wantMessage: "Login berhasil",
+			check:       checkToken,
+		},
+		{
+			name:        "the challenge can't be used again",
+			req:         loginTotp(code("ok-3")),
+			wantCode:    http.StatusUnauthorized,
+			wantMessage: "Sesi login telah berakhir, silakan login kembali",
+		},
+		login("direktur2"),
+		{
+			name:        "director without an authenticator can't list the projects",
+			req:         testRequest{method: http.MethodGet, path: "/v1/project", user: "director2"},
+			wantCode:    http.StatusForbidden,
+			wantMessage: "Silakan aktifkan autentikasi dua langkah terlebih dahulu",
+		},
+		{
+			name:     "director enrolls an authenticator",
+			req:      testRequest{method: http.MethodPost, path: "/v1/user/totp", user: "director2"},
+			wantCode: http.StatusOK,
+			check: func(t *testing.T, res testResponse) {
+				var enrollment model.TotpEnrollmentResponse
+				decodeData(t, res, &enrollment)
+				if enrollment.Secret != "TESTSECRET" || !strings.Contains(enrollment.ProvisioningURI, "direktur2") {
+					t.Errorf("got enrollment %+v", enrollment)
+				}
+			},
+		},
+		{
+			name: "director enables the authenticator with a wrong code",
+			req: testRequest{
+				method: http.MethodPost,
+				path:   "/v1/user/totp/verify",
+				user:   "director2",
+				body:   model.TotpCodeBody{Code: "123456"},
+			},
+			wantCode:    http.StatusBadRequest,
+			wantMessage: "Kode autentikator salah",
+		},
+		{
+			name: "director enables the authenticator",
+			req: testRequest{
+				method: http.MethodPost,
+				path:   "/v1/user/totp/verify",
+				user:   "director2",
+				body:   model.TotpCodeBody{Code: "ok-1"},
+			},
+			wantCode: http.StatusOK,
+			check: func(t *testing.T, res testResponse) {
+				var response model.TotpRecoveryCodesResponse
+				decodeData(t, res, &response)
+				if len(response.RecoveryCodes) != 10 {
+					t.Fatalf("got %d recovery codes, want 10", len(response.RecoveryCodes))
+				}
+				recoveryCodes = response.RecoveryCodes
+			},
+		},
+		{
+			name:     "director with an authenticator lists the projects",
+			req:      testRequest{method: http.MethodGet, path: "/v1/project", user: "director2"},
+			wantCode: http.StatusOK,
+		},
+		{
+			name: "director can't disable the authenticator",
+			req: testRequest{
+				method: http.MethodPost,
+				path:   "/v1/user/totp/disable",
+				user:   "director2",
+				body:   model.TotpCodeBody{Code: "ok-2"},
+			},
+			wantCode:    http.StatusBadRequest,
+			wantMessage: "Autentikasi dua langkah wajib untuk direktur",
+		},
+		login("direktur2"),
+	})
+
+	recoveryCode := strings.ToUpper(recoveryCodes[0])
+	s.run(t, []testCase{
+		{
+			name:     "director logs in with a recovery code",
+			req:      loginTotp(&recoveryCode),
+			wantCode: http.StatusOK,
+			check:    checkToken,
+		},
+		login("direktur2"),
+	})
+
+	s.run(t, []testCase{
+		{
+			name:     "a recovery code can't be used again",
+			req:      loginTotp(&recoveryCode),
+			wantCode: http.StatusUnauthorized,
+		},
+	})
+
+	// the inspector logs in before being made a director, the token still has the old role
+	totpEnabledAt := time.Now().Unix()
+	if err := s.repo.User().Create(context.Background(), &model.User{
+		Username:          "pengawas1",
+		Name:              "Pengawas Satu",
+		Password:          hashedPassword,
+		IsFirstLogin:      new(bool),
+		Role:              model.Inspector,
+		PasswordChangedAt: time.Now().Unix(),
+		TotpSecret:        "encrypted-TESTSECRET",
+		TotpEnabledAt:     &totpEnabledAt,
+	}); err != nil {
+		t.Fatal(err)
+	}
+	s.login(t, "inspector", "pengawas1")
+
+	inspector, err := s.repo.User().GetByUsername(context.Background(), "pengawas1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.repo.User().UpdateRole(context.Background(), inspector.ID, model.Admin); err != nil {
+		t.Fatal(err)
+	}
+
+	s.run(t, []testCase{
+		{
+			name: "promoted inspector can't disable the authenticator with the token of the old role",
+			req: testRequest{
+				method: http.MethodPost,
+				path:   "/v1/user/totp/disable",
+				user:   "inspector",
+				body:   model.TotpCodeBody{Code: "ok-100"},
+			},
+			wantCode:    http.StatusBadRequest,
+			wantMessage: "Autentikasi dua langkah wajib untuk direktur",
+		},
+	})
+
+	// the real authenticator codes, the fake accepts the codes of any step
+	totpLib := totp.Init(cryptolib.Init("0123456789abcdef0123456789abcdef"))
+	secret, err := totpLib.GenerateSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	now, err := totp.GenerateCode(secret.Plain, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := totpLib.Verify(secret.Encrypted, now); !ok {
+		t.Errorf("code %s of now isn't accepted", now)
+	}
+
+	old, err := totp.GenerateCode(secret.Plain, time.Now().Add(-5*time.Minute))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := totpLib.Verify(secret.Encrypted, old); ok && old != now {
+		t.Errorf("code %s of 5 minutes ago is accepted", old)
+	}
+}
+
+func TestPermission(t *testing.T) {
+	s := newTestServer(t)
+	s.login(t, "director", "direktur")
+
+	createInspector := func(username string) testCase {
+		return testCase{
+			name: "director creates " + username,
+			req: testRequest{
+				method: http.MethodPost,
+				path:   "/v1/user/inspector",
+				user:   "director",
+				body: model.CreateInspectorBody{
+					Username: username,
+					Name:     username,
+					Password: testPassword,
+				},
+			},
+			wantCode: http.StatusCreated,
+		}
+	}
+
+	checkProjectCount := func(want int) func(t *testing.T, res testResponse) {
+		return func(t *testing.T, res testResponse) {
+			var projects []model.ProjectListResponse
+			decodeData(t, res, &projects)
+
+			if len(projects) != want {
+				t.Errorf("got %d projects, want %d", len(projects), want)
+			}
+		}
+	}
+
+	income := func(user string) testRequest {
+		return testRequest{
+			method:  http.MethodPost,
+			path:    "/v1/project/1/income",
+			user:    user,
+			form:    map[string]string{"amount": "1000000", "ref": "Termin 1"},
+			receipt: true,
+		}
+	}
+
+	threshold := int64(100000)
+
+	s.run(t, []testCase{
+		createInspector("pengawas1"),
+		createInspector("pengawas2"),
+		{
+			name: "director creates a project of pengawas1",
+			req: testRequest{
+				method: http.MethodPost,
+				path:   "/v1/project",
+				user:   "director",
+				body: model.CreateProjectBody{
+					Name:        "Saluran Desa",
+					Description: "Pembangunan saluran",
+					Type:        string(model.Drainage),
+					DeptName:    "Dinas PU",
+					CompanyName: "Tigaputera",
+					InspectorID: 2,
+					StartDate:   1700000000,
+					FinalDate:   1710000000,
+				},
+			},
+			wantCode: http.StatusCreated,
+			check: func(t *testing.T, res testResponse) {
+				s.login(t, "inspector", "pengawas1")
+				s.login(t, "other", "pengawas2")
+			},
+		},
+		{
+			name:     "assigned inspector sees the project",
+			req:      testRequest{method: http.MethodGet, path: "/v1/project/1", user: "inspector"},
+			wantCode: http.StatusOK,
+		},
+		{
+			name:     "other inspector doesn't list the project",
+			req:      testRequest{method: http.MethodGet, path: "/v1/project", user: "other"},
+			wantCode: http.StatusOK,
+			check:    checkProjectCount(0),
+		},
+		{
+			name:        "other inspector can't see the project",
+			req:         testRequest{method: http.MethodGet, path: "/v1/project/1", user: "other"},
+			wantCode:    http.StatusNotFound,
+			wantMessage: "proyek tidak ditemukan",
+		},
+		{
+			name:     "other inspector can't see the project detail",
+			req:      testRequest{method: http.MethodGet, path: "/v1/project/1/detail", user: "other"},
+			wantCode: http.StatusNotFound,
+		},
+		{
+			name:     "other inspector can't see the project ledger",
+			req:      testRequest{method: http.MethodGet, path: "/v1/project/1/ledger", user: "other"},
+			wantCode: http.StatusNotFound,
+		},
+		{
+			name:        "other inspector can't add an income to the project",
+			req:         income("other"),
+			wantCode:    http.StatusNotFound,
+			wantMessage: "proyek tidak ditemukan",
+		},
+		{
+			name:     "assigned inspector adds an income",
+			req:      income("inspector"),
+			wantCode: http.StatusCreated,
+		},
+		{
+			name:     "inspector can't list the roles",
+			req:      testRequest{method: http.MethodGet, path: "/v1/role", user: "inspector"},
+			wantCode: http.StatusUnauthorized,
+		},
+		{
+			name: "director can't change the permissions of the director",
+			req: testRequest{
+				method: http.MethodPut,
+				path:   "/v1/role/Direktur",
+				user:   "director",
+				body:   model.UpdateRoleBody{Permissions: []model.Permission{model.ProjectReadAll}},
+			},
+			wantCode:    http.StatusBadRequest,
+			wantMessage: "Hak akses direktur tidak dapat diubah",
+		},
+		{
+			name: "director can't grant an unknown permission",
+			req: testRequest{
+				method: http.MethodPut,
+				path:   "/v1/role/Auditor",
+				user:   "director",
+				body:   model.UpdateRoleBody{Permissions: []model.Permission{"ledger:delete"}},
+			},
+			wantCode:    http.StatusBadRequest,
+			wantMessage: "Hak akses ledger:delete tidak dikenal",
+		},
+		{
+			name: "director creates a read-only auditor role",
+			req: testRequest{
+				method: http.MethodPut,
+				path:   "/v1/role/Auditor",
+				user:   "director",
+				body: model.UpdateRoleBody{Permissions: []model.Permission{
+					model.ProjectReadAll,
+					model.LedgerReadAll,
+					model.ProjectReadAll,
+				}},
+			},
+			wantCode:    http.StatusOK,
+			wantMessage: "Berhasil mengubah hak akses peran",
+		},
+		{
+			name:     "director lists the roles",
+			req:      testRequest{method: http.MethodGet, path: "/v1/role", user: "director"},
+			wantCode: http.StatusOK,
+			check: func(t *testing.T, res testResponse) {
+				var roles []model.RoleResponse
+				decodeData(t, res, &roles)
+
+				want := map[model.Role]int{
+					model.Admin:     len(model.AdminPermissions),
+					"Auditor":       2,
+					model.Inspector: len(model.DefaultRolePermissions[model.Inspector]),
+				}
+				if len(roles) != len(want) {
+					t.Fatalf("got %d roles, want %d", len(roles), len(want))
+				}
+				for _, role := range roles {
+					if len(role.Permissions) != want[role.Role] {
+						t.Errorf("got %d permissions of %s, want %d", len(role.Permissions), role.Role, want[role.Role])
+					}
+				}
+			},
+		},
+		{
+			name: "director can't give an unknown role",
+			req: testRequest{
+				method: http.MethodPatch,
+				path:   "/v1/user/3/role",
+				user:   "director",
+				body:   model.UpdateUserRoleBody{Role: "Bendahara"},
+			},
+			wantCode:    http.StatusNotFound,
+			wantMessage: "Peran tidak ditemukan",
+		},
+		{
+			name: "director can't give the director role",
+			req: testRequest{
+				method: http.MethodPatch,
+				path:   "/v1/user/3/role",
+				user:   "director",
+				body:   model.UpdateUserRoleBody{Role: string(model.Admin)},
+			},
+			wantCode: http.StatusBadRequest,
+		},
+		{
+			name: "director can't change the role of a director",
+			req: testRequest{
+				method: http.MethodPatch,
+				path:   "/v1/user/1/role",
+				user:   "director",
+				body:   model.UpdateUserRoleBody{Role: "Auditor"},
+			},
+			wantCode: http.StatusBadRequest,
+		},
+		{
+			name: "director makes pengawas2 an auditor",
+			req: testRequest{
+				method: http.MethodPatch,
+				path:   "/v1/user/3/role",
+				user:   "director",
+				body:   model.UpdateUserRoleBody{Role: "Auditor"},
+			},
+			wantCode:    http.StatusOK,
+			wantMessage: "Berhasil mengubah peran pengguna",
+		},
+		{
+			name:     "auditor lists every project without logging in again",
+			req:      testRequest{method: http.MethodGet, path: "/v1/project", user: "other"},
+			wantCode: http.StatusOK,
+			check:    checkProjectCount(1),
+		},
+		{
+			name:     "auditor sees the project ledger",
+			req:      testRequest{method: http.MethodGet, path: "/v1/project/1/ledger", user: "other"},
+			wantCode: http.StatusOK,
+			check:    checkProjectBalance("Rp. 1.000.000", 1),
+		},
+		{
+			name:     "auditor can't add an income",
+			req:      income("other"),
+			wantCode: http.StatusUnauthorized,
+		},
+		{
+			name:     "auditor can't create a project",
+			req:      testRequest{method: http.MethodPost, path: "/v1/project", user: "other", body: model.CreateProjectBody{}},
+			wantCode: http.StatusUnauthorized,
+		},
+		{
+			name:     "profile shows the permissions of the role",
+			req:      testRequest{method: http.MethodGet, path: "/v1/user/profile", user: "other"},
+			wantCode: http.StatusOK,
+			check: func(t *testing.T, res testResponse) {
+				var user model.User
+				decodeData(t, res, &user)
+
+				if len(user.Permissions) != 2 || user.Permissions[0] != model.ProjectReadAll {
+					t.Errorf("got permissions %v, want project:read:all and ledger:read:all", user.Permissions)
+				}
+			},
+		},
+		{
+			name:     "director lists the auditor with the inspectors",
+			req:      testRequest{method: http.MethodGet, path: "/v1/user/inspector", user: "director"},
+			wantCode: http.StatusOK,
+			check: func(t *testing.T, res testResponse) {
+				var users []model.User
+				decodeData(t, res, &users)
+
+				if len(users) != 2 || users[1].Role != "Auditor" {
+					t.Errorf("got users %+v, want pengawas1 and the auditor", users)
+				}
+			},
+		},
+		{
+			name: "director renames the auditor",
+			req: testRequest{
+				method: http.MethodPatch,
+				path:   "/v1/user/inspector/3",
+				user:   "director",
+				body:   model.UpdateInspectorBody{Name: "Auditor Satu"},
+			},
+			wantCode: http.StatusOK,
+		},
+		{
+			name: "director can't rename a director as an inspector",
+			req: testRequest{
+				method: http.MethodPatch,
+				path:   "/v1/user/inspector/1",
+				user:   "director",
+				body:   model.UpdateInspectorBody{Name: "Direktur Baru"},
+			},
+			wantCode:    http.StatusNotFound,
+			wantMessage: "Pengawas tidak ditemukan",
+		},
+		{
+			name: "a read-only auditor can't be given a project",
+			req: testRequest{
+				method: http.MethodPatch,
+				path:   "/v1/project/1/inspector",
+				user:   "director",
+				body:   model.ReassignProjectBody{InspectorID: 3},
+			},
+			wantCode:    http.StatusNotFound,
+			wantMessage: "Pengawas tidak ditemukan",
+		},
+		{
+			name: "director creates a finance role that records and approves",
+			req: testRequest{
+				method: http.MethodPut,
+				path:   "/v1/role/Bendahara",
+				user:   "director",
+				body: model.UpdateRoleBody{Permissions: []model.Permission{
+					model.ProjectReadOwn,
+					model.LedgerReadOwn,
+					model.LedgerWrite,
+					model.LedgerApprove,
+				}},
+			},
+			wantCode: http.StatusOK,
+		},
+		{
+			name: "director makes pengawas1 a finance officer",
+			req: testRequest{
+				method: http.MethodPatch,
+				path:   "/v1/user/2/role",
+				user:   "director",
+				body:   model.UpdateUserRoleBody{Role: "Bendahara"},
+			},
+			wantCode: http.StatusOK,
+		},
+		{
+			name: "director sets the approval threshold",
+			req: testRequest{
+				method: http.MethodPatch,
+				path:   "/v1/project/1/approval-threshold",
+				user:   "director",
+				body:   model.UpdateApprovalThresholdBody{Threshold: &threshold},
+			},
+			wantCode: http.StatusOK,
+		},
+		{
+			name: "finance officer spends above the threshold",
+			req: testRequest{
+				method:  http.MethodPost,
+				path:    "/v1/project/1/expenditure/1/transaction",
+				user:    "inspector",
+				form:    map[string]string{"name": "Semen", "price": "500000", "amount": "1"},
+				receipt: true,
+			},
+			wantCode:    http.StatusCreated,
+			wantMessage: "Pengeluaran proyek menunggu persetujuan direktur",
+		},
+		{
+			name: "finance officer can't approve the own expenditure",
+			req: testRequest{
+				method: http.MethodPatch,
+				path:   "/v1/project/1/expenditure/1/transaction/2/approve",
+				user:   "inspector",
+			},
+			wantCode:    http.StatusBadRequest,
+			wantMessage: "Pengeluaran tidak dapat disetujui oleh pengajunya sendiri",
+		},
+		{
+			name: "director approves the expenditure",
+			req: testRequest{
+				method: http.MethodPatch,
+				path:   "/v1/project/1/expenditure/1/transaction/2/approve",
+				user:   "director",
+			},
+			wantCode: http.StatusOK,
+		},
+	})
+}
+
+func TestAuditLog(t *testing.T) {
+	s := newTestServer(t)
+	s.login(t, "director", "direktur")
+
+	// the memory repositories have no gorm callbacks, so the logs are created like the callbacks create them
+	auditLogs, ok := s.repo.AuditLog().(interface {
+		Create(ctx context.Context, log *model.AuditLog) error
+	})
+	if !ok {
+		t.Skip("the audit logs of postgres are created by the callbacks, see TestAuditCallbacks")
+	}
+
+	directorID := int64(1)
+	for _, log := range []model.AuditLog{
+		{CreatedAt: 1700000000, UserID: &directorID, Action: model.AuditCreate, Entity: "projects", EntityID: 1},
+		{
+			CreatedAt: 1700000100,
+			UserID:    &directorID,
+			RequestID: "request-1",
+			IPAddress: "10.0.0.1",
+			Action:    model.AuditUpdate,
+			Entity:    "projects",
+			EntityID:  1,
+			Before:    json.RawMessage(`{"budget":1000000}`),
+			After:     json.RawMessage(`{"budget":2000000}`),
+		},
+		{CreatedAt: 1700000200, Action: model.AuditUpdate, Entity: "projects", EntityID: 2},
+		{CreatedAt: 1700000300, UserID: &directorID, Action: model.AuditDelete, Entity: "users", EntityID: 2},
+	} {
+		log := log
+		if err := auditLogs.Create(context.Background(), &log); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	checkAuditLogs := func(wantIDs ...int64) func(t *testing.T, res testResponse) {
+		return func(t *testing.T, res testResponse) {
+			var logs []model.AuditLog
+			decodeData(t, res, &logs)
+
+			ids := []int64{}
+			for _, log := range logs {
+				ids = append(ids, log.ID)
+			}
+			if fmt.Sprint(ids) != fmt.Sprint(wantIDs) {
+				t.Errorf("got audit logs %v, want %v", ids, wantIDs)
+			}
+		}
+	}
+
+	s.run(t, []testCase{
+		{
+			name: "director creates an inspector",
+			req: testRequest{
+				method: http.MethodPost,
+				path:   "/v1/user/inspector",
+				user:   "director",
+				body: model.CreateInspectorBody{
+					Username: "pengawas1",
+					Name:     "Pengawas Satu",
+					Password: testPassword,
+				},
+			},
+			wantCode: http.StatusCreated,
+			check: func(t *testing.T, res testResponse) {
+				s.login(t, "inspector", "pengawas1")
+			},
+		},
+		{
+			name:     "inspector can't see the audit logs",
+			req:      testRequest{method: http.MethodGet, path: "/v1/audit-log", user: "inspector"},
+			wantCode: http.StatusUnauthorized,
+		},
+		{
+			name:     "director lists the audit logs, the latest first",
+			req:      testRequest{method: http.MethodGet, path: "/v1/audit-log", user: "director"},
+			wantCode: http.StatusOK,
+			check:    checkAuditLogs(4, 3, 2, 1),
+		},
+		{
+			name:     "director filters the audit logs of a project",
+			req:      testRequest{method: http.MethodGet, path: "/v1/audit-log?entity=projects&entity_id=1", user: "director"},
+			wantCode: http.StatusOK,
+			check: func(t *testing.T, res testResponse) {
+				checkAuditLogs(2, 1)(t, res)
+
+				var logs []model.AuditLog
+				decodeData(t, res, &logs)
+				if string(logs[0].Before) != `{"budget":1000000}` || string(logs[0].After) != `{"budget":2000000}` {
+					t.Errorf("got the change %s to %s, want the budget change", logs[0].Before, logs[0].After)
+				}
+			},
+		},
+		{
+			name:     "director filters the audit logs of a user",
+			req:      testRequest{method: http.MethodGet, path: "/v1/audit-log?user_id=1", user: "director"},
+			wantCode: http.StatusOK,
+			check:    checkAuditLogs(4, 2, 1),
+		},
+		{
+			name: "director filters the audit logs by the date range",
+			req: testRequest{
+				method: http.MethodGet,
+				path:   "/v1/audit-log?from=1700000100&to=1700000200",
+				user:   "director",
+			},
+			wantCode: http.StatusOK,
+			check:    checkAuditLogs(3, 2),
+		},
+		{
+			name: "director can't filter by a reversed date range",
+			req: testRequest{
+				method: http.MethodGet,
+				path:   "/v1/audit-log?from=1700000200&to=1700000100",
+				user:   "director",
+			},
+			wantCode:    http.StatusBadRequest,
+			wantMessage: "Tanggal awal harus sebelum tanggal akhir",
+		},
+	})
+}
+
+func TestAuditCallbacks(t *testing.T) {
+	s := newTestServer(t)
+	if s.db == nil {
+		t.Skip("the memory repositories have no callbacks, run with TEST_DATABASE=postgres")
+	}
+	s.login(t, "director", "direktur")
+
+	var logs []model.AuditLog
+	listLogs := testRequest{method: http.MethodGet, path: "/v1/audit-log?entity=users&entity_id=2", user: "director"}
+	checkLatestLog := func(action model.AuditAction, wantBefore string, wantAfter string) func(t *testing.T, res testResponse) {
+		return func(t *testing.T, res testResponse) {
+			logs = nil
+			decodeData(t, res, &logs)
+			if len(logs) == 0 {
+				t.Fatal("got no audit log")
+			}
+
+			log := logs[0]
+			if log.Action != action || log.UserID == nil || *log.UserID != 1 || log.RequestID == "" {
+				t.Errorf("got the log %+v, want a %s by the director with its request", log, action)
+			}
+			if wantBefore != "" && !isJSONEqual(t, log.Before, wantBefore) {
+				t.Errorf("got before %s, want %s", log.Before, wantBefore)
+			}
+			if wantAfter != "" && !isJSONEqual(t, log.After, wantAfter) {
+				t.Errorf("got after %s, want %s", log.After, wantAfter)
+			}
+		}
+	}
+
+	s.run(t, []testCase{
+		{
+			name: "director creates an inspector",
+			req: testRequest{
+				method: http.MethodPost,
+				path:   "/v1/user/inspector",
+				user:   "director",
+				body: model.CreateInspectorBody{
+					Username: "pengawas1",
+					Name:     "Pengawas Satu",
+					Password: testPassword,
+				},
+			},
+			wantCode: http.StatusCreated,
+		},
+		{
+			name:     "the create is logged without the password",
+			req:      listLogs,
+			wantCode: http.StatusOK,
+			check: func(t *testing.T, res testResponse) {
+				checkLatestLog(model.AuditCreate, "", "")(t, res)
+
+				var after map[string]interface{}
+				if err := json.Unmarshal(logs[0].After, &after); err != nil {
+					t.Fatal(err)
+				}
+				if after["username"] != "pengawas1" || after["created_by"] != float64(1) {
+					t.Errorf("got the created row %v, want pengawas1 created by the director", after)
+				}
+				if _, ok := after["password"]; ok {
+					t.Error("the password is logged")
+				}
+			},
+		},
+		{
+			name: "director renames the inspector",
+			req: testRequest{
+				method: http.MethodPatch,
+				path:   "/v1/user/inspector/2",
+				user:   "director",
+				body:   model.UpdateInspectorBody{Name: "Pengawas Baru"},
+			},
+			wantCode: http.StatusOK,
+		},
+		{
+			name:     "the update is logged with the changed columns only",
+			req:      listLogs,
+			wantCode: http.StatusOK,
+			check: func(t *testing.T, res testResponse) {
+				checkLatestLog(model.AuditUpdate, `{"name":"Pengawas Satu"}`, `{"name":"Pengawas Baru"}`)(t, res)
+
+				inspector, err := s.repo.User().Get(context.Background(), 2)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if inspector.UpdatedBy == nil || *inspector.UpdatedBy != 1 {
+					t.Errorf("got updated by %v, want the director", inspector.UpdatedBy)
+				}
+			},
+		},
+		{
+			name:     "director deactivates the inspector",
+			req:      testRequest{method: http.MethodDelete, path: "/v1/user/inspector/2", user: "director"},
+			wantCode: http.StatusOK,
+		},
+		{
+			name:     "the soft delete is logged with the deleted row",
+			req:      listLogs,
+			wantCode: http.StatusOK,
+			check: func(t *testing.T, res testResponse) {
+				checkLatestLog(model.AuditDelete, "", "")(t, res)
+
+				var before map[string]interface{}
+				if err := json.Unmarshal(logs[0].Before, &before); err != nil {
+					t.Fatal(err)
+				}
+				if before["name"] != "Pengawas Baru" || (logs[0].After != nil && string(logs[0].After) != "null") {
+					t.Errorf("got the change %s to %s, want the deleted row only", logs[0].Before, logs[0].After)
+				}
+
+				var inspector model.User
+				if err := s.db.Unscoped().Take(&inspector, 2).Error; err != nil {
+					t.Fatal(err)
+				}
+				if inspector.DeletedBy == nil || *inspector.DeletedBy != 1 {
+					t.Errorf("got deleted by %v, want the director", inspector.DeletedBy)
+				}
+			},
+		},
+	})
+
+	// the logs can't be changed even without the api
+	for _, query := range []string{
+		"UPDATE audit_logs SET action = 'update'",
+		"DELETE FROM audit_logs",
+	} {
+		if err := s.db.Exec(query).Error; err == nil || !strings.Contains(err.Error(), "append-only") {
+			t.Errorf("%s: got %v, want the append-only error", query, err)
+		}
+	}
+}
+
+func isJSONEqual(t *testing.T, got json.RawMessage, want string) bool {
+	t.Helper()
+
+	var gotValue, wantValue interface{}
+	if err := json.Unmarshal(got, &gotValue); err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal([]byte(want), &wantValue); err != nil {
+		t.Fatal(err)
+	}
+
+	return fmt.Sprint(gotValue) == fmt.Sprint(wantValue)
+}
+
+func TestInspectorLifecycle(t *testing.T) {
+	s := newTestServer(t)
+	s.login(t, "director", "direktur")
+
+	threshold := int64(100000)
+
+	checkInspectors := func(want ...string) func(t *testing.T, res testResponse) {
+		return func(t *testing.T, res testResponse) {
+			var users []model.User
+			decodeData(t, res, &users)
+
+			names := []string{}
+			for _, user := range users {
+				names = append(names, user.Name)
+			}
+			if fmt.Sprint(names) != fmt.Sprint(want) {
+				t.Errorf("got inspectors %v, want %v", names, want)
+			}
+		}
+	}
+
+	s.run(t, []testCase{
+		{
+			name: "director creates an inspector",
+			req: testRequest{
+				method: http.MethodPost,
+				path:   "/v1/user/inspector",
+				user:   "director",
+				body: model.CreateInspectorBody{
+					Username: "pengawas1",
+					Name:     "Pengawas Satu",
+					Password: testPassword,
+				},
+			},
+			wantCode: http.StatusCreated,
+			check: func(t *testing.T, res testResponse) {
+				s.login(t, "inspector", "pengawas1")
+			},
+		},
+		{
+			name: "director creates a project of the inspector",
+			req: testRequest{
+				method: http.MethodPost,
+				path:   "/v1/project",
+				user:   "director",
+				body: model.CreateProjectBody{
+					Name:        "Saluran Desa",
+					Description: "Pembangunan saluran",
+					Type:        string(model.Drainage),
+					DeptName:    "Dinas PU",
+					CompanyName: "Tigaputera",
+					InspectorID: 2,
+					StartDate:   1700000000,
+					FinalDate:   1710000000,
+				},
+			},
+			wantCode: http.StatusCreated,
+		},
+		{
+			name: "inspector adds an income",
+			req: testRequest{
+				method:  http.MethodPost,
+				path:    "/v1/project/1/income",
+				user:    "inspector",
+				form:    map[string]string{"amount": "1000000", "ref": "Termin 1"},
+				receipt: true,
+			},
+			wantCode: http.StatusCreated,
+		},
+		{
+			name:        "director can't deactivate an inspector holding a balance",
+			req:         testRequest{method: http.MethodDelete, path: "/v1/user/inspector/2", user: "director"},
+			wantCode:    http.StatusBadRequest,
+			wantMessage: "Pengawas masih memiliki saldo Rp. 1.000.000, serahkan saldo sebelum menonaktifkan pengawas",
+		},
+		{
+			name: "director sets the approval threshold",
+			req: testRequest{
+				method: http.MethodPatch,
+				path:   "/v1/project/1/approval-threshold",
+				user:   "director",
+				body:   model.UpdateApprovalThresholdBody{Threshold: &threshold},
+			},
+			wantCode: http.StatusOK,
+		},
+		{
+			name: "inspector spends above the threshold",
+			req: testRequest{
+				method:  http.MethodPost,
+				path:    "/v1/project/1/expenditure/1/transaction",
+				user:    "inspector",
+				form:    map[string]string{"name": "Semen", "price": "500000", "amount": "1"},
+				receipt: true,
+			},
+			wantCode: http.StatusCreated,
+		},
+		{
+			name:     "director can't deactivate an inspector with a pending expenditure",
+			req:      testRequest{method: http.MethodDelete, path: "/v1/user/inspector/2", user: "director"},
+			wantCode: http.StatusBadRequest,
+			wantMessage: "Pengawas masih memiliki 1 pengeluaran yang menunggu persetujuan, " +
+				"setujui atau tolak pengeluaran sebelum menonaktifkan pengawas",
+		},
+		{
+			name: "director rejects the expenditure",
+			req: testRequest{
+				method: http.MethodPatch,
+				path:   "/v1/project/1/expenditure/1/transaction/2/reject",
+				user:   "director",
+				body:   model.RejectExpenditureBody{Reason: "Harga terlalu mahal"},
+			},
+			wantCode: http.StatusOK,
+		},
+		{
+			name: "director sends funds to the inspector",
+			req: testRequest{
+				method: http.MethodPost,
+				path:   "/v1/project/1/transfer",
+				user:   "director",
+				body:   model.CreateFundTransferBody{Amount: 500000},
+			},
+			wantCode: http.StatusCreated,
+		},
+		{
+			name:     "director can't deactivate an inspector with a pending transfer",
+			req:      testRequest{method: http.MethodDelete, path: "/v1/user/inspector/2", user: "director"},
+			wantCode: http.StatusBadRequest,
+			wantMessage: "Pengawas masih memiliki 1 transfer dana yang belum dikonfirmasi, " +
+				"batalkan transfer sebelum menonaktifkan pengawas",
+		},
+		{
+			name:     "director cancels the transfer",
+			req:      testRequest{method: http.MethodDelete, path: "/v1/project/1/transfer/1", user: "director"},
+			wantCode: http.StatusOK,
+		},
+		{
+			name:     "inspector cancels the income",
+			req:      testRequest{method: http.MethodDelete, path: "/v1/project/1/income/1", user: "inspector"},
+			wantCode: http.StatusOK,
+		},
+		{
+			name:     "director can't deactivate an inspector with a running project",
+			req:      testRequest{method: http.MethodDelete, path: "/v1/user/inspector/2", user: "director"},
+			wantCode: http.StatusBadRequest,
+			wantMessage: "Pengawas masih menjadi anggota 1 proyek yang sedang berjalan atau ditunda, " +
+				"pindahkan proyek atau keluarkan pengawas dari proyek sebelum menonaktifkan pengawas",
+		},
+		{
+			name: "director postpones the project",
+			req: testRequest{
+				method: http.MethodPatch,
+				path:   "/v1/project/1/status",
+				user:   "director",
+				body:   model.UpdateProjectStatusBody{Status: string(model.Postponed)},
+			},
+			wantCode: http.StatusOK,
+		},
+		{
+			name:     "director can't deactivate an inspector with a postponed project",
+			req:      testRequest{method: http.MethodDelete, path: "/v1/user/inspector/2", user: "director"},
+			wantCode: http.StatusBadRequest,
+		},
+		{
+			name: "director finishes the project",
+			req: testRequest{
+				method: http.MethodPatch,
+				path:   "/v1/project/1/status",
+				user:   "director",
+				body:   model.UpdateProjectStatusBody{Status: string(model.Finished)},
+			},
+			wantCode: http.StatusOK,
+		},
+		{
+			name: "inspector can't rename themselves",
+			req: testRequest{
+				method: http.MethodPatch,
+				path:   "/v1/user/inspector/2",
+				user:   "inspector",
+				body:   model.UpdateInspectorBody{Name: "Pengawas Baru"},
+			},
+			wantCode: http.StatusUnauthorized,
+		},
+		{
+			name: "director can't rename the director",
+			req: testRequest{
+				method: http.MethodPatch,
+				path:   "/v1/user/inspector/1",
+				user:   "director",
+				body:   model.UpdateInspectorBody{Name: "Pengawas Baru"},
+			},
+			wantCode:    http.StatusNotFound,
+			wantMessage: "Pengawas tidak ditemukan",
+		},
+		{
+			name: "director renames the inspector",
+			req: testRequest{
+				method: http.MethodPatch,
+				path:   "/v1/user/inspector/2",
+				user:   "director",
+				body:   model.UpdateInspectorBody{Name: "Pengawas Baru"},
+			},
+			wantCode:    http.StatusOK,
+			wantMessage: "Berhasil mengubah pengawas",
+		},
+		{
+			name: "director can't reset the password to a previous one",
+			req: testRequest{
+				method: http.MethodPatch,
+				path:   "/v1/user/inspector/2/password",
+				user:   "director",
+				body:   model.ResetPasswordBody{NewPassword: testPassword},
+			},
+			wantCode:    http.StatusBadRequest,
+			wantMessage: "Password tidak boleh sama dengan 5 password terakhir",
+		},
+		{
+			name: "director resets the password of the inspector",
+			req: testRequest{
+				method: http.MethodPatch,
+				path:   "/v1/user/inspector/2/password",
+				user:   "director",
+				body:   model.ResetPasswordBody{NewPassword: "PasswordBaru123"},
+			},
+			wantCode:    http.StatusOK,
+			wantMessage: "Berhasil mengatur ulang password pengawas",
+		},
+		{
+			name:     "session of the inspector is revoked by the password reset",
+			req:      testRequest{method: http.MethodGet, path: "/v1/user/profile", user: "inspector"},
+			wantCode: http.StatusUnauthorized,
+		},
+		{
+			name: "inspector logs in with the new password and must change it",
+			req: testRequest{
+				method: http.MethodPost,
+				path:   "/v1/auth/login",
+				body:   model.UserLoginBody{Username: "pengawas1", Password: "PasswordBaru123"},
+			},
+			wantCode: http.StatusOK,
+			check: func(t *testing.T, res testResponse) {
+				var loginResponse model.UserLoginResponse
+				decodeData(t, res, &loginResponse)
+				if !loginResponse.User.MustChangePassword() {
+					t.Errorf("got user %+v, want a required password change", loginResponse.User)
+				}
+				if loginResponse.User.Name != "Pengawas Baru" {
+					t.Errorf("got name %q, want %q", loginResponse.User.Name, "Pengawas Baru")
+				}
+			},
+		},
+		{
+			name:        "director deactivates the inspector",
+			req:         testRequest{method: http.MethodDelete, path: "/v1/user/inspector/2", user: "director"},
+			wantCode:    http.StatusOK,
+			wantMessage: "Berhasil menonaktifkan pengawas",
+		},
+		{
+			name:     "deactivated inspector isn't listed",
+			req:      testRequest{method: http.MethodGet, path: "/v1/user/inspector", user: "director"},
+			wantCode: http.StatusOK,
+			check:    checkInspectors(),
+		},
+		{
+			name:     "director lists the deactivated inspectors",
+			req:      testRequest{method: http.MethodGet, path: "/v1/user/inspector?is_deleted=true", user: "director"},
+			wantCode: http.StatusOK,
+			check:    checkInspectors("Pengawas Baru"),
+		},
+		{
+			name: "director can't reset the password of a deactivated inspector",
+			req: testRequest{
+				method: http.MethodPatch,
+				path:   "/v1/user/inspector/2/password",
+				user:   "director",
+				body:   model.ResetPasswordBody{NewPassword: "PasswordLain123"},
+			},
+			wantCode: http.StatusNotFound,
+		},
+		{
+			name:        "director can't reactivate an unknown inspector",
+			req:         testRequest{method: http.MethodPost, path: "/v1/user/inspector/99/reactivate", user: "director"},
+			wantCode:    http.StatusNotFound,
+			wantMessage: "Pengawas tidak ditemukan",
+		},
+		{
+			name:        "director reactivates the inspector",
+			req:         testRequest{method: http.MethodPost, path: "/v1/user/inspector/2/reactivate", user: "director"},
+			wantCode:    http.StatusOK,
+			wantMessage: "Berhasil mengaktifkan kembali pengawas",
+		},
+		{
+			name:        "director can't reactivate an active inspector",
+			req:         testRequest{method: http.MethodPost, path: "/v1/user/inspector/2/reactivate", user: "director"},
+			wantCode:    http.StatusBadRequest,
+			wantMessage: "Pengawas masih aktif",
+		},
+		{
+			name:     "reactivated inspector is listed",
+			req:      testRequest{method: http.MethodGet, path: "/v1/user/inspector", user: "director"},
+			wantCode: http.StatusOK,
+			check:    checkInspectors("Pengawas Baru"),
+		},
+	})
+}
+
+func TestProjectReassignment(t *testing.T) {
+	s := newTestServer(t)
+	s.login(t, "director", "direktur")
+
+	createInspector := func(username string) testCase {
+		return testCase{
+			name: "director creates " + username,
+			req: testRequest{
+				method: http.MethodPost,
+				path:   "/v1/user/inspector",
+				user:   "director",
+				body: model.CreateInspectorBody{
+					Username: username,
+					Name:     username,
+					Password: testPassword,
+				},
+			},
+			wantCode: http.StatusCreated,
+		}
+	}
+
+	reassign := func(user string, inspectorID int64) testRequest {
+		return testRequest{
+			method: http.MethodPatch,
+			path:   "/v1/project/1/inspector",
+			user:   user,
+			body:   model.ReassignProjectBody{InspectorID: inspectorID, Note: "Pengawas pindah tugas"},
+		}
+	}
+
+	s.run(t, []testCase{
+		createInspector("pengawas1"),
+		createInspector("pengawas2"),
+		{
+			name: "director creates a project of pengawas1",
+			req: testRequest{
+				method: http.MethodPost,
+				path:   "/v1/project",
+				user:   "director",
+				body: model.CreateProjectBody{
+					Name:        "Saluran Desa",
+					Description: "Pembangunan saluran",
+					Type:        string(model.Drainage),
+					DeptName:    "Dinas PU",
+					CompanyName: "Tigaputera",
+					InspectorID: 2,
+					StartDate:   1700000000,
+					FinalDate:   1710000000,
+				},
+			},
+			wantCode: http.StatusCreated,
+			check: func(t *testing.T, res testResponse) {
+				s.login(t, "inspector", "pengawas1")
+				s.login(t, "other", "pengawas2")
+			},
+		},
+		{
+			name: "inspector adds an income",
+			req: testRequest{
+				method:  http.MethodPost,
+				path:    "/v1/project/1/income",
+				user:    "inspector",
+				form:    map[string]string{"amount": "1000000", "ref": "Termin 1"},
+				receipt: true,
+			},
+			wantCode: http.StatusCreated,
+		},
+		{
+			name: "director sends funds to the inspector",
+			req: testRequest{
+				method: http.MethodPost,
+				path:   "/v1/project/1/transfer",
+				user:   "director",
+				body:   model.CreateFundTransferBody{Amount: 500000},
+			},
+			wantCode: http.StatusCreated,
+		},
+		{
+			name:        "director can't reassign a project with an unconfirmed transfer",
+			req:         reassign("director", 3),
+			wantCode:    http.StatusBadRequest,
+			wantMessage: "Masih ada transfer dana yang menunggu konfirmasi, selesaikan sebelum memindahkan proyek",
+		},
+		{
+			name:     "director cancels the transfer",
+			req:      testRequest{method: http.MethodDelete, path: "/v1/project/1/transfer/1", user: "director"},
+			wantCode: http.StatusOK,
+		},
+		{
+			name:     "inspector can't reassign the project",
+			req:      reassign("inspector", 3),
+			wantCode: http.StatusUnauthorized,
+		},
+		{
+			name:        "director can't reassign the project to its inspector",
+			req:         reassign("director", 2),
+			wantCode:    http.StatusBadRequest,
+			wantMessage: "Proyek sudah ditangani oleh pengawas tersebut",
+		},
+		{
+			name:        "director can't reassign the project to a director",
+			req:         reassign("director", 1),
+			wantCode:    http.StatusNotFound,
+			wantMessage: "Pengawas tidak ditemukan",
+		},
+		{
+			name:        "director reassigns the project",
+			req:         reassign("director", 3),
+			wantCode:    http.StatusOK,
+			wantMessage: "Berhasil memindahkan proyek ke pengawas",
+		},
+		{
+			name:     "project ledger shows the transactions of both inspectors",
+			req:      testRequest{method: http.MethodGet, path: "/v1/project/1/ledger", user: "director"},
+			wantCode: http.StatusOK,
+			check: func(t *testing.T, res testResponse) {
+				checkProjectBalance("Rp. 1.000.000", 3)(t, res)
+
+				var ledger model.ProjectLedgerResponse
+				decodeData(t, res, &ledger)
+				if ledger.Account.InspectorName != "pengawas2" {
+					t.Errorf("got inspector %q, want %q", ledger.Account.InspectorName, "pengawas2")
+				}
+			},
+		},
+		{
+			name:     "previous inspector ledger is closed",
+			req:      testRequest{method: http.MethodGet, path: "/v1/user/inspector/ledger", user: "inspector"},
+			wantCode: http.StatusOK,
+			check:    checkInspectorBalance("Rp. 0"),
+		},
+		{
+			name:     "new inspector ledger is opened",
+			req:      testRequest{method: http.MethodGet, path: "/v1/user/inspector/ledger", user: "other"},
+			wantCode: http.StatusOK,
+			check:    checkInspectorBalance("Rp. 1.000.000"),
+		},
+		{
+			name:     "previous inspector can't see the project anymore",
+			req:      testRequest{method: http.MethodGet, path: "/v1/project/1", user: "inspector"},
+			wantCode: http.StatusNotFound,
+		},
+		{
+			name:        "previous inspector can't cancel the income",
+			req:         testRequest{method: http.MethodDelete, path: "/v1/project/1/income/1", user: "inspector"},
+			wantCode:    http.StatusNotFound,
+			wantMessage: "transaksi proyek tidak ditemukan",
+		},
+		{
+			name:     "new inspector sees the project ledger",
+			req:      testRequest{method: http.MethodGet, path: "/v1/project/1/ledger", user: "other"},
+			wantCode: http.StatusOK,
+			check:    checkProjectBalance("Rp. 1.000.000", 3),
+		},
+		{
+			name:     "new inspector sees the assignment history",
+			req:      testRequest{method: http.MethodGet, path: "/v1/project/1/assignment", user: "other"},
+			wantCode: http.StatusOK,
+			check: func(t *testing.T, res testResponse) {
+				var assignments []model.ProjectAssignmentResponse
+				decodeData(t, res, &assignments)
+
+				if len(assignments) != 2 {
+					t.Fatalf("got %d assignments, want 2", len(assignments))
+				}
+				if assignments[0].InspectorName != "pengawas1" || assignments[0].HandoverAmount != "Rp. 0" {
+					t.Errorf("got first assignment %+v, want pengawas1 without a handover", assignments[0])
+				}
+				want := model.ProjectAssignmentResponse{
+					ID:                    2,
+					Timestamp:             assignments[1].Timestamp,
+					InspectorName:         "pengawas2",
+					PreviousInspectorName: "pengawas1",
+					HandoverAmount:        "Rp. 1.000.000",
+					Note:                  "Pengawas pindah tugas",
+				}
+				if assignments[1] != want {
+					t.Errorf("got assignment %+v, want %+v", assignments[1], want)
+				}
+			},
+		},
+		{
+			name:     "ledger stays consistent after the handover",
+			req:      testRequest{method: http.MethodGet, path: "/v1/ledger/integrity", user: "director"},
+			wantCode: http.StatusOK,
+			check: func(t *testing.T, res testResponse) {
+				var report model.LedgerIntegrityReport
+				decodeData(t, res, &report)
+				if !report.IsConsistent() {
+					t.Errorf("got an inconsistent ledger %+v", report)
+				}
+			},
+		},
+		{
+			name:        "previous inspector can be deactivated after the handover",
+			req:         testRequest{method: http.MethodDelete, path: "/v1/user/inspector/2", user: "director"},
+			wantCode:    http.StatusOK,
+			wantMessage: "Berhasil menonaktifkan pengawas",
+		},
+	})
+}
+
+func TestProjectLedger(t *testing.T) {
+	s := newTestServer(t)
+	s.login(t, "director", "direktur")
+
+	var inspectorID int64
+
+	// the retries of the income send the same receipt
+	incomeReceipt := testReceiptImage(t, 1000, 0)
+
+	s.run(t, []testCase{
+		{
+			name: "director creates an inspector",
+			req: testRequest{
+				method: http.MethodPost,
+				path:   "/v1/user/inspector",
+				user:   "director",
+				body: model.CreateInspectorBody{
+					Username: "pengawas1",
+					Name:     "Pengawas Satu",
+					Password: testPassword,
+				},
+			},
+			wantCode:    http.StatusCreated,
+			wantMessage: "Berhasil membuat pengawas",
+			check: func(t *testing.T, res testResponse) {
+				inspector, err := s.repo.User().GetByUsername(context.Background(), "pengawas1")
+				if err != nil {
+					t.Fatal(err)
+				}
+				inspectorID = inspector.ID
+				s.login(t, "inspector", "pengawas1")
+			},
+		},
+		{
+			name: "inspector can't create a project",
+			req: testRequest{
+				method: http.MethodPost,
+				path:   "/v1/project",
+				user:   "inspector",
+				body:   model.CreateProjectBody{Name: "Proyek"},
+			},
+			wantCode: http.StatusUnauthorized,
+		},
+		{
+			name: "director creates a project with a wrong type",
+			req: testRequest{
+				method: http.MethodPost,
+				path:   "/v1/project",
+				user:   "director",
+				body: model.CreateProjectBody{
+					Name:        "Saluran Desa",
+					Description: "Pembangunan saluran",
+					Type:        "Jembatan",
+					DeptName:    "Dinas PU",
+					CompanyName: "Tigaputera",
+					InspectorID: 2,
+					StartDate:   1700000000,
+					FinalDate:   1710000000,
+				},
+			},
+			wantCode: http.StatusBadRequest,
+		},
+		{
+			name: "director creates a project",
+			req: testRequest{
+				method: http.MethodPost,
+				path:   "/v1/project",
+				user:   "director",
+				body: model.CreateProjectBody{
+					Name:        "Saluran Desa",
+					Description: "Pembangunan saluran",
+					Type:        string(model.Drainage),
+					DeptName:    "Dinas PU",
+					CompanyName: "Tigaputera",
+					InspectorID: 2,
+					StartDate:   1700000000,
+					FinalDate:   1710000000,
+				},
+			},
+			wantCode:    http.StatusCreated,
+			wantMessage: "Berhasil membuat proyek",
+			check: func(t *testing.T, res testResponse) {
+				project, err := s.repo.Project().Get(context.Background(), 1)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if project.InspectorID != inspectorID {
+					t.Errorf("got inspector %d, want %d", project.InspectorID, inspectorID)
+				}
+			},
+		},
+		{
+			name:     "inspector lists the projects",
+			req:      testRequest{method: http.MethodGet, path: "/v1/project", user: "inspector"},
+			wantCode: http.StatusOK,
+			check: func(t *testing.T, res testResponse) {
+				var projects []model.ProjectListResponse
+				decodeData(t, res, &projects)
+				if len(projects) != 1 || projects[0].Name != "Saluran Desa" {
+					t.Errorf("got projects %+v", projects)
+				}
+				if res.body.Pagination == nil || res.body.Pagination.TotalElement != 1 {
+					t.Errorf("got pagination %+v", res.body.Pagination)
+				}
+			},
+		},
+		{
+			name: "director creates another inspector",
+			req: testRequest{
+				method: http.MethodPost,
+				path:   "/v1/user/inspector",
+				user:   "director",
+				body: model.CreateInspectorBody{
+					Username: "pengawas2",
+					Name:     "Pengawas Dua",
+					Password: testPassword,
+				},
+			},
+			wantCode: http.StatusCreated,
+			check: func(t *testing.T, res testResponse) {
+				s.login(t, "other", "pengawas2")
+			},
+		},
+		{
+			name:     "another inspector can't see the project ledger",
+			req:      testRequest{method: http.MethodGet, path: "/v1/project/1/ledger", user: "other"},
+			wantCode: http.StatusNotFound,
+		},
+		{
+			name: "another inspector can't see the expenditure receipts",
+			req: testRequest{
+				method: http.MethodGet,
+				path:   "/v1/project/1/expenditure/1/transaction",
+				user:   "other",
+			},
+			wantCode: http.StatusNotFound,
+		},
+		{
+			name: "inspector adds an income without a receipt",
+			req: testRequest{
+				method: http.MethodPost,
+				path:   "/v1/project/1/income",
+				user:   "inspector",
+				form:   map[string]string{"amount": "1000000", "ref": "Termin 1"},
+			},
+			wantCode: http.StatusBadRequest,
+		},
+		{
+			name: "inspector adds an income with a corrupted receipt",
+			req: testRequest{
+				method:         http.MethodPost,
+				path:           "/v1/project/1/income",
+				user:           "inspector",
+				form:           map[string]string{"amount": "1000000", "ref": "Termin 1"},
+				receipt:        true,
+				receiptContent: []byte("\x89PNG\r\n\x1a\nnot a png"),
+			},
+			wantCode:    http.StatusBadRequest,
+			wantMessage: "Gambar bukti rusak atau tidak dapat dibaca",
+		},
+		{
+			name: "inspector adds an income with a text file",
+			req: testRequest{
+				method:         http.MethodPost,
+				path:           "/v1/project/1/income",
+				user:           "inspector",
+				form:           map[string]string{"amount": "1000000", "ref": "Termin 1"},
+				receipt:        true,
+				receiptContent: []byte("not an image"),
+			},
+			wantCode:    http.StatusBadRequest,
+			wantMessage: "Bukti transaksi harus berupa png, jpg, jpeg, atau pdf",
+		},
+		{
+			name: "inspector adds an income",
+			req: testRequest{
+				method:         http.MethodPost,
+				path:           "/v1/project/1/income",
+				user:           "inspector",
+				headers:        map[string]string{appcontext.HeaderIdempotencyKey: "income-1"},
+				form:           map[string]string{"amount": "1000000", "ref": "Termin 1"},
+				receipt:        true,
+				receiptContent: incomeReceipt,
+			},
+			wantCode:    http.StatusCreated,
+			wantMessage: "Berhasil menambahkan pemasukan pengawas",
+			check: func(t *testing.T, res testResponse) {
+				// the receipt and its thumbnail
+				if len(s.storage.objects) != 2 {
+					t.Errorf("got %d uploaded objects, want 2", len(s.storage.objects))
+				}
+			},
+		},
+		{
+			name: "retried income is replayed",
+			req: testRequest{
+				method:         http.MethodPost,
+				path:           "/v1/project/1/income",
+				user:           "inspector",
+				headers:        map[string]string{appcontext.HeaderIdempotencyKey: "income-1"},
+				form:           map[string]string{"amount": "1000000", "ref": "Termin 1"},
+				receipt:        true,
+				receiptContent: incomeReceipt,
+			},
+			wantCode:    http.StatusCreated,
+			wantMessage: "Berhasil menambahkan pemasukan pengawas",
+			check: func(t *testing.T, res testResponse) {
+				if res.header.Get(appcontext.HeaderIdempotentReplayed) != "true" {
+					t.Error("the retry wasn't replayed")
+				}
+			},
+		},
+		{
+			name: "key reused with another body is refused",
+			req: testRequest{
+				method:         http.MethodPost,
+				path:           "/v1/project/1/income",
+				user:           "inspector",
+				headers:        map[string]string{appcontext.HeaderIdempotencyKey: "income-1"},
+				form:           map[string]string{"amount": "2000000", "ref": "Termin 1"},
+				receipt:        true,
+				receiptContent: incomeReceipt,
+			},
+			wantCode:    http.StatusUnprocessableEntity,
+			wantMessage: "Idempotency-Key sudah digunakan dengan isi request yang berbeda",
+		},
+		{
+			name:     "project ledger after the income",
+			req:      testRequest{method: http.MethodGet, path: "/v1/project/1/ledger", user: "director"},
+			wantCode: http.StatusOK,
+			check: func(t *testing.T, res testResponse) {
+				checkProjectBalance("Rp. 1.000.000", 1)(t, res)
+
+				income, err := s.repo.Ledger().Get(context.Background(), repository.LedgerFilter{
+					LedgerType: model.Debit,
+				})
+				if err != nil {
+					t.Fatal(err)
+				} else if income.ID != 1 {
+					t.Fatalf("got income id %d, want 1", income.ID)
+				}
+			},
+		},
+		{
+			name: "inspector spends more than the balance",
+			req: testRequest{
+				method:  http.MethodPost,
+				path:    "/v1/project/1/expenditure/1/transaction",
+				user:    "inspector",
+				form:    map[string]string{"name": "Semen", "price": "600000", "amount": "2"},
+				receipt: true,
+			},
+			wantCode:    http.StatusBadRequest,
+			wantMessage: "Saldo anda tidak mencukupi",
+		},
+		{
+			name: "download a missing receipt",
+			req: testRequest{
+				method: http.MethodGet,
+				path:   s.signedURL.Sign("incomes/missing.png"),
+			},
+			wantCode: http.StatusNotFound,
+		},
+		{
+			name: "download outside the receipt folders",
+			req: testRequest{
+				method: http.MethodGet,
+				path:   s.signedURL.Sign("secrets/receipt.png"),
+			},
+			wantCode: http.StatusNotFound,
+		},
+		{
+			name: "inspector adds an expenditure",
+			req: testRequest{
+				method:  http.MethodPost,
+				path:    "/v1/project/1/expenditure/1/transaction",
+				user:    "inspector",
+				form:    map[string]string{"name": "Semen", "price": "100000", "amount": "2"},
+				receipt: true,
+			},
+			wantCode:    http.StatusCreated,
+			wantMessage: "Berhasil membuat detail pengeluaran proyek",
+		},
+		{
+			name: "expenditure transaction list",
+			req: testRequest{
+				method: http.MethodGet,
+				path:   "/v1/project/1/expenditure/1/transaction",
+				user:   "director",
+			},
+			wantCode: http.StatusOK,
+			check: func(t *testing.T, res testResponse) {
+				var list model.ExpenditureDetailListResponse
+				decodeData(t, res, &list)
+				if len(list.Details) != 1 || list.Details[0].ID != 2 || list.SumTotal != "Rp. 200.000" {
+					t.Fatalf("got expenditure list %+v", list)
+				}
+
+				for _, url := range []string{list.Details[0].ReceiptURL, list.Details[0].ThumbnailURL} {
+					recorder := httptest.NewRecorder()
+					s.rest.http.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, url, nil))
+					if recorder.Code != http.StatusOK || recorder.Header().Get("Content-Type") != "image/jpeg" {
+						t.Errorf("download %s: got status %d, %s", url, recorder.Code, recorder.Body)
+					}
+				}
+			},
+		},
+		{
+			name: "inspector adds a pdf and a photo to the expenditure",
+			req: testRequest{
+				method: http.MethodPost,
+				path:   "/v1/project/1/ledger/2/attachment",
+				user:   "inspector",
+				attachments: []testAttachment{
+					{fileName: "nota.pdf", content: testReceiptPDF},
+					{fileName: "foto.png", content: testReceiptImage(t, -1, 0)},
+				},
+			},
+			wantCode:    http.StatusCreated,
+			wantMessage: "Berhasil menambahkan bukti transaksi",
+		},
+		{
+			name: "income with more files than allowed is refused before any of them is processed",
+			req: testRequest{
+				method:  http.MethodPost,
+				path:    "/v1/project/1/income",
+				user:    "inspector",
+				form:    map[string]string{"amount": "100000", "ref": "Termin"},
+				receipt: true,
+				attachments: []testAttachment{
+					{fileName: "nota.pdf", content: testReceiptPDF},
+					{fileName: "nota.pdf", content: testReceiptPDF},
+					{fileName: "nota.pdf", content: testReceiptPDF},
+					{fileName: "nota.pdf", content: testReceiptPDF},
+					{fileName: "nota.txt", content: []byte("bukan bukti transaksi")},
+				},
+			},
+			wantCode:    http.StatusBadRequest,
+			wantMessage: "Bukti transaksi maksimal 5 file",
+		},
+		{
+			name: "inspector adds too many attachments",
+			req: testRequest{
+				method: http.MethodPost,
+				path:   "/v1/project/1/ledger/2/attachment",
+				user:   "inspector",
+				attachments: []testAttachment{
+					{fileName: "nota.pdf", content: testReceiptPDF},
+					{fileName: "nota.pdf", content: testReceiptPDF},
+					{fileName: "nota.pdf", content: testReceiptPDF},
+				},
+			},
+			wantCode:    http.StatusBadRequest,
+			wantMessage: "Bukti transaksi maksimal 5 file",
+		},
+		{
+			name: "another inspector can't see the attachments",
+			req: testRequest{
+				method: http.MethodGet,
+				path:   "/v1/project/1/ledger/2/attachment",
+				user:   "other",
+			},
+			wantCode: http.StatusNotFound,
+		},
+		{
+			name: "director lists the attachments",
+			req: testRequest{
+				method: http.MethodGet,
+				path:   "/v1/project/1/ledger/2/attachment",
+				user:   "director",
+			},
+			wantCode: http.StatusOK,
+			check: func(t *testing.T, res testResponse) {
+				var attachments []model.LedgerAttachmentResponse
+				decodeData(t, res, &attachments)
+				if len(attachments) != 3 ||
+					attachments[1].FileType != model.PDFAttachment ||
+					attachments[1].ThumbnailURL != "" {
+					t.Fatalf("got attachments %+v", attachments)
+				}
+
+				recorder := httptest.NewRecorder()
+				s.rest.http.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, attachments[1].URL, nil))
+				if recorder.Code != http.StatusOK ||
+					recorder.Header().Get("Content-Type") != "application/pdf" ||
+					!bytes.Equal(recorder.Body.Bytes(), testReceiptPDF) {
+					t.Errorf("download %s: got status %d, %s", attachments[1].URL, recorder.Code, recorder.Body)
+				}
+			},
+		},
+		{
+			name: "inspector deletes the first attachment",
+			req: testRequest{
+				method: http.MethodDelete,
+				path:   "/v1/project/1/ledger/2/attachment/2",
+				user:   "inspector",
+			},
+			wantCode:    http.StatusOK,
+			wantMessage: "Berhasil menghapus bukti transaksi",
+			check: func(t *testing.T, res testResponse) {
+				ledger, err := s.repo.Ledger().Get(context.Background(), repository.LedgerFilter{ID: 2})
+				if err != nil {
+					t.Fatal(err)
+				}
+				if !strings.HasSuffix(ledger.ReceiptKey, ".pdf") || ledger.ThumbnailKey != "" {
+					t.Errorf("got receipt %+v, want the pdf", ledger.LedgerReceipt)
+				}
+			},
+		},
+		{
+			name: "inspector deletes the pdf",
+			req: testRequest{
+				method: http.MethodDelete,
+				path:   "/v1/project/1/ledger/2/attachment/3",
+				user:   "inspector",
+			},
+			wantCode: http.StatusOK,
+		},
+		{
+			name: "inspector can't delete the last attachment",
+			req: testRequest{
+				method: http.MethodDelete,
+				path:   "/v1/project/1/ledger/2/attachment/4",
+				user:   "inspector",
+			},
+			wantCode:    http.StatusBadRequest,
+			wantMessage: "Transaksi harus memiliki minimal satu bukti",
+		},
+		{
+			name: "expenditure list shows the remaining attachment",
+			req: testRequest{
+				method: http.MethodGet,
+				path:   "/v1/project/1/expenditure/1/transaction",
+				user:   "director",
+			},
+			wantCode: http.StatusOK,
+			check: func(t *testing.T, res testResponse) {
+				var list model.ExpenditureDetailListResponse
+				decodeData(t, res, &list)
+				attachments := list.Details[0].Attachments
+				if len(attachments) != 1 || attachments[0].ID != 4 || attachments[0].FileType != model.ImageAttachment {
+					t.Errorf("got attachments %+v", attachments)
+				}
+			},
+		},
+		{
+			name: "inspector reuses the receipt of the expenditure for the income",
+			req: testRequest{
+				method:         http.MethodPost,
+				path:           "/v1/project/1/ledger/1/attachment",
+				user:           "inspector",
+				receipt:        true,
+				receiptContent: testReceiptImage(t, -1, 0),
+			},
+			wantCode:    http.StatusBadRequest,
+			wantMessage: "Bukti transaksi sudah digunakan pada transaksi lain",
+		},
+		{
+			name: "inspector adds another photo of the expenditure receipt to the income",
+			req: testRequest{
+				method:         http.MethodPost,
+				path:           "/v1/project/1/ledger/1/attachment",
+				user:           "inspector",
+				receipt:        true,
+				receiptContent: testReceiptImage(t, -1, 40),
+			},
+			wantCode: http.StatusCreated,
+		},
+		{
+			name:     "inspector can't see the duplicate receipt report",
+			req:      testRequest{method: http.MethodGet, path: "/v1/ledger/duplicate-receipt", user: "inspector"},
+			wantCode: http.StatusUnauthorized,
+		},
+		{
+			name:     "duplicate receipt report",
+			req:      testRequest{method: http.MethodGet, path: "/v1/ledger/duplicate-receipt", user: "director"},
+			wantCode: http.StatusOK,
+			check: func(t *testing.T, res testResponse) {
+				var report []model.DuplicateReceiptResponse
+				decodeData(t, res, &report)
+				if len(report) != 1 ||
+					report[0].Receipt.Transaction.ID != 1 ||
+					report[0].DuplicateOf.Transaction.ID != 2 ||
+					report[0].DuplicateOf.Attachment.ID != 4 ||
+					report[0].Distance > 10 {
+					t.Errorf("got report %+v", report)
+				}
+			},
+		},
+		{
+			name:     "project ledger after the expenditure",
+			req:      testRequest{method: http.MethodGet, path: "/v1/project/1/ledger", user: "director"},
+			wantCode: http.StatusOK,
+			check:    checkProjectBalance("Rp. 800.000", 2),
+		},
+		{
+			name:     "inspector ledger after the expenditure",
+			req:      testRequest{method: http.MethodGet, path: "/v1/user/inspector/ledger", user: "inspector"},
+			wantCode: http.StatusOK,
+			check:    checkInspectorBalance("Rp. 800.000"),
+		},
+		{
+			name: "refresh statistics",
+			req: testRequest{
+				method:  http.MethodPut,
+				path:    "/v1/user/statistics/refresh",
+				headers: map[string]string{"scheduler-key": testSchedulerKey},
+			},
+			wantCode:    http.StatusOK,
+			wantMessage: "Berhasil memperbarui statistik",
+		},
+		{
+			name:     "director statistics",
+			req:      testRequest{method: http.MethodGet, path: "/v1/user/statistics", user: "director"},
+			wantCode: http.StatusOK,
+			check: func(t *testing.T, res testResponse) {
+				var stats model.InspectorStatsResponse
+				decodeData(t, res, &stats)
+				want := model.InspectorStatsResponse{
+					TotalProject:     1,
+					TotalExpenditure: "Rp. 200.000",
+					TotalIncome:      "Rp. 1.000.000",
+					Margin:           "Rp. 800.000",
+				}
+				if stats != want {
+					t.Errorf("got stats %+v, want %+v", stats, want)
+				}
+			},
+		},
+		{
+			name:     "all inspectors ledger uses the statistics balance",
+			req:      testRequest{method: http.MethodGet, path: "/v1/user/inspector/ledger", user: "director"},
+			wantCode: http.StatusOK,
+			check:    checkInspectorBalance("Rp. 800.000"),
+		},
+		{
+			name: "inspector cancels the expenditure",
+			req: testRequest{
+				method: http.MethodDelete,
+				path:   "/v1/project/1/expenditure/1/transaction/2",
+				user:   "inspector",
+			},
+			wantCode:    http.StatusOK,
+			wantMessage: "Berhasil menghapus detail pengeluaran proyek",
+		},
+		{
+			name:     "project ledger after the cancellation",
+			req:      testRequest{method: http.MethodGet, path: "/v1/project/1/ledger", user: "director"},
+			wantCode: http.StatusOK,
+			check:    checkProjectBalance("Rp. 1.000.000", 3),
+		},
+		{
+			name: "canceled expenditure can't be canceled again",
+			req: testRequest{
+				method: http.MethodDelete,
+				path:   "/v1/project/1/expenditure/1/transaction/2",
+				user:   "inspector",
+			},
+			wantCode: http.StatusNotFound,
+		},
+		{
+			name: "inspector cancels the income",
+			req: testRequest{
+				method: http.MethodDelete,
+				path:   "/v1/project/1/income/1",
+				user:   "inspector",
+			},
+			wantCode:    http.StatusOK,
+			wantMessage: "Berhasil membatalkan pemasukan proyek",
+		},
+		{
+			name:     "project ledger after the income cancellation",
+			req:      testRequest{method: http.MethodGet, path: "/v1/project/1/ledger", user: "director"},
+			wantCode: http.StatusOK,
+			check:    checkProjectBalance("Rp. 0", 4),
+		},
+		{
+			name:     "duplicate receipt report leaves out the canceled income",
+			req:      testRequest{method: http.MethodGet, path: "/v1/ledger/duplicate-receipt", user: "director"},
+			wantCode: http.StatusOK,
+			check: func(t *testing.T, res testResponse) {
+				var report []model.DuplicateReceiptResponse
+				decodeData(t, res, &report)
+				if len(report) != 0 {
+					t.Errorf("got report %+v", report)
+				}
+			},
+		},
+		{
+			name:     "ledger integrity",
+			req:      testRequest{method: http.MethodGet, path: "/v1/ledger/integrity", user: "director"},
+			wantCode: http.StatusOK,
+			check: func(t *testing.T, res testResponse) {
+				var report model.LedgerIntegrityReport
+				decodeData(t, res, &report)
+				if !report.IsConsistent() {
+					t.Errorf("got an inconsistent ledger %+v", report)
+				}
+			},
+		},
+	})
+}
+
+func TestStorageGarbageCollection(t *testing.T) {
+	s := newTestServer(t)
+	s.login(t, "director", "direktur")
+
+	for _, key := range []string{
+		"expenditures/orphan.jpg",
+		"expenditures/uploading.jpg",
+		"ledger_report/1_month_ledger.xlsx",
+		"ledger_report/2_month_ledger.xlsx",
+	} {
+		path, fileName, _ := strings.Cut(key, "/")
+		if _, err := s.storage.UploadFromBytes(context.Background(), bytes.NewReader([]byte(key)), fileName, path); err != nil {
+			t.Fatal(err)
+		}
+		if key != "expenditures/uploading.jpg" {
+			s.storage.age(key, 2*time.Hour)
+		}
+	}
+
+	checkKeys := func(t *testing.T, name string, got []string, want ...string) {
+		t.Helper()
+		if strings.Join(got, ",") != strings.Join(want, ",") {
+			t.Errorf("got %s %v, want %v", name, got, want)
+		}
+	}
+
+	collect := func(dryRun bool) testRequest {
+		return testRequest{
+			method:  http.MethodPost,
+			path:    fmt.Sprintf("/v1/storage/garbage-collection?dry_run=%v", dryRun),
+			headers: map[string]string{"scheduler-key": testSchedulerKey},
+		}
+	}
+
+	s.run(t, []testCase{
+		{
+			name:     "collect without the scheduler key",
+			req:      testRequest{method: http.MethodPost, path: "/v1/storage/garbage-collection"},
+			wantCode: http.StatusUnauthorized,
+		},
+		{
+			name:     "director checks the orphaned objects",
+			req:      testRequest{method: http.MethodGet, path: "/v1/storage/garbage-collection", user: "director"},
+			wantCode: http.StatusOK,
+			check: func(t *testing.T, res testResponse) {
+				var report model.StorageGarbageReport
+				decodeData(t, res, &report)
+				if !report.DryRun || report.CheckedObjects != 4 {
+					t.Errorf("got report %+v", report)
+				}
+				checkKeys(t, "quarantined", report.Quarantined, "expenditures/orphan.jpg", "ledger_report/2_month_ledger.xlsx")
+				if len(s.storage.objects) != 4 {
+					t.Errorf("got %d objects after a dry run, want 4", len(s.storage.objects))
+				}
+			},
+		},
+		{
+			name:     "scheduler quarantines the orphaned objects",
+			req:      collect(false),
+			wantCode: http.StatusOK,
+			check: func(t *testing.T, res testResponse) {
+				var report model.StorageGarbageReport
+				decodeData(t, res, &report)
+				checkKeys(t, "quarantined", report.Quarantined, "expenditures/orphan.jpg", "ledger_report/2_month_ledger.xlsx")
+				for _, key := range []string{"quarantine/expenditures/orphan.jpg", "expenditures/uploading.jpg"} {
+					if _, ok := s.storage.objects[key]; !ok {
+						t.Errorf("object %s not found", key)
+					}
+				}
+				if _, ok := s.storage.objects["expenditures/orphan.jpg"]; ok {
+					t.Error("the orphaned object is still served")
+				}
+
+				// a receipt recorded after it was quarantined
+				if err := s.repo.LedgerAttachment().Create(context.Background(), &model.LedgerAttachment{
+					LedgerID:  1,
+					FileType:  model.ImageAttachment,
+					ObjectKey: "expenditures/orphan.jpg",
+				}); err != nil {
+					t.Fatal(err)
+				}
+			},
+		},
+		{
+			name:     "scheduler restores the referenced object",
+			req:      collect(false),
+			wantCode: http.StatusOK,
+			check: func(t *testing.T, res testResponse) {
+				var report model.StorageGarbageReport
+				decodeData(t, res, &report)
+				checkKeys(t, "restored", report.Restored, "expenditures/orphan.jpg")
+				checkKeys(t, "deleted", report.Deleted)
+				if _, ok := s.storage.objects["expenditures/orphan.jpg"]; !ok {
+					t.Error("the referenced object wasn't restored")
+				}
+
+				s.storage.age("quarantine/ledger_report/2_month_ledger.xlsx", 8*24*time.Hour)
+			},
+		},
+		{
+			name:     "dry run after the grace period",
+			req:      collect(true),
+			wantCode: http.StatusOK,
+			check: func(t *testing.T, res testResponse) {
+				var report model.StorageGarbageReport
+				decodeData(t, res, &report)
+				checkKeys(t, "deleted", report.Deleted, "ledger_report/2_month_ledger.xlsx")
+				if _, ok := s.storage.objects["quarantine/ledger_report/2_month_ledger.xlsx"]; !ok {
+					t.Error("the dry run deleted the quarantined object")
+				}
+			},
+		},
+		{
+			name:     "scheduler deletes the objects quarantined for the grace period",
+			req:      collect(false),
+			wantCode: http.StatusOK,
+			check: func(t *testing.T, res testResponse) {
+				var report model.StorageGarbageReport
+				decodeData(t, res, &report)
+				checkKeys(t, "deleted", report.Deleted, "ledger_report/2_month_ledger.xlsx")
+				if len(s.storage.objects) != 3 {
+					t.Errorf("got %d objects, want 3", len(s.storage.objects))
+				}
+			},
+		},
+	})
+
+	// without a configured key the scheduled jobs can't be triggered, even with an empty header
+	t.Setenv("SCHEDULER_KEY", "")
+	if res := s.do(t, testRequest{
+		method:  http.MethodPost,
+		path:    "/v1/storage/garbage-collection",
+		headers: map[string]string{"scheduler-key": ""},
+	}); res.code != http.StatusUnauthorized {
+		t.Errorf("got code %d without a scheduler key configured, want %d", res.code, http.StatusUnauthorized)
+	}
+}
+
+func TestProjectMember(t *testing.T) {
+	s := newTestServer(t)
+	s.login(t, "director", "direktur")
+
+	createInspector := func(username string) testCase {
+		return testCase{
+			name: "director creates " + username,
+			req: testRequest{
+				method: http.MethodPost,
+				path:   "/v1/user/inspector",
+				user:   "director",
+				body: model.CreateInspectorBody{
+					Username: username,
+					Name:     username,
+					Password: testPassword,
+				},
+			},
+			wantCode: http.StatusCreated,
+		}
+	}
+
+	addIncome := func(user string, amount string) testCase {
+		return testCase{
+			name: user + " adds an income",
+			req: testRequest{
+				method:  http.MethodPost,
+				path:    "/v1/project/1/income",
+				user:    user,
+				form:    map[string]string{"amount": amount, "ref": "Termin"},
+				receipt: true,
+			},
+			wantCode: http.StatusCreated,
+		}
+	}
+
+	checkMembers := func(want ...model.ProjectMemberResponse) func(t *testing.T, res testResponse) {
+		return func(t *testing.T, res testResponse) {
+			var members []model.ProjectMemberResponse
+			decodeData(t, res, &members)
+
+			if len(members) != len(want) {
+				t.Fatalf("got %d members, want %d", len(members), len(want))
+			}
+			for i := range want {
+				if members[i] != want[i] {
+					t.Errorf("got member %+v, want %+v", members[i], want[i])
+				}
+			}
+		}
+	}
+
+	s.run(t, []testCase{
+		createInspector("pengawas1"),
+		createInspector("pengawas2"),
+		{
+			name: "director creates a project of pengawas1",
+			req: testRequest{
+				method: http.MethodPost,
+				path:   "/v1/project",
+				user:   "director",
+				body: model.CreateProjectBody{
+					Name:        "Saluran Desa",
+					Description: "Pembangunan saluran",
+					Type:        string(model.Drainage),
+					DeptName:    "Dinas PU",
+					CompanyName: "Tigaputera",
+					InspectorID: 2,
+					StartDate:   1700000000,
+					FinalDate:   1710000000,
+				},
+			},
+			wantCode: http.StatusCreated,
+			check: func(t *testing.T, res testResponse) {
+				s.login(t, "lead", "pengawas1")
+				s.login(t, "assistant", "pengawas2")
+			},
+		},
+		{
+			name:     "assistant can't see the project before joining it",
+			req:      testRequest{method: http.MethodGet, path: "/v1/project/1", user: "assistant"},
+			wantCode: http.StatusNotFound,
+		},
+		{
+			name: "director adds pengawas2 to the project",
+			req: testRequest{
+				method: http.MethodPost,
+				path:   "/v1/project/1/member",
+				user:   "director",
+				body:   model.AddProjectMemberBody{InspectorID: 3},
+			},
+			wantCode:    http.StatusCreated,
+			wantMessage: "Berhasil menambahkan anggota proyek",
+		},
+		{
+			name: "director can't add a member twice",
+			req: testRequest{
+				method: http.MethodPost,
+				path:   "/v1/project/1/member",
+				user:   "director",
+				body:   model.AddProjectMemberBody{InspectorID: 3},
+			},
+			wantCode:    http.StatusBadRequest,
+			wantMessage: "Pengawas sudah menjadi anggota proyek",
+		},
+		{
+			name: "director can't add a director to the project",
+			req: testRequest{
+				method: http.MethodPost,
+				path:   "/v1/project/1/member",
+				user:   "director",
+				body:   model.AddProjectMemberBody{InspectorID: 1},
+			},
+			wantCode:    http.StatusNotFound,
+			wantMessage: "Pengawas tidak ditemukan",
+		},
+		{
+			name: "director can't send funds to an inspector outside the project",
+			req: testRequest{
+				method: http.MethodPost,
+				path:   "/v1/project/1/transfer",
+				user:   "director",
+				body:   model.CreateFundTransferBody{Amount: 500000, InspectorID: 1},
+			},
+			wantCode:    http.StatusBadRequest,
+			wantMessage: "Pengawas bukan anggota proyek",
+		},
+		addIncome("lead", "1000000"),
+		addIncome("assistant", "300000"),
+		{
+			name:     "assistant sees the project",
+			req:      testRequest{method: http.MethodGet, path: "/v1/project/1", user: "assistant"},
+			wantCode: http.StatusOK,
+		},
+		{
+			name:     "project balance is the sum of the member balances",
+			req:      testRequest{method: http.MethodGet, path: "/v1/project/1/ledger", user: "assistant"},
+			wantCode: http.StatusOK,
+			check:    checkProjectBalance("Rp. 1.300.000", 2),
+		},
+		{
+			name:     "members keep their own project balance",
+			req:      testRequest{method: http.MethodGet, path: "/v1/project/1/member", user: "lead"},
+			wantCode: http.StatusOK,
+			check: checkMembers(
+				model.ProjectMemberResponse{
+					InspectorID:    2,
+					InspectorName:  "pengawas1",
+					Role:           model.LeadInspector,
+					CurrentBalance: "Rp. 1.000.000",
+				},
+				model.ProjectMemberResponse{
+					InspectorID:    3,
+					InspectorName:  "pengawas2",
+					Role:           model.AssistantInspector,
+					CurrentBalance: "Rp. 300.000",
+				},
+			),
+		},
+		{
+			name: "director sends funds to the assistant",
+			req: testRequest{
+				method: http.MethodPost,
+				path:   "/v1/project/1/transfer",
+				user:   "director",
+				body:   model.CreateFundTransferBody{Amount: 200000, InspectorID: 3},
+			},
+			wantCode: http.StatusCreated,
+			check: func(t *testing.T, res testResponse) {
+				// the assistant leaves the project between the transfer and its confirmation
+				if err := s.repo.ProjectMember().Delete(context.Background(), 1, 3); err != nil {
+					t.Fatal(err)
+				}
+			},
+		},
+		{
+			name: "inspector who left the project can't confirm the transfer",
+			req: testRequest{
+				method:  http.MethodPost,
+				path:    "/v1/project/1/transfer/1/confirm",
+				user:    "assistant",
+				receipt: true,
+			},
+			wantCode:    http.StatusBadRequest,
+			wantMessage: "Pengawas bukan lagi anggota proyek, transfer dana tidak dapat dikonfirmasi",
+			check: func(t *testing.T, res testResponse) {
+				if err := s.repo.ProjectMember().Create(context.Background(), &model.ProjectMember{
+					ProjectID:   1,
+					InspectorID: 3,
+					Role:        model.AssistantInspector,
+				}); err != nil {
+					t.Fatal(err)
+				}
+			},
+		},
+		{
+			name:     "director cancels the transfer",
+			req:      testRequest{method: http.MethodDelete, path: "/v1/project/1/transfer/1", user: "director"},
+			wantCode: http.StatusOK,
+		},
+		{
+			name:        "director can't remove the lead",
+			req:         testRequest{method: http.MethodDelete, path: "/v1/project/1/member/2", user: "director"},
+			wantCode:    http.StatusBadRequest,
+			wantMessage: "Pengawas utama tidak dapat dihapus, pindahkan proyek ke pengawas lain terlebih dahulu",
+		},
+		{
+			name:     "lead can't remove the assistant",
+			req:      testRequest{method: http.MethodDelete, path: "/v1/project/1/member/3", user: "lead"},
+			wantCode: http.StatusUnauthorized,
+		},
+		{
+			name:        "director removes the assistant",
+			req:         testRequest{method: http.MethodDelete, path: "/v1/project/1/member/3", user: "director"},
+			wantCode:    http.StatusOK,
+			wantMessage: "Berhasil menghapus anggota proyek",
+		},
+		{
+			name:        "director can't remove a member twice",
+			req:         testRequest{method: http.MethodDelete, path: "/v1/project/1/member/3", user: "director"},
+			wantCode:    http.StatusNotFound,
+			wantMessage: "Anggota proyek tidak ditemukan",
+		},
+		{
+			name:     "assistant balance is handed over to the lead",
+			req:      testRequest{method: http.MethodGet, path: "/v1/project/1/member", user: "director"},
+			wantCode: http.StatusOK,
+			check: checkMembers(model.ProjectMemberResponse{
+				InspectorID:    2,
+				InspectorName:  "pengawas1",
+				Role:           model.LeadInspector,
+				CurrentBalance: "Rp. 1.300.000",
+			}),
+		},
+		{
+			name:     "project ledger keeps the transactions of the removed assistant",
+			req:      testRequest{method: http.MethodGet, path: "/v1/project/1/ledger", user: "lead"},
+			wantCode: http.StatusOK,
+			check:    checkProjectBalance("Rp. 1.300.000", 4),
+		},
+		{
+			name:     "removed assistant can't see the project anymore",
+			req:      testRequest{method: http.MethodGet, path: "/v1/project/1", user: "assistant"},
+			wantCode: http.StatusNotFound,
+		},
+		{
+			name:        "removed assistant can't cancel their income",
+			req:         testRequest{method: http.MethodDelete, path: "/v1/project/1/income/2", user: "assistant"},
+			wantCode:    http.StatusNotFound,
+			wantMessage: "transaksi proyek tidak ditemukan",
+		},
+		{
+			name:     "ledger stays consistent after the removal",
+			req:      testRequest{method: http.MethodGet, path: "/v1/ledger/integrity", user: "director"},
+			wantCode: http.StatusOK,
+			check: func(t *testing.T, res testResponse) {
+				var report model.LedgerIntegrityReport
+				decodeData(t, res, &report)
+				if !report.IsConsistent() {
+					t.Errorf("got an inconsistent ledger %+v", report)
+				}
+			},
+		},
+		{
+			name: "director finishes the project",
+			req: testRequest{
+				method: http.MethodPatch,
+				path:   "/v1/project/1/status",
+				user:   "director",
+				body:   model.UpdateProjectStatusBody{Status: string(model.Finished)},
+			},
+			wantCode: http.StatusOK,
+		},
+		{
+			name: "director can't send funds to a finished project",
+			req: testRequest{
+				method: http.MethodPost,
+				path:   "/v1/project/1/transfer",
+				user:   "director",
+				body:   model.CreateFundTransferBody{Amount: 200000},
+			},
+			wantCode:    http.StatusBadRequest,
+			wantMessage: "Dana hanya dapat dikirim ke proyek yang sedang berjalan",
+		},
+		{
+			name: "director can't add a member to a finished project",
+			req: testRequest{
+				method: http.MethodPost,
+				path:   "/v1/project/1/member",
+				user:   "director",
+				body:   model.AddProjectMemberBody{InspectorID: 3},
+			},
+			wantCode:    http.StatusBadRequest,
+			wantMessage: "Anggota hanya dapat ditambahkan ke proyek yang sedang berjalan",
+		},
+	})
+}
+
+func TestFundTransfer(t *testing.T) {
+	s := newTestServer(t)
+	s.login(t, "director", "direktur")
+
+	checkTransactions := func(wantInspectorNames ...string) func(t *testing.T, res testResponse) {
+		return func(t *testing.T, res testResponse) {
+			var ledger model.InspectorLedgerResponse
+			decodeData(t, res, &ledger)
+
+			if len(ledger.Transactions) != len(wantInspectorNames) {
+				t.Fatalf("got %d transactions, want %d", len(ledger.Transactions), len(wantInspectorNames))
+			}
+			for i, want := range wantInspectorNames {
+				if ledger.Transactions[i].InspectorName != want {
+					t.Errorf("got a transaction of %q, want %q", ledger.Transactions[i].InspectorName, want)
+				}
+			}
+		}
+	}
+
+	s.run(t, []testCase{
+		{
+			name: "director creates an inspector",
+			req: testRequest{
+				method: http.MethodPost,
+				path:   "/v1/user/inspector",
+				user:   "director",
+				body: model.CreateInspectorBody{
+					Username: "pengawas1",
+					Name:     "pengawas1",
+					Password: testPassword,
+				},
+			},
+			wantCode: http.StatusCreated,
+			check: func(t *testing.T, res testResponse) {
+				s.login(t, "inspector", "pengawas1")
+			},
+		},
+		{
+			name: "director creates a project",
+			req: testRequest{
+				method: http.MethodPost,
+				path:   "/v1/project",
+				user:   "director",
+				body: model.CreateProjectBody{
+					Name:        "Saluran Desa",
+					Description: "Pembangunan saluran",
+					Type:        string(model.Drainage),
+					DeptName:    "Dinas PU",
+					CompanyName: "Tigaputera",
+					InspectorID: 2,
+					StartDate:   1700000000,
+					FinalDate:   1710000000,
+				},
+			},
+			wantCode: http.StatusCreated,
+		},
+		{
+			name: "director sends funds to the inspector",
+			req: testRequest{
+				method: http.MethodPost,
+				path:   "/v1/project/1/transfer",
+				user:   "director",
+				body:   model.CreateFundTransferBody{Amount: 500000},
+			},
+			wantCode:    http.StatusCreated,
+			wantMessage: "Berhasil mengirim dana ke pengawas",
+		},
+		{
+			name: "inspector confirms the transfer",
+			req: testRequest{
+				method:  http.MethodPost,
+				path:    "/v1/project/1/transfer/1/confirm",
+				user:    "inspector",
+				receipt: true,
+			},
+			wantCode:    http.StatusOK,
+			wantMessage: "Berhasil mengonfirmasi penerimaan dana",
+		},
+		{
+			name:     "inspector receives the funds",
+			req:      testRequest{method: http.MethodGet, path: "/v1/user/inspector/ledger", user: "inspector"},
+			wantCode: http.StatusOK,
+			check:    checkInspectorBalance("Rp. 500.000"),
+		},
+		{
+			name:     "all inspectors ledger leaves the leg of the director out",
+			req:      testRequest{method: http.MethodGet, path: "/v1/user/inspector/ledger", user: "director"},
+			wantCode: http.StatusOK,
+			check:    checkTransactions("pengawas1"),
+		},
+		{
+			name: "director has no inspector ledger",
+			req: testRequest{
+				method: http.MethodGet,
+				path:   "/v1/user/inspector/ledger?inspector_id=1",
+				user:   "director",
+			},
+			wantCode: http.StatusOK,
+			check: func(t *testing.T, res testResponse) {
+				checkTransactions()(t, res)
+				checkInspectorBalance("Rp. 0")(t, res)
+			},
+		},
+		{
+			name:     "project ledger only shows the inspector leg",
+			req:      testRequest{method: http.MethodGet, path: "/v1/project/1/ledger", user: "director"},
+			wantCode: http.StatusOK,
+			check:    checkProjectBalance("Rp. 500.000", 1),
+		},
+		{
+			name:     "both legs keep the ledger consistent",
+			req:      testRequest{method: http.MethodGet, path: "/v1/ledger/integrity", user: "director"},
+			wantCode: http.StatusOK,
+			check: func(t *testing.T, res testResponse) {
+				var report model.LedgerIntegrityReport
+				decodeData(t, res, &report)
+				if !report.IsConsistent() {
+					t.Errorf("got an inconsistent ledger %+v", report)
+				}
+			},
+		},
+	})
+}
+
+func TestUpdateProject(t *testing.T) {
+	s := newTestServer(t)
+	s.login(t, "director", "direktur")
+
+	stringPtr := func(value string) *string { return &value }
+	int64Ptr := func(value int64) *int64 { return &value }
+
+	createProject := func(name string) testCase {
+		return testCase{
+			name: "director creates " + name,
+			req: testRequest{
+				method: http.MethodPost,
+				path:   "/v1/project",
+				user:   "director",
+				body: model.CreateProjectBody{
+					Name:        name,
+					Description: "Pembangunan saluran",
+					Type:        string(model.Drainage),
+					DeptName:    "Dinas PU",
+					CompanyName: "Tigaputera",
+					InspectorID: 2,
+					StartDate:   1700000000,
+					FinalDate:   1710000000,
+				},
+			},
+			wantCode: http.StatusCreated,
+		}
+	}
+
+	update := func(user string, path string, body model.UpdateProjectBody) testRequest {
+		return testRequest{method: http.MethodPatch, path: path, user: user, body: body}
+	}
+
+	s.run(t, []testCase{
+		{
+			name: "director creates an inspector",
+			req: testRequest{
+				method: http.MethodPost,
+				path:   "/v1/user/inspector",
+				user:   "director",
+				body: model.CreateInspectorBody{
+					Username: "pengawas1",
+					Name:     "Pengawas Satu",
+					Password: testPassword,
+				},
+			},
+			wantCode: http.StatusCreated,
+			check: func(t *testing.T, res testResponse) {
+				s.login(t, "inspector", "pengawas1")
+			},
+		},
+		createProject("Saluran Desa"),
+		createProject("Saluran Kota"),
+		{
+			name:     "inspector can't update the project",
+			req:      update("inspector", "/v1/project/1", model.UpdateProjectBody{Name: stringPtr("Saluran Dusun")}),
+			wantCode: http.StatusUnauthorized,
+		},
+		{
+			name:     "director can't clear the name",
+			req:      update("director", "/v1/project/1", model.UpdateProjectBody{Name: stringPtr("")}),
+			wantCode: http.StatusBadRequest,
+		},
+		{
+			name:        "director can't take the name of another project",
+			req:         update("director", "/v1/project/1", model.UpdateProjectBody{Name: stringPtr("Saluran Kota")}),
+			wantCode:    http.StatusBadRequest,
+			wantMessage: "Nama proyek sudah ada",
+		},
+		{
+			name:        "director can't end the project before it starts",
+			req:         update("director", "/v1/project/1", model.UpdateProjectBody{FinalDate: int64Ptr(1600000000)}),
+			wantCode:    http.StatusBadRequest,
+			wantMessage: "Tanggal selesai proyek harus setelah tanggal mulai",
+		},
+		{
+			name:        "director can't set an unknown type",
+			req:         update("director", "/v1/project/1", model.UpdateProjectBody{Type: stringPtr("Jembatan")}),
+			wantCode:    http.StatusBadRequest,
+			wantMessage: "Tipe proyek harus Drainase, Beton, Hotmix, atau Bangunan",
+		},
+		{
+			name:        "director can't update a missing project",
+			req:         update("director", "/v1/project/9", model.UpdateProjectBody{Name: stringPtr("Saluran Dusun")}),
+			wantCode:    http.StatusNotFound,
+			wantMessage: "Proyek tidak ditemukan",
+		},
+		{
+			name: "director fixes the project",
+			req: update("director", "/v1/project/1", model.UpdateProjectBody{
+				Name:      stringPtr("Saluran Dusun"),
+				Type:      stringPtr(string(model.Concrete)),
+				StartDate: int64Ptr(1705000000),
+				Volume:    int64Ptr(120),
+			}),
+			wantCode:    http.StatusOK,
+			wantMessage: "Berhasil mengubah data proyek",
+		},
+		{
+			name:     "inspector sees the fixed project",
+			req:      testRequest{method: http.MethodGet, path: "/v1/project/1", user: "inspector"},
+			wantCode: http.StatusOK,
+			check: func(t *testing.T, res testResponse) {
+				var project model.Project
+				decodeData(t, res, &project)
+
+				if project.Name != "Saluran Dusun" || project.Type != string(model.Concrete) {
+					t.Errorf("got name %q and type %q, want the fixed ones", project.Name, project.Type)
+				}
+				if project.StartDate != 1705000000 || project.FinalDate != 1710000000 {
+					t.Errorf("got dates %d - %d, want the final date kept", project.StartDate, project.FinalDate)
+				}
+				if project.Volume == nil || *project.Volume != 120 || project.Description != "Pembangunan saluran" {
+					t.Errorf("got volume %v and description %q, want the fields left out kept", project.Volume, project.Description)
+				}
+			},
+		},
+		{
+			name: "inspector adds an income",
+			req: testRequest{
+				method:  http.MethodPost,
+				path:    "/v1/project/1/income",
+				user:    "inspector",
+				form:    map[string]string{"amount": "1000000", "ref": "Termin 1"},
+				receipt: true,
+			},
+			wantCode: http.StatusCreated,
+		},
+		{
+			name:        "director can't change the type once the project has a transaction",
+			req:         update("director", "/v1/project/1", model.UpdateProjectBody{Type: stringPtr(string(model.Drainage))}),
+			wantCode:    http.StatusBadRequest,
+			wantMessage: "Tipe proyek tidak dapat diubah karena proyek sudah memiliki transaksi",
+		},
+		{
+			name: "director still fixes the other fields",
+			req: update("director", "/v1/project/1", model.UpdateProjectBody{
+				Type:        stringPtr(string(model.Concrete)),
+				CompanyName: stringPtr("Tigaputera Abadi"),
+			}),
+			wantCode: http.StatusOK,
+		},
+	})
+}
+
+// TestConcurrentLedgerPostings fires the postings and the cancellations of one inspector and project at once,
+// the chains must stay unbroken whatever order the ledger locks let them in
+func TestConcurrentLedgerPostings(t *testing.T) {
+	s := newTestServer(t)
+	s.login(t, "director", "direktur")
+
+	const (
+		initialIncomes  = 5
+		canceledIncomes = 3
+		parallelIncomes = 10
+		parallelSpends  = 10
+	)
+
+	income := func(amount string, seed int64) testRequest {
+		return testRequest{
+			method:         http.MethodPost,
+			path:           "/v1/project/1/income",
+			user:           "inspector",
+			form:           map[string]string{"amount": amount, "ref": "Termin"},
+			receipt:        true,
+			receiptContent: testReceiptImage(t, seed, 0),
+		}
+	}
+
+	cases := []testCase{
+		{
+			name: "director creates an inspector",
+			req: testRequest{
+				method: http.MethodPost,
+				path:   "/v1/user/inspector",
+				user:   "director",
+				body: model.CreateInspectorBody{
+					Username: "pengawas1",
+					Name:     "Pengawas Satu",
+					Password: testPassword,
+				},
+			},
+			wantCode: http.StatusCreated,
+		},
+		{
+			name: "director creates a project",
+			req: testRequest{
+				method: http.MethodPost,
+				path:   "/v1/project",
+				user:   "director",
+				body: model.CreateProjectBody{
+					Name:        "Saluran Desa",
+					Description: "Pembangunan saluran",
+					Type:        string(model.Drainage),
+					DeptName:    "Dinas PU",
+					CompanyName: "Tigaputera",
+					InspectorID: 2,
+					StartDate:   1700000000,
+					FinalDate:   1710000000,
+				},
+			},
+			wantCode: http.StatusCreated,
+			check: func(t *testing.T, res testResponse) {
+				s.login(t, "inspector", "pengawas1")
+			},
+		},
+	}
+	for i := 1; i <= initialIncomes; i++ {
+		cases = append(cases, testCase{
+			name:     fmt.Sprintf("inspector adds income %d", i),
+			req:      income("1000000", int64(i)),
+			wantCode: http.StatusCreated,
+		})
+	}
+	s.run(t, cases)
+
+	// the requests are built before the goroutines, the receipts are different images so none is a duplicate
+	type parallelRequest struct {
+		req      testRequest
+		wantCode int
+	}
+	requests := []parallelRequest{}
+	for i := 0; i < parallelIncomes; i++ {
+		requests = append(requests, parallelRequest{income("100000", int64(100+i)), http.StatusCreated})
+	}
+	for i := 0; i < parallelSpends; i++ {
+		requests = append(requests, parallelRequest{testRequest{
+			method:         http.MethodPost,
+			path:           "/v1/project/1/expenditure/1/transaction",
+			user:           "inspector",
+			form:           map[string]string{"name": "Semen", "price": "50000", "amount": "1"},
+			receipt:        true,
+			receiptContent: testReceiptImage(t, int64(200+i), 0),
+		}, http.StatusCreated})
+	}
+	for i := 1; i <= canceledIncomes; i++ {
+		requests = append(requests, parallelRequest{testRequest{
+			method: http.MethodDelete,
+			path:   fmt.Sprintf("/v1/project/1/income/%d", i),
+			user:   "inspector",
+		}, http.StatusOK})
+	}
+
+	codes := make([]int, len(requests))
+	var wg sync.WaitGroup
+	for i := range requests {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			codes[i] = s.do(t, requests[i].req).code
+		}(i)
+	}
+	wg.Wait()
+
+	for i, request := range requests {
+		if codes[i] != request.wantCode {
+			t.Errorf("%s %s: got code %d, want %d", request.req.method, request.req.path, codes[i], request.wantCode)
+		}
+	}
+
+	ledgers, err := s.repo.Ledger().List(context.Background(), repository.LedgerFilter{
+		InspectorID: 2,
+		ProjectID:   1,
+		Status:      model.Posted,
+		Ascending:   true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	wantLedgers := initialIncomes + parallelIncomes + parallelSpends + canceledIncomes
+	if len(ledgers) != wantLedgers {
+		t.Fatalf("got %d ledgers, want %d", len(ledgers), wantLedgers)
+	}
+
+	var inspectorBalance, projectBalance, sum int64
+	for _, ledger := range ledgers {
+		if *ledger.CurrentInspectorBalance != inspectorBalance || *ledger.CurrentProjectBalance != projectBalance {
+			t.Errorf(
+				"ledger %d starts at %d/%d, want the previous final balances %d/%d",
+				ledger.ID, *ledger.CurrentInspectorBalance, *ledger.CurrentProjectBalance, inspectorBalance, projectBalance,
+			)
+		}
+
+		sum += ledger.TotalPrice
+		inspectorBalance = *ledger.FinalInspectorBalance
+		projectBalance = *ledger.FinalProjectBalance
+		if inspectorBalance != *ledger.CurrentInspectorBalance+ledger.TotalPrice {
+			t.Errorf("ledger %d final inspector balance %d doesn't add its total %d", ledger.ID, inspectorBalance, ledger.TotalPrice)
+		}
+	}
+
+	wantBalance := int64((initialIncomes-canceledIncomes)*1000000 + parallelIncomes*100000 - parallelSpends*50000)
+	if sum != wantBalance || inspectorBalance != wantBalance || projectBalance != wantBalance {
+		t.Errorf("got sum %d and final balances %d/%d, want %d", sum, inspectorBalance, projectBalance, wantBalance)
+	}
+}
+
+func TestExpenditureApproval(t *testing.T) {
+	s := newTestServer(t)
+	s.login(t, "director", "direktur")
+
+	spend := func(price string, seed int64) testRequest {
+		return testRequest{
+			method:         http.MethodPost,
+			path:           "/v1/project/1/expenditure/1/transaction",
+			user:           "inspector",
+			form:           map[string]string{"name": "Semen", "price": price, "amount": "1"},
+			receipt:        true,
+			receiptContent: testReceiptImage(t, seed, 0),
+		}
+	}
+
+	getLedger := func(t *testing.T, id int64) model.Ledger {
+		t.Helper()
+		ledger, err := s.repo.Ledger().Get(context.Background(), repository.LedgerFilter{ID: id})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return ledger
+	}
+
+	threshold := int64(100000)
+	var submittedAt int64
+	s.run(t, []testCase{
+		{
+			name: "director creates an inspector",
+			req: testRequest{
+				method: http.MethodPost,
+				path:   "/v1/user/inspector",
+				user:   "director",
+				body: model.CreateInspectorBody{
+					Username: "pengawas1",
+					Name:     "Pengawas Satu",
+					Password: testPassword,
+				},
+			},
+			wantCode: http.StatusCreated,
+		},
+		{
+			name: "director creates a project",
+			req: testRequest{
+				method: http.MethodPost,
+				path:   "/v1/project",
+				user:   "director",
+				body: model.CreateProjectBody{
+					Name:        "Saluran Desa",
+					Description: "Pembangunan saluran",
+					Type:        string(model.Drainage),
+					DeptName:    "Dinas PU",
+					CompanyName: "Tigaputera",
+					InspectorID: 2,
+					StartDate:   1700000000,
+					FinalDate:   1710000000,
+				},
+			},
+			wantCode: http.StatusCreated,
+			check: func(t *testing.T, res testResponse) {
+				s.login(t, "inspector", "pengawas1")
+			},
+		},
+		{
+			name: "director sets the approval threshold",
+			req: testRequest{
+				method: http.MethodPatch,
+				path:   "/v1/project/1/approval-threshold",
+				user:   "director",
+				body:   model.UpdateApprovalThresholdBody{Threshold: &threshold},
+			},
+			wantCode: http.StatusOK,
+		},
+		{
+			name: "inspector adds an income",
+			req: testRequest{
+				method:         http.MethodPost,
+				path:           "/v1/project/1/income",
+				user:           "inspector",
+				form:           map[string]string{"amount": "1000000", "ref": "Termin"},
+				receipt:        true,
+				receiptContent: testReceiptImage(t, 1, 0),
+			},
+			wantCode: http.StatusCreated,
+		},
+		{
+			name:     "expenditure above the threshold waits for approval",
+			req:      spend("400000", 2),
+			wantCode: http.StatusCreated,
+			check: func(t *testing.T, res testResponse) {
+				ledger := getLedger(t, 2)
+				if ledger.Status != model.Pending {
+					t.Fatalf("got status %s, want %s", ledger.Status, model.Pending)
+				}
+				submittedAt = ledger.CreatedAt
+			},
+		},
+		{
+			name:        "expenditure above the balance left by the pending one is refused before uploading",
+			req:         spend("700000", 3),
+			wantCode:    http.StatusBadRequest,
+			wantMessage: "Saldo anda tidak mencukupi",
+			check: func(t *testing.T, res testResponse) {
+				// the income and the pending expenditure receipts with their thumbnails
+				if len(s.storage.objects) != 4 {
+					t.Errorf("got %d uploaded objects, want 4", len(s.storage.objects))
+				}
+			},
+		},
+		{
+			name: "inspector adds an income after the pending expenditure",
+			req: testRequest{
+				method:         http.MethodPost,
+				path:           "/v1/project/1/income",
+				user:           "inspector",
+				form:           map[string]string{"amount": "500000", "ref": "Termin"},
+				receipt:        true,
+				receiptContent: testReceiptImage(t, 4, 0),
+			},
+			wantCode: http.StatusCreated,
+		},
+		{
+			name: "director approves the pending expenditure",
+			req: testRequest{
+				method: http.MethodPatch,
+				path:   "/v1/project/1/expenditure/1/transaction/2/approve",
+				user:   "director",
+			},
+			wantCode: http.StatusOK,
+			check: func(t *testing.T, res testResponse) {
+				approved, income := getLedger(t, 2), getLedger(t, 3)
+				if approved.CreatedAt != submittedAt {
+					t.Errorf("got created at %d, want the submission time %d", approved.CreatedAt, submittedAt)
+				}
+				if approved.PostedAt <= income.PostedAt {
+					t.Errorf("got posted at %d, want after the income posted at %d", approved.PostedAt, income.PostedAt)
+				}
+				if *approved.CurrentProjectBalance != 1500000 || *approved.FinalProjectBalance != 1100000 {
+					t.Errorf(
+						"got project balances %d/%d, want 1500000/1100000",
+						*approved.CurrentProjectBalance, *approved.FinalProjectBalance,
+					)
+				}
+			},
+		},
+		{
+			name:     "second expenditure waits for approval",
+			req:      spend("600000", 5),
+			wantCode: http.StatusCreated,
+			check: func(t *testing.T, res testResponse) {
+				// a hold left from before the balance check, above what the approval can still cover
+				createdBy, expenditureID, description := int64(2), int64(1), "Semen"
+				if err := s.repo.Ledger().Create(context.Background(), &model.Ledger{
+					CreatedBy:   &createdBy,
+					InspectorID: 2,
+					ProjectID:   1,
+					LedgerType:  model.Credit,
+					RefID:       &expenditureID,
+					Ref:         "Semen",
+					Description: &description,
+					Amount:      1,
+					Price:       -600000,
+					TotalPrice:  -600000,
+					Status:      model.Pending,
+				}); err != nil {
+					t.Fatal(err)
+				}
+			},
+		},
+		{
+			name: "approval is refused when the other pending holds exceed the balance left",
+			req: testRequest{
+				method: http.MethodPatch,
+				path:   "/v1/project/1/expenditure/1/transaction/5/approve",
+				user:   "director",
+			},
+			wantCode:    http.StatusBadRequest,
+			wantMessage: "Saldo pengawas tidak mencukupi",
+		},
+		// the approved expenditure was posted a second ahead of the income before it,
+		// the ledgers posted in the same second must still chain after it
+		{
+			name: "inspector adds an income after the approval",
+			req: testRequest{
+				method:         http.MethodPost,
+				path:           "/v1/project/1/income",
+				user:           "inspector",
+				form:           map[string]string{"amount": "100000", "ref": "Termin"},
+				receipt:        true,
+				receiptContent: testReceiptImage(t, 6, 0),
+			},
+			wantCode: http.StatusCreated,
+		},
+		{
+			name: "inspector adds another income after the approval",
+			req: testRequest{
+				method:         http.MethodPost,
+				path:           "/v1/project/1/income",
+				user:           "inspector",
+				form:           map[string]string{"amount": "200000", "ref": "Termin"},
+				receipt:        true,
+				receiptContent: testReceiptImage(t, 7, 0),
+			},
+			wantCode: http.StatusCreated,
+			check: func(t *testing.T, res testResponse) {
+				approved, first, second := getLedger(t, 2), getLedger(t, 6), getLedger(t, 7)
+				if first.PostedAt < approved.PostedAt || second.PostedAt < first.PostedAt {
+					t.Errorf(
+						"got posted at %d, %d and %d, want the incomes after the approval",
+						approved.PostedAt, first.PostedAt, second.PostedAt,
+					)
+				}
+				if *second.FinalProjectBalance != 1400000 {
+					t.Errorf("got project balance %d, want 1400000", *second.FinalProjectBalance)
+				}
+			},
+		},
+		{
+			name:     "ledger stays consistent after the incomes",
+			req:      testRequest{method: http.MethodGet, path: "/v1/ledger/integrity", user: "director"},
+			wantCode: http.StatusOK,
+			check: func(t *testing.T, res testResponse) {
+				var report model.LedgerIntegrityReport
+				decodeData(t, res, &report)
+				if !report.IsConsistent() {
+					t.Errorf("got an inconsistent ledger %+v", report)
+				}
+			},
+		},
+		{
+			name: "director postpones the project",
+			req: testRequest{
+				method: http.MethodPatch,
+				path:   "/v1/project/1/status",
+				user:   "director",
+				body:   model.UpdateProjectStatusBody{Status: string(model.Postponed)},
+			},
+			wantCode: http.StatusOK,
+		},
+		{
+			name: "expenditure of a postponed project can't be approved",
+			req: testRequest{
+				method: http.MethodPatch,
+				path:   "/v1/project/1/expenditure/1/transaction/4/approve",
+				user:   "director",
+			},
+			wantCode:    http.StatusBadRequest,
+			wantMessage: "Pengeluaran hanya dapat disetujui pada proyek yang sedang berjalan",
+		},
+		{
+			name: "director resumes the project",
+			req: testRequest{
+				method: http.MethodPatch,
+				path:   "/v1/project/1/status",
+				user:   "director",
+				body:   model.UpdateProjectStatusBody{Status: string(model.Running)},
+			},
+			wantCode: http.StatusOK,
+			check: func(t *testing.T, res testResponse) {
+				// the inspector leaves the project while the expenditure is pending
+				if err := s.repo.ProjectMember().Delete(context.Background(), 1, 2); err != nil {
+					t.Fatal(err)
+				}
+			},
+		},
+		{
+			name: "expenditure of an inspector who left the project can't be approved",
+			req: testRequest{
+				method: http.MethodPatch,
+				path:   "/v1/project/1/expenditure/1/transaction/4/approve",
+				user:   "director",
+			},
+			wantCode:    http.StatusBadRequest,
+			wantMessage: "Pengawas bukan lagi anggota proyek, pengeluaran tidak dapat disetujui",
+		},
+	})
+}
+
+func TestConcurrentIdempotentRequests(t *testing.T) {
+	s := newTestServer(t)
+	s.login(t, "director", "direktur")
+
+	const parallelRetries = 5
+
+	income := func(key string) testRequest {
+		return testRequest{
+			method:         http.MethodPost,
+			path:           "/v1/project/1/income",
+			user:           "inspector",
+			headers:        map[string]string{appcontext.HeaderIdempotencyKey: key},
+			form:           map[string]string{"amount": "1000000", "ref": "Termin"},
+			receipt:        true,
+			receiptContent: testReceiptImage(t, int64(len(key)), 0),
+		}
+	}
+
+	countIncomes := func(t *testing.T) int64 {
+		t.Helper()
+		count, err := s.repo.Ledger().Count(context.Background(), repository.LedgerFilter{
+			InspectorID: 2,
+			ProjectID:   1,
+			LedgerType:  model.Debit,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return count
+	}
+
+	s.run(t, []testCase{
+		{
+			name: "director creates an inspector",
+			req: testRequest{
+				method: http.MethodPost,
+				path:   "/v1/user/inspector",
+				user:   "director",
+				body: model.CreateInspectorBody{
+					Username: "pengawas1",
+					Name:     "Pengawas Satu",
+					Password: testPassword,
+				},
+			},
+			wantCode: http.StatusCreated,
+		},
+		{
+			name: "director creates a project",
+			req: testRequest{
+				method: http.MethodPost,
+				path:   "/v1/project",
+				user:   "director",
+				body: model.CreateProjectBody{
+					Name:        "Saluran Desa",
+					Description: "Pembangunan saluran",
+					Type:        string(model.Drainage),
+					DeptName:    "Dinas PU",
+					CompanyName: "Tigaputera",
+					InspectorID: 2,
+					StartDate:   1700000000,
+					FinalDate:   1710000000,
+				},
+			},
+			wantCode: http.StatusCreated,
+			check: func(t *testing.T, res testResponse) {
+				s.login(t, "inspector", "pengawas1")
+			},
+		},
+	})
+
+	// the same request sent at once, the first one is processed and the others are replayed or told to wait
+	responses := make([]testResponse, parallelRetries)
+	var wg sync.WaitGroup
+	for i := range responses {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			responses[i] = s.do(t, income("income-1"))
+		}(i)
+	}
+	wg.Wait()
+
+	processed := 0
+	for _, res := range responses {
+		switch {
+		case res.code == http.StatusCreated && res.header.Get(appcontext.HeaderIdempotentReplayed) == "true":
+		case res.code == http.StatusCreated:
+			processed++
+		case res.code == http.StatusConflict:
+		default:
+			t.Errorf("got code %d, want a created, replayed or conflict response", res.code)
+		}
+	}
+	if processed != 1 {
+		t.Errorf("got %d processed requests, want 1", processed)
+	}
+	if count := countIncomes(t); count != 1 {
+		t.Errorf("got %d incomes, want 1", count)
+	}
+
+	// a key left in progress by a request that died is taken over once it is stale
+	if _, err := s.repo.IdempotencyKey().Create(context.Background(), &model.IdempotencyKey{
+		CreatedAt: time.Now().Add(-time.Hour).Unix(),
+		UserID:    2,
+		Key:       "income-stale",
+		Method:    http.MethodPost,
+		Path:      "/v1/project/1/income",
+		ExpiresAt: time.Now().Add(time.Hour).Unix(),
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	res := s.do(t, income("income-stale"))
+	if res.code != http.StatusCreated || res.header.Get(appcontext.HeaderIdempotentReplayed) == "true" {
+		t.Errorf("got code %d replayed %q, want the stale key to be processed again", res.code, res.header.Get(appcontext.HeaderIdempotentReplayed))
+	}
+	if count := countIncomes(t); count != 2 {
+		t.Errorf("got %d incomes, want 2", count)
+	}
+}
+
+func TestSignedURLSecretKey(t *testing.T) {
+	for _, secretKey := range []string{"", "storage-url-secret"} {
+		t.Run(fmt.Sprintf("%d bytes key", len(secretKey)), func(t *testing.T) {
+			t.Setenv("STORAGE_URL_SECRET_KEY", secretKey)
+
+			defer func() {
+				if recover() == nil {
+					t.Error("the signed urls were initialized with a short secret key")
+				}
+			}()
+			signedurl.Init()
+		})
+	}
+}
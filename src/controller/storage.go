@@ -0,0 +1,97 @@
+package controller
+
+import (
+	"mime"
+	"net/http"
+	"path/filepath"
+
+	errors "tigaputera-backend/sdk/error"
+	"tigaputera-backend/src/model"
+
+	"github.com/gin-gonic/gin"
+)
+
+// @Summary Download Storage Object
+// @Description Download a private receipt or report through the signed url returned by the other endpoints
+// @Tags Storage
+// @Produce octet-stream
+// @Param path path string true "path"
+// @Param file_name path string true "file_name"
+// @Param expires query int true "expires"
+// @Param signature query string true "signature"
+// @Success 200 {file} file
+// @Failure 401 {object} model.HTTPResponse{}
+// @Failure 404 {object} model.HTTPResponse{}
+// @Failure 500 {object} model.HTTPResponse{}
+// @Router /v1/storage/{path}/{file_name} [GET]
+func (r *rest) DownloadStorageObject(c *gin.Context) {
+	var param model.StorageObjectParam
+	if err := r.BindParam(c, &param); err != nil {
+		r.ErrorResponse(c, err)
+		return
+	}
+
+	reader, err := r.svc.Storage.Download(c.Request.Context(), param)
+	if err != nil {
+		r.ErrorResponse(c, err)
+		return
+	}
+	defer reader.Close()
+
+	contentType := mime.TypeByExtension(filepath.Ext(param.FileName))
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+
+	c.DataFromReader(http.StatusOK, -1, contentType, reader, nil)
+}
+
+// @Summary Collect Storage Garbage
+// @Description Quarantine the receipts and reports nothing refers to, and delete the ones quarantined for a week
+// @Tags Storage
+// @Produce json
+// @Param scheduler-key header string true "scheduler-key"
+// @Param dry_run query bool false "dry_run"
+// @Success 200 {object} model.HTTPResponse{data=model.StorageGarbageReport}
+// @Failure 401 {object} model.HTTPResponse{}
+// @Failure 500 {object} model.HTTPResponse{}
+// @Router /v1/storage/garbage-collection [POST]
+func (r *rest) CollectStorageGarbage(c *gin.Context) {
+	if !isSchedulerKeyValid(c) {
+		r.ErrorResponse(c, errors.Unauthorized("scheduler-key tidak valid"))
+		return
+	}
+
+	var param model.StorageGarbageParam
+	if err := r.BindParam(c, &param); err != nil {
+		r.ErrorResponse(c, err)
+		return
+	}
+
+	report, err := r.svc.Storage.CollectGarbage(c.Request.Context(), param)
+	if err != nil {
+		r.ErrorResponse(c, err)
+		return
+	}
+
+	r.SuccessResponse(c, "Berhasil membersihkan file yang tidak terpakai", report, nil)
+}
+
+// @Summary Get Storage Garbage Report
+// @Description Dry run of the storage garbage collection, nothing is moved or deleted
+// @Tags Storage
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} model.HTTPResponse{data=model.StorageGarbageReport}
+// @Failure 401 {object} model.HTTPResponse{}
+// @Failure 500 {object} model.HTTPResponse{}
+// @Router /v1/storage/garbage-collection [GET]
+func (r *rest) GetStorageGarbageReport(c *gin.Context) {
+	report, err := r.svc.Storage.CollectGarbage(c.Request.Context(), model.StorageGarbageParam{DryRun: true})
+	if err != nil {
+		r.ErrorResponse(c, err)
+		return
+	}
+
+	r.SuccessResponse(c, "Berhasil memeriksa file yang tidak terpakai", report, nil)
+}
@@ -0,0 +1,132 @@
+package controller
+
+import (
+	"tigaputera-backend/sdk/auth"
+	errors "tigaputera-backend/sdk/error"
+	"tigaputera-backend/src/model"
+
+	"github.com/gin-gonic/gin"
+)
+
+// @Summary Login with authenticator
+// @Description Finish the login with the code of the authenticator or a recovery code
+// @Tags User
+// @Produce json
+// @Param totpLoginBody body model.TotpLoginBody true "body"
+// @Success 200 {object} model.HTTPResponse{data=model.UserLoginResponse}
+// @Failure 400 {object} model.HTTPResponse{}
+// @Failure 401 {object} model.HTTPResponse{}
+// @Failure 429 {object} model.HTTPResponse{}
+// @Failure 500 {object} model.HTTPResponse{}
+// @Router /v1/auth/login/totp [POST]
+func (r *rest) VerifyLoginTotp(c *gin.Context) {
+	ctx := c.Request.Context()
+	var body model.TotpLoginBody
+
+	if err := r.BindBody(c, &body); err != nil {
+		r.ErrorResponse(c, err)
+		return
+	}
+
+	if err := r.validator.ValidateStruct(body); err != nil {
+		r.ErrorResponse(c, errors.BadRequest(err.Error()))
+		return
+	}
+
+	userResponse, err := r.svc.User.VerifyLoginTotp(ctx, body)
+	if err != nil {
+		r.ErrorResponse(c, err)
+		return
+	}
+
+	r.SuccessResponse(c, "Login berhasil", userResponse, nil)
+}
+
+// @Summary Enroll authenticator
+// @Description Generate the secret of a new authenticator, it is enabled by verifying one of its codes
+// @Tags User
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} model.HTTPResponse{data=model.TotpEnrollmentResponse}
+// @Failure 400 {object} model.HTTPResponse{}
+// @Failure 401 {object} model.HTTPResponse{}
+// @Failure 500 {object} model.HTTPResponse{}
+// @Router /v1/user/totp [POST]
+func (r *rest) EnrollTotp(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	enrollment, err := r.svc.User.EnrollTotp(ctx, auth.GetUser(ctx))
+	if err != nil {
+		r.ErrorResponse(c, err)
+		return
+	}
+
+	r.SuccessResponse(c, "Pindai kode QR dengan aplikasi autentikator", enrollment, nil)
+}
+
+// @Summary Enable authenticator
+// @Description Enable the enrolled authenticator, the recovery codes are only returned once
+// @Tags User
+// @Produce json
+// @Security BearerAuth
+// @Param totpCodeBody body model.TotpCodeBody true "body"
+// @Success 200 {object} model.HTTPResponse{data=model.TotpRecoveryCodesResponse}
+// @Failure 400 {object} model.HTTPResponse{}
+// @Failure 401 {object} model.HTTPResponse{}
+// @Failure 500 {object} model.HTTPResponse{}
+// @Router /v1/user/totp/verify [POST]
+func (r *rest) EnableTotp(c *gin.Context) {
+	ctx := c.Request.Context()
+	var body model.TotpCodeBody
+
+	if err := r.BindBody(c, &body); err != nil {
+		r.ErrorResponse(c, err)
+		return
+	}
+
+	if err := r.validator.ValidateStruct(body); err != nil {
+		r.ErrorResponse(c, errors.BadRequest(err.Error()))
+		return
+	}
+
+	recoveryCodes, err := r.svc.User.EnableTotp(ctx, auth.GetUser(ctx), body)
+	if err != nil {
+		r.ErrorResponse(c, err)
+		return
+	}
+
+	r.SuccessResponse(c, "Autentikasi dua langkah berhasil diaktifkan", recoveryCodes, nil)
+}
+
+// @Summary Disable authenticator
+// @Description Disable the authenticator, the directors can't disable it
+// @Tags User
+// @Produce json
+// @Security BearerAuth
+// @Param totpCodeBody body model.TotpCodeBody true "body"
+// @Success 200 {object} model.HTTPResponse{}
+// @Failure 400 {object} model.HTTPResponse{}
+// @Failure 401 {object} model.HTTPResponse{}
+// @Failure 500 {object} model.HTTPResponse{}
+// @Router /v1/user/totp/disable [POST]
+func (r *rest) DisableTotp(c *gin.Context) {
+	ctx := c.Request.Context()
+	var body model.TotpCodeBody
+
+	if err := r.BindBody(c, &body); err != nil {
+		r.ErrorResponse(c, err)
+		return
+	}
+
+	if err := r.validator.ValidateStruct(body); err != nil {
+		r.ErrorResponse(c, errors.BadRequest(err.Error()))
+		return
+	}
+
+	if err := r.svc.User.DisableTotp(ctx, auth.GetUser(ctx), body); err != nil {
+		r.ErrorResponse(c, err)
+		return
+	}
+
+	r.SuccessResponse(c, "Autentikasi dua langkah berhasil dinonaktifkan", nil, nil)
+}
@@ -8,13 +8,14 @@ import (
 )
 
 // @Summary Login
-// @Description Login for user
+// @Description Login for user, a user with an authenticator gets a challenge token for /v1/auth/login/totp
 // @Tags User
 // @Produce json
 // @Param loginBody body model.UserLoginBody true "body"
 // @Success 200 {object} model.HTTPResponse{data=model.UserLoginResponse}
 // @Failure 400 {object} model.HTTPResponse{}
-// @Failure 404 {object} model.HTTPResponse{}
+// @Failure 401 {object} model.HTTPResponse{}
+// @Failure 429 {object} model.HTTPResponse{}
 // @Failure 500 {object} model.HTTPResponse{}
 // @Router /v1/auth/login [POST]
 func (r *rest) Login(c *gin.Context) {
@@ -31,33 +32,71 @@ func (r *rest) Login(c *gin.Context) {
 		return
 	}
 
-	userParam := model.UserParam{Username: loginBody.Username}
-	var user model.User
+	userResponse, err := r.svc.User.Login(ctx, loginBody)
+	if err != nil {
+		r.ErrorResponse(c, err)
+		return
+	}
+
+	if userResponse.IsTotpRequired {
+		r.SuccessResponse(c, "Masukkan kode autentikator", userResponse, nil)
+		return
+	}
+
+	r.SuccessResponse(c, "Login berhasil", userResponse, nil)
+}
+
+// @Summary Refresh token
+// @Description Exchange a refresh token for a new access token and refresh token
+// @Tags User
+// @Produce json
+// @Param refreshTokenBody body model.RefreshTokenBody true "body"
+// @Success 200 {object} model.HTTPResponse{data=model.UserLoginResponse}
+// @Failure 400 {object} model.HTTPResponse{}
+// @Failure 401 {object} model.HTTPResponse{}
+// @Failure 500 {object} model.HTTPResponse{}
+// @Router /v1/auth/refresh [POST]
+func (r *rest) RefreshToken(c *gin.Context) {
+	ctx := c.Request.Context()
+	var refreshTokenBody model.RefreshTokenBody
 
-	if err := r.db.WithContext(ctx).
-		Where(&userParam).
-		First(&user).Error; err != nil {
-		r.ErrorResponse(c, errors.NotFound("Pengguna tidak ditemukan"))
+	if err := r.BindBody(c, &refreshTokenBody); err != nil {
+		r.ErrorResponse(c, err)
 		return
 	}
 
-	if !r.password.Compare(user.Password, loginBody.Password) {
-		r.ErrorResponse(c, errors.BadRequest("Password anda salah"))
+	if err := r.validator.ValidateStruct(refreshTokenBody); err != nil {
+		r.ErrorResponse(c, errors.BadRequest(err.Error()))
 		return
 	}
 
-	token, err := r.jwt.GenerateToken(user)
+	userResponse, err := r.svc.User.RefreshToken(ctx, refreshTokenBody)
 	if err != nil {
-		r.ErrorResponse(c, errors.InternalServerError(err.Error()))
+		r.ErrorResponse(c, err)
 		return
 	}
 
-	userResponse := model.UserLoginResponse{
-		User:  user,
-		Token: token,
+	r.SuccessResponse(c, "Berhasil memperbarui token", userResponse, nil)
+}
+
+// @Summary Logout
+// @Description Revoke the session of the access token
+// @Tags User
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} model.HTTPResponse{}
+// @Failure 401 {object} model.HTTPResponse{}
+// @Failure 500 {object} model.HTTPResponse{}
+// @Router /v1/auth/logout [POST]
+func (r *rest) Logout(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	if err := r.svc.User.Logout(ctx, auth.GetUser(ctx)); err != nil {
+		r.ErrorResponse(c, err)
+		return
 	}
 
-	r.SuccessResponse(c, "Login berhasil", userResponse, nil)
+	r.SuccessResponse(c, "Logout berhasil", nil, nil)
 }
 
 // @Summary Get user profile
@@ -78,12 +117,15 @@ func (r *rest) GetUserProfile(c *gin.Context) {
 		Name:     user.Name,
 		Role:     model.Role(user.Role),
 	}
+	for _, permission := range user.Permissions {
+		userResponse.Permissions = append(userResponse.Permissions, model.Permission(permission))
+	}
 
 	r.SuccessResponse(c, "Berhasil menampilkan profil", userResponse, nil)
 }
 
 // @Summary Reset password
-// @Description Reset password
+// @Description Change the password, the current password is required unless the password must be changed
 // @Tags User
 // @Produce json
 // @Security BearerAuth
@@ -96,22 +138,6 @@ func (r *rest) GetUserProfile(c *gin.Context) {
 // @Router /v1/user/reset-password [PATCH]
 func (r *rest) ResetPassword(c *gin.Context) {
 	ctx := c.Request.Context()
-	userInfo := auth.GetUser(ctx)
-	userParam := model.UserParam{Username: userInfo.Username}
-	var user model.User
-
-	if err := r.db.WithContext(ctx).
-		Where(&userParam).
-		First(&user).Error; err != nil {
-		r.ErrorResponse(c, errors.NotFound("Pengguna tidak ditemukan"))
-		return
-	}
-
-	if !*user.IsFirstLogin {
-		r.ErrorResponse(c, errors.BadRequest("Anda sudah pernah mengganti password"))
-		return
-	}
-
 	var resetPasswordBody model.ResetPasswordBody
 
 	if err := r.BindBody(c, &resetPasswordBody); err != nil {
@@ -124,22 +150,8 @@ func (r *rest) ResetPassword(c *gin.Context) {
 		return
 	}
 
-	newPassword, err := r.password.Hash(resetPasswordBody.NewPassword)
-	if err != nil {
-		r.ErrorResponse(c, errors.InternalServerError(err.Error()))
-		return
-	}
-
-	updatedUser := model.User{
-		Password:     newPassword,
-		IsFirstLogin: new(bool), // false
-	}
-
-	if err := r.db.WithContext(ctx).
-		Model(model.User{}).
-		Where(&userParam).
-		Updates(&updatedUser).Error; err != nil {
-		r.ErrorResponse(c, errors.InternalServerError(err.Error()))
+	if err := r.svc.User.ResetPassword(ctx, auth.GetUser(ctx), resetPasswordBody); err != nil {
+		r.ErrorResponse(c, err)
 		return
 	}
 
@@ -171,28 +183,8 @@ func (r *rest) CreateInspector(c *gin.Context) {
 		return
 	}
 
-	hashedPassword, err := r.password.Hash(createInspectorBody.Password)
-	if err != nil {
-		r.ErrorResponse(c, errors.InternalServerError(err.Error()))
-		return
-	}
-
-	userInfo := auth.GetUser(ctx)
-	newUser := model.User{
-		Username:  createInspectorBody.Username,
-		Name:      createInspectorBody.Name,
-		Password:  hashedPassword,
-		Role:      model.Inspector,
-		CreatedBy: &userInfo.ID,
-		UpdatedBy: &userInfo.ID,
-	}
-
-	insertError := r.db.WithContext(ctx).Create(&newUser).Error
-	if insertError != nil && r.isUniqueKeyViolation(insertError) {
-		r.ErrorResponse(c, errors.BadRequest("Username sudah digunakan"))
-		return
-	} else if insertError != nil {
-		r.ErrorResponse(c, errors.InternalServerError(insertError.Error()))
+	if err := r.svc.User.CreateInspector(ctx, auth.GetUser(ctx), createInspectorBody); err != nil {
+		r.ErrorResponse(c, err)
 		return
 	}
 
@@ -206,6 +198,7 @@ func (r *rest) CreateInspector(c *gin.Context) {
 // @Security BearerAuth
 // @param limit query int false "limit"
 // @param page query int false "page"
+// @param is_deleted query bool false "list the deactivated inspectors"
 // @Success 200 {object} model.HTTPResponse{data=[]model.User}
 // @Failure 401 {object} model.HTTPResponse{}
 // @Failure 500 {object} model.HTTPResponse{}
@@ -218,38 +211,105 @@ func (r *rest) GetListInspector(c *gin.Context) {
 		return
 	}
 
-	userParam.Role = string(model.Inspector)
-	userParam.PaginationParam.SetDefaultPagination()
+	users, err := r.svc.User.GetListInspector(ctx, &userParam)
+	if err != nil {
+		r.ErrorResponse(c, err)
+		return
+	}
 
-	var users []model.User
+	r.SuccessResponse(c, "Berhasil mendapatkan list pengawas", users, &userParam.PaginationParam)
+}
 
-	if err := r.db.WithContext(ctx).
-		Where(&userParam).
-		Offset(int(userParam.Offset)).
-		Limit(int(userParam.Limit)).
-		Find(&users).Error; err != nil {
-		r.ErrorResponse(c, errors.InternalServerError(err.Error()))
+// @Summary Update inspector
+// @Description Rename an inspector
+// @Tags User
+// @Produce json
+// @Security BearerAuth
+// @Param user_id path int true "user_id"
+// @Param updateInspectorBody body model.UpdateInspectorBody true "body"
+// @Success 200 {object} model.HTTPResponse{}
+// @Failure 400 {object} model.HTTPResponse{}
+// @Failure 401 {object} model.HTTPResponse{}
+// @Failure 404 {object} model.HTTPResponse{}
+// @Failure 500 {object} model.HTTPResponse{}
+// @Router /v1/user/inspector/{user_id} [PATCH]
+func (r *rest) UpdateInspector(c *gin.Context) {
+	ctx := c.Request.Context()
+	var param model.UserParam
+	var body model.UpdateInspectorBody
+
+	if err := r.BindParam(c, &param); err != nil {
+		r.ErrorResponse(c, err)
+		return
 	}
 
-	if err := r.db.WithContext(ctx).
-		Model(model.User{}).
-		Where(&userParam).
-		Count(&userParam.TotalElement).Error; err != nil {
-		r.ErrorResponse(c, errors.InternalServerError(err.Error()))
+	if err := r.BindBody(c, &body); err != nil {
+		r.ErrorResponse(c, err)
+		return
 	}
 
-	userParam.PaginationParam.ProcessPagination(int64(len(users)))
+	if err := r.validator.ValidateStruct(body); err != nil {
+		r.ErrorResponse(c, errors.BadRequest(err.Error()))
+		return
+	}
 
-	r.SuccessResponse(c, "Berhasil mendapatkan list pengawas", users, &userParam.PaginationParam)
+	if err := r.svc.User.UpdateInspector(ctx, param, body); err != nil {
+		r.ErrorResponse(c, err)
+		return
+	}
+
+	r.SuccessResponse(c, "Berhasil mengubah pengawas", nil, nil)
+}
+
+// @Summary Reset inspector password
+// @Description Set a new password for an inspector who forgot it, the inspector changes it on the next login
+// @Tags User
+// @Produce json
+// @Security BearerAuth
+// @Param user_id path int true "user_id"
+// @Param resetPasswordBody body model.ResetPasswordBody true "body"
+// @Success 200 {object} model.HTTPResponse{}
+// @Failure 400 {object} model.HTTPResponse{}
+// @Failure 401 {object} model.HTTPResponse{}
+// @Failure 404 {object} model.HTTPResponse{}
+// @Failure 500 {object} model.HTTPResponse{}
+// @Router /v1/user/inspector/{user_id}/password [PATCH]
+func (r *rest) ResetInspectorPassword(c *gin.Context) {
+	ctx := c.Request.Context()
+	var param model.UserParam
+	var body model.ResetPasswordBody
+
+	if err := r.BindParam(c, &param); err != nil {
+		r.ErrorResponse(c, err)
+		return
+	}
+
+	if err := r.BindBody(c, &body); err != nil {
+		r.ErrorResponse(c, err)
+		return
+	}
+
+	if err := r.validator.ValidateStruct(body); err != nil {
+		r.ErrorResponse(c, errors.BadRequest(err.Error()))
+		return
+	}
+
+	if err := r.svc.User.ResetInspectorPassword(ctx, param, body); err != nil {
+		r.ErrorResponse(c, err)
+		return
+	}
+
+	r.SuccessResponse(c, "Berhasil mengatur ulang password pengawas", nil, nil)
 }
 
 // @Summary Deactive Inspector
-// @Description Deactive an inspector
+// @Description Deactive an inspector, the balance and the running projects are handed over first
 // @Tags User
 // @Produce json
 // @Security BearerAuth
 // @Param user_id path int true "user_id"
 // @Success 200 {object} model.HTTPResponse{}
+// @Failure 400 {object} model.HTTPResponse{}
 // @Failure 401 {object} model.HTTPResponse{}
 // @Failure 404 {object} model.HTTPResponse{}
 // @Failure 500 {object} model.HTTPResponse{}
@@ -261,18 +321,65 @@ func (r *rest) DeactiveInspector(c *gin.Context) {
 		return
 	}
 
+	if err := r.svc.User.DeactivateInspector(c.Request.Context(), inspectorParam.ID); err != nil {
+		r.ErrorResponse(c, err)
+		return
+	}
+
+	r.SuccessResponse(c, "Berhasil menonaktifkan pengawas", nil, nil)
+}
+
+// @Summary Reactivate inspector
+// @Description Reactivate a deactivated inspector
+// @Tags User
+// @Produce json
+// @Security BearerAuth
+// @Param user_id path int true "user_id"
+// @Success 200 {object} model.HTTPResponse{}
+// @Failure 400 {object} model.HTTPResponse{}
+// @Failure 401 {object} model.HTTPResponse{}
+// @Failure 404 {object} model.HTTPResponse{}
+// @Failure 500 {object} model.HTTPResponse{}
+// @Router /v1/user/inspector/{user_id}/reactivate [POST]
+func (r *rest) ReactivateInspector(c *gin.Context) {
+	var param model.UserParam
+	if err := r.BindParam(c, &param); err != nil {
+		r.ErrorResponse(c, err)
+		return
+	}
+
+	if err := r.svc.User.ReactivateInspector(c.Request.Context(), param.ID); err != nil {
+		r.ErrorResponse(c, err)
+		return
+	}
+
+	r.SuccessResponse(c, "Berhasil mengaktifkan kembali pengawas", nil, nil)
+}
+
+// @Summary Unlock user
+// @Description Clear the failed logins of a user locked out by them
+// @Tags User
+// @Produce json
+// @Security BearerAuth
+// @Param user_id path int true "user_id"
+// @Success 200 {object} model.HTTPResponse{}
+// @Failure 401 {object} model.HTTPResponse{}
+// @Failure 404 {object} model.HTTPResponse{}
+// @Failure 500 {object} model.HTTPResponse{}
+// @Router /v1/user/{user_id}/unlock [POST]
+func (r *rest) UnlockUser(c *gin.Context) {
 	ctx := c.Request.Context()
-	err := r.db.WithContext(ctx).
-		Where(&inspectorParam).
-		Delete(&model.User{}).Error
+	var param model.UserParam
 
-	if r.isNoRecordFound(err) {
-		r.ErrorResponse(c, errors.NotFound("Pengawas tidak ditemukan"))
+	if err := r.BindParam(c, &param); err != nil {
+		r.ErrorResponse(c, err)
 		return
-	} else if err != nil {
-		r.ErrorResponse(c, errors.InternalServerError(err.Error()))
+	}
+
+	if err := r.svc.User.UnlockUser(ctx, param.ID); err != nil {
+		r.ErrorResponse(c, err)
 		return
 	}
 
-	r.SuccessResponse(c, "Berhasil menonaktifkan pengawas", nil, nil)
+	r.SuccessResponse(c, "Berhasil membuka kunci pengguna", nil, nil)
 }
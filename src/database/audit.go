@@ -0,0 +1,348 @@
+package database
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"tigaputera-backend/sdk/appcontext"
+	"tigaputera-backend/sdk/auth"
+	"tigaputera-backend/src/model"
+)
+
+const (
+	auditSkipKey   = "audit:skip"
+	auditBeforeKey = "audit:before"
+)
+
+// auditSkippedTables aren't audited, they are logs themselves or they change on every login and request
+var auditSkippedTables = map[string]bool{
+	"audit_logs":          true,
+	"idempotency_keys":    true,
+	"login_attempts":      true,
+	"login_throttles":     true,
+	"login_challenges":    true,
+	"mqt_inspector_stats": true,
+	"mqt_project_stats":   true,
+}
+
+// auditIgnoredColumns change on every write, a write changing only them isn't logged
+var auditIgnoredColumns = map[string]bool{
+	"updated_at": true,
+	"updated_by": true,
+}
+
+// auditRow is a row as it is logged, keyed by the column names
+type auditRow map[string]interface{}
+
+// RegisterAuditCallbacks logs every create, update and delete done through gorm into the audit logs
+// inside the transaction of the write, so no repository can forget it. The created_by, updated_by and
+// deleted_by columns are filled from the user of the context. It is registered after the migrations
+// and the seeds, they aren't written by a user.
+func (db *DB) RegisterAuditCallbacks() error {
+	callback := db.DB.Callback()
+
+	if err := callback.Create().Before("gorm:create").Register("audit:before_create", setAuditCreatedBy); err != nil {
+		return err
+	}
+	if err := callback.Create().Before("gorm:after_create").Register("audit:after_create", auditCreate); err != nil {
+		return err
+	}
+	if err := callback.Update().Before("gorm:update").Register("audit:before_update", beforeAuditUpdate); err != nil {
+		return err
+	}
+	if err := callback.Update().Before("gorm:after_update").Register("audit:after_update", auditUpdate); err != nil {
+		return err
+	}
+	if err := callback.Delete().Before("gorm:delete").Register("audit:before_delete", beforeAuditDelete); err != nil {
+		return err
+	}
+
+	return callback.Delete().Before("gorm:after_delete").Register("audit:after_delete", auditDelete)
+}
+
+func isAudited(db *gorm.DB) bool {
+	if db.Error != nil || db.Statement.Schema == nil || auditSkippedTables[db.Statement.Table] {
+		return false
+	}
+
+	skip, _ := db.Get(auditSkipKey)
+	return skip != true
+}
+
+func getAuditActor(db *gorm.DB) *int64 {
+	userID := auth.GetUserID(db.Statement.Context)
+	if userID == 0 {
+		return nil
+	}
+
+	return &userID
+}
+
+func setAuditCreatedBy(db *gorm.DB) {
+	actor := getAuditActor(db)
+	if !isAudited(db) || actor == nil {
+		return
+	}
+
+	for _, name := range []string{"CreatedBy", "UpdatedBy"} {
+		field := db.Statement.Schema.LookUpField(name)
+		if field == nil {
+			continue
+		}
+
+		eachAuditValue(db.Statement.ReflectValue, func(value reflect.Value) {
+			if _, isZero := field.ValueOf(db.Statement.Context, value); isZero {
+				db.AddError(field.Set(db.Statement.Context, value, *actor))
+			}
+		})
+	}
+}
+
+func auditCreate(db *gorm.DB) {
+	if !isAudited(db) || db.RowsAffected == 0 {
+		return
+	}
+
+	logs := []model.AuditLog{}
+	eachAuditValue(db.Statement.ReflectValue, func(value reflect.Value) {
+		id, row := getAuditRow(db.Statement, value)
+		logs = append(logs, newAuditLog(db, model.AuditCreate, id, nil, row))
+	})
+
+	createAuditLogs(db, logs)
+}
+
+// beforeAuditUpdate keeps the rows matched by the update, the updated_by is set by the user
+// unless the repository sets it
+func beforeAuditUpdate(db *gorm.DB) {
+	if !isAudited(db) {
+		return
+	}
+
+	if field := db.Statement.Schema.LookUpField("UpdatedBy"); field != nil {
+		if updates, ok := db.Statement.Dest.(map[string]interface{}); ok {
+			if _, ok := updates[field.DBName]; !ok && getAuditActor(db) != nil {
+				db.Statement.SetColumn(field.DBName, *getAuditActor(db))
+			}
+		}
+	}
+
+	// an unscoped update like a restore matches the deleted rows too
+	rows, err := findAuditRows(db, getAuditCondition(db), db.Statement.Unscoped)
+	if err != nil {
+		db.AddError(err)
+		return
+	}
+	db.InstanceSet(auditBeforeKey, rows)
+}
+
+func auditUpdate(db *gorm.DB) {
+	value, ok := db.InstanceGet(auditBeforeKey)
+	if !isAudited(db) || !ok || db.RowsAffected == 0 {
+		return
+	}
+	before := value.(map[int64]auditRow)
+	if len(before) == 0 {
+		return
+	}
+
+	rows, err := findAuditRows(db, getAuditIDCondition(db, before), true)
+	if err != nil {
+		db.AddError(err)
+		return
+	}
+
+	logs := []model.AuditLog{}
+	for id, row := range rows {
+		changedBefore, changedAfter := diffAuditRows(before[id], row)
+		if len(changedAfter) > 0 {
+			logs = append(logs, newAuditLog(db, model.AuditUpdate, id, changedBefore, changedAfter))
+		}
+	}
+
+	createAuditLogs(db, logs)
+}
+
+func beforeAuditDelete(db *gorm.DB) {
+	if !isAudited(db) {
+		return
+	}
+
+	rows, err := findAuditRows(db, getAuditCondition(db), false)
+	if err != nil {
+		db.AddError(err)
+		return
+	}
+	db.InstanceSet(auditBeforeKey, rows)
+}
+
+func auditDelete(db *gorm.DB) {
+	value, ok := db.InstanceGet(auditBeforeKey)
+	if !isAudited(db) || !ok || db.RowsAffected == 0 {
+		return
+	}
+	before := value.(map[int64]auditRow)
+	if len(before) == 0 {
+		return
+	}
+
+	// the soft delete only sets deleted_at, the user who deleted the rows is set after it
+	actor := getAuditActor(db)
+	field := db.Statement.Schema.LookUpField("DeletedBy")
+	if field != nil && actor != nil && !db.Statement.Unscoped {
+		err := db.Session(&gorm.Session{NewDB: true}).
+			Set(auditSkipKey, true).
+			Unscoped().
+			Model(reflect.New(db.Statement.Schema.ModelType).Interface()).
+			Clauses(getAuditIDCondition(db, before)).
+			UpdateColumn(field.DBName, *actor).Error
+		if err != nil {
+			db.AddError(err)
+			return
+		}
+	}
+
+	logs := []model.AuditLog{}
+	for id, row := range before {
+		logs = append(logs, newAuditLog(db, model.AuditDelete, id, row, nil))
+	}
+
+	createAuditLogs(db, logs)
+}
+
+// getAuditCondition copies the conditions of the write, the primary key of the model is added
+// like gorm adds it when the model isn't empty
+func getAuditCondition(db *gorm.DB) clause.Where {
+	where := clause.Where{}
+	if c, ok := db.Statement.Clauses["WHERE"]; ok {
+		if w, ok := c.Expression.(clause.Where); ok {
+			where.Exprs = append(where.Exprs, w.Exprs...)
+		}
+	}
+
+	field := db.Statement.Schema.PrioritizedPrimaryField
+	if field != nil && db.Statement.ReflectValue.Kind() == reflect.Struct {
+		if id, isZero := field.ValueOf(db.Statement.Context, db.Statement.ReflectValue); !isZero {
+			where.Exprs = append(where.Exprs, clause.Eq{Column: clause.Column{Name: field.DBName}, Value: id})
+		}
+	}
+
+	return where
+}
+
+func getAuditIDCondition(db *gorm.DB, rows map[int64]auditRow) clause.Where {
+	ids := []interface{}{}
+	for id := range rows {
+		ids = append(ids, id)
+	}
+
+	column := clause.Column{Name: db.Statement.Schema.PrioritizedPrimaryField.DBName}
+	return clause.Where{Exprs: []clause.Expression{clause.IN{Column: column, Values: ids}}}
+}
+
+// findAuditRows reads the rows in the transaction of the write
+func findAuditRows(db *gorm.DB, where clause.Where, unscoped bool) (map[int64]auditRow, error) {
+	rows := map[int64]auditRow{}
+	if len(where.Exprs) == 0 || db.Statement.Schema.PrioritizedPrimaryField == nil {
+		return rows, nil
+	}
+
+	query := db.Session(&gorm.Session{NewDB: true}).Set(auditSkipKey, true)
+	if unscoped {
+		query = query.Unscoped()
+	}
+
+	values := reflect.New(reflect.SliceOf(db.Statement.Schema.ModelType))
+	if err := query.Table(db.Statement.Table).Clauses(where).Find(values.Interface()).Error; err != nil {
+		return rows, err
+	}
+
+	for i := 0; i < values.Elem().Len(); i++ {
+		id, row := getAuditRow(db.Statement, values.Elem().Index(i))
+		rows[id] = row
+	}
+
+	return rows, nil
+}
+
+// getAuditRow reads the columns of the row, the columns hidden from the responses like the passwords
+// are hidden from the audit logs too
+func getAuditRow(stmt *gorm.Statement, value reflect.Value) (int64, auditRow) {
+	var id int64
+	row := auditRow{}
+	for _, field := range stmt.Schema.Fields {
+		if field.DBName == "" || field.Tag.Get("json") == "-" {
+			continue
+		}
+
+		fieldValue, _ := field.ValueOf(stmt.Context, value)
+		if field == stmt.Schema.PrioritizedPrimaryField {
+			id = reflect.Indirect(reflect.ValueOf(fieldValue)).Int()
+		}
+		row[field.DBName] = fieldValue
+	}
+
+	return id, row
+}
+
+// diffAuditRows returns the columns whose value changed, before and after the write
+func diffAuditRows(before auditRow, after auditRow) (auditRow, auditRow) {
+	changedBefore, changedAfter := auditRow{}, auditRow{}
+	for column, value := range after {
+		if auditIgnoredColumns[column] {
+			continue
+		}
+
+		beforeJSON, _ := json.Marshal(before[column])
+		afterJSON, _ := json.Marshal(value)
+		if !bytes.Equal(beforeJSON, afterJSON) {
+			changedBefore[column] = before[column]
+			changedAfter[column] = value
+		}
+	}
+
+	return changedBefore, changedAfter
+}
+
+func eachAuditValue(value reflect.Value, fn func(value reflect.Value)) {
+	switch value.Kind() {
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < value.Len(); i++ {
+			fn(reflect.Indirect(value.Index(i)))
+		}
+	case reflect.Struct:
+		fn(value)
+	}
+}
+
+func newAuditLog(db *gorm.DB, action model.AuditAction, id int64, before auditRow, after auditRow) model.AuditLog {
+	ctx := db.Statement.Context
+	log := model.AuditLog{
+		UserID:    getAuditActor(db),
+		RequestID: appcontext.GetRequestId(ctx),
+		IPAddress: appcontext.GetClientIP(ctx),
+		Action:    action,
+		Entity:    db.Statement.Table,
+		EntityID:  id,
+	}
+
+	if before != nil {
+		log.Before, _ = json.Marshal(before)
+	}
+	if after != nil {
+		log.After, _ = json.Marshal(after)
+	}
+
+	return log
+}
+
+func createAuditLogs(db *gorm.DB, logs []model.AuditLog) {
+	if len(logs) == 0 {
+		return
+	}
+
+	db.AddError(db.Session(&gorm.Session{NewDB: true}).Set(auditSkipKey, true).Create(&logs).Error)
+}
@@ -57,14 +57,149 @@ func initPostgres(dbLogger log.LogInterface) (*gorm.DB, error) {
 }
 
 func (db *DB) Migrate() error {
-	return db.DB.AutoMigrate(
+	if err := db.DB.AutoMigrate(
 		&model.User{},
 		&model.Project{},
 		&model.ProjectExpenditure{},
+		&model.ProjectAssignment{},
+		&model.ProjectMember{},
 		&model.Ledger{},
+		&model.LedgerAttachment{},
+		&model.FundTransfer{},
+		&model.IdempotencyKey{},
+		&model.UserSession{},
+		&model.PasswordHistory{},
+		&model.LoginAttempt{},
+		&model.LoginThrottle{},
+		&model.LoginChallenge{},
+		&model.RecoveryCode{},
+		&model.RolePermission{},
+		&model.AuditLog{},
 		&model.MqtInspectorStats{},
 		&model.MqtProjectStats{},
-	)
+	); err != nil {
+		return err
+	}
+
+	if err := db.migrateReceiptKeys(); err != nil {
+		return err
+	}
+
+	if err := db.migratePasswordChangedAt(); err != nil {
+		return err
+	}
+
+	if err := db.migrateAuditLogAppendOnly(); err != nil {
+		return err
+	}
+
+	if err := db.migrateProjectAssignments(); err != nil {
+		return err
+	}
+
+	if err := db.migrateProjectMembers(); err != nil {
+		return err
+	}
+
+	if err := db.migrateLedgerPostedAt(); err != nil {
+		return err
+	}
+
+	return db.migrateLedgerAttachments()
+}
+
+// migrateReceiptKeys keeps only the object key of the receipts uploaded while the storage returned public urls,
+// e.g. https://storage.googleapis.com/bucket/incomes/file.png becomes incomes/file.png
+func (db *DB) migrateReceiptKeys() error {
+	receiptKey := gorm.Expr(`regexp_replace(receipt_url, '^https?://.*/([^/]+/[^/]+)$', '\1')`)
+
+	for _, table := range []interface{}{&model.Ledger{}, &model.FundTransfer{}} {
+		if err := db.DB.Model(table).
+			Where("receipt_url LIKE ?", "http%").
+			UpdateColumn("receipt_url", receiptKey).Error; err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// migratePasswordChangedAt starts the password expiry of the users created before it at their last update,
+// instead of expiring every password at once
+func (db *DB) migratePasswordChangedAt() error {
+	return db.DB.Model(&model.User{}).
+		Where("password_changed_at = 0").
+		UpdateColumn("password_changed_at", gorm.Expr("updated_at")).Error
+}
+
+// migrateAuditLogAppendOnly rejects any update or delete of the audit logs, even the ones not done by the api
+func (db *DB) migrateAuditLogAppendOnly() error {
+	return db.DB.Exec(`
+		CREATE OR REPLACE FUNCTION reject_audit_log_change() RETURNS trigger AS $$
+		BEGIN
+			RAISE EXCEPTION 'audit logs are append-only';
+		END;
+		$$ LANGUAGE plpgsql;
+
+		DROP TRIGGER IF EXISTS audit_logs_append_only ON audit_logs;
+		CREATE TRIGGER audit_logs_append_only
+			BEFORE UPDATE OR DELETE OR TRUNCATE ON audit_logs
+			FOR EACH STATEMENT EXECUTE PROCEDURE reject_audit_log_change();
+	`).Error
+}
+
+// migrateProjectAssignments records the inspector of the projects created before the assignments
+// as their first assignment
+func (db *DB) migrateProjectAssignments() error {
+	return db.DB.Exec(`
+		INSERT INTO project_assignments (created_at, created_by, project_id, inspector_id)
+		SELECT p.created_at, p.created_by, p.id, p.inspector_id
+		FROM projects p
+		WHERE NOT EXISTS (
+			SELECT 1 FROM project_assignments a WHERE a.project_id = p.id
+		)`,
+	).Error
+}
+
+// migrateProjectMembers adds the inspector of the projects created before the members as their lead
+func (db *DB) migrateProjectMembers() error {
+	return db.DB.Exec(`
+		INSERT INTO project_members (created_at, updated_at, created_by, project_id, inspector_id, role)
+		SELECT p.created_at, p.created_at, p.created_by, p.id, p.inspector_id, ?
+		FROM projects p
+		WHERE NOT EXISTS (
+			SELECT 1 FROM project_members m
+			WHERE m.project_id = p.id AND m.inspector_id = p.inspector_id AND m.deleted_at IS NULL
+		)`,
+		model.LeadInspector,
+	).Error
+}
+
+// migrateLedgerPostedAt orders the ledgers created before the posting time by their creation,
+// the approvals used to move the creation time
+func (db *DB) migrateLedgerPostedAt() error {
+	return db.DB.Model(&model.Ledger{}).
+		Where("posted_at = 0").
+		UpdateColumn("posted_at", gorm.Expr("created_at")).Error
+}
+
+// migrateLedgerAttachments copies the receipt of the ledgers created before the attachments
+// into their first attachment
+func (db *DB) migrateLedgerAttachments() error {
+	return db.DB.Exec(`
+		INSERT INTO ledger_attachments (
+			created_at, updated_at, created_by, ledger_id, file_type,
+			object_key, thumbnail_key, captured_at, latitude, longitude
+		)
+		SELECT
+			l.created_at, l.created_at, l.inspector_id, l.id, ?,
+			l.receipt_url, l.thumbnail_key, l.captured_at, l.latitude, l.longitude
+		FROM ledgers l
+		WHERE l.receipt_url <> '' AND NOT EXISTS (
+			SELECT 1 FROM ledger_attachments a WHERE a.ledger_id = l.id
+		)`,
+		model.ImageAttachment,
+	).Error
 }
 
 func (db *DB) SeedSuperAdmin() error {
@@ -76,6 +211,28 @@ func (db *DB) SeedSuperAdmin() error {
 	}
 }
 
+// SeedRolePermissions grants the default permissions to the roles that have none,
+// the permissions changed afterwards are kept
+func (db *DB) SeedRolePermissions() error {
+	for role, permissions := range model.DefaultRolePermissions {
+		rolePermission := db.DB.Where("role = ?", role).First(&model.RolePermission{})
+		if rolePermission.RowsAffected > 0 {
+			continue
+		}
+
+		rolePermissions := []model.RolePermission{}
+		for _, permission := range permissions {
+			rolePermissions = append(rolePermissions, model.RolePermission{Role: role, Permission: permission})
+		}
+
+		if err := db.DB.Create(&rolePermissions).Error; err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
 func (db *DB) createSuperAdmin() error {
 	password := password.Init()
 	adminPassword, err := password.Hash(os.Getenv("SUPER_ADMIN_PASSWORD"))
@@ -87,5 +244,7 @@ func (db *DB) createSuperAdmin() error {
 		Name:     os.Getenv("SUPER_ADMIN_NAME"),
 		Password: adminPassword,
 		Role:     model.Admin,
+		// the super admin changes the seeded password on the first login
+		PasswordChangedAt: time.Now().Unix(),
 	}).Error
 }
@@ -0,0 +1,39 @@
+package model
+
+import (
+	"encoding/json"
+)
+
+type AuditAction string
+
+const (
+	AuditCreate AuditAction = "create"
+	AuditUpdate AuditAction = "update"
+	AuditDelete AuditAction = "delete"
+)
+
+// AuditLog records a write of a row, the logs are only inserted.
+// Before and After hold the changed columns only, a created row has no Before and a deleted row no After.
+type AuditLog struct {
+	ID        int64 `gorm:"primaryKey" json:"id"`
+	CreatedAt int64 `gorm:"index" json:"createdAt"`
+
+	UserID    *int64          `gorm:"index" json:"userId"`
+	RequestID string          `gorm:"type:varchar(64);default:''" json:"requestId"`
+	IPAddress string          `gorm:"type:varchar(64);default:''" json:"ipAddress"`
+	Action    AuditAction     `gorm:"not null;type:varchar(16)" json:"action"`
+	Entity    string          `gorm:"not null;type:varchar(255);index:idx_audit_log_entity" json:"entity"`
+	EntityID  int64           `gorm:"not null;index:idx_audit_log_entity" json:"entityId"`
+	Before    json.RawMessage `gorm:"type:jsonb" json:"before" swaggertype:"object"`
+	After     json.RawMessage `gorm:"type:jsonb" json:"after" swaggertype:"object"`
+}
+
+// AuditLogParam filters the audit logs, the dates are unix timestamps and a zero field matches everything
+type AuditLogParam struct {
+	Entity      string `form:"entity"`
+	EntityID    int64  `form:"entity_id"`
+	UserID      int64  `form:"user_id"`
+	CreatedFrom int64  `form:"from"`
+	CreatedTo   int64  `form:"to"`
+	PaginationParam
+}
@@ -0,0 +1,78 @@
+package model
+
+import (
+	"gorm.io/gorm"
+)
+
+type TransferStatus string
+
+const (
+	TransferPending   TransferStatus = "Menunggu Konfirmasi"
+	TransferConfirmed TransferStatus = "Diterima"
+	TransferCanceled  TransferStatus = "Dibatalkan"
+)
+
+type FundTransfer struct {
+	ID        int64          `gorm:"primaryKey" json:"id"`
+	CreatedAt int64          `json:"createdAt"`
+	UpdatedAt int64          `json:"updatedAt"`
+	DeletedAt gorm.DeletedAt `gorm:"index" json:"-"`
+	CreatedBy *int64         `json:"createdBy"`
+	UpdatedBy *int64         `json:"updatedBy"`
+	DeletedBy *int64         `json:"deletedBy"`
+
+	ProjectID   int64          `gorm:"not null;index" json:"projectId"`
+	SenderID    int64          `gorm:"not null" json:"senderId"`
+	InspectorID int64          `gorm:"not null;index" json:"inspectorId"`
+	Amount      int64          `gorm:"not null" json:"amount"`
+	Note        string         `gorm:"type:varchar(255);default:''" json:"note"`
+	Status      TransferStatus `gorm:"not null;type:varchar(255)" json:"status"`
+	ReceiptKey  string         `gorm:"column:receipt_url;type:varchar(255);default:''" json:"receiptKey"`
+	ConfirmedAt *int64         `json:"confirmedAt"`
+	Project     Project        `gorm:"foreignKey:ProjectID" json:"project"`
+	Sender      User           `gorm:"foreignKey:SenderID" json:"sender"`
+	Inspector   User           `gorm:"foreignKey:InspectorID" json:"inspector"`
+}
+
+type FundTransferParam struct {
+	ID        int64 `uri:"transfer_id" param:"transfer_id"`
+	ProjectID int64 `uri:"project_id" param:"project_id"`
+	PaginationParam
+}
+
+// CreateFundTransferBody sends the funds to a member of the project, to the lead when InspectorID is empty
+type CreateFundTransferBody struct {
+	Amount      int64  `json:"amount" validate:"required,min=1"`
+	Note        string `json:"note" validate:"max=255"`
+	InspectorID int64  `json:"inspectorId"`
+}
+
+type FundTransferResponse struct {
+	ID            int64      `json:"id"`
+	Timestamp     int64      `json:"timestamp"`
+	ProjectName   string     `json:"projectName"`
+	SenderName    string     `json:"senderName"`
+	InspectorName string     `json:"inspectorName"`
+	Amount        string     `json:"amount"`
+	Note          string     `json:"note"`
+	Status        LabelStyle `json:"status"`
+	ReceiptURL    string     `json:"receiptUrl"`
+	ConfirmedAt   *int64     `json:"confirmedAt"`
+}
+
+func GetTransferStatusStyle(status TransferStatus) LabelStyle {
+	labelStyle := LabelStyle{
+		Name:         string(status),
+		TextColorHex: string(White),
+	}
+	switch status {
+	case TransferPending:
+		labelStyle.BGColorHex = string(Orange)
+	case TransferConfirmed:
+		labelStyle.BGColorHex = string(Green)
+	case TransferCanceled:
+		labelStyle.BGColorHex = string(Red)
+	}
+
+	return labelStyle
+}
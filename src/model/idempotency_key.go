@@ -0,0 +1,19 @@
+package model
+
+// IdempotencyKey stores the first response of a request sent with an Idempotency-Key header,
+// so the retries of the same user are replayed instead of being processed again
+type IdempotencyKey struct {
+	ID        int64 `gorm:"primaryKey" json:"id"`
+	CreatedAt int64 `json:"createdAt"`
+	UpdatedAt int64 `json:"updatedAt"`
+
+	UserID       int64  `gorm:"not null;uniqueIndex:idx_idempotency_keys_user_key" json:"userId"`
+	Key          string `gorm:"not null;type:varchar(255);uniqueIndex:idx_idempotency_keys_user_key" json:"key"`
+	Method       string `gorm:"not null;type:varchar(16)" json:"method"`
+	Path         string `gorm:"not null;type:varchar(255)" json:"path"`
+	RequestHash  string `gorm:"not null;type:varchar(64);default:''" json:"-"`
+	IsCompleted  bool   `gorm:"not null;default:false" json:"isCompleted"`
+	StatusCode   int    `json:"statusCode"`
+	ResponseBody []byte `json:"-"`
+	ExpiresAt    int64  `gorm:"not null;index" json:"expiresAt"`
+}
@@ -7,8 +7,17 @@ import (
 type LedgerType string
 
 const (
-	Debit  LedgerType = "Pemasukan"
-	Credit LedgerType = "Pengeluaran"
+	Debit    LedgerType = "Pemasukan"
+	Credit   LedgerType = "Pengeluaran"
+	Transfer LedgerType = "Transfer"
+)
+
+type LedgerStatus string
+
+const (
+	Posted   LedgerStatus = "Diposting"
+	Pending  LedgerStatus = "Menunggu Persetujuan"
+	Rejected LedgerStatus = "Ditolak"
 )
 
 type Ledger struct {
@@ -20,23 +29,64 @@ type Ledger struct {
 	UpdatedBy *int64         `json:"updatedBy"`
 	DeletedBy *int64         `json:"deletedBy"`
 
-	InspectorID             int64      `json:"inspectorId"`
-	ProjectID               int64      `json:"projectId"`
-	LedgerType              LedgerType `gorm:"not null;type:varchar(255)" json:"ledgerType"`
-	RefID                   *int64     `gorm:"default:0" json:"refId"`
-	Ref                     string     `gorm:"default:'Direktur'" json:"ref"`
-	Description             *string    `gorm:"type:varchar(255);default:''" json:"description"`
-	Amount                  int64      `gorm:"not null" json:"amount"`
-	Price                   int64      `gorm:"not null" json:"price"`
-	TotalPrice              int64      `gorm:"not null" json:"totalPrice"`
-	CurrentInspectorBalance *int64     `gorm:"default:0" json:"currentBalance"`
-	FinalInspectorBalance   *int64     `gorm:"default:0" json:"finalBalance"`
-	CurrentProjectBalance   *int64     `gorm:"default:0" json:"currentProjectBalance"`
-	FinalProjectBalance     *int64     `gorm:"default:0" json:"finalProjectBalance"`
-	ReceiptURL              string     `gorm:"type:varchar(255);default:''" json:"receiptUrl"`
-	IsCanceled              *bool      `gorm:"default:false" json:"isCanceled"`
-	Inspector               User       `gorm:"foreignKey:InspectorID" json:"inspector"`
-	Project                 Project    `gorm:"foreignKey:ProjectID" json:"project"`
+	InspectorID             int64        `json:"inspectorId"`
+	ProjectID               int64        `json:"projectId"`
+	LedgerType              LedgerType   `gorm:"not null;type:varchar(255)" json:"ledgerType"`
+	RefID                   *int64       `gorm:"default:0" json:"refId"`
+	Ref                     string       `gorm:"default:'Direktur'" json:"ref"`
+	Description             *string      `gorm:"type:varchar(255);default:''" json:"description"`
+	Amount                  int64        `gorm:"not null" json:"amount"`
+	Price                   int64        `gorm:"not null" json:"price"`
+	TotalPrice              int64        `gorm:"not null" json:"totalPrice"`
+	CurrentInspectorBalance *int64       `gorm:"default:0" json:"currentBalance"`
+	FinalInspectorBalance   *int64       `gorm:"default:0" json:"finalBalance"`
+	CurrentProjectBalance   *int64       `gorm:"default:0" json:"currentProjectBalance"`
+	FinalProjectBalance     *int64       `gorm:"default:0" json:"finalProjectBalance"`
+	IsCanceled              *bool        `gorm:"default:false" json:"isCanceled"`
+	TransferID              *int64       `gorm:"index" json:"transferId"`
+	Status                  LedgerStatus `gorm:"not null;type:varchar(255);default:'Diposting';index" json:"status"`
+	RejectionReason         *string      `gorm:"type:varchar(255)" json:"rejectionReason"`
+	ReviewedBy              *int64       `json:"reviewedBy"`
+	ReviewedAt              *int64       `json:"reviewedAt"`
+	// PostedAt orders the chains of balances, CreatedAt keeps when the ledger was submitted.
+	// A pending expenditure is ordered by its submission until it is approved.
+	PostedAt       int64   `gorm:"not null;default:0;index" json:"postedAt"`
+	ReversalOfID   *int64  `gorm:"index" json:"reversalOfId"`
+	CorrectionOfID *int64  `gorm:"index" json:"correctionOfId"`
+	Inspector      User    `gorm:"foreignKey:InspectorID" json:"inspector"`
+	Project        Project `gorm:"foreignKey:ProjectID" json:"project"`
+
+	LedgerReceipt `gorm:"embedded"`
+	Attachments   []LedgerAttachment `gorm:"foreignKey:LedgerID" json:"attachments"`
+}
+
+// SetAttachments attaches the files to a new ledger, the first one is the receipt shown in the ledger lists.
+// The attachments are created by the creator of the ledger, or by its inspector when it isn't set.
+func (l *Ledger) SetAttachments(attachments []LedgerAttachment) {
+	createdBy := l.InspectorID
+	if l.CreatedBy != nil {
+		createdBy = *l.CreatedBy
+	}
+	l.Attachments = make([]LedgerAttachment, len(attachments))
+	for i, attachment := range attachments {
+		attachment.ID = 0
+		attachment.LedgerID = 0
+		attachment.CreatedBy = &createdBy
+		l.Attachments[i] = attachment
+	}
+
+	if len(attachments) > 0 {
+		l.LedgerReceipt = attachments[0].GetReceipt()
+	}
+}
+
+// LedgerReceipt is the processed receipt image of a ledger, the capture time and the location come from its exif
+type LedgerReceipt struct {
+	ReceiptKey   string   `gorm:"column:receipt_url;type:varchar(255);default:''" json:"receiptKey"`
+	ThumbnailKey string   `gorm:"type:varchar(255);default:''" json:"thumbnailKey"`
+	CapturedAt   *int64   `json:"capturedAt"`
+	Latitude     *float64 `json:"latitude"`
+	Longitude    *float64 `json:"longitude"`
 }
 
 type LedgerParam struct {
@@ -64,6 +114,7 @@ type InspectorLedgerAccount struct {
 }
 
 type InspectorLedgerTransaction struct {
+	ID            int64  `json:"id"`
 	Timestamp     int64  `json:"timestamp"`
 	InspectorName string `json:"inspectorName"`
 	Type          string `json:"type"`
@@ -71,6 +122,7 @@ type InspectorLedgerTransaction struct {
 	ProjectName   string `json:"projectName"`
 	Amount        string `json:"amount"`
 	RecieptURL    string `json:"receiptUrl"`
+	ThumbnailURL  string `json:"thumbnailUrl"`
 }
 
 type ProjectLedgerResponse struct {
@@ -78,11 +130,13 @@ type ProjectLedgerResponse struct {
 	Transactions []InspectorLedgerTransaction `json:"transactions"`
 }
 
+// ProjectLedgerAccount holds the balance of the project, the sum of the balances of its members
 type ProjectLedgerAccount struct {
-	ProjectID      int64  `json:"projectId"`
-	ProjectName    string `json:"projectName"`
-	InspectorName  string `json:"inspectorName"`
-	CurrentBalance string `json:"currentBalance"`
+	ProjectID      int64                   `json:"projectId"`
+	ProjectName    string                  `json:"projectName"`
+	InspectorName  string                  `json:"inspectorName"`
+	CurrentBalance string                  `json:"currentBalance"`
+	Members        []ProjectMemberResponse `json:"members"`
 }
 
 type CreateExpenditureDetailBody struct {
@@ -99,13 +153,61 @@ type ExpenditureDetailParam struct {
 	PaginationParam
 }
 
+type LedgerTransactionParam struct {
+	ID        int64 `uri:"transaction_id" param:"transaction_id"`
+	ProjectID int64 `uri:"project_id" param:"project_id"`
+}
+
+// CorrectLedgerBody replaces a posted income or expenditure transaction, empty fields keep
+// the value of the corrected transaction. Amount and Name only apply to expenditures.
+type CorrectLedgerBody struct {
+	Price  int64  `json:"price" validate:"required,min=1"`
+	Amount int64  `json:"amount" validate:"omitempty,min=1"`
+	Name   string `json:"name" validate:"max=255"`
+	Ref    string `json:"ref" validate:"max=255"`
+}
+
+type ExpenditureApprovalParam struct {
+	ID            int64  `uri:"transaction_id" param:"transaction_id"`
+	ProjectID     int64  `uri:"project_id" param:"project_id"`
+	ExpenditureID int64  `uri:"expenditure_id" param:"expenditure_id"`
+	Status        string `form:"status"`
+	PaginationParam
+}
+
+type UpdateApprovalThresholdBody struct {
+	Threshold *int64 `json:"threshold" validate:"omitempty,min=0"`
+}
+
+type RejectExpenditureBody struct {
+	Reason string `json:"reason" validate:"required,max=255"`
+}
+
+type ExpenditureApprovalResponse struct {
+	ID              int64      `json:"id"`
+	Timestamp       int64      `json:"timestamp"`
+	ExpenditureID   int64      `json:"expenditureId"`
+	ExpenditureName string     `json:"expenditureName"`
+	InspectorName   string     `json:"inspectorName"`
+	Name            string     `json:"name"`
+	Price           string     `json:"price"`
+	Amount          int64      `json:"amount"`
+	TotalPrice      string     `json:"totalPrice"`
+	ReceiptURL      string     `json:"receiptUrl"`
+	ThumbnailURL    string     `json:"thumbnailUrl"`
+	Status          LabelStyle `json:"status"`
+	RejectionReason *string    `json:"rejectionReason"`
+}
+
 type ExpenditureDetailList struct {
-	ID         int64  `json:"id"`
-	Name       string `json:"name"`
-	Price      string `json:"price"`
-	Amount     int64  `json:"amount"`
-	TotalPrice string `json:"totalPrice"`
-	ReceiptURL string `json:"receiptUrl"`
+	ID           int64                      `json:"id"`
+	Name         string                     `json:"name"`
+	Price        string                     `json:"price"`
+	Amount       int64                      `json:"amount"`
+	TotalPrice   string                     `json:"totalPrice"`
+	ReceiptURL   string                     `json:"receiptUrl"`
+	ThumbnailURL string                     `json:"thumbnailUrl"`
+	Attachments  []LedgerAttachmentResponse `json:"attachments"`
 }
 
 type ExpenditureDetailListResponse struct {
@@ -115,3 +217,47 @@ type ExpenditureDetailListResponse struct {
 	Details         []ExpenditureDetailList `json:"details"`
 	SumTotal        string                  `json:"sumTotal"`
 }
+
+// IsIncome tells whether the ledger is counted in the project income, expenditure
+// cancellations are debits too but they refer to the canceled expenditure
+func (l Ledger) IsIncome() bool {
+	return l.LedgerType == Debit && (l.TransferID != nil || l.RefID == nil || *l.RefID == 0)
+}
+
+// IsExpenditure tells whether the ledger RefID refers to a project expenditure
+func (l Ledger) IsExpenditure() bool {
+	return l.LedgerType != Transfer && !l.IsIncome() && l.RefID != nil
+}
+
+func IsLedgerStatusCorrect(status string) bool {
+	statusNames := []string{
+		string(Posted),
+		string(Pending),
+		string(Rejected),
+	}
+
+	for _, s := range statusNames {
+		if s == status {
+			return true
+		}
+	}
+
+	return false
+}
+
+func GetLedgerStatusStyle(status LedgerStatus) LabelStyle {
+	labelStyle := LabelStyle{
+		Name:         string(status),
+		TextColorHex: string(White),
+	}
+	switch status {
+	case Posted:
+		labelStyle.BGColorHex = string(Green)
+	case Pending:
+		labelStyle.BGColorHex = string(Orange)
+	case Rejected:
+		labelStyle.BGColorHex = string(Red)
+	}
+
+	return labelStyle
+}
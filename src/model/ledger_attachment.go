@@ -0,0 +1,80 @@
+package model
+
+import (
+	"gorm.io/gorm"
+)
+
+type AttachmentType string
+
+const (
+	ImageAttachment AttachmentType = "Gambar"
+	PDFAttachment   AttachmentType = "PDF"
+)
+
+// MaxLedgerAttachments is how many files a transaction can have, e.g. a nota and a delivery note
+const MaxLedgerAttachments = 5
+
+type LedgerAttachment struct {
+	ID        int64          `gorm:"primaryKey" json:"id"`
+	CreatedAt int64          `json:"createdAt"`
+	UpdatedAt int64          `json:"updatedAt"`
+	DeletedAt gorm.DeletedAt `gorm:"index" json:"-"`
+	CreatedBy *int64         `json:"createdBy"`
+	UpdatedBy *int64         `json:"updatedBy"`
+	DeletedBy *int64         `json:"deletedBy"`
+
+	LedgerID     int64          `gorm:"not null;index" json:"ledgerId"`
+	FileType     AttachmentType `gorm:"not null;type:varchar(255)" json:"fileType"`
+	ObjectKey    string         `gorm:"not null;type:varchar(255)" json:"objectKey"`
+	ThumbnailKey string         `gorm:"type:varchar(255);default:''" json:"thumbnailKey"`
+	CapturedAt   *int64         `json:"capturedAt"`
+	Latitude     *float64       `json:"latitude"`
+	Longitude    *float64       `json:"longitude"`
+	// the hashes are empty for the receipts uploaded before they were recorded
+	ContentHash    string `gorm:"type:varchar(64);index;default:''" json:"contentHash"`
+	PerceptualHash *int64 `json:"perceptualHash"`
+	// DuplicateOfID is the attachment of another transaction a near-identical image was uploaded for
+	DuplicateOfID *int64 `gorm:"index" json:"duplicateOfId"`
+}
+
+// GetReceipt returns the attachment as the receipt shown in the ledger lists
+func (a LedgerAttachment) GetReceipt() LedgerReceipt {
+	return LedgerReceipt{
+		ReceiptKey:   a.ObjectKey,
+		ThumbnailKey: a.ThumbnailKey,
+		CapturedAt:   a.CapturedAt,
+		Latitude:     a.Latitude,
+		Longitude:    a.Longitude,
+	}
+}
+
+type LedgerAttachmentParam struct {
+	ID        int64 `uri:"attachment_id" param:"attachment_id"`
+	LedgerID  int64 `uri:"transaction_id" param:"transaction_id"`
+	ProjectID int64 `uri:"project_id" param:"project_id"`
+}
+
+type LedgerAttachmentResponse struct {
+	ID           int64          `json:"id"`
+	FileType     AttachmentType `json:"fileType"`
+	URL          string         `json:"url"`
+	ThumbnailURL string         `json:"thumbnailUrl"`
+	CapturedAt   *int64         `json:"capturedAt"`
+	Latitude     *float64       `json:"latitude"`
+	Longitude    *float64       `json:"longitude"`
+	// DuplicateOfID warns that a near-identical image backs another transaction
+	DuplicateOfID *int64 `json:"duplicateOfId"`
+}
+
+type DuplicateReceipt struct {
+	Attachment  LedgerAttachmentResponse   `json:"attachment"`
+	Transaction InspectorLedgerTransaction `json:"transaction"`
+}
+
+// DuplicateReceiptResponse pairs a receipt with the earlier one of another transaction it looks like,
+// distance is the number of different bits of their perceptual hashes, 0 for identical images
+type DuplicateReceiptResponse struct {
+	Distance    int              `json:"distance"`
+	Receipt     DuplicateReceipt `json:"receipt"`
+	DuplicateOf DuplicateReceipt `json:"duplicateOf"`
+}
@@ -0,0 +1,54 @@
+package model
+
+type BalanceType string
+
+const (
+	InspectorBalance BalanceType = "inspector"
+	ProjectBalance   BalanceType = "project"
+)
+
+type LedgerIntegrityReport struct {
+	TotalLedger                int64                     `json:"totalLedger"`
+	IsFixed                    bool                      `json:"isFixed"`
+	BalanceBreaks              []LedgerBalanceBreak      `json:"balanceBreaks"`
+	BalanceCorrections         []LedgerBalanceCorrection `json:"balanceCorrections"`
+	ProjectIncomeMismatches    []LedgerTotalMismatch     `json:"projectIncomeMismatches"`
+	ExpenditureTotalMismatches []LedgerTotalMismatch     `json:"expenditureTotalMismatches"`
+}
+
+// LedgerBalanceBreak is a ledger whose current balance isn't the final balance of the previous
+// ledger in its chain, or whose final balance isn't its current balance plus its total price
+type LedgerBalanceBreak struct {
+	LedgerID     int64       `json:"ledgerId"`
+	PrevLedgerID *int64      `json:"prevLedgerId"`
+	InspectorID  int64       `json:"inspectorId"`
+	ProjectID    int64       `json:"projectId"`
+	BalanceType  BalanceType `json:"balanceType"`
+	Reason       string      `json:"reason"`
+	Expected     int64       `json:"expected"`
+	Actual       int64       `json:"actual"`
+}
+
+// LedgerBalanceCorrection is a stored balance that differs from the balance rebuilt from the
+// start of its chain, it is the diff applied by the rebuild
+type LedgerBalanceCorrection struct {
+	LedgerID     int64       `json:"ledgerId"`
+	BalanceType  BalanceType `json:"balanceType"`
+	StoredStart  int64       `json:"storedStart"`
+	StoredFinal  int64       `json:"storedFinal"`
+	RebuiltStart int64       `json:"rebuiltStart"`
+	RebuiltFinal int64       `json:"rebuiltFinal"`
+}
+
+type LedgerTotalMismatch struct {
+	ID      int64  `json:"id"`
+	Name    string `json:"name"`
+	Stored  int64  `json:"stored"`
+	Rebuilt int64  `json:"rebuilt"`
+}
+
+func (l LedgerIntegrityReport) IsConsistent() bool {
+	return len(l.BalanceCorrections) == 0 &&
+		len(l.ProjectIncomeMismatches) == 0 &&
+		len(l.ExpenditureTotalMismatches) == 0
+}
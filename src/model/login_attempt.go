@@ -0,0 +1,34 @@
+package model
+
+type LoginFailureReason string
+
+const (
+	UnknownUser   LoginFailureReason = "unknown_user"
+	WrongPassword LoginFailureReason = "wrong_password"
+	WrongTotp     LoginFailureReason = "wrong_totp"
+	LockedOut     LoginFailureReason = "locked_out"
+)
+
+// LoginAttempt records a failed login, the username is recorded as typed even when no user has it
+type LoginAttempt struct {
+	ID        int64 `gorm:"primaryKey" json:"id"`
+	CreatedAt int64 `gorm:"index" json:"createdAt"`
+
+	Username  string             `gorm:"not null;type:varchar(255);index" json:"username"`
+	UserID    *int64             `gorm:"index" json:"userId"`
+	IPAddress string             `gorm:"not null;type:varchar(64);index" json:"ipAddress"`
+	UserAgent string             `gorm:"type:text;default:''" json:"userAgent"`
+	Reason    LoginFailureReason `gorm:"not null;type:varchar(32)" json:"reason"`
+}
+
+// LoginThrottle counts the consecutive failed logins of a username or an ip address,
+// the key is prefixed with what it counts, e.g. username:budi or ip:10.0.0.1
+type LoginThrottle struct {
+	ID        int64 `gorm:"primaryKey" json:"id"`
+	CreatedAt int64 `json:"createdAt"`
+	UpdatedAt int64 `json:"updatedAt"`
+
+	Key          string `gorm:"not null;unique;type:varchar(320)" json:"key"`
+	FailedCount  int64  `gorm:"not null;default:0" json:"failedCount"`
+	LastFailedAt int64  `gorm:"not null;default:0" json:"lastFailedAt"`
+}
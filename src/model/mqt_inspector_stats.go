@@ -80,3 +80,12 @@ type StatsString struct {
 	Percentage float64 `json:"percentage"`
 }
 
+
+type LedgerReportParam struct {
+	IntervalMonth int64 `form:"interval_month"`
+}
+
+type LedgerReportResponse struct {
+	IntervalMonth int64  `json:"intervalMonth"`
+	URL           string `json:"url"`
+}
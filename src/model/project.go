@@ -60,6 +60,32 @@ type Project struct {
 	Width       *int64  `json:"width"`
 	InspectorID int64   `json:"inspectorId"`
 	Inspector   User    `gorm:"foreignKey:InspectorID" json:"inspector"`
+
+	// expenditures above the threshold wait for a director approval, nil means no approval is needed
+	ApprovalThreshold *int64 `json:"approvalThreshold"`
+
+	// Members are loaded by the project repository Get, the lead is a member too
+	Members []ProjectMember `gorm:"foreignKey:ProjectID" json:"-"`
+}
+
+// IsMember reports whether the inspector works on the project
+func (p Project) IsMember(inspectorID int64) bool {
+	if p.InspectorID == inspectorID {
+		return true
+	}
+
+	for _, member := range p.Members {
+		if member.InspectorID == inspectorID {
+			return true
+		}
+	}
+
+	return false
+}
+
+// IsRunning reports whether the project still takes funds and members
+func (p Project) IsRunning() bool {
+	return p.Status == string(Running)
 }
 
 type ProjectParam struct {
@@ -144,6 +170,20 @@ type UpdateProjectBudgetBody struct {
 	PPH    float64 `json:"pph" validate:"required,min=0,max=1"`
 }
 
+// UpdateProjectBody changes the master data of the project, a missing field is left as it is
+type UpdateProjectBody struct {
+	Name        *string `json:"name" validate:"omitempty,min=1,max=255"`
+	Description *string `json:"description" validate:"omitempty,min=1,max=255"`
+	Type        *string `json:"type" validate:"omitempty,min=1"`
+	DeptName    *string `json:"deptName" validate:"omitempty,min=1,max=255"`
+	CompanyName *string `json:"companyName" validate:"omitempty,min=1,max=255"`
+	StartDate   *int64  `json:"startDate" validate:"omitempty,min=1"`
+	FinalDate   *int64  `json:"finalDate" validate:"omitempty,min=1"`
+	Volume      *int64  `json:"volume" validate:"omitempty,min=0"`
+	Length      *int64  `json:"length" validate:"omitempty,min=0"`
+	Width       *int64  `json:"width" validate:"omitempty,min=0"`
+}
+
 type UpdateProjectStatusBody struct {
 	Status string `json:"status" validate:"required"`
 }
@@ -0,0 +1,31 @@
+package model
+
+// ProjectAssignment records an inspector taking over a project, the first one is created with the project.
+// The project balance of the previous inspector is handed over to the new one.
+type ProjectAssignment struct {
+	ID        int64  `gorm:"primaryKey" json:"id"`
+	CreatedAt int64  `json:"createdAt"`
+	CreatedBy *int64 `json:"createdBy"`
+
+	ProjectID           int64  `gorm:"not null;index" json:"projectId"`
+	InspectorID         int64  `gorm:"not null;index" json:"inspectorId"`
+	PreviousInspectorID *int64 `json:"previousInspectorId"`
+	HandoverAmount      int64  `gorm:"not null;default:0" json:"handoverAmount"`
+	Note                string `gorm:"type:varchar(255);default:''" json:"note"`
+	Inspector           User   `gorm:"foreignKey:InspectorID" json:"inspector"`
+	PreviousInspector   User   `gorm:"foreignKey:PreviousInspectorID" json:"previousInspector"`
+}
+
+type ReassignProjectBody struct {
+	InspectorID int64  `json:"inspectorId" validate:"required"`
+	Note        string `json:"note" validate:"max=255"`
+}
+
+type ProjectAssignmentResponse struct {
+	ID                    int64  `json:"id"`
+	Timestamp             int64  `json:"timestamp"`
+	InspectorName         string `json:"inspectorName"`
+	PreviousInspectorName string `json:"previousInspectorName"`
+	HandoverAmount        string `json:"handoverAmount"`
+	Note                  string `json:"note"`
+}
@@ -17,6 +17,9 @@ type ProjectExpenditure struct {
 	TotalPrice  *int64  `gorm:"default:0" json:"totalPrice"`
 	IsFixedCost *bool   `gorm:"default:true" json:"isFixedCost"`
 	Project     Project `gorm:"foreignKey:ProjectID" json:"project"`
+
+	// overrides the approval threshold of the project when it isn't nil
+	ApprovalThreshold *int64 `json:"approvalThreshold"`
 }
 
 type ProjectExpenditureParam struct {
@@ -67,3 +70,14 @@ var InitialProjectExpenditures = []ProjectExpenditure{
 		IsFixedCost: new(bool), // false
 	},
 }
+
+// IsApprovalRequired tells whether an expenditure transaction of totalPrice has to be approved
+// before it is posted, the expenditure must be loaded with its project
+func (p ProjectExpenditure) IsApprovalRequired(totalPrice int64) bool {
+	threshold := p.Project.ApprovalThreshold
+	if p.ApprovalThreshold != nil {
+		threshold = p.ApprovalThreshold
+	}
+
+	return threshold != nil && totalPrice > *threshold
+}
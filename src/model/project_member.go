@@ -0,0 +1,45 @@
+package model
+
+import (
+	"gorm.io/gorm"
+)
+
+type ProjectMemberRole string
+
+const (
+	LeadInspector      ProjectMemberRole = "Pengawas Utama"
+	AssistantInspector ProjectMemberRole = "Asisten Pengawas"
+)
+
+// ProjectMember is an inspector working on a project, the lead is the inspector of the project.
+// Every member records the transactions on their own project balance.
+type ProjectMember struct {
+	ID        int64          `gorm:"primaryKey" json:"id"`
+	CreatedAt int64          `json:"createdAt"`
+	UpdatedAt int64          `json:"updatedAt"`
+	DeletedAt gorm.DeletedAt `gorm:"index" json:"-"`
+	CreatedBy *int64         `json:"createdBy"`
+	UpdatedBy *int64         `json:"updatedBy"`
+	DeletedBy *int64         `json:"deletedBy"`
+
+	ProjectID   int64             `gorm:"not null;uniqueIndex:idx_project_member,where:deleted_at IS NULL" json:"projectId"`
+	InspectorID int64             `gorm:"not null;uniqueIndex:idx_project_member,where:deleted_at IS NULL;index" json:"inspectorId"`
+	Role        ProjectMemberRole `gorm:"not null;type:varchar(255)" json:"role"`
+	Inspector   User              `gorm:"foreignKey:InspectorID" json:"inspector"`
+}
+
+type ProjectMemberParam struct {
+	ProjectID   int64 `uri:"project_id" param:"project_id"`
+	InspectorID int64 `uri:"user_id" param:"user_id"`
+}
+
+type AddProjectMemberBody struct {
+	InspectorID int64 `json:"inspectorId" validate:"required"`
+}
+
+type ProjectMemberResponse struct {
+	InspectorID    int64             `json:"inspectorId"`
+	InspectorName  string            `json:"inspectorName"`
+	Role           ProjectMemberRole `json:"role"`
+	CurrentBalance string            `json:"currentBalance"`
+}
@@ -0,0 +1,113 @@
+package model
+
+// Permission allows an action, the :own permissions are limited to the projects assigned to the user
+// while the :all permissions cover every project
+type Permission string
+
+const (
+	UserRead  Permission = "user:read"
+	UserWrite Permission = "user:write"
+	RoleWrite Permission = "role:write"
+
+	ProjectReadOwn     Permission = "project:read:own"
+	ProjectReadAll     Permission = "project:read:all"
+	ProjectWrite       Permission = "project:write"
+	ProjectBudgetWrite Permission = "project:budget:write"
+
+	LedgerReadOwn      Permission = "ledger:read:own"
+	LedgerReadAll      Permission = "ledger:read:all"
+	LedgerWrite        Permission = "ledger:write"
+	LedgerApprove      Permission = "ledger:approve"
+	LedgerIntegrity    Permission = "ledger:integrity"
+	TransferWrite      Permission = "transfer:write"
+	TransferConfirm    Permission = "transfer:confirm"
+	ReportRead         Permission = "report:read"
+	StorageMaintenance Permission = "storage:maintenance"
+	AuditRead          Permission = "audit:read"
+)
+
+// Permissions are every permission known
+var Permissions = []Permission{
+	UserRead,
+	UserWrite,
+	RoleWrite,
+	ProjectReadOwn,
+	ProjectReadAll,
+	ProjectWrite,
+	ProjectBudgetWrite,
+	LedgerReadOwn,
+	LedgerReadAll,
+	LedgerWrite,
+	LedgerApprove,
+	LedgerIntegrity,
+	TransferWrite,
+	TransferConfirm,
+	ReportRead,
+	StorageMaintenance,
+	AuditRead,
+}
+
+// AdminPermissions are the permissions of the director, they aren't stored so no change of the roles
+// can lock the directors out. The transactions are recorded by the inspectors, not by the director.
+var AdminPermissions = []Permission{
+	UserRead,
+	UserWrite,
+	RoleWrite,
+	ProjectReadAll,
+	ProjectWrite,
+	ProjectBudgetWrite,
+	LedgerReadAll,
+	LedgerApprove,
+	LedgerIntegrity,
+	TransferWrite,
+	ReportRead,
+	StorageMaintenance,
+	AuditRead,
+}
+
+// DefaultRolePermissions are seeded for the roles that have no permission yet
+var DefaultRolePermissions = map[Role][]Permission{
+	Inspector: {
+		ProjectReadOwn,
+		LedgerReadOwn,
+		LedgerWrite,
+		TransferConfirm,
+	},
+}
+
+func IsPermissionCorrect(permission Permission) bool {
+	for _, p := range Permissions {
+		if p == permission {
+			return true
+		}
+	}
+
+	return false
+}
+
+// RolePermission grants a permission to every user of the role, a role exists as long as it has a permission
+// and the director isn't stored
+type RolePermission struct {
+	ID        int64 `gorm:"primaryKey" json:"id"`
+	CreatedAt int64 `json:"createdAt"`
+
+	Role       Role       `gorm:"not null;type:varchar(255);uniqueIndex:idx_role_permission" json:"role"`
+	Permission Permission `gorm:"not null;type:varchar(255);uniqueIndex:idx_role_permission" json:"permission"`
+}
+
+type RoleParam struct {
+	Role string `uri:"role" param:"role"`
+}
+
+type UpdateRoleBody struct {
+	Permissions []Permission `json:"permissions" validate:"required,min=1"`
+}
+
+type UpdateUserRoleBody struct {
+	Role string `json:"role" validate:"required"`
+}
+
+type RoleResponse struct {
+	Role        Role         `json:"role"`
+	Permissions []Permission `json:"permissions"`
+}
@@ -0,0 +1,22 @@
+package model
+
+type StorageObjectParam struct {
+	Path      string `uri:"path" binding:"required"`
+	FileName  string `uri:"file_name" binding:"required"`
+	Expires   int64  `form:"expires"`
+	Signature string `form:"signature"`
+}
+
+type StorageGarbageParam struct {
+	DryRun bool `form:"dry_run"`
+}
+
+// StorageGarbageReport lists the object keys the garbage collection moved or deleted,
+// on a dry run the objects it would have moved or deleted
+type StorageGarbageReport struct {
+	DryRun         bool     `json:"dryRun"`
+	CheckedObjects int64    `json:"checkedObjects"`
+	Quarantined    []string `json:"quarantined"`
+	Restored       []string `json:"restored"`
+	Deleted        []string `json:"deleted"`
+}
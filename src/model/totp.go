@@ -0,0 +1,43 @@
+package model
+
+// LoginChallenge is the second step of the login of a user with an authenticator, it can only be used once
+type LoginChallenge struct {
+	ID        int64 `gorm:"primaryKey" json:"id"`
+	CreatedAt int64 `json:"createdAt"`
+
+	UserID    int64  `gorm:"not null;index" json:"userId"`
+	TokenHash string `gorm:"not null;unique;type:varchar(64)" json:"-"`
+	ExpiresAt int64  `gorm:"not null" json:"expiresAt"`
+	UsedAt    *int64 `json:"usedAt"`
+}
+
+// RecoveryCode signs in once instead of a code of the authenticator, only its hash is stored
+type RecoveryCode struct {
+	ID        int64 `gorm:"primaryKey" json:"id"`
+	CreatedAt int64 `json:"createdAt"`
+
+	UserID   int64  `gorm:"not null;index" json:"userId"`
+	CodeHash string `gorm:"not null;type:varchar(64);index" json:"-"`
+	UsedAt   *int64 `json:"usedAt"`
+}
+
+type TotpLoginBody struct {
+	ChallengeToken string `json:"challengeToken" validate:"required"`
+	// Code is the code of the authenticator or a recovery code
+	Code string `json:"code" validate:"required"`
+}
+
+type TotpCodeBody struct {
+	Code string `json:"code" validate:"required"`
+}
+
+// TotpEnrollmentResponse is scanned by the authenticator app, the secret is for typing it in by hand
+type TotpEnrollmentResponse struct {
+	Secret          string `json:"secret"`
+	ProvisioningURI string `json:"provisioningUri"`
+}
+
+// TotpRecoveryCodesResponse is shown once, the recovery codes can't be read again
+type TotpRecoveryCodesResponse struct {
+	RecoveryCodes []string `json:"recoveryCodes"`
+}
@@ -25,12 +25,44 @@ type User struct {
 	Password     string `gorm:"not null;type:text" json:"-"`
 	IsFirstLogin *bool  `gorm:"default:true" json:"isFirstLogin"`
 	Role         Role   `gorm:"type:varchar(255);default:Inspector;index" json:"role"`
+
+	PasswordChangedAt int64 `gorm:"not null;default:0" json:"passwordChangedAt"`
+	IsPasswordExpired bool  `gorm:"-" json:"isPasswordExpired"`
+
+	// TotpSecret is encrypted, it is set by the enrollment and only used once TotpEnabledAt is set
+	TotpSecret       string `gorm:"type:text;default:''" json:"-"`
+	TotpEnabledAt    *int64 `json:"totpEnabledAt"`
+	TotpLastUsedStep int64  `gorm:"not null;default:0" json:"-"`
+
+	// Permissions are the permissions of the role, they are read on every request
+	Permissions []Permission `gorm:"-" json:"permissions,omitempty"`
+}
+
+// MustChangePassword reports whether the user can only change the password until it is changed
+func (u User) MustChangePassword() bool {
+	return (u.IsFirstLogin != nil && *u.IsFirstLogin) || u.IsPasswordExpired
+}
+
+// MustEnrollTotp reports whether the user can only enroll an authenticator until it is enabled,
+// the two-factor authentication is mandatory for the directors
+func (u User) MustEnrollTotp() bool {
+	return u.Role == Admin && u.TotpEnabledAt == nil
+}
+
+// PasswordHistory keeps the hash of every password set, the latest ones can't be used again
+type PasswordHistory struct {
+	ID        int64 `gorm:"primaryKey" json:"id"`
+	CreatedAt int64 `json:"createdAt"`
+
+	UserID   int64  `gorm:"not null;index" json:"userId"`
+	Password string `gorm:"not null;type:text" json:"-"`
 }
 
 type UserParam struct {
-	ID       int64  `param:"id" uri:"user_id"`
-	Username string `param:"username"`
-	Role     string `param:"role"`
+	ID        int64  `param:"id" uri:"user_id"`
+	Username  string `param:"username"`
+	Role      string `param:"role"`
+	IsDeleted bool   `param:"is_deleted" form:"is_deleted"`
 	PaginationParam
 }
 
@@ -39,17 +71,29 @@ type UserLoginBody struct {
 	Password string `json:"password" validate:"required"`
 }
 
+// UserLoginResponse is returned by the login and the refresh, the token is the short-lived access token.
+// A user with an authenticator gets a challenge token instead of the tokens, it is exchanged with the code.
 type UserLoginResponse struct {
-	User  User   `json:"user"`
-	Token string `json:"token"`
+	User           User   `json:"user"`
+	Token          string `json:"token,omitempty"`
+	RefreshToken   string `json:"refreshToken,omitempty"`
+	IsTotpRequired bool   `json:"isTotpRequired"`
+	ChallengeToken string `json:"challengeToken,omitempty"`
 }
 
+// ResetPasswordBody changes the password, CurrentPassword is only optional for the users who must change it
+// and isn't read when a director resets the password of an inspector
 type ResetPasswordBody struct {
-	NewPassword string `json:"newPassword" validate:"required,min=8"`
+	CurrentPassword string `json:"currentPassword"`
+	NewPassword     string `json:"newPassword" validate:"required"`
 }
 
 type CreateInspectorBody struct {
 	Username string `json:"username" validate:"required,min=8"`
 	Name     string `json:"name" validate:"required"`
-	Password string `json:"password" validate:"required,min=8"`
+	Password string `json:"password" validate:"required"`
+}
+
+type UpdateInspectorBody struct {
+	Name string `json:"name" validate:"required"`
 }
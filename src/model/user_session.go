@@ -0,0 +1,24 @@
+package model
+
+// UserSession is a login of a user, its refresh token rotates on every refresh and only the token hashes are stored
+type UserSession struct {
+	ID        int64 `gorm:"primaryKey" json:"id"`
+	CreatedAt int64 `json:"createdAt"`
+	UpdatedAt int64 `json:"updatedAt"`
+
+	UserID           int64  `gorm:"not null;index" json:"userId"`
+	RefreshTokenHash string `gorm:"not null;unique;type:varchar(64)" json:"-"`
+	// PreviousTokenHash catches a rotated refresh token used again, the session is revoked when it is
+	PreviousTokenHash string `gorm:"type:varchar(64);index;default:''" json:"-"`
+	UserAgent         string `gorm:"type:text;default:''" json:"userAgent"`
+	ExpiresAt         int64  `gorm:"not null" json:"expiresAt"`
+	RevokedAt         *int64 `json:"revokedAt"`
+}
+
+func (s UserSession) IsActive(now int64) bool {
+	return s.RevokedAt == nil && s.ExpiresAt > now
+}
+
+type RefreshTokenBody struct {
+	RefreshToken string `json:"refreshToken" validate:"required"`
+}
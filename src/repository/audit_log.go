@@ -0,0 +1,53 @@
+package repository
+
+import (
+	"context"
+
+	"tigaputera-backend/src/model"
+
+	"gorm.io/gorm"
+)
+
+type auditLogRepository struct {
+	db *gorm.DB
+}
+
+func (r *auditLogRepository) List(ctx context.Context, filter AuditLogFilter) ([]model.AuditLog, error) {
+	logs := []model.AuditLog{}
+	query := r.filter(ctx, filter).Order("created_at desc, id desc")
+	if filter.Limit > 0 {
+		query = query.Limit(int(filter.Limit)).Offset(int(filter.Offset))
+	}
+
+	err := query.Find(&logs).Error
+
+	return logs, translateError(err)
+}
+
+func (r *auditLogRepository) Count(ctx context.Context, filter AuditLogFilter) (int64, error) {
+	var count int64
+	err := r.filter(ctx, filter).Count(&count).Error
+
+	return count, translateError(err)
+}
+
+func (r *auditLogRepository) filter(ctx context.Context, filter AuditLogFilter) *gorm.DB {
+	query := r.db.WithContext(ctx).Model(&model.AuditLog{})
+	if filter.Entity != "" {
+		query = query.Where("entity = ?", filter.Entity)
+	}
+	if filter.EntityID != 0 {
+		query = query.Where("entity_id = ?", filter.EntityID)
+	}
+	if filter.UserID != 0 {
+		query = query.Where("user_id = ?", filter.UserID)
+	}
+	if filter.CreatedFrom != 0 {
+		query = query.Where("created_at >= ?", filter.CreatedFrom)
+	}
+	if filter.CreatedTo != 0 {
+		query = query.Where("created_at <= ?", filter.CreatedTo)
+	}
+
+	return query
+}
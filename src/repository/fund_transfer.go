@@ -0,0 +1,95 @@
+package repository
+
+import (
+	"context"
+
+	"tigaputera-backend/src/model"
+
+	"gorm.io/gorm"
+)
+
+type fundTransferRepository struct {
+	db *gorm.DB
+}
+
+func (r *fundTransferRepository) Get(ctx context.Context, filter FundTransferFilter) (model.FundTransfer, error) {
+	var transfer model.FundTransfer
+	err := r.filter(ctx, filter).
+		Joins("Project").
+		Joins("Sender").
+		Joins("Inspector").
+		Take(&transfer).Error
+
+	return transfer, translateError(err)
+}
+
+func (r *fundTransferRepository) List(ctx context.Context, filter FundTransferFilter) ([]model.FundTransfer, error) {
+	transfers := []model.FundTransfer{}
+	query := r.filter(ctx, filter).
+		Joins("Project").
+		Joins("Sender").
+		Joins("Inspector").
+		Order("fund_transfers.created_at desc, fund_transfers.id desc")
+	if filter.Limit > 0 {
+		query = query.Limit(int(filter.Limit)).Offset(int(filter.Offset))
+	}
+
+	err := query.Find(&transfers).Error
+
+	return transfers, translateError(err)
+}
+
+func (r *fundTransferRepository) Count(ctx context.Context, filter FundTransferFilter) (int64, error) {
+	var count int64
+	err := r.filter(ctx, filter).Count(&count).Error
+
+	return count, translateError(err)
+}
+
+func (r *fundTransferRepository) filter(ctx context.Context, filter FundTransferFilter) *gorm.DB {
+	query := r.db.WithContext(ctx).Model(&model.FundTransfer{})
+	if filter.ID != 0 {
+		query = query.Where("fund_transfers.id = ?", filter.ID)
+	}
+	if filter.ProjectID != 0 {
+		query = query.Where("fund_transfers.project_id = ?", filter.ProjectID)
+	}
+	if filter.InspectorID != 0 {
+		query = query.Where("fund_transfers.inspector_id = ?", filter.InspectorID)
+	}
+	if filter.Status != "" {
+		query = query.Where("fund_transfers.status = ?", filter.Status)
+	}
+
+	return query
+}
+
+func (r *fundTransferRepository) Create(ctx context.Context, transfer *model.FundTransfer) error {
+	return translateError(r.db.WithContext(ctx).Create(transfer).Error)
+}
+
+func (r *fundTransferRepository) UpdateStatus(
+	ctx context.Context,
+	transfer model.FundTransfer,
+	from model.TransferStatus,
+) error {
+	transferUpdate := map[string]interface{}{
+		"status":     transfer.Status,
+		"updated_by": transfer.UpdatedBy,
+	}
+	if transfer.ReceiptKey != "" {
+		transferUpdate["receipt_url"] = transfer.ReceiptKey
+	}
+	if transfer.ConfirmedAt != nil {
+		transferUpdate["confirmed_at"] = transfer.ConfirmedAt
+	}
+
+	return updateResult(r.db.WithContext(ctx).
+		Model(&model.FundTransfer{}).
+		Where("id = ? AND project_id = ? AND status = ?", transfer.ID, transfer.ProjectID, from).
+		Updates(transferUpdate))
+}
+
+func (r *fundTransferRepository) ListObjectKeys(ctx context.Context) ([]string, error) {
+	return listObjectKeys(ctx, r.db, &model.FundTransfer{}, "receipt_url")
+}
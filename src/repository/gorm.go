@@ -0,0 +1,145 @@
+package repository
+
+import (
+	"context"
+	"strings"
+
+	"tigaputera-backend/src/database"
+
+	"gorm.io/gorm"
+)
+
+type repository struct {
+	db *gorm.DB
+}
+
+// Init returns the repositories stored in postgres
+func Init(db *database.DB) Interface {
+	return &repository{db: db.DB}
+}
+
+func (r *repository) Transaction(ctx context.Context, fn func(tx Interface) error) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		return fn(&repository{db: tx})
+	})
+}
+
+func (r *repository) User() UserRepository {
+	return &userRepository{db: r.db}
+}
+
+func (r *repository) Project() ProjectRepository {
+	return &projectRepository{db: r.db}
+}
+
+func (r *repository) ProjectExpenditure() ProjectExpenditureRepository {
+	return &projectExpenditureRepository{db: r.db}
+}
+
+func (r *repository) Ledger() LedgerRepository {
+	return &ledgerRepository{db: r.db}
+}
+
+func (r *repository) LedgerAttachment() LedgerAttachmentRepository {
+	return &ledgerAttachmentRepository{db: r.db}
+}
+
+func (r *repository) FundTransfer() FundTransferRepository {
+	return &fundTransferRepository{db: r.db}
+}
+
+func (r *repository) Statistics() StatisticsRepository {
+	return &statisticsRepository{db: r.db}
+}
+
+func (r *repository) IdempotencyKey() IdempotencyKeyRepository {
+	return &idempotencyKeyRepository{db: r.db}
+}
+
+func (r *repository) UserSession() UserSessionRepository {
+	return &userSessionRepository{db: r.db}
+}
+
+func (r *repository) PasswordHistory() PasswordHistoryRepository {
+	return &passwordHistoryRepository{db: r.db}
+}
+
+func (r *repository) LoginAttempt() LoginAttemptRepository {
+	return &loginAttemptRepository{db: r.db}
+}
+
+func (r *repository) LoginThrottle() LoginThrottleRepository {
+	return &loginThrottleRepository{db: r.db}
+}
+
+func (r *repository) LoginChallenge() LoginChallengeRepository {
+	return &loginChallengeRepository{db: r.db}
+}
+
+func (r *repository) RecoveryCode() RecoveryCodeRepository {
+	return &recoveryCodeRepository{db: r.db}
+}
+
+func (r *repository) RolePermission() RolePermissionRepository {
+	return &rolePermissionRepository{db: r.db}
+}
+
+func (r *repository) AuditLog() AuditLogRepository {
+	return &auditLogRepository{db: r.db}
+}
+
+func (r *repository) ProjectAssignment() ProjectAssignmentRepository {
+	return &projectAssignmentRepository{db: r.db}
+}
+
+func (r *repository) ProjectMember() ProjectMemberRepository {
+	return &projectMemberRepository{db: r.db}
+}
+
+// translateError turns the postgres errors the services handle into the repository errors
+func translateError(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	switch {
+	case strings.Contains(err.Error(), ErrNotFound.Error()):
+		return ErrNotFound
+	case strings.Contains(err.Error(), ErrDuplicate.Error()):
+		return ErrDuplicate
+	case strings.Contains(err.Error(), ErrLockTimeout.Error()):
+		return ErrLockTimeout
+	}
+
+	return err
+}
+
+// updateResult returns ErrNotFound when the conditional update didn't match any row
+func updateResult(res *gorm.DB) error {
+	if res.Error != nil {
+		return translateError(res.Error)
+	} else if res.RowsAffected == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
+
+// listObjectKeys plucks the non-empty storage keys of the columns from every row of the model, deleted or not
+func listObjectKeys(ctx context.Context, db *gorm.DB, model interface{}, columns ...string) ([]string, error) {
+	keys := []string{}
+	for _, column := range columns {
+		columnKeys := []string{}
+		if err := db.WithContext(ctx).
+			Unscoped().
+			Model(model).
+			Where(column+" <> ''").
+			Pluck(column, &columnKeys).Error; err != nil {
+			return nil, translateError(err)
+		}
+
+		keys = append(keys, columnKeys...)
+	}
+
+	return keys, nil
+}
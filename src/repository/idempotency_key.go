@@ -0,0 +1,66 @@
+package repository
+
+import (
+	"context"
+
+	"tigaputera-backend/src/model"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type idempotencyKeyRepository struct {
+	db *gorm.DB
+}
+
+func (r *idempotencyKeyRepository) Get(
+	ctx context.Context,
+	userID int64,
+	key string,
+) (model.IdempotencyKey, error) {
+	var idempotencyKey model.IdempotencyKey
+	err := r.db.WithContext(ctx).
+		Where("user_id = ? AND key = ?", userID, key).
+		Take(&idempotencyKey).Error
+
+	return idempotencyKey, translateError(err)
+}
+
+func (r *idempotencyKeyRepository) Create(ctx context.Context, idempotencyKey *model.IdempotencyKey) (bool, error) {
+	res := r.db.WithContext(ctx).
+		Clauses(clause.OnConflict{DoNothing: true}).
+		Create(idempotencyKey)
+	if res.Error != nil {
+		return false, translateError(res.Error)
+	}
+
+	return res.RowsAffected > 0, nil
+}
+
+func (r *idempotencyKeyRepository) Complete(ctx context.Context, idempotencyKey model.IdempotencyKey) error {
+	return translateError(r.db.WithContext(ctx).
+		Model(&model.IdempotencyKey{}).
+		Where("id = ?", idempotencyKey.ID).
+		Updates(map[string]interface{}{
+			"is_completed":  true,
+			"status_code":   idempotencyKey.StatusCode,
+			"response_body": idempotencyKey.ResponseBody,
+		}).Error)
+}
+
+func (r *idempotencyKeyRepository) Delete(ctx context.Context, id int64) error {
+	return translateError(r.db.WithContext(ctx).Delete(&model.IdempotencyKey{}, id).Error)
+}
+
+func (r *idempotencyKeyRepository) DeleteExpired(
+	ctx context.Context,
+	userID int64,
+	key string,
+	now int64,
+	staleAt int64,
+) error {
+	return translateError(r.db.WithContext(ctx).
+		Where("user_id = ? AND key = ?", userID, key).
+		Where("expires_at < ? OR (is_completed = false AND created_at < ?)", now, staleAt).
+		Delete(&model.IdempotencyKey{}).Error)
+}
@@ -0,0 +1,256 @@
+package repository
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"tigaputera-backend/src/model"
+
+	"gorm.io/gorm"
+)
+
+// Namespaces of the postgres advisory locks, so the inspector and project ids can't collide
+const (
+	inspectorLedgerLock = 1
+	projectLedgerLock   = 2
+)
+
+// how long a posting waits for another posting of the same inspector or project
+const ledgerLockTimeout = "10s"
+
+type ledgerRepository struct {
+	db *gorm.DB
+}
+
+// Lock takes transaction scoped advisory locks, they are released on commit or rollback
+func (r *ledgerRepository) Lock(ctx context.Context, projectID int64, inspectorIDs ...int64) error {
+	db := r.db.WithContext(ctx)
+	if err := db.Exec("SET LOCAL lock_timeout = '" + ledgerLockTimeout + "'").Error; err != nil {
+		return translateError(err)
+	}
+
+	// always lock the inspectors in ascending order before the project to avoid deadlocks
+	sortedInspectorIDs := append([]int64{}, inspectorIDs...)
+	sort.Slice(sortedInspectorIDs, func(i, j int) bool {
+		return sortedInspectorIDs[i] < sortedInspectorIDs[j]
+	})
+
+	keys := []int64{}
+	for _, inspectorID := range sortedInspectorIDs {
+		keys = append(keys, getLedgerLockKey(inspectorLedgerLock, inspectorID))
+	}
+	if projectID != 0 {
+		keys = append(keys, getLedgerLockKey(projectLedgerLock, projectID))
+	}
+
+	for _, key := range keys {
+		if err := db.Exec("SELECT pg_advisory_xact_lock(?)", key).Error; err != nil {
+			return translateError(err)
+		}
+	}
+
+	return nil
+}
+
+// getLedgerLockKey packs the namespace into the top byte of the bigint key of the lock,
+// the two int4 form of the lock can't hold the ids past 2^31
+func getLedgerLockKey(namespace int64, id int64) int64 {
+	return namespace<<56 | id&(1<<56-1)
+}
+
+func (r *ledgerRepository) LockAll(ctx context.Context) error {
+	return translateError(r.db.WithContext(ctx).
+		Exec("LOCK TABLE ledgers IN SHARE ROW EXCLUSIVE MODE").Error)
+}
+
+func (r *ledgerRepository) Get(ctx context.Context, filter LedgerFilter) (model.Ledger, error) {
+	var ledger model.Ledger
+	err := r.order(r.filter(ctx, filter), filter).
+		Joins("Inspector").
+		Joins("Project").
+		Take(&ledger).Error
+
+	return ledger, translateError(err)
+}
+
+func (r *ledgerRepository) List(ctx context.Context, filter LedgerFilter) ([]model.Ledger, error) {
+	ledgers := []model.Ledger{}
+	query := r.order(r.filter(ctx, filter), filter).
+		Joins("Inspector").
+		Joins("Project")
+	if filter.Limit > 0 {
+		query = query.Limit(int(filter.Limit)).Offset(int(filter.Offset))
+	}
+
+	err := query.Find(&ledgers).Error
+
+	return ledgers, translateError(err)
+}
+
+func (r *ledgerRepository) Count(ctx context.Context, filter LedgerFilter) (int64, error) {
+	var count int64
+	err := r.filter(ctx, filter).Count(&count).Error
+
+	return count, translateError(err)
+}
+
+func (r *ledgerRepository) SumTotalPrice(ctx context.Context, filter LedgerFilter) (int64, error) {
+	var total int64
+	err := r.filter(ctx, filter).
+		Select("COALESCE(SUM(ledgers.total_price), 0) AS total").
+		Scan(&total).Error
+
+	return total, translateError(err)
+}
+
+func (r *ledgerRepository) filter(ctx context.Context, filter LedgerFilter) *gorm.DB {
+	query := r.db.WithContext(ctx).Model(&model.Ledger{})
+	if filter.ID != 0 {
+		query = query.Where("ledgers.id = ?", filter.ID)
+	}
+	if len(filter.IDs) > 0 {
+		query = query.Where("ledgers.id IN ?", filter.IDs)
+	}
+	if filter.InspectorID != 0 {
+		query = query.Where("ledgers.inspector_id = ?", filter.InspectorID)
+	}
+	if len(filter.InspectorIDs) > 0 {
+		query = query.Where("ledgers.inspector_id IN ?", filter.InspectorIDs)
+	}
+	if filter.ProjectID != 0 {
+		query = query.Where("ledgers.project_id = ?", filter.ProjectID)
+	}
+	if filter.RefID != 0 {
+		query = query.Where("ledgers.ref_id = ?", filter.RefID)
+	}
+	if filter.LedgerType != "" {
+		query = query.Where("ledgers.ledger_type = ?", filter.LedgerType)
+	}
+	if filter.ExcludeType != "" {
+		query = query.Where("ledgers.ledger_type <> ?", filter.ExcludeType)
+	}
+	if filter.ExcludeInspectorRole != "" {
+		query = query.Where(
+			"ledgers.inspector_id NOT IN (SELECT id FROM users WHERE role = ?)",
+			filter.ExcludeInspectorRole,
+		)
+	}
+	if filter.Status != "" {
+		query = query.Where("ledgers.status = ?", filter.Status)
+	}
+	if filter.IsCanceled != nil {
+		query = query.Where("ledgers.is_canceled = ?", *filter.IsCanceled)
+	}
+	if filter.CreatedFrom != 0 {
+		query = query.Where("ledgers.created_at >= ?", filter.CreatedFrom)
+	}
+
+	return query
+}
+
+func (r *ledgerRepository) order(query *gorm.DB, filter LedgerFilter) *gorm.DB {
+	if filter.Ascending {
+		return query.Order("ledgers.posted_at, ledgers.id")
+	}
+
+	return query.Order("ledgers.posted_at desc, ledgers.id desc")
+}
+
+func (r *ledgerRepository) Create(ctx context.Context, ledger *model.Ledger) error {
+	if ledger.CreatedAt == 0 {
+		ledger.CreatedAt = time.Now().Unix()
+	}
+	if ledger.PostedAt == 0 {
+		ledger.PostedAt = ledger.CreatedAt
+	}
+
+	return translateError(r.db.WithContext(ctx).Create(ledger).Error)
+}
+
+func (r *ledgerRepository) UpdateReceipt(ctx context.Context, id int64, receipt model.LedgerReceipt) error {
+	return updateResult(r.db.WithContext(ctx).
+		Model(&model.Ledger{}).
+		Where("id = ?", id).
+		Updates(map[string]interface{}{
+			"receipt_url":   receipt.ReceiptKey,
+			"thumbnail_key": receipt.ThumbnailKey,
+			"captured_at":   receipt.CapturedAt,
+			"latitude":      receipt.Latitude,
+			"longitude":     receipt.Longitude,
+		}))
+}
+
+func (r *ledgerRepository) Cancel(
+	ctx context.Context,
+	id int64,
+	status model.LedgerStatus,
+	updatedBy int64,
+) error {
+	return updateResult(r.db.WithContext(ctx).
+		Model(&model.Ledger{}).
+		Where("id = ? AND status = ? AND is_canceled = ?", id, status, false).
+		Updates(map[string]interface{}{
+			"is_canceled": true,
+			"updated_by":  updatedBy,
+		}))
+}
+
+func (r *ledgerRepository) Approve(ctx context.Context, ledger model.Ledger) error {
+	return updateResult(r.db.WithContext(ctx).
+		Model(&model.Ledger{}).
+		Where("id = ? AND status = ? AND is_canceled = ?", ledger.ID, model.Pending, false).
+		Updates(map[string]interface{}{
+			"status":                    model.Posted,
+			"posted_at":                 ledger.PostedAt,
+			"current_inspector_balance": ledger.CurrentInspectorBalance,
+			"final_inspector_balance":   ledger.FinalInspectorBalance,
+			"current_project_balance":   ledger.CurrentProjectBalance,
+			"final_project_balance":     ledger.FinalProjectBalance,
+			"reviewed_by":               ledger.ReviewedBy,
+			"reviewed_at":               ledger.ReviewedAt,
+			"updated_by":                ledger.UpdatedBy,
+		}))
+}
+
+func (r *ledgerRepository) Reject(ctx context.Context, ledger model.Ledger) error {
+	return updateResult(r.db.WithContext(ctx).
+		Model(&model.Ledger{}).
+		Where(
+			"id = ? AND project_id = ? AND ref_id = ? AND status = ? AND is_canceled = ?",
+			ledger.ID,
+			ledger.ProjectID,
+			ledger.RefID,
+			model.Pending,
+			false,
+		).
+		Updates(map[string]interface{}{
+			"status":           model.Rejected,
+			"rejection_reason": ledger.RejectionReason,
+			"reviewed_by":      ledger.ReviewedBy,
+			"reviewed_at":      ledger.ReviewedAt,
+			"updated_by":       ledger.UpdatedBy,
+		}))
+}
+
+func (r *ledgerRepository) UpdateBalance(ctx context.Context, correction model.LedgerBalanceCorrection) error {
+	balanceUpdate := map[string]interface{}{
+		"current_inspector_balance": correction.RebuiltStart,
+		"final_inspector_balance":   correction.RebuiltFinal,
+	}
+	if correction.BalanceType == model.ProjectBalance {
+		balanceUpdate = map[string]interface{}{
+			"current_project_balance": correction.RebuiltStart,
+			"final_project_balance":   correction.RebuiltFinal,
+		}
+	}
+
+	return translateError(r.db.WithContext(ctx).
+		Model(&model.Ledger{}).
+		Where("id = ?", correction.LedgerID).
+		Updates(balanceUpdate).Error)
+}
+
+func (r *ledgerRepository) ListObjectKeys(ctx context.Context) ([]string, error) {
+	return listObjectKeys(ctx, r.db, &model.Ledger{}, "receipt_url", "thumbnail_key")
+}
@@ -0,0 +1,91 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"tigaputera-backend/src/model"
+
+	"gorm.io/gorm"
+)
+
+type ledgerAttachmentRepository struct {
+	db *gorm.DB
+}
+
+func (r *ledgerAttachmentRepository) List(
+	ctx context.Context,
+	ledgerIDs ...int64,
+) ([]model.LedgerAttachment, error) {
+	attachments := []model.LedgerAttachment{}
+	if len(ledgerIDs) == 0 {
+		return attachments, nil
+	}
+
+	err := r.db.WithContext(ctx).
+		Where("ledger_id IN ?", ledgerIDs).
+		Order("id").
+		Find(&attachments).Error
+
+	return attachments, translateError(err)
+}
+
+func (r *ledgerAttachmentRepository) ListOfActiveLedgers(
+	ctx context.Context,
+	filter LedgerAttachmentFilter,
+) ([]model.LedgerAttachment, error) {
+	query := r.db.WithContext(ctx).
+		Joins("JOIN ledgers ON ledgers.id = ledger_attachments.ledger_id AND ledgers.deleted_at IS NULL").
+		Where("ledgers.is_canceled = ? AND ledgers.status <> ?", false, model.Rejected)
+	if len(filter.IDs) > 0 {
+		query = query.Where("ledger_attachments.id IN ?", filter.IDs)
+	}
+	if filter.ExcludeLedgerID != 0 {
+		query = query.Where("ledger_attachments.ledger_id <> ?", filter.ExcludeLedgerID)
+	}
+	if filter.ContentHash != "" {
+		query = query.Where("ledger_attachments.content_hash = ?", filter.ContentHash)
+	}
+	if filter.SimilarTo != nil {
+		// the distance is computed by postgres (bit_count needs version 14), so the hashes of every receipt
+		// aren't loaded for each upload
+		query = query.Where(
+			"bit_count((ledger_attachments.perceptual_hash # ?)::bit(64)) <= ?",
+			*filter.SimilarTo,
+			filter.MaxDistance,
+		)
+	}
+	if filter.IsDuplicate {
+		query = query.Where("ledger_attachments.duplicate_of_id IS NOT NULL")
+	}
+	if filter.Limit > 0 {
+		query = query.Limit(int(filter.Limit))
+	}
+
+	attachments := []model.LedgerAttachment{}
+	err := query.Order("ledger_attachments.id").Find(&attachments).Error
+
+	return attachments, translateError(err)
+}
+
+func (r *ledgerAttachmentRepository) Create(ctx context.Context, attachments ...*model.LedgerAttachment) error {
+	if len(attachments) == 0 {
+		return nil
+	}
+
+	return translateError(r.db.WithContext(ctx).Create(attachments).Error)
+}
+
+func (r *ledgerAttachmentRepository) Delete(ctx context.Context, ledgerID int64, id int64, deletedBy int64) error {
+	return updateResult(r.db.WithContext(ctx).
+		Model(&model.LedgerAttachment{}).
+		Where("id = ? AND ledger_id = ?", id, ledgerID).
+		Updates(map[string]interface{}{
+			"deleted_at": time.Now(),
+			"deleted_by": deletedBy,
+		}))
+}
+
+func (r *ledgerAttachmentRepository) ListObjectKeys(ctx context.Context) ([]string, error) {
+	return listObjectKeys(ctx, r.db, &model.LedgerAttachment{}, "object_key", "thumbnail_key")
+}
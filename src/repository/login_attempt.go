@@ -0,0 +1,57 @@
+package repository
+
+import (
+	"context"
+
+	"tigaputera-backend/src/model"
+
+	"gorm.io/gorm"
+)
+
+type loginAttemptRepository struct {
+	db *gorm.DB
+}
+
+func (r *loginAttemptRepository) Create(ctx context.Context, attempt *model.LoginAttempt) error {
+	return translateError(r.db.WithContext(ctx).Create(attempt).Error)
+}
+
+type loginThrottleRepository struct {
+	db *gorm.DB
+}
+
+func (r *loginThrottleRepository) Get(ctx context.Context, key string) (model.LoginThrottle, error) {
+	var throttle model.LoginThrottle
+	err := r.db.WithContext(ctx).Where("key = ?", key).Take(&throttle).Error
+
+	return throttle, translateError(err)
+}
+
+func (r *loginThrottleRepository) Fail(
+	ctx context.Context,
+	key string,
+	now int64,
+	windowStart int64,
+) (model.LoginThrottle, error) {
+	// an upsert, so the concurrent failures of a key are all counted
+	var throttle model.LoginThrottle
+	err := r.db.WithContext(ctx).Raw(`
+		INSERT INTO login_throttles (created_at, updated_at, key, failed_count, last_failed_at)
+		VALUES (?, ?, ?, 1, ?)
+		ON CONFLICT (key) DO UPDATE SET
+			failed_count = CASE
+				WHEN login_throttles.last_failed_at < ? THEN 1
+				ELSE login_throttles.failed_count + 1
+			END,
+			last_failed_at = EXCLUDED.last_failed_at,
+			updated_at = EXCLUDED.updated_at
+		RETURNING *`,
+		now, now, key, now, windowStart,
+	).Scan(&throttle).Error
+
+	return throttle, translateError(err)
+}
+
+func (r *loginThrottleRepository) Reset(ctx context.Context, key string) error {
+	return translateError(r.db.WithContext(ctx).Where("key = ?", key).Delete(&model.LoginThrottle{}).Error)
+}
@@ -0,0 +1,65 @@
+package memory
+
+import (
+	"context"
+	"sort"
+
+	"tigaputera-backend/src/model"
+	"tigaputera-backend/src/repository"
+)
+
+// auditLogRepository only holds the logs created through it, the memory repositories have no callbacks
+// writing them
+type auditLogRepository struct {
+	*repositories
+}
+
+func (r *auditLogRepository) List(ctx context.Context, filter repository.AuditLogFilter) ([]model.AuditLog, error) {
+	defer r.lock()()
+
+	return paginate(r.filter(filter), filter.Limit, filter.Offset), nil
+}
+
+func (r *auditLogRepository) Count(ctx context.Context, filter repository.AuditLogFilter) (int64, error) {
+	defer r.lock()()
+
+	return int64(len(r.filter(filter))), nil
+}
+
+func (r *auditLogRepository) filter(filter repository.AuditLogFilter) []model.AuditLog {
+	logs := []model.AuditLog{}
+	for _, log := range r.db.data.auditLogs {
+		if (filter.Entity != "" && log.Entity != filter.Entity) ||
+			(filter.EntityID != 0 && log.EntityID != filter.EntityID) ||
+			(filter.UserID != 0 && (log.UserID == nil || *log.UserID != filter.UserID)) ||
+			(filter.CreatedFrom != 0 && log.CreatedAt < filter.CreatedFrom) ||
+			(filter.CreatedTo != 0 && log.CreatedAt > filter.CreatedTo) {
+			continue
+		}
+
+		logs = append(logs, log)
+	}
+
+	sort.Slice(logs, func(i, j int) bool {
+		if logs[i].CreatedAt != logs[j].CreatedAt {
+			return logs[i].CreatedAt > logs[j].CreatedAt
+		}
+
+		return logs[i].ID > logs[j].ID
+	})
+
+	return logs
+}
+
+// Create stands in for the gorm callbacks, it isn't part of the repository so the tests seed the logs with it
+func (r *auditLogRepository) Create(ctx context.Context, log *model.AuditLog) error {
+	defer r.lock()()
+
+	if log.CreatedAt == 0 {
+		log.CreatedAt = now()
+	}
+	log.ID = r.db.data.nextID("audit_logs")
+	r.db.data.auditLogs[log.ID] = *log
+
+	return nil
+}
@@ -0,0 +1,128 @@
+package memory
+
+import (
+	"context"
+	"sort"
+
+	"tigaputera-backend/src/model"
+	"tigaputera-backend/src/repository"
+)
+
+type fundTransferRepository struct {
+	*repositories
+}
+
+func (r *fundTransferRepository) Get(
+	ctx context.Context,
+	filter repository.FundTransferFilter,
+) (model.FundTransfer, error) {
+	defer r.lock()()
+
+	transfers := r.filter(filter)
+	if len(transfers) == 0 {
+		return model.FundTransfer{}, repository.ErrNotFound
+	}
+
+	return transfers[0], nil
+}
+
+func (r *fundTransferRepository) List(
+	ctx context.Context,
+	filter repository.FundTransferFilter,
+) ([]model.FundTransfer, error) {
+	defer r.lock()()
+
+	return paginate(r.filter(filter), filter.Limit, filter.Offset), nil
+}
+
+func (r *fundTransferRepository) Count(ctx context.Context, filter repository.FundTransferFilter) (int64, error) {
+	defer r.lock()()
+
+	return int64(len(r.filter(filter))), nil
+}
+
+func (r *fundTransferRepository) filter(filter repository.FundTransferFilter) []model.FundTransfer {
+	transfers := []model.FundTransfer{}
+	for _, transfer := range r.db.data.fundTransfers {
+		if transfer.DeletedAt.Valid ||
+			(filter.ID != 0 && transfer.ID != filter.ID) ||
+			(filter.ProjectID != 0 && transfer.ProjectID != filter.ProjectID) ||
+			(filter.InspectorID != 0 && transfer.InspectorID != filter.InspectorID) ||
+			(filter.Status != "" && transfer.Status != filter.Status) {
+			continue
+		}
+
+		transfer.Project = r.db.data.project(transfer.ProjectID)
+		transfer.Sender = r.db.data.user(transfer.SenderID)
+		transfer.Inspector = r.db.data.user(transfer.InspectorID)
+		transfers = append(transfers, transfer)
+	}
+
+	sort.Slice(transfers, func(i, j int) bool {
+		if transfers[i].CreatedAt != transfers[j].CreatedAt {
+			return transfers[i].CreatedAt > transfers[j].CreatedAt
+		}
+
+		return transfers[i].ID > transfers[j].ID
+	})
+
+	return transfers
+}
+
+func (r *fundTransferRepository) Create(ctx context.Context, transfer *model.FundTransfer) error {
+	defer r.lock()()
+
+	if transfer.CreatedAt == 0 {
+		transfer.CreatedAt = now()
+	}
+	transfer.UpdatedAt = transfer.CreatedAt
+	transfer.ID = r.db.data.nextID("fund_transfers")
+
+	stored := *transfer
+	stored.Project = model.Project{}
+	stored.Sender = model.User{}
+	stored.Inspector = model.User{}
+	r.db.data.fundTransfers[transfer.ID] = stored
+
+	return nil
+}
+
+func (r *fundTransferRepository) UpdateStatus(
+	ctx context.Context,
+	updated model.FundTransfer,
+	from model.TransferStatus,
+) error {
+	defer r.lock()()
+
+	transfer, ok := r.db.data.fundTransfers[updated.ID]
+	if !ok ||
+		transfer.DeletedAt.Valid ||
+		transfer.ProjectID != updated.ProjectID ||
+		transfer.Status != from {
+		return repository.ErrNotFound
+	}
+
+	transfer.Status = updated.Status
+	transfer.UpdatedBy = updated.UpdatedBy
+	if updated.ReceiptKey != "" {
+		transfer.ReceiptKey = updated.ReceiptKey
+	}
+	if updated.ConfirmedAt != nil {
+		transfer.ConfirmedAt = updated.ConfirmedAt
+	}
+	transfer.UpdatedAt = now()
+	r.db.data.fundTransfers[transfer.ID] = transfer
+
+	return nil
+}
+
+func (r *fundTransferRepository) ListObjectKeys(ctx context.Context) ([]string, error) {
+	defer r.lock()()
+
+	keys := []string{}
+	for _, transfer := range r.db.data.fundTransfers {
+		keys = appendObjectKeys(keys, transfer.ReceiptKey)
+	}
+
+	return keys, nil
+}
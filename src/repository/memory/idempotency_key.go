@@ -0,0 +1,97 @@
+package memory
+
+import (
+	"context"
+
+	"tigaputera-backend/src/model"
+	"tigaputera-backend/src/repository"
+)
+
+type idempotencyKeyRepository struct {
+	*repositories
+}
+
+func (r *idempotencyKeyRepository) get(userID int64, key string) (model.IdempotencyKey, bool) {
+	for _, idempotencyKey := range r.db.data.idempotencyKeys {
+		if idempotencyKey.UserID == userID && idempotencyKey.Key == key {
+			return idempotencyKey, true
+		}
+	}
+
+	return model.IdempotencyKey{}, false
+}
+
+func (r *idempotencyKeyRepository) Get(
+	ctx context.Context,
+	userID int64,
+	key string,
+) (model.IdempotencyKey, error) {
+	defer r.lock()()
+
+	idempotencyKey, ok := r.get(userID, key)
+	if !ok {
+		return idempotencyKey, repository.ErrNotFound
+	}
+
+	return idempotencyKey, nil
+}
+
+func (r *idempotencyKeyRepository) Create(ctx context.Context, idempotencyKey *model.IdempotencyKey) (bool, error) {
+	defer r.lock()()
+
+	if _, ok := r.get(idempotencyKey.UserID, idempotencyKey.Key); ok {
+		return false, nil
+	}
+
+	if idempotencyKey.CreatedAt == 0 {
+		idempotencyKey.CreatedAt = now()
+	}
+	idempotencyKey.UpdatedAt = idempotencyKey.CreatedAt
+	idempotencyKey.ID = r.db.data.nextID("idempotency_keys")
+	r.db.data.idempotencyKeys[idempotencyKey.ID] = *idempotencyKey
+
+	return true, nil
+}
+
+func (r *idempotencyKeyRepository) Complete(ctx context.Context, completed model.IdempotencyKey) error {
+	defer r.lock()()
+
+	idempotencyKey, ok := r.db.data.idempotencyKeys[completed.ID]
+	if !ok {
+		return nil
+	}
+
+	idempotencyKey.IsCompleted = true
+	idempotencyKey.StatusCode = completed.StatusCode
+	idempotencyKey.ResponseBody = append([]byte{}, completed.ResponseBody...)
+	idempotencyKey.UpdatedAt = now()
+	r.db.data.idempotencyKeys[idempotencyKey.ID] = idempotencyKey
+
+	return nil
+}
+
+func (r *idempotencyKeyRepository) Delete(ctx context.Context, id int64) error {
+	defer r.lock()()
+
+	delete(r.db.data.idempotencyKeys, id)
+
+	return nil
+}
+
+func (r *idempotencyKeyRepository) DeleteExpired(
+	ctx context.Context,
+	userID int64,
+	key string,
+	now int64,
+	staleAt int64,
+) error {
+	defer r.lock()()
+
+	idempotencyKey, ok := r.get(userID, key)
+	isStale := !idempotencyKey.IsCompleted && idempotencyKey.CreatedAt < staleAt
+	if ok && (idempotencyKey.ExpiresAt < now || isStale) {
+		delete(r.db.data.idempotencyKeys, idempotencyKey.ID)
+	}
+
+	return nil
+}
@@ -0,0 +1,261 @@
+package memory
+
+import (
+	"context"
+	"sort"
+
+	"tigaputera-backend/src/model"
+	"tigaputera-backend/src/repository"
+)
+
+type ledgerRepository struct {
+	*repositories
+}
+
+func (r *ledgerRepository) Lock(ctx context.Context, projectID int64, inspectorIDs ...int64) error {
+	return ctx.Err()
+}
+
+func (r *ledgerRepository) LockAll(ctx context.Context) error {
+	return ctx.Err()
+}
+
+func (r *ledgerRepository) Get(ctx context.Context, filter repository.LedgerFilter) (model.Ledger, error) {
+	defer r.lock()()
+
+	ledgers := r.filter(filter)
+	if len(ledgers) == 0 {
+		return model.Ledger{}, repository.ErrNotFound
+	}
+
+	return ledgers[0], nil
+}
+
+func (r *ledgerRepository) List(ctx context.Context, filter repository.LedgerFilter) ([]model.Ledger, error) {
+	defer r.lock()()
+
+	return paginate(r.filter(filter), filter.Limit, filter.Offset), nil
+}
+
+func (r *ledgerRepository) Count(ctx context.Context, filter repository.LedgerFilter) (int64, error) {
+	defer r.lock()()
+
+	return int64(len(r.filter(filter))), nil
+}
+
+func (r *ledgerRepository) SumTotalPrice(ctx context.Context, filter repository.LedgerFilter) (int64, error) {
+	defer r.lock()()
+
+	var total int64
+	for _, ledger := range r.filter(filter) {
+		total += ledger.TotalPrice
+	}
+
+	return total, nil
+}
+
+// filter returns the ledgers matching the filter with their inspector and project, ordered like postgres
+func (r *ledgerRepository) filter(filter repository.LedgerFilter) []model.Ledger {
+	ledgers := []model.Ledger{}
+	for _, ledger := range r.db.data.ledgers {
+		if ledger.DeletedAt.Valid ||
+			(filter.ID != 0 && ledger.ID != filter.ID) ||
+			(len(filter.IDs) > 0 && !containsID(filter.IDs, ledger.ID)) ||
+			(filter.InspectorID != 0 && ledger.InspectorID != filter.InspectorID) ||
+			(len(filter.InspectorIDs) > 0 && !containsID(filter.InspectorIDs, ledger.InspectorID)) ||
+			(filter.ProjectID != 0 && ledger.ProjectID != filter.ProjectID) ||
+			(filter.RefID != 0 && getInt64(ledger.RefID) != filter.RefID) ||
+			(filter.LedgerType != "" && ledger.LedgerType != filter.LedgerType) ||
+			(filter.ExcludeType != "" && ledger.LedgerType == filter.ExcludeType) ||
+			(filter.ExcludeInspectorRole != "" &&
+				r.db.data.user(ledger.InspectorID).Role == filter.ExcludeInspectorRole) ||
+			(filter.Status != "" && ledger.Status != filter.Status) ||
+			(filter.IsCanceled != nil && *ledger.IsCanceled != *filter.IsCanceled) ||
+			ledger.CreatedAt < filter.CreatedFrom {
+			continue
+		}
+
+		ledger.Inspector = r.db.data.user(ledger.InspectorID)
+		ledger.Project = r.db.data.project(ledger.ProjectID)
+		ledgers = append(ledgers, ledger)
+	}
+
+	sort.Slice(ledgers, func(i, j int) bool {
+		isBefore := ledgers[i].PostedAt < ledgers[j].PostedAt ||
+			(ledgers[i].PostedAt == ledgers[j].PostedAt && ledgers[i].ID < ledgers[j].ID)
+		if filter.Ascending {
+			return isBefore
+		}
+
+		return !isBefore
+	})
+
+	return ledgers
+}
+
+func (r *ledgerRepository) Create(ctx context.Context, ledger *model.Ledger) error {
+	defer r.lock()()
+
+	if ledger.RefID == nil {
+		ledger.RefID = new(int64)
+	}
+	if ledger.Ref == "" {
+		ledger.Ref = "Direktur"
+	}
+	if ledger.Description == nil {
+		ledger.Description = new(string)
+	}
+	if ledger.CurrentInspectorBalance == nil {
+		ledger.CurrentInspectorBalance = new(int64)
+	}
+	if ledger.FinalInspectorBalance == nil {
+		ledger.FinalInspectorBalance = new(int64)
+	}
+	if ledger.CurrentProjectBalance == nil {
+		ledger.CurrentProjectBalance = new(int64)
+	}
+	if ledger.FinalProjectBalance == nil {
+		ledger.FinalProjectBalance = new(int64)
+	}
+	if ledger.IsCanceled == nil {
+		ledger.IsCanceled = new(bool)
+	}
+	if ledger.Status == "" {
+		ledger.Status = model.Posted
+	}
+	if ledger.CreatedAt == 0 {
+		ledger.CreatedAt = now()
+	}
+	if ledger.PostedAt == 0 {
+		ledger.PostedAt = ledger.CreatedAt
+	}
+	ledger.UpdatedAt = ledger.CreatedAt
+	ledger.ID = r.db.data.nextID("ledgers")
+
+	// the attachments are created with the ledger like the gorm associations
+	for i := range ledger.Attachments {
+		ledger.Attachments[i].LedgerID = ledger.ID
+		r.db.data.createLedgerAttachment(&ledger.Attachments[i])
+	}
+
+	stored := *ledger
+	stored.Inspector = model.User{}
+	stored.Project = model.Project{}
+	stored.Attachments = nil
+	r.db.data.ledgers[ledger.ID] = stored
+
+	return nil
+}
+
+func (r *ledgerRepository) UpdateReceipt(ctx context.Context, id int64, receipt model.LedgerReceipt) error {
+	defer r.lock()()
+
+	return r.update(id, func(ledger *model.Ledger) bool {
+		ledger.LedgerReceipt = receipt
+		return true
+	})
+}
+
+// update applies fn to a ledger that isn't deleted, fn returns false when the ledger doesn't match
+func (r *ledgerRepository) update(id int64, fn func(ledger *model.Ledger) bool) error {
+	ledger, ok := r.db.data.ledgers[id]
+	if !ok || ledger.DeletedAt.Valid || !fn(&ledger) {
+		return repository.ErrNotFound
+	}
+
+	ledger.UpdatedAt = now()
+	r.db.data.ledgers[id] = ledger
+
+	return nil
+}
+
+func (r *ledgerRepository) Cancel(
+	ctx context.Context,
+	id int64,
+	status model.LedgerStatus,
+	updatedBy int64,
+) error {
+	defer r.lock()()
+
+	return r.update(id, func(ledger *model.Ledger) bool {
+		if ledger.Status != status || *ledger.IsCanceled {
+			return false
+		}
+
+		isCanceled := true
+		ledger.IsCanceled = &isCanceled
+		ledger.UpdatedBy = &updatedBy
+
+		return true
+	})
+}
+
+func (r *ledgerRepository) Approve(ctx context.Context, approved model.Ledger) error {
+	defer r.lock()()
+
+	return r.update(approved.ID, func(ledger *model.Ledger) bool {
+		if ledger.Status != model.Pending || *ledger.IsCanceled {
+			return false
+		}
+
+		ledger.Status = model.Posted
+		ledger.PostedAt = approved.PostedAt
+		ledger.CurrentInspectorBalance = approved.CurrentInspectorBalance
+		ledger.FinalInspectorBalance = approved.FinalInspectorBalance
+		ledger.CurrentProjectBalance = approved.CurrentProjectBalance
+		ledger.FinalProjectBalance = approved.FinalProjectBalance
+		ledger.ReviewedBy = approved.ReviewedBy
+		ledger.ReviewedAt = approved.ReviewedAt
+		ledger.UpdatedBy = approved.UpdatedBy
+
+		return true
+	})
+}
+
+func (r *ledgerRepository) Reject(ctx context.Context, rejected model.Ledger) error {
+	defer r.lock()()
+
+	return r.update(rejected.ID, func(ledger *model.Ledger) bool {
+		if ledger.ProjectID != rejected.ProjectID ||
+			getInt64(ledger.RefID) != getInt64(rejected.RefID) ||
+			ledger.Status != model.Pending ||
+			*ledger.IsCanceled {
+			return false
+		}
+
+		ledger.Status = model.Rejected
+		ledger.RejectionReason = rejected.RejectionReason
+		ledger.ReviewedBy = rejected.ReviewedBy
+		ledger.ReviewedAt = rejected.ReviewedAt
+		ledger.UpdatedBy = rejected.UpdatedBy
+
+		return true
+	})
+}
+
+func (r *ledgerRepository) UpdateBalance(ctx context.Context, correction model.LedgerBalanceCorrection) error {
+	defer r.lock()()
+
+	return ignoreNotFound(r.update(correction.LedgerID, func(ledger *model.Ledger) bool {
+		if correction.BalanceType == model.ProjectBalance {
+			ledger.CurrentProjectBalance = int64Ptr(correction.RebuiltStart)
+			ledger.FinalProjectBalance = int64Ptr(correction.RebuiltFinal)
+		} else {
+			ledger.CurrentInspectorBalance = int64Ptr(correction.RebuiltStart)
+			ledger.FinalInspectorBalance = int64Ptr(correction.RebuiltFinal)
+		}
+
+		return true
+	}))
+}
+
+func (r *ledgerRepository) ListObjectKeys(ctx context.Context) ([]string, error) {
+	defer r.lock()()
+
+	keys := []string{}
+	for _, ledger := range r.db.data.ledgers {
+		keys = appendObjectKeys(keys, ledger.ReceiptKey, ledger.ThumbnailKey)
+	}
+
+	return keys, nil
+}
@@ -0,0 +1,128 @@
+package memory
+
+import (
+	"context"
+	"math/bits"
+	"sort"
+	"time"
+
+	"tigaputera-backend/src/model"
+	"tigaputera-backend/src/repository"
+
+	"gorm.io/gorm"
+)
+
+type ledgerAttachmentRepository struct {
+	*repositories
+}
+
+func (r *ledgerAttachmentRepository) List(
+	ctx context.Context,
+	ledgerIDs ...int64,
+) ([]model.LedgerAttachment, error) {
+	defer r.lock()()
+
+	isListed := map[int64]bool{}
+	for _, ledgerID := range ledgerIDs {
+		isListed[ledgerID] = true
+	}
+
+	attachments := []model.LedgerAttachment{}
+	for _, attachment := range r.db.data.ledgerAttachments {
+		if !attachment.DeletedAt.Valid && isListed[attachment.LedgerID] {
+			attachments = append(attachments, attachment)
+		}
+	}
+
+	sort.Slice(attachments, func(i, j int) bool {
+		return attachments[i].ID < attachments[j].ID
+	})
+
+	return attachments, nil
+}
+
+func (r *ledgerAttachmentRepository) ListOfActiveLedgers(
+	ctx context.Context,
+	filter repository.LedgerAttachmentFilter,
+) ([]model.LedgerAttachment, error) {
+	defer r.lock()()
+
+	attachments := []model.LedgerAttachment{}
+	for _, attachment := range r.db.data.ledgerAttachments {
+		ledger, ok := r.db.data.ledgers[attachment.LedgerID]
+		if attachment.DeletedAt.Valid ||
+			!ok ||
+			ledger.DeletedAt.Valid ||
+			*ledger.IsCanceled ||
+			ledger.Status == model.Rejected ||
+			(len(filter.IDs) > 0 && !containsID(filter.IDs, attachment.ID)) ||
+			(filter.ExcludeLedgerID != 0 && attachment.LedgerID == filter.ExcludeLedgerID) ||
+			(filter.ContentHash != "" && attachment.ContentHash != filter.ContentHash) ||
+			(filter.SimilarTo != nil && !isSimilarImage(attachment, *filter.SimilarTo, filter.MaxDistance)) ||
+			(filter.IsDuplicate && attachment.DuplicateOfID == nil) {
+			continue
+		}
+
+		attachments = append(attachments, attachment)
+	}
+
+	sort.Slice(attachments, func(i, j int) bool {
+		return attachments[i].ID < attachments[j].ID
+	})
+
+	if filter.Limit > 0 && int64(len(attachments)) > filter.Limit {
+		attachments = attachments[:filter.Limit]
+	}
+
+	return attachments, nil
+}
+
+func isSimilarImage(attachment model.LedgerAttachment, perceptualHash int64, maxDistance int) bool {
+	return attachment.PerceptualHash != nil &&
+		bits.OnesCount64(uint64(*attachment.PerceptualHash^perceptualHash)) <= maxDistance
+}
+
+func (r *ledgerAttachmentRepository) Create(ctx context.Context, attachments ...*model.LedgerAttachment) error {
+	defer r.lock()()
+
+	for _, attachment := range attachments {
+		r.db.data.createLedgerAttachment(attachment)
+	}
+
+	return nil
+}
+
+func (r *ledgerAttachmentRepository) Delete(ctx context.Context, ledgerID int64, id int64, deletedBy int64) error {
+	defer r.lock()()
+
+	attachment, ok := r.db.data.ledgerAttachments[id]
+	if !ok || attachment.DeletedAt.Valid || attachment.LedgerID != ledgerID {
+		return repository.ErrNotFound
+	}
+
+	attachment.DeletedAt = gorm.DeletedAt{Time: time.Now(), Valid: true}
+	attachment.DeletedBy = &deletedBy
+	r.db.data.ledgerAttachments[id] = attachment
+
+	return nil
+}
+
+func (s *store) createLedgerAttachment(attachment *model.LedgerAttachment) {
+	if attachment.CreatedAt == 0 {
+		attachment.CreatedAt = now()
+	}
+	attachment.UpdatedAt = attachment.CreatedAt
+	attachment.ID = s.nextID("ledger_attachments")
+	s.ledgerAttachments[attachment.ID] = *attachment
+}
+
+func (r *ledgerAttachmentRepository) ListObjectKeys(ctx context.Context) ([]string, error) {
+	defer r.lock()()
+
+	keys := []string{}
+	for _, attachment := range r.db.data.ledgerAttachments {
+		keys = appendObjectKeys(keys, attachment.ObjectKey, attachment.ThumbnailKey)
+	}
+
+	return keys, nil
+}
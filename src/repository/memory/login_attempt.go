@@ -0,0 +1,73 @@
+package memory
+
+import (
+	"context"
+
+	"tigaputera-backend/src/model"
+	"tigaputera-backend/src/repository"
+)
+
+type loginAttemptRepository struct {
+	*repositories
+}
+
+func (r *loginAttemptRepository) Create(ctx context.Context, attempt *model.LoginAttempt) error {
+	defer r.lock()()
+
+	attempt.CreatedAt = now()
+	attempt.ID = r.db.data.nextID("login_attempts")
+	r.db.data.loginAttempts[attempt.ID] = *attempt
+
+	return nil
+}
+
+type loginThrottleRepository struct {
+	*repositories
+}
+
+func (r *loginThrottleRepository) Get(ctx context.Context, key string) (model.LoginThrottle, error) {
+	defer r.lock()()
+
+	throttle, ok := r.db.data.loginThrottles[key]
+	if !ok {
+		return throttle, repository.ErrNotFound
+	}
+
+	return throttle, nil
+}
+
+func (r *loginThrottleRepository) Fail(
+	ctx context.Context,
+	key string,
+	now int64,
+	windowStart int64,
+) (model.LoginThrottle, error) {
+	defer r.lock()()
+
+	throttle, ok := r.db.data.loginThrottles[key]
+	if !ok {
+		throttle = model.LoginThrottle{
+			ID:        r.db.data.nextID("login_throttles"),
+			CreatedAt: now,
+			Key:       key,
+		}
+	}
+
+	if throttle.LastFailedAt < windowStart {
+		throttle.FailedCount = 0
+	}
+	throttle.FailedCount++
+	throttle.LastFailedAt = now
+	throttle.UpdatedAt = now
+	r.db.data.loginThrottles[key] = throttle
+
+	return throttle, nil
+}
+
+func (r *loginThrottleRepository) Reset(ctx context.Context, key string) error {
+	defer r.lock()()
+
+	delete(r.db.data.loginThrottles, key)
+
+	return nil
+}
@@ -0,0 +1,360 @@
+// Package memory keeps the repositories in maps, so the services can be tested without postgres.
+// It mimics the column defaults and the conditional updates of the postgres repositories.
+package memory
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"tigaputera-backend/src/model"
+	"tigaputera-backend/src/repository"
+)
+
+type store struct {
+	users               map[int64]model.User
+	projects            map[int64]model.Project
+	projectExpenditures map[int64]model.ProjectExpenditure
+	ledgers             map[int64]model.Ledger
+	ledgerAttachments   map[int64]model.LedgerAttachment
+	fundTransfers       map[int64]model.FundTransfer
+	idempotencyKeys     map[int64]model.IdempotencyKey
+	userSessions        map[int64]model.UserSession
+	passwordHistories   map[int64]model.PasswordHistory
+	loginAttempts       map[int64]model.LoginAttempt
+	loginThrottles      map[string]model.LoginThrottle
+	loginChallenges     map[int64]model.LoginChallenge
+	recoveryCodes       map[int64]model.RecoveryCode
+	rolePermissions     map[int64]model.RolePermission
+	auditLogs           map[int64]model.AuditLog
+	projectAssignments  map[int64]model.ProjectAssignment
+	projectMembers      map[int64]model.ProjectMember
+	inspectorStats      []model.MqtInspectorStats
+	lastID              map[string]int64
+}
+
+// database serializes the transactions and the queries run outside of them,
+// so the ledger locks aren't needed
+type database struct {
+	mu   sync.Mutex
+	data *store
+}
+
+type repositories struct {
+	db   *database
+	inTx bool
+}
+
+// Init returns empty repositories kept in memory
+func Init() repository.Interface {
+	return &repositories{db: &database{data: newStore()}}
+}
+
+func newStore() *store {
+	return &store{
+		users:               map[int64]model.User{},
+		projects:            map[int64]model.Project{},
+		projectExpenditures: map[int64]model.ProjectExpenditure{},
+		ledgers:             map[int64]model.Ledger{},
+		ledgerAttachments:   map[int64]model.LedgerAttachment{},
+		fundTransfers:       map[int64]model.FundTransfer{},
+		idempotencyKeys:     map[int64]model.IdempotencyKey{},
+		userSessions:        map[int64]model.UserSession{},
+		passwordHistories:   map[int64]model.PasswordHistory{},
+		loginAttempts:       map[int64]model.LoginAttempt{},
+		loginThrottles:      map[string]model.LoginThrottle{},
+		loginChallenges:     map[int64]model.LoginChallenge{},
+		recoveryCodes:       map[int64]model.RecoveryCode{},
+		rolePermissions:     map[int64]model.RolePermission{},
+		auditLogs:           map[int64]model.AuditLog{},
+		projectAssignments:  map[int64]model.ProjectAssignment{},
+		projectMembers:      map[int64]model.ProjectMember{},
+		inspectorStats:      []model.MqtInspectorStats{},
+		lastID:              map[string]int64{},
+	}
+}
+
+// clone copies the rows, the pointers inside the rows are shared because they are never written through
+func (s *store) clone() *store {
+	c := newStore()
+	for id, user := range s.users {
+		c.users[id] = user
+	}
+	for id, project := range s.projects {
+		c.projects[id] = project
+	}
+	for id, projectExpenditure := range s.projectExpenditures {
+		c.projectExpenditures[id] = projectExpenditure
+	}
+	for id, ledger := range s.ledgers {
+		c.ledgers[id] = ledger
+	}
+	for id, attachment := range s.ledgerAttachments {
+		c.ledgerAttachments[id] = attachment
+	}
+	for id, transfer := range s.fundTransfers {
+		c.fundTransfers[id] = transfer
+	}
+	for id, idempotencyKey := range s.idempotencyKeys {
+		c.idempotencyKeys[id] = idempotencyKey
+	}
+	for id, session := range s.userSessions {
+		c.userSessions[id] = session
+	}
+	for id, history := range s.passwordHistories {
+		c.passwordHistories[id] = history
+	}
+	for id, attempt := range s.loginAttempts {
+		c.loginAttempts[id] = attempt
+	}
+	for key, throttle := range s.loginThrottles {
+		c.loginThrottles[key] = throttle
+	}
+	for id, challenge := range s.loginChallenges {
+		c.loginChallenges[id] = challenge
+	}
+	for id, code := range s.recoveryCodes {
+		c.recoveryCodes[id] = code
+	}
+	for id, permission := range s.rolePermissions {
+		c.rolePermissions[id] = permission
+	}
+	for id, log := range s.auditLogs {
+		c.auditLogs[id] = log
+	}
+	for id, assignment := range s.projectAssignments {
+		c.projectAssignments[id] = assignment
+	}
+	for id, member := range s.projectMembers {
+		c.projectMembers[id] = member
+	}
+	c.inspectorStats = append(c.inspectorStats, s.inspectorStats...)
+	for table, id := range s.lastID {
+		c.lastID[table] = id
+	}
+
+	return c
+}
+
+func (s *store) nextID(table string) int64 {
+	s.lastID[table]++
+	return s.lastID[table]
+}
+
+// user returns the user joined to another row, a deleted user is joined as an empty user
+func (s *store) user(id int64) model.User {
+	user, ok := s.users[id]
+	if !ok || user.DeletedAt.Valid {
+		return model.User{}
+	}
+
+	return user
+}
+
+func (s *store) project(id int64) model.Project {
+	project, ok := s.projects[id]
+	if !ok || project.DeletedAt.Valid {
+		return model.Project{}
+	}
+
+	project.Inspector = s.user(project.InspectorID)
+	project.Members = s.members(id)
+
+	return project
+}
+
+// members returns the current members of the project ordered by id
+func (s *store) members(projectID int64) []model.ProjectMember {
+	members := []model.ProjectMember{}
+	for _, member := range s.projectMembers {
+		if member.ProjectID == projectID && !member.DeletedAt.Valid {
+			members = append(members, member)
+		}
+	}
+
+	sort.Slice(members, func(i, j int) bool {
+		return members[i].ID < members[j].ID
+	})
+
+	return members
+}
+
+// isMember reports whether the inspector is a current member of the project
+func (s *store) isMember(projectID int64, inspectorID int64) bool {
+	for _, member := range s.members(projectID) {
+		if member.InspectorID == inspectorID {
+			return true
+		}
+	}
+
+	return false
+}
+
+// lock returns the unlock of the database, the queries of a transaction already hold the lock
+func (r *repositories) lock() func() {
+	if r.inTx {
+		return func() {}
+	}
+
+	r.db.mu.Lock()
+	return r.db.mu.Unlock
+}
+
+func (r *repositories) Transaction(ctx context.Context, fn func(tx repository.Interface) error) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	// a nested transaction rolls back to its savepoint only
+	if r.inTx {
+		savepoint := r.db.data.clone()
+		if err := fn(r); err != nil {
+			r.db.data = savepoint
+			return err
+		}
+
+		return nil
+	}
+
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	snapshot := r.db.data.clone()
+	if err := fn(&repositories{db: r.db, inTx: true}); err != nil {
+		r.db.data = snapshot
+		return err
+	}
+
+	return nil
+}
+
+func (r *repositories) User() repository.UserRepository {
+	return &userRepository{r}
+}
+
+func (r *repositories) Project() repository.ProjectRepository {
+	return &projectRepository{r}
+}
+
+func (r *repositories) ProjectExpenditure() repository.ProjectExpenditureRepository {
+	return &projectExpenditureRepository{r}
+}
+
+func (r *repositories) Ledger() repository.LedgerRepository {
+	return &ledgerRepository{r}
+}
+
+func (r *repositories) LedgerAttachment() repository.LedgerAttachmentRepository {
+	return &ledgerAttachmentRepository{r}
+}
+
+func (r *repositories) FundTransfer() repository.FundTransferRepository {
+	return &fundTransferRepository{r}
+}
+
+func (r *repositories) Statistics() repository.StatisticsRepository {
+	return &statisticsRepository{r}
+}
+
+func (r *repositories) IdempotencyKey() repository.IdempotencyKeyRepository {
+	return &idempotencyKeyRepository{r}
+}
+
+func (r *repositories) UserSession() repository.UserSessionRepository {
+	return &userSessionRepository{r}
+}
+
+func (r *repositories) PasswordHistory() repository.PasswordHistoryRepository {
+	return &passwordHistoryRepository{r}
+}
+
+func (r *repositories) LoginAttempt() repository.LoginAttemptRepository {
+	return &loginAttemptRepository{r}
+}
+
+func (r *repositories) LoginThrottle() repository.LoginThrottleRepository {
+	return &loginThrottleRepository{r}
+}
+
+func (r *repositories) LoginChallenge() repository.LoginChallengeRepository {
+	return &loginChallengeRepository{r}
+}
+
+func (r *repositories) RecoveryCode() repository.RecoveryCodeRepository {
+	return &recoveryCodeRepository{r}
+}
+
+func (r *repositories) RolePermission() repository.RolePermissionRepository {
+	return &rolePermissionRepository{r}
+}
+
+func (r *repositories) AuditLog() repository.AuditLogRepository {
+	return &auditLogRepository{r}
+}
+
+func (r *repositories) ProjectAssignment() repository.ProjectAssignmentRepository {
+	return &projectAssignmentRepository{r}
+}
+
+func (r *repositories) ProjectMember() repository.ProjectMemberRepository {
+	return &projectMemberRepository{r}
+}
+
+func now() int64 {
+	return time.Now().Unix()
+}
+
+func int64Ptr(value int64) *int64 {
+	return &value
+}
+
+func getInt64(value *int64) int64 {
+	if value == nil {
+		return 0
+	}
+
+	return *value
+}
+
+func paginate[T any](rows []T, limit int64, offset int64) []T {
+	if limit <= 0 {
+		return rows
+	}
+
+	if offset >= int64(len(rows)) {
+		return []T{}
+	}
+
+	end := offset + limit
+	if end > int64(len(rows)) {
+		end = int64(len(rows))
+	}
+
+	return rows[offset:end]
+}
+
+func containsFold(s string, substr string) bool {
+	return strings.Contains(strings.ToLower(s), strings.ToLower(substr))
+}
+
+func containsID(ids []int64, id int64) bool {
+	for _, value := range ids {
+		if value == id {
+			return true
+		}
+	}
+
+	return false
+}
+
+func appendObjectKeys(keys []string, objectKeys ...string) []string {
+	for _, key := range objectKeys {
+		if key != "" {
+			keys = append(keys, key)
+		}
+	}
+
+	return keys
+}
@@ -0,0 +1,47 @@
+package memory
+
+import (
+	"context"
+	"sort"
+
+	"tigaputera-backend/src/model"
+)
+
+type passwordHistoryRepository struct {
+	*repositories
+}
+
+func (r *passwordHistoryRepository) List(
+	ctx context.Context,
+	userID int64,
+	limit int64,
+) ([]model.PasswordHistory, error) {
+	defer r.lock()()
+
+	histories := []model.PasswordHistory{}
+	for _, history := range r.db.data.passwordHistories {
+		if history.UserID == userID {
+			histories = append(histories, history)
+		}
+	}
+
+	sort.Slice(histories, func(i, j int) bool {
+		return histories[i].ID > histories[j].ID
+	})
+
+	if int64(len(histories)) > limit {
+		histories = histories[:limit]
+	}
+
+	return histories, nil
+}
+
+func (r *passwordHistoryRepository) Create(ctx context.Context, history *model.PasswordHistory) error {
+	defer r.lock()()
+
+	history.CreatedAt = now()
+	history.ID = r.db.data.nextID("password_histories")
+	r.db.data.passwordHistories[history.ID] = *history
+
+	return nil
+}
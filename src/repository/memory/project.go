@@ -0,0 +1,224 @@
+package memory
+
+import (
+	"context"
+	"sort"
+
+	"tigaputera-backend/src/model"
+	"tigaputera-backend/src/repository"
+)
+
+type projectRepository struct {
+	*repositories
+}
+
+func (r *projectRepository) Get(ctx context.Context, id int64) (model.Project, error) {
+	defer r.lock()()
+
+	project := r.db.data.project(id)
+	if project.ID == 0 {
+		return project, repository.ErrNotFound
+	}
+
+	return project, nil
+}
+
+func (r *projectRepository) List(ctx context.Context, filter repository.ProjectFilter) ([]model.Project, error) {
+	defer r.lock()()
+
+	projects := r.filter(filter)
+
+	return paginate(projects, filter.Limit, filter.Offset), nil
+}
+
+func (r *projectRepository) Count(ctx context.Context, filter repository.ProjectFilter) (int64, error) {
+	defer r.lock()()
+
+	return int64(len(r.filter(filter))), nil
+}
+
+func (r *projectRepository) filter(filter repository.ProjectFilter) []model.Project {
+	projects := []model.Project{}
+	for id := range r.db.data.projects {
+		project := r.db.data.project(id)
+		if project.ID == 0 ||
+			(filter.Keyword != "" && !containsFold(project.Name, filter.Keyword)) ||
+			(filter.InspectorID != 0 && !r.db.data.isMember(project.ID, filter.InspectorID)) ||
+			(filter.Status != "" && project.Status != string(filter.Status)) {
+			continue
+		}
+
+		projects = append(projects, project)
+	}
+
+	sort.Slice(projects, func(i, j int) bool {
+		if projects[i].UpdatedAt != projects[j].UpdatedAt {
+			return projects[i].UpdatedAt > projects[j].UpdatedAt
+		}
+
+		return projects[i].ID > projects[j].ID
+	})
+
+	return projects
+}
+
+func (r *projectRepository) Create(ctx context.Context, project *model.Project) error {
+	defer r.lock()()
+
+	for _, p := range r.db.data.projects {
+		if p.Name == project.Name {
+			return repository.ErrDuplicate
+		}
+	}
+
+	if project.Budget == nil {
+		project.Budget = new(int64)
+	}
+	if project.Income == nil {
+		project.Income = new(int64)
+	}
+	if project.PPN == 0 {
+		project.PPN = 0.11
+	}
+	if project.PPH == 0 {
+		project.PPH = 0.015
+	}
+	if project.CreatedAt == 0 {
+		project.CreatedAt = now()
+	}
+	project.UpdatedAt = project.CreatedAt
+	project.ID = r.db.data.nextID("projects")
+
+	stored := *project
+	stored.Inspector = model.User{}
+	r.db.data.projects[project.ID] = stored
+
+	return nil
+}
+
+// update applies fn to a project that isn't deleted
+func (r *projectRepository) update(id int64, fn func(project *model.Project)) error {
+	project, ok := r.db.data.projects[id]
+	if !ok || project.DeletedAt.Valid {
+		return repository.ErrNotFound
+	}
+
+	fn(&project)
+	project.UpdatedAt = now()
+	r.db.data.projects[id] = project
+
+	return nil
+}
+
+func (r *projectRepository) Update(ctx context.Context, project model.Project, updatedBy int64) error {
+	defer r.lock()()
+
+	for _, p := range r.db.data.projects {
+		if p.ID != project.ID && p.Name == project.Name {
+			return repository.ErrDuplicate
+		}
+	}
+
+	return r.update(project.ID, func(stored *model.Project) {
+		stored.Name = project.Name
+		stored.Description = project.Description
+		stored.Type = project.Type
+		stored.DeptName = project.DeptName
+		stored.CompanyName = project.CompanyName
+		stored.StartDate = project.StartDate
+		stored.FinalDate = project.FinalDate
+		stored.Volume = project.Volume
+		stored.Length = project.Length
+		stored.Width = project.Width
+		stored.UpdatedBy = &updatedBy
+	})
+}
+
+func (r *projectRepository) UpdateBudget(
+	ctx context.Context,
+	id int64,
+	budget int64,
+	ppn float64,
+	pph float64,
+) error {
+	defer r.lock()()
+
+	return r.update(id, func(project *model.Project) {
+		project.Budget = &budget
+		project.PPN = ppn
+		project.PPH = pph
+	})
+}
+
+func (r *projectRepository) UpdateStatus(ctx context.Context, id int64, status string) error {
+	defer r.lock()()
+
+	return r.update(id, func(project *model.Project) {
+		project.Status = status
+	})
+}
+
+func (r *projectRepository) UpdateApprovalThreshold(
+	ctx context.Context,
+	id int64,
+	threshold *int64,
+	updatedBy int64,
+) error {
+	defer r.lock()()
+
+	return r.update(id, func(project *model.Project) {
+		project.ApprovalThreshold = threshold
+		project.UpdatedBy = &updatedBy
+	})
+}
+
+func (r *projectRepository) AddIncome(ctx context.Context, id int64, amount int64, updatedBy int64) error {
+	defer r.lock()()
+
+	return ignoreNotFound(r.update(id, func(project *model.Project) {
+		project.Income = int64Ptr(getInt64(project.Income) + amount)
+		project.UpdatedBy = &updatedBy
+	}))
+}
+
+func (r *projectRepository) SetIncome(ctx context.Context, id int64, income int64) error {
+	defer r.lock()()
+
+	return ignoreNotFound(r.update(id, func(project *model.Project) {
+		project.Income = &income
+	}))
+}
+
+func (r *projectRepository) SetUpdatedBy(ctx context.Context, id int64, updatedBy int64) error {
+	defer r.lock()()
+
+	return ignoreNotFound(r.update(id, func(project *model.Project) {
+		project.UpdatedBy = &updatedBy
+	}))
+}
+
+func (r *projectRepository) UpdateInspector(
+	ctx context.Context,
+	id int64,
+	inspectorID int64,
+	previousInspectorID int64,
+) error {
+	defer r.lock()()
+
+	if project, ok := r.db.data.projects[id]; ok && project.InspectorID != previousInspectorID {
+		return repository.ErrNotFound
+	}
+
+	return r.update(id, func(project *model.Project) {
+		project.InspectorID = inspectorID
+	})
+}
+
+// ignoreNotFound matches the unconditional updates of postgres that don't fail without a row
+func ignoreNotFound(err error) error {
+	if repository.IsNotFound(err) {
+		return nil
+	}
+
+	return err
+}
@@ -0,0 +1,47 @@
+package memory
+
+import (
+	"context"
+	"sort"
+
+	"tigaputera-backend/src/model"
+)
+
+type projectAssignmentRepository struct {
+	*repositories
+}
+
+func (r *projectAssignmentRepository) List(ctx context.Context, projectID int64) ([]model.ProjectAssignment, error) {
+	defer r.lock()()
+
+	assignments := []model.ProjectAssignment{}
+	for _, assignment := range r.db.data.projectAssignments {
+		if assignment.ProjectID != projectID {
+			continue
+		}
+
+		assignment.Inspector = r.db.data.user(assignment.InspectorID)
+		if assignment.PreviousInspectorID != nil {
+			assignment.PreviousInspector = r.db.data.user(*assignment.PreviousInspectorID)
+		}
+		assignments = append(assignments, assignment)
+	}
+
+	sort.Slice(assignments, func(i, j int) bool {
+		return assignments[i].ID < assignments[j].ID
+	})
+
+	return assignments, nil
+}
+
+func (r *projectAssignmentRepository) Create(ctx context.Context, assignment *model.ProjectAssignment) error {
+	defer r.lock()()
+
+	if assignment.CreatedAt == 0 {
+		assignment.CreatedAt = now()
+	}
+	assignment.ID = r.db.data.nextID("project_assignments")
+	r.db.data.projectAssignments[assignment.ID] = *assignment
+
+	return nil
+}
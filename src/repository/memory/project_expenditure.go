@@ -0,0 +1,207 @@
+package memory
+
+import (
+	"context"
+	"sort"
+
+	"tigaputera-backend/src/model"
+	"tigaputera-backend/src/repository"
+)
+
+type projectExpenditureRepository struct {
+	*repositories
+}
+
+// get returns the expenditure with its project, both must not be deleted
+func (r *projectExpenditureRepository) get(id int64) (model.ProjectExpenditure, bool) {
+	projectExpenditure, ok := r.db.data.projectExpenditures[id]
+	if !ok || projectExpenditure.DeletedAt.Valid {
+		return model.ProjectExpenditure{}, false
+	}
+
+	projectExpenditure.Project = r.db.data.project(projectExpenditure.ProjectID)
+	if projectExpenditure.Project.ID == 0 {
+		return model.ProjectExpenditure{}, false
+	}
+
+	return projectExpenditure, true
+}
+
+func (r *projectExpenditureRepository) Get(
+	ctx context.Context,
+	projectID int64,
+	id int64,
+) (model.ProjectExpenditure, error) {
+	defer r.lock()()
+
+	projectExpenditure, ok := r.get(id)
+	if !ok || (projectID != 0 && projectExpenditure.ProjectID != projectID) {
+		return model.ProjectExpenditure{}, repository.ErrNotFound
+	}
+
+	return projectExpenditure, nil
+}
+
+func (r *projectExpenditureRepository) GetOfInspector(
+	ctx context.Context,
+	inspectorID int64,
+	id int64,
+) (model.ProjectExpenditure, error) {
+	defer r.lock()()
+
+	projectExpenditure, ok := r.get(id)
+	if !ok || !r.db.data.isMember(projectExpenditure.ProjectID, inspectorID) {
+		return model.ProjectExpenditure{}, repository.ErrNotFound
+	}
+
+	return projectExpenditure, nil
+}
+
+func (r *projectExpenditureRepository) GetLastSequence(
+	ctx context.Context,
+	projectID int64,
+) (model.ProjectExpenditure, error) {
+	defer r.lock()()
+
+	projectExpenditures := r.list(projectID)
+	if len(projectExpenditures) == 0 {
+		return model.ProjectExpenditure{}, repository.ErrNotFound
+	}
+
+	return projectExpenditures[len(projectExpenditures)-1], nil
+}
+
+func (r *projectExpenditureRepository) List(
+	ctx context.Context,
+	projectID int64,
+) ([]model.ProjectExpenditure, error) {
+	defer r.lock()()
+
+	return r.list(projectID), nil
+}
+
+func (r *projectExpenditureRepository) list(projectID int64) []model.ProjectExpenditure {
+	projectExpenditures := []model.ProjectExpenditure{}
+	for _, projectExpenditure := range r.db.data.projectExpenditures {
+		if projectExpenditure.DeletedAt.Valid ||
+			(projectID != 0 && projectExpenditure.ProjectID != projectID) {
+			continue
+		}
+
+		projectExpenditures = append(projectExpenditures, projectExpenditure)
+	}
+
+	sort.Slice(projectExpenditures, func(i, j int) bool {
+		if projectID != 0 {
+			return projectExpenditures[i].Sequence < projectExpenditures[j].Sequence
+		}
+
+		return projectExpenditures[i].ID < projectExpenditures[j].ID
+	})
+
+	return projectExpenditures
+}
+
+func (r *projectExpenditureRepository) Create(
+	ctx context.Context,
+	expenditures ...*model.ProjectExpenditure,
+) error {
+	defer r.lock()()
+
+	// the batch is inserted at once, a duplicate sequence inserts nothing
+	for i, expenditure := range expenditures {
+		for _, e := range r.db.data.projectExpenditures {
+			if e.ProjectID == expenditure.ProjectID && e.Sequence == expenditure.Sequence {
+				return repository.ErrDuplicate
+			}
+		}
+		for _, e := range expenditures[:i] {
+			if e.ProjectID == expenditure.ProjectID && e.Sequence == expenditure.Sequence {
+				return repository.ErrDuplicate
+			}
+		}
+	}
+
+	for _, expenditure := range expenditures {
+		if expenditure.TotalPrice == nil {
+			expenditure.TotalPrice = new(int64)
+		}
+		if expenditure.IsFixedCost == nil {
+			isFixedCost := true
+			expenditure.IsFixedCost = &isFixedCost
+		}
+		if expenditure.CreatedAt == 0 {
+			expenditure.CreatedAt = now()
+		}
+		expenditure.UpdatedAt = expenditure.CreatedAt
+		expenditure.ID = r.db.data.nextID("project_expenditures")
+
+		stored := *expenditure
+		stored.Project = model.Project{}
+		r.db.data.projectExpenditures[expenditure.ID] = stored
+	}
+
+	return nil
+}
+
+func (r *projectExpenditureRepository) update(
+	id int64,
+	fn func(projectExpenditure *model.ProjectExpenditure) bool,
+) error {
+	projectExpenditure, ok := r.db.data.projectExpenditures[id]
+	if !ok || projectExpenditure.DeletedAt.Valid || !fn(&projectExpenditure) {
+		return repository.ErrNotFound
+	}
+
+	projectExpenditure.UpdatedAt = now()
+	r.db.data.projectExpenditures[id] = projectExpenditure
+
+	return nil
+}
+
+func (r *projectExpenditureRepository) UpdateApprovalThreshold(
+	ctx context.Context,
+	projectID int64,
+	id int64,
+	threshold *int64,
+	updatedBy int64,
+) error {
+	defer r.lock()()
+
+	return r.update(id, func(projectExpenditure *model.ProjectExpenditure) bool {
+		if projectExpenditure.ProjectID != projectID {
+			return false
+		}
+
+		projectExpenditure.ApprovalThreshold = threshold
+		projectExpenditure.UpdatedBy = &updatedBy
+
+		return true
+	})
+}
+
+func (r *projectExpenditureRepository) AddTotalPrice(
+	ctx context.Context,
+	id int64,
+	amount int64,
+	updatedBy int64,
+) error {
+	defer r.lock()()
+
+	return ignoreNotFound(r.update(id, func(projectExpenditure *model.ProjectExpenditure) bool {
+		projectExpenditure.TotalPrice = int64Ptr(getInt64(projectExpenditure.TotalPrice) + amount)
+		projectExpenditure.UpdatedBy = &updatedBy
+
+		return true
+	}))
+}
+
+func (r *projectExpenditureRepository) SetTotalPrice(ctx context.Context, id int64, totalPrice int64) error {
+	defer r.lock()()
+
+	return ignoreNotFound(r.update(id, func(projectExpenditure *model.ProjectExpenditure) bool {
+		projectExpenditure.TotalPrice = &totalPrice
+
+		return true
+	}))
+}
@@ -0,0 +1,124 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"tigaputera-backend/src/model"
+	"tigaputera-backend/src/repository"
+
+	"gorm.io/gorm"
+)
+
+type projectMemberRepository struct {
+	*repositories
+}
+
+func (r *projectMemberRepository) Get(
+	ctx context.Context,
+	projectID int64,
+	inspectorID int64,
+) (model.ProjectMember, error) {
+	defer r.lock()()
+
+	id, ok := r.find(projectID, inspectorID)
+	if !ok {
+		return model.ProjectMember{}, repository.ErrNotFound
+	}
+
+	member := r.db.data.projectMembers[id]
+	member.Inspector = r.db.data.user(member.InspectorID)
+
+	return member, nil
+}
+
+func (r *projectMemberRepository) List(
+	ctx context.Context,
+	filter repository.ProjectMemberFilter,
+) ([]model.ProjectMember, error) {
+	defer r.lock()()
+
+	members := []model.ProjectMember{}
+	for _, member := range r.db.data.projectMembers {
+		if member.ProjectID != filter.ProjectID || (member.DeletedAt.Valid && !filter.WithRemoved) {
+			continue
+		}
+
+		member.Inspector = r.db.data.users[member.InspectorID]
+		if !filter.WithRemoved {
+			member.Inspector = r.db.data.user(member.InspectorID)
+		}
+		members = append(members, member)
+	}
+
+	sort.Slice(members, func(i, j int) bool {
+		return members[i].ID < members[j].ID
+	})
+
+	return members, nil
+}
+
+func (r *projectMemberRepository) Create(ctx context.Context, member *model.ProjectMember) error {
+	defer r.lock()()
+
+	if _, ok := r.find(member.ProjectID, member.InspectorID); ok {
+		return repository.ErrDuplicate
+	}
+
+	if member.CreatedAt == 0 {
+		member.CreatedAt = now()
+	}
+	member.UpdatedAt = member.CreatedAt
+	member.ID = r.db.data.nextID("project_members")
+	r.db.data.projectMembers[member.ID] = *member
+
+	return nil
+}
+
+func (r *projectMemberRepository) UpdateRole(
+	ctx context.Context,
+	projectID int64,
+	inspectorID int64,
+	role model.ProjectMemberRole,
+) error {
+	defer r.lock()()
+
+	id, ok := r.find(projectID, inspectorID)
+	if !ok {
+		return repository.ErrNotFound
+	}
+
+	member := r.db.data.projectMembers[id]
+	member.Role = role
+	member.UpdatedAt = now()
+	r.db.data.projectMembers[id] = member
+
+	return nil
+}
+
+func (r *projectMemberRepository) Delete(ctx context.Context, projectID int64, inspectorID int64) error {
+	defer r.lock()()
+
+	id, ok := r.find(projectID, inspectorID)
+	if !ok {
+		return repository.ErrNotFound
+	}
+
+	member := r.db.data.projectMembers[id]
+	member.DeletedAt = gorm.DeletedAt{Time: time.Now(), Valid: true}
+	r.db.data.projectMembers[id] = member
+
+	return nil
+}
+
+// find returns the id of the current member, like the unique index of the active members
+func (r *projectMemberRepository) find(projectID int64, inspectorID int64) (int64, bool) {
+	for id, member := range r.db.data.projectMembers {
+		if member.ProjectID == projectID && member.InspectorID == inspectorID && !member.DeletedAt.Valid {
+			return id, true
+		}
+	}
+
+	return 0, false
+}
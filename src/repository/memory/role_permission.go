@@ -0,0 +1,55 @@
+package memory
+
+import (
+	"context"
+	"sort"
+
+	"tigaputera-backend/src/model"
+)
+
+type rolePermissionRepository struct {
+	*repositories
+}
+
+func (r *rolePermissionRepository) List(ctx context.Context, role model.Role) ([]model.RolePermission, error) {
+	defer r.lock()()
+
+	permissions := []model.RolePermission{}
+	for _, permission := range r.db.data.rolePermissions {
+		if role == "" || permission.Role == role {
+			permissions = append(permissions, permission)
+		}
+	}
+
+	sort.Slice(permissions, func(i, j int) bool {
+		if permissions[i].Role != permissions[j].Role {
+			return permissions[i].Role < permissions[j].Role
+		}
+		return permissions[i].ID < permissions[j].ID
+	})
+
+	return permissions, nil
+}
+
+func (r *rolePermissionRepository) Replace(
+	ctx context.Context,
+	role model.Role,
+	permissions []model.RolePermission,
+) error {
+	defer r.lock()()
+
+	for id, permission := range r.db.data.rolePermissions {
+		if permission.Role == role {
+			delete(r.db.data.rolePermissions, id)
+		}
+	}
+
+	for i := range permissions {
+		permissions[i].Role = role
+		permissions[i].CreatedAt = now()
+		permissions[i].ID = r.db.data.nextID("role_permissions")
+		r.db.data.rolePermissions[permissions[i].ID] = permissions[i]
+	}
+
+	return nil
+}
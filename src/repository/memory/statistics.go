@@ -0,0 +1,77 @@
+package memory
+
+import (
+	"context"
+
+	"tigaputera-backend/src/model"
+	"tigaputera-backend/src/repository"
+)
+
+type statisticsRepository struct {
+	*repositories
+}
+
+func (r *statisticsRepository) CountProject(ctx context.Context, filter repository.StatisticsFilter) (int64, error) {
+	defer r.lock()()
+
+	var total int64
+	for _, project := range r.db.data.projects {
+		if project.DeletedAt.Valid ||
+			project.CreatedAt < filter.CreatedFrom ||
+			(filter.ProjectType != "" && project.Type != filter.ProjectType) ||
+			(filter.InspectorID != 0 && !r.db.data.isMember(project.ID, filter.InspectorID)) {
+			continue
+		}
+
+		total++
+	}
+
+	return total, nil
+}
+
+func (r *statisticsRepository) SumLedger(ctx context.Context, filter repository.StatisticsFilter) (int64, error) {
+	defer r.lock()()
+
+	var total int64
+	for _, ledger := range r.db.data.ledgers {
+		project, ok := r.db.data.projects[ledger.ProjectID]
+		if !ok ||
+			ledger.DeletedAt.Valid ||
+			ledger.Status != model.Posted ||
+			ledger.CreatedAt < filter.CreatedFrom ||
+			(filter.LedgerType != "" && ledger.LedgerType != filter.LedgerType) ||
+			(filter.ProjectType != "" && project.Type != filter.ProjectType) ||
+			(filter.InspectorID != 0 && ledger.InspectorID != filter.InspectorID) ||
+			(filter.ProjectID != 0 && ledger.ProjectID != filter.ProjectID) {
+			continue
+		}
+
+		total += ledger.TotalPrice
+	}
+
+	return total, nil
+}
+
+func (r *statisticsRepository) GetInspectorStats(
+	ctx context.Context,
+	inspectorID int64,
+	intervalMonth int64,
+) (model.MqtInspectorStats, error) {
+	defer r.lock()()
+
+	for _, stats := range r.db.data.inspectorStats {
+		if getInt64(stats.InspectorID) == inspectorID && stats.IntervalMonth == intervalMonth {
+			return stats, nil
+		}
+	}
+
+	return model.MqtInspectorStats{}, repository.ErrNotFound
+}
+
+func (r *statisticsRepository) ReplaceInspectorStats(ctx context.Context, stats []model.MqtInspectorStats) error {
+	defer r.lock()()
+
+	r.db.data.inspectorStats = append([]model.MqtInspectorStats{}, stats...)
+
+	return nil
+}
@@ -0,0 +1,95 @@
+package memory
+
+import (
+	"context"
+
+	"tigaputera-backend/src/model"
+	"tigaputera-backend/src/repository"
+)
+
+type loginChallengeRepository struct {
+	*repositories
+}
+
+func (r *loginChallengeRepository) GetByTokenHash(ctx context.Context, tokenHash string) (model.LoginChallenge, error) {
+	defer r.lock()()
+
+	for _, challenge := range r.db.data.loginChallenges {
+		if challenge.TokenHash == tokenHash {
+			return challenge, nil
+		}
+	}
+
+	return model.LoginChallenge{}, repository.ErrNotFound
+}
+
+func (r *loginChallengeRepository) Create(ctx context.Context, challenge *model.LoginChallenge) error {
+	defer r.lock()()
+
+	challenge.CreatedAt = now()
+	challenge.ID = r.db.data.nextID("login_challenges")
+	r.db.data.loginChallenges[challenge.ID] = *challenge
+
+	return nil
+}
+
+func (r *loginChallengeRepository) Use(ctx context.Context, id int64) error {
+	defer r.lock()()
+
+	challenge, ok := r.db.data.loginChallenges[id]
+	if !ok || challenge.UsedAt != nil {
+		return repository.ErrNotFound
+	}
+
+	challenge.UsedAt = int64Ptr(now())
+	r.db.data.loginChallenges[id] = challenge
+
+	return nil
+}
+
+type recoveryCodeRepository struct {
+	*repositories
+}
+
+func (r *recoveryCodeRepository) Replace(ctx context.Context, userID int64, codes []model.RecoveryCode) error {
+	defer r.lock()()
+
+	r.deleteByUser(userID)
+	for i := range codes {
+		codes[i].CreatedAt = now()
+		codes[i].ID = r.db.data.nextID("recovery_codes")
+		r.db.data.recoveryCodes[codes[i].ID] = codes[i]
+	}
+
+	return nil
+}
+
+func (r *recoveryCodeRepository) Use(ctx context.Context, userID int64, codeHash string) error {
+	defer r.lock()()
+
+	for _, code := range r.db.data.recoveryCodes {
+		if code.UserID == userID && code.CodeHash == codeHash && code.UsedAt == nil {
+			code.UsedAt = int64Ptr(now())
+			r.db.data.recoveryCodes[code.ID] = code
+			return nil
+		}
+	}
+
+	return repository.ErrNotFound
+}
+
+func (r *recoveryCodeRepository) DeleteByUser(ctx context.Context, userID int64) error {
+	defer r.lock()()
+
+	r.deleteByUser(userID)
+
+	return nil
+}
+
+func (r *recoveryCodeRepository) deleteByUser(userID int64) {
+	for id, code := range r.db.data.recoveryCodes {
+		if code.UserID == userID {
+			delete(r.db.data.recoveryCodes, id)
+		}
+	}
+}
@@ -0,0 +1,229 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"tigaputera-backend/src/model"
+	"tigaputera-backend/src/repository"
+
+	"gorm.io/gorm"
+)
+
+type userRepository struct {
+	*repositories
+}
+
+func (r *userRepository) Get(ctx context.Context, id int64) (model.User, error) {
+	defer r.lock()()
+
+	user, ok := r.db.data.users[id]
+	if !ok || user.DeletedAt.Valid {
+		return model.User{}, repository.ErrNotFound
+	}
+
+	return user, nil
+}
+
+func (r *userRepository) GetByUsername(ctx context.Context, username string) (model.User, error) {
+	defer r.lock()()
+
+	for _, user := range r.db.data.users {
+		if !user.DeletedAt.Valid && user.Username == username {
+			return user, nil
+		}
+	}
+
+	return model.User{}, repository.ErrNotFound
+}
+
+func (r *userRepository) List(ctx context.Context, filter repository.UserFilter) ([]model.User, error) {
+	defer r.lock()()
+
+	users := r.filter(filter)
+
+	return paginate(users, filter.Limit, filter.Offset), nil
+}
+
+func (r *userRepository) Count(ctx context.Context, filter repository.UserFilter) (int64, error) {
+	defer r.lock()()
+
+	return int64(len(r.filter(filter))), nil
+}
+
+func (r *userRepository) filter(filter repository.UserFilter) []model.User {
+	users := []model.User{}
+	for _, user := range r.db.data.users {
+		if user.DeletedAt.Valid != filter.IsDeleted || (filter.Role != "" && user.Role != filter.Role) {
+			continue
+		}
+		if filter.ExcludeRole != "" && user.Role == filter.ExcludeRole {
+			continue
+		}
+
+		users = append(users, user)
+	}
+
+	sort.Slice(users, func(i, j int) bool {
+		return users[i].ID < users[j].ID
+	})
+
+	return users
+}
+
+func (r *userRepository) Create(ctx context.Context, user *model.User) error {
+	defer r.lock()()
+
+	for _, u := range r.db.data.users {
+		if u.Username == user.Username {
+			return repository.ErrDuplicate
+		}
+	}
+
+	if user.IsFirstLogin == nil {
+		isFirstLogin := true
+		user.IsFirstLogin = &isFirstLogin
+	}
+	if user.Role == "" {
+		user.Role = "Inspector"
+	}
+	if user.CreatedAt == 0 {
+		user.CreatedAt = now()
+	}
+	user.UpdatedAt = user.CreatedAt
+	user.ID = r.db.data.nextID("users")
+	r.db.data.users[user.ID] = *user
+
+	return nil
+}
+
+func (r *userRepository) UpdatePassword(ctx context.Context, id int64, password string) error {
+	defer r.lock()()
+
+	user, ok := r.db.data.users[id]
+	if !ok || user.DeletedAt.Valid {
+		return repository.ErrNotFound
+	}
+
+	user.Password = password
+	user.IsFirstLogin = new(bool) // false
+	user.PasswordChangedAt = now()
+	user.UpdatedAt = now()
+	r.db.data.users[id] = user
+
+	return nil
+}
+
+func (r *userRepository) UpdateTotpSecret(ctx context.Context, id int64, secret string) error {
+	return r.update(id, func(user *model.User) bool {
+		if user.TotpEnabledAt != nil {
+			return false
+		}
+
+		user.TotpSecret = secret
+		user.TotpLastUsedStep = 0
+		return true
+	})
+}
+
+func (r *userRepository) EnableTotp(ctx context.Context, id int64, step int64) error {
+	return r.update(id, func(user *model.User) bool {
+		if user.TotpEnabledAt != nil || user.TotpSecret == "" {
+			return false
+		}
+
+		user.TotpEnabledAt = int64Ptr(now())
+		user.TotpLastUsedStep = step
+		return true
+	})
+}
+
+func (r *userRepository) UseTotpStep(ctx context.Context, id int64, step int64) error {
+	return r.update(id, func(user *model.User) bool {
+		if user.TotpLastUsedStep >= step {
+			return false
+		}
+
+		user.TotpLastUsedStep = step
+		return true
+	})
+}
+
+func (r *userRepository) DisableTotp(ctx context.Context, id int64) error {
+	return r.update(id, func(user *model.User) bool {
+		user.TotpSecret = ""
+		user.TotpEnabledAt = nil
+		user.TotpLastUsedStep = 0
+		return true
+	})
+}
+
+func (r *userRepository) UpdateRole(ctx context.Context, id int64, role model.Role) error {
+	return r.update(id, func(user *model.User) bool {
+		user.Role = role
+		return true
+	})
+}
+
+func (r *userRepository) UpdateName(ctx context.Context, id int64, name string) error {
+	return r.update(id, func(user *model.User) bool {
+		user.Name = name
+		return true
+	})
+}
+
+func (r *userRepository) ResetPassword(ctx context.Context, id int64, password string) error {
+	isFirstLogin := true
+	return r.update(id, func(user *model.User) bool {
+		user.Password = password
+		user.IsFirstLogin = &isFirstLogin
+		user.PasswordChangedAt = now()
+		return true
+	})
+}
+
+// update applies fn to the user like a conditional update, ErrNotFound is returned when fn doesn't match
+func (r *userRepository) update(id int64, fn func(user *model.User) bool) error {
+	defer r.lock()()
+
+	user, ok := r.db.data.users[id]
+	if !ok || user.DeletedAt.Valid || !fn(&user) {
+		return repository.ErrNotFound
+	}
+
+	user.UpdatedAt = now()
+	r.db.data.users[id] = user
+
+	return nil
+}
+
+func (r *userRepository) Delete(ctx context.Context, id int64) error {
+	defer r.lock()()
+
+	user, ok := r.db.data.users[id]
+	if !ok || user.DeletedAt.Valid {
+		return repository.ErrNotFound
+	}
+
+	user.DeletedAt = gorm.DeletedAt{Time: time.Now(), Valid: true}
+	r.db.data.users[id] = user
+
+	return nil
+}
+
+func (r *userRepository) Restore(ctx context.Context, id int64) error {
+	defer r.lock()()
+
+	user, ok := r.db.data.users[id]
+	if !ok || !user.DeletedAt.Valid {
+		return repository.ErrNotFound
+	}
+
+	user.DeletedAt = gorm.DeletedAt{}
+	user.DeletedBy = nil
+	user.UpdatedAt = now()
+	r.db.data.users[id] = user
+
+	return nil
+}
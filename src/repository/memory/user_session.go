@@ -0,0 +1,119 @@
+package memory
+
+import (
+	"context"
+
+	"tigaputera-backend/src/model"
+	"tigaputera-backend/src/repository"
+)
+
+type userSessionRepository struct {
+	*repositories
+}
+
+func (r *userSessionRepository) Get(ctx context.Context, id int64) (model.UserSession, error) {
+	defer r.lock()()
+
+	session, ok := r.db.data.userSessions[id]
+	if !ok {
+		return session, repository.ErrNotFound
+	}
+
+	return session, nil
+}
+
+func (r *userSessionRepository) GetByTokenHash(ctx context.Context, tokenHash string) (model.UserSession, error) {
+	defer r.lock()()
+
+	for _, session := range r.db.data.userSessions {
+		if session.RefreshTokenHash == tokenHash || session.PreviousTokenHash == tokenHash {
+			return session, nil
+		}
+	}
+
+	return model.UserSession{}, repository.ErrNotFound
+}
+
+func (r *userSessionRepository) Create(ctx context.Context, session *model.UserSession) error {
+	defer r.lock()()
+
+	for _, existing := range r.db.data.userSessions {
+		if existing.RefreshTokenHash == session.RefreshTokenHash {
+			return repository.ErrDuplicate
+		}
+	}
+
+	session.CreatedAt = now()
+	session.UpdatedAt = session.CreatedAt
+	session.ID = r.db.data.nextID("user_sessions")
+	r.db.data.userSessions[session.ID] = *session
+
+	return nil
+}
+
+func (r *userSessionRepository) Rotate(
+	ctx context.Context,
+	id int64,
+	tokenHash string,
+	newTokenHash string,
+	expiresAt int64,
+) error {
+	defer r.lock()()
+
+	session, ok := r.db.data.userSessions[id]
+	if !ok || session.RefreshTokenHash != tokenHash || session.RevokedAt != nil {
+		return repository.ErrNotFound
+	}
+
+	session.RefreshTokenHash = newTokenHash
+	session.PreviousTokenHash = tokenHash
+	session.ExpiresAt = expiresAt
+	session.UpdatedAt = now()
+	r.db.data.userSessions[id] = session
+
+	return nil
+}
+
+func (r *userSessionRepository) Revoke(ctx context.Context, id int64) error {
+	defer r.lock()()
+
+	if session, ok := r.db.data.userSessions[id]; ok {
+		r.revoke(session)
+	}
+
+	return nil
+}
+
+func (r *userSessionRepository) RevokeByUser(ctx context.Context, userID int64) error {
+	defer r.lock()()
+
+	for _, session := range r.db.data.userSessions {
+		if session.UserID == userID {
+			r.revoke(session)
+		}
+	}
+
+	return nil
+}
+
+func (r *userSessionRepository) RevokeOthers(ctx context.Context, userID int64, keptID int64) error {
+	defer r.lock()()
+
+	for _, session := range r.db.data.userSessions {
+		if session.UserID == userID && session.ID != keptID {
+			r.revoke(session)
+		}
+	}
+
+	return nil
+}
+
+func (r *userSessionRepository) revoke(session model.UserSession) {
+	if session.RevokedAt != nil {
+		return
+	}
+
+	session.RevokedAt = int64Ptr(now())
+	session.UpdatedAt = now()
+	r.db.data.userSessions[session.ID] = session
+}
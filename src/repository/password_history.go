@@ -0,0 +1,32 @@
+package repository
+
+import (
+	"context"
+
+	"tigaputera-backend/src/model"
+
+	"gorm.io/gorm"
+)
+
+type passwordHistoryRepository struct {
+	db *gorm.DB
+}
+
+func (r *passwordHistoryRepository) List(
+	ctx context.Context,
+	userID int64,
+	limit int64,
+) ([]model.PasswordHistory, error) {
+	var histories []model.PasswordHistory
+	err := r.db.WithContext(ctx).
+		Where("user_id = ?", userID).
+		Order("id DESC").
+		Limit(int(limit)).
+		Find(&histories).Error
+
+	return histories, translateError(err)
+}
+
+func (r *passwordHistoryRepository) Create(ctx context.Context, history *model.PasswordHistory) error {
+	return translateError(r.db.WithContext(ctx).Create(history).Error)
+}
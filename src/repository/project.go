@@ -0,0 +1,158 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"tigaputera-backend/src/model"
+
+	"gorm.io/gorm"
+)
+
+type projectRepository struct {
+	db *gorm.DB
+}
+
+func (r *projectRepository) Get(ctx context.Context, id int64) (model.Project, error) {
+	var project model.Project
+	err := r.db.WithContext(ctx).
+		Joins("Inspector").
+		Preload("Members").
+		First(&project, id).Error
+
+	return project, translateError(err)
+}
+
+func (r *projectRepository) List(ctx context.Context, filter ProjectFilter) ([]model.Project, error) {
+	projects := []model.Project{}
+	query := r.filter(ctx, filter).
+		Joins("Inspector").
+		Order("projects.updated_at DESC, projects.id DESC")
+	if filter.Limit > 0 {
+		query = query.Limit(int(filter.Limit)).Offset(int(filter.Offset))
+	}
+
+	err := query.Find(&projects).Error
+
+	return projects, translateError(err)
+}
+
+func (r *projectRepository) Count(ctx context.Context, filter ProjectFilter) (int64, error) {
+	var count int64
+	err := r.filter(ctx, filter).Count(&count).Error
+
+	return count, translateError(err)
+}
+
+func (r *projectRepository) filter(ctx context.Context, filter ProjectFilter) *gorm.DB {
+	query := r.db.WithContext(ctx).Model(&model.Project{})
+	if filter.Keyword != "" {
+		query = query.Where("projects.name ILIKE ?", "%"+filter.Keyword+"%")
+	}
+	if filter.InspectorID != 0 {
+		query = query.Where(fmt.Sprintf(projectMemberQuery, "projects.id"), filter.InspectorID)
+	}
+	if filter.Status != "" {
+		query = query.Where("projects.status = ?", filter.Status)
+	}
+
+	return query
+}
+
+func (r *projectRepository) Create(ctx context.Context, project *model.Project) error {
+	return translateError(r.db.WithContext(ctx).Create(project).Error)
+}
+
+func (r *projectRepository) Update(ctx context.Context, project model.Project, updatedBy int64) error {
+	return updateResult(r.db.WithContext(ctx).
+		Model(&model.Project{}).
+		Where("id = ?", project.ID).
+		Updates(map[string]interface{}{
+			"name":         project.Name,
+			"description":  project.Description,
+			"type":         project.Type,
+			"dept_name":    project.DeptName,
+			"company_name": project.CompanyName,
+			"start_date":   project.StartDate,
+			"final_date":   project.FinalDate,
+			"volume":       project.Volume,
+			"length":       project.Length,
+			"width":        project.Width,
+			"updated_by":   updatedBy,
+		}))
+}
+
+func (r *projectRepository) UpdateBudget(
+	ctx context.Context,
+	id int64,
+	budget int64,
+	ppn float64,
+	pph float64,
+) error {
+	return updateResult(r.db.WithContext(ctx).
+		Model(&model.Project{}).
+		Where("id = ?", id).
+		Updates(map[string]interface{}{
+			"budget": budget,
+			"ppn":    ppn,
+			"pph":    pph,
+		}))
+}
+
+func (r *projectRepository) UpdateStatus(ctx context.Context, id int64, status string) error {
+	return updateResult(r.db.WithContext(ctx).
+		Model(&model.Project{}).
+		Where("id = ?", id).
+		Update("status", status))
+}
+
+func (r *projectRepository) UpdateApprovalThreshold(
+	ctx context.Context,
+	id int64,
+	threshold *int64,
+	updatedBy int64,
+) error {
+	return updateResult(r.db.WithContext(ctx).
+		Model(&model.Project{}).
+		Where("id = ?", id).
+		Updates(map[string]interface{}{
+			"approval_threshold": threshold,
+			"updated_by":         updatedBy,
+		}))
+}
+
+func (r *projectRepository) AddIncome(ctx context.Context, id int64, amount int64, updatedBy int64) error {
+	return translateError(r.db.WithContext(ctx).
+		Model(&model.Project{}).
+		Where("id = ?", id).
+		Updates(map[string]interface{}{
+			"income":     gorm.Expr("income + ?", amount),
+			"updated_by": updatedBy,
+		}).Error)
+}
+
+func (r *projectRepository) SetIncome(ctx context.Context, id int64, income int64) error {
+	return translateError(r.db.WithContext(ctx).
+		Model(&model.Project{}).
+		Where("id = ?", id).
+		Update("income", income).Error)
+}
+
+func (r *projectRepository) SetUpdatedBy(ctx context.Context, id int64, updatedBy int64) error {
+	return translateError(r.db.WithContext(ctx).
+		Model(&model.Project{}).
+		Where("id = ?", id).
+		Update("updated_by", updatedBy).Error)
+}
+
+func (r *projectRepository) UpdateInspector(
+	ctx context.Context,
+	id int64,
+	inspectorID int64,
+	previousInspectorID int64,
+) error {
+	return updateResult(r.db.WithContext(ctx).
+		Model(&model.Project{}).
+		Where("id = ? AND inspector_id = ?", id, previousInspectorID).
+		Update("inspector_id", inspectorID))
+}
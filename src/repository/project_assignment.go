@@ -0,0 +1,29 @@
+package repository
+
+import (
+	"context"
+
+	"tigaputera-backend/src/model"
+
+	"gorm.io/gorm"
+)
+
+type projectAssignmentRepository struct {
+	db *gorm.DB
+}
+
+func (r *projectAssignmentRepository) List(ctx context.Context, projectID int64) ([]model.ProjectAssignment, error) {
+	assignments := []model.ProjectAssignment{}
+	err := r.db.WithContext(ctx).
+		Joins("Inspector").
+		Joins("PreviousInspector").
+		Where("project_assignments.project_id = ?", projectID).
+		Order("project_assignments.id").
+		Find(&assignments).Error
+
+	return assignments, translateError(err)
+}
+
+func (r *projectAssignmentRepository) Create(ctx context.Context, assignment *model.ProjectAssignment) error {
+	return translateError(r.db.WithContext(ctx).Create(assignment).Error)
+}
@@ -0,0 +1,123 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"tigaputera-backend/src/model"
+
+	"gorm.io/gorm"
+)
+
+type projectExpenditureRepository struct {
+	db *gorm.DB
+}
+
+func (r *projectExpenditureRepository) Get(
+	ctx context.Context,
+	projectID int64,
+	id int64,
+) (model.ProjectExpenditure, error) {
+	var projectExpenditure model.ProjectExpenditure
+	query := r.db.WithContext(ctx).InnerJoins("Project")
+	if projectID != 0 {
+		query = query.Where("project_expenditures.project_id = ?", projectID)
+	}
+
+	err := query.First(&projectExpenditure, id).Error
+
+	return projectExpenditure, translateError(err)
+}
+
+func (r *projectExpenditureRepository) GetOfInspector(
+	ctx context.Context,
+	inspectorID int64,
+	id int64,
+) (model.ProjectExpenditure, error) {
+	var projectExpenditure model.ProjectExpenditure
+	err := r.db.WithContext(ctx).
+		InnerJoins("Project").
+		Where(fmt.Sprintf(projectMemberQuery, `"Project".id`), inspectorID).
+		First(&projectExpenditure, id).Error
+
+	return projectExpenditure, translateError(err)
+}
+
+func (r *projectExpenditureRepository) GetLastSequence(
+	ctx context.Context,
+	projectID int64,
+) (model.ProjectExpenditure, error) {
+	var projectExpenditure model.ProjectExpenditure
+	err := r.db.WithContext(ctx).
+		Where("project_id = ?", projectID).
+		Order("sequence desc").
+		Take(&projectExpenditure).Error
+
+	return projectExpenditure, translateError(err)
+}
+
+func (r *projectExpenditureRepository) List(
+	ctx context.Context,
+	projectID int64,
+) ([]model.ProjectExpenditure, error) {
+	projectExpenditures := []model.ProjectExpenditure{}
+	query := r.db.WithContext(ctx).Order("id")
+	if projectID != 0 {
+		query = r.db.WithContext(ctx).
+			Where("project_id = ?", projectID).
+			Order("sequence")
+	}
+
+	err := query.Find(&projectExpenditures).Error
+
+	return projectExpenditures, translateError(err)
+}
+
+func (r *projectExpenditureRepository) Create(
+	ctx context.Context,
+	expenditures ...*model.ProjectExpenditure,
+) error {
+	if len(expenditures) == 0 {
+		return nil
+	}
+
+	return translateError(r.db.WithContext(ctx).Create(expenditures).Error)
+}
+
+func (r *projectExpenditureRepository) UpdateApprovalThreshold(
+	ctx context.Context,
+	projectID int64,
+	id int64,
+	threshold *int64,
+	updatedBy int64,
+) error {
+	return updateResult(r.db.WithContext(ctx).
+		Model(&model.ProjectExpenditure{}).
+		Where("id = ? AND project_id = ?", id, projectID).
+		Updates(map[string]interface{}{
+			"approval_threshold": threshold,
+			"updated_by":         updatedBy,
+		}))
+}
+
+func (r *projectExpenditureRepository) AddTotalPrice(
+	ctx context.Context,
+	id int64,
+	amount int64,
+	updatedBy int64,
+) error {
+	return translateError(r.db.WithContext(ctx).
+		Model(&model.ProjectExpenditure{}).
+		Where("id = ?", id).
+		Updates(map[string]interface{}{
+			"total_price": gorm.Expr("total_price + ?", amount),
+			"updated_by":  updatedBy,
+		}).Error)
+}
+
+func (r *projectExpenditureRepository) SetTotalPrice(ctx context.Context, id int64, totalPrice int64) error {
+	return translateError(r.db.WithContext(ctx).
+		Model(&model.ProjectExpenditure{}).
+		Where("id = ?", id).
+		Update("total_price", totalPrice).Error)
+}
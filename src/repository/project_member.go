@@ -0,0 +1,70 @@
+package repository
+
+import (
+	"context"
+
+	"tigaputera-backend/src/model"
+
+	"gorm.io/gorm"
+)
+
+// projectMemberQuery matches the projects the inspector is a member of, the project is in the outer query
+const projectMemberQuery = `EXISTS (
+	SELECT 1 FROM project_members pm
+	WHERE pm.project_id = %s AND pm.inspector_id = ? AND pm.deleted_at IS NULL
+)`
+
+type projectMemberRepository struct {
+	db *gorm.DB
+}
+
+func (r *projectMemberRepository) Get(
+	ctx context.Context,
+	projectID int64,
+	inspectorID int64,
+) (model.ProjectMember, error) {
+	var member model.ProjectMember
+	err := r.db.WithContext(ctx).
+		Joins("Inspector").
+		Where("project_members.project_id = ? AND project_members.inspector_id = ?", projectID, inspectorID).
+		Take(&member).Error
+
+	return member, translateError(err)
+}
+
+func (r *projectMemberRepository) List(ctx context.Context, filter ProjectMemberFilter) ([]model.ProjectMember, error) {
+	members := []model.ProjectMember{}
+	query := r.db.WithContext(ctx).
+		Joins("Inspector").
+		Where("project_members.project_id = ?", filter.ProjectID).
+		Order("project_members.id")
+	if filter.WithRemoved {
+		query = query.Unscoped()
+	}
+
+	err := query.Find(&members).Error
+
+	return members, translateError(err)
+}
+
+func (r *projectMemberRepository) Create(ctx context.Context, member *model.ProjectMember) error {
+	return translateError(r.db.WithContext(ctx).Create(member).Error)
+}
+
+func (r *projectMemberRepository) UpdateRole(
+	ctx context.Context,
+	projectID int64,
+	inspectorID int64,
+	role model.ProjectMemberRole,
+) error {
+	return updateResult(r.db.WithContext(ctx).
+		Model(&model.ProjectMember{}).
+		Where("project_id = ? AND inspector_id = ?", projectID, inspectorID).
+		Update("role", role))
+}
+
+func (r *projectMemberRepository) Delete(ctx context.Context, projectID int64, inspectorID int64) error {
+	return updateResult(r.db.WithContext(ctx).
+		Where("project_id = ? AND inspector_id = ?", projectID, inspectorID).
+		Delete(&model.ProjectMember{}))
+}
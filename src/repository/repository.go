@@ -0,0 +1,346 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"tigaputera-backend/src/model"
+)
+
+var (
+	ErrNotFound    = errors.New("record not found")
+	ErrDuplicate   = errors.New("duplicate key value violates unique constraint")
+	ErrLockTimeout = errors.New("lock timeout")
+)
+
+// Interface gives the services access to the stored data without knowing the storage behind it,
+// the repositories given to fn by Transaction are bound to a single transaction
+type Interface interface {
+	Transaction(ctx context.Context, fn func(tx Interface) error) error
+	User() UserRepository
+	Project() ProjectRepository
+	ProjectExpenditure() ProjectExpenditureRepository
+	Ledger() LedgerRepository
+	LedgerAttachment() LedgerAttachmentRepository
+	FundTransfer() FundTransferRepository
+	Statistics() StatisticsRepository
+	IdempotencyKey() IdempotencyKeyRepository
+	UserSession() UserSessionRepository
+	PasswordHistory() PasswordHistoryRepository
+	LoginAttempt() LoginAttemptRepository
+	LoginThrottle() LoginThrottleRepository
+	LoginChallenge() LoginChallengeRepository
+	RecoveryCode() RecoveryCodeRepository
+	RolePermission() RolePermissionRepository
+	AuditLog() AuditLogRepository
+	ProjectAssignment() ProjectAssignmentRepository
+	ProjectMember() ProjectMemberRepository
+}
+
+// UserFilter matches the active users, IsDeleted matches the deactivated users instead
+type UserFilter struct {
+	Role model.Role
+	// ExcludeRole skips the users of the role, e.g. the directors
+	ExcludeRole model.Role
+	IsDeleted   bool
+	Limit       int64
+	Offset      int64
+}
+
+type UserRepository interface {
+	Get(ctx context.Context, id int64) (model.User, error)
+	GetByUsername(ctx context.Context, username string) (model.User, error)
+	List(ctx context.Context, filter UserFilter) ([]model.User, error)
+	Count(ctx context.Context, filter UserFilter) (int64, error)
+	Create(ctx context.Context, user *model.User) error
+	// UpdatePassword also clears the first login and restarts the password expiry
+	UpdatePassword(ctx context.Context, id int64, password string) error
+	// UpdateTotpSecret starts the enrollment of an authenticator, it returns ErrNotFound once one is enabled
+	UpdateTotpSecret(ctx context.Context, id int64, secret string) error
+	// EnableTotp enables the enrolled authenticator, the step of the code that enabled it is used
+	EnableTotp(ctx context.Context, id int64, step int64) error
+	// UseTotpStep returns ErrNotFound when the step of the code isn't after the last used one
+	UseTotpStep(ctx context.Context, id int64, step int64) error
+	DisableTotp(ctx context.Context, id int64) error
+	UpdateRole(ctx context.Context, id int64, role model.Role) error
+	UpdateName(ctx context.Context, id int64, name string) error
+	// ResetPassword sets a password given by the director, the user changes it on the next login
+	ResetPassword(ctx context.Context, id int64, password string) error
+	Delete(ctx context.Context, id int64) error
+	// Restore reactivates a deleted user, it returns ErrNotFound when the user isn't deleted
+	Restore(ctx context.Context, id int64) error
+}
+
+// ProjectFilter matches the projects whose name contains the keyword and the projects the inspector
+// is a member of, a zero field matches everything
+type ProjectFilter struct {
+	Keyword     string
+	InspectorID int64
+	Status      model.ProjectStatus
+	Limit       int64
+	Offset      int64
+}
+
+type ProjectRepository interface {
+	// Get returns the project with its inspector and its members
+	Get(ctx context.Context, id int64) (model.Project, error)
+	// List returns the projects with their inspector, the latest updated first
+	List(ctx context.Context, filter ProjectFilter) ([]model.Project, error)
+	Count(ctx context.Context, filter ProjectFilter) (int64, error)
+	Create(ctx context.Context, project *model.Project) error
+	// Update saves the master data of the project: its name, description, type, department, company,
+	// dates and dimensions
+	Update(ctx context.Context, project model.Project, updatedBy int64) error
+	UpdateBudget(ctx context.Context, id int64, budget int64, ppn float64, pph float64) error
+	UpdateStatus(ctx context.Context, id int64, status string) error
+	UpdateApprovalThreshold(ctx context.Context, id int64, threshold *int64, updatedBy int64) error
+	AddIncome(ctx context.Context, id int64, amount int64, updatedBy int64) error
+	SetIncome(ctx context.Context, id int64, income int64) error
+	SetUpdatedBy(ctx context.Context, id int64, updatedBy int64) error
+	// UpdateInspector returns ErrNotFound when the project was reassigned from previousInspectorID meanwhile
+	UpdateInspector(ctx context.Context, id int64, inspectorID int64, previousInspectorID int64) error
+}
+
+type ProjectExpenditureRepository interface {
+	// Get returns the expenditure with its project, a zero projectID matches any project
+	Get(ctx context.Context, projectID int64, id int64) (model.ProjectExpenditure, error)
+	// GetOfInspector returns the expenditure with its project when the inspector is a member of the project
+	GetOfInspector(ctx context.Context, inspectorID int64, id int64) (model.ProjectExpenditure, error)
+	GetLastSequence(ctx context.Context, projectID int64) (model.ProjectExpenditure, error)
+	// List returns the expenditures of a project ordered by sequence, a zero projectID returns all of them
+	List(ctx context.Context, projectID int64) ([]model.ProjectExpenditure, error)
+	Create(ctx context.Context, expenditures ...*model.ProjectExpenditure) error
+	UpdateApprovalThreshold(ctx context.Context, projectID int64, id int64, threshold *int64, updatedBy int64) error
+	AddTotalPrice(ctx context.Context, id int64, amount int64, updatedBy int64) error
+	SetTotalPrice(ctx context.Context, id int64, totalPrice int64) error
+}
+
+// LedgerFilter matches the ledgers having every non zero field, the newest ledger comes first
+// unless Ascending is set and ties are ordered by id
+type LedgerFilter struct {
+	ID           int64
+	IDs          []int64
+	InspectorID  int64
+	InspectorIDs []int64
+	ProjectID    int64
+	RefID        int64
+	LedgerType   model.LedgerType
+	ExcludeType  model.LedgerType
+	// ExcludeInspectorRole skips the ledgers of the users of the role, e.g. the transfer legs of the directors
+	ExcludeInspectorRole model.Role
+	Status               model.LedgerStatus
+	IsCanceled           *bool
+	CreatedFrom          int64
+	Limit                int64
+	Offset               int64
+	Ascending            bool
+}
+
+type LedgerRepository interface {
+	// Lock takes the ledger locks of the inspectors and the project until the transaction ends,
+	// it must be called inside Transaction and returns ErrLockTimeout when waiting too long.
+	// A zero projectID locks the inspectors only.
+	Lock(ctx context.Context, projectID int64, inspectorIDs ...int64) error
+	// LockAll blocks every posting until the transaction ends
+	LockAll(ctx context.Context) error
+	// Get returns the first ledger matching the filter with its inspector and project
+	Get(ctx context.Context, filter LedgerFilter) (model.Ledger, error)
+	// List returns the ledgers matching the filter with their inspector and project
+	List(ctx context.Context, filter LedgerFilter) ([]model.Ledger, error)
+	Count(ctx context.Context, filter LedgerFilter) (int64, error)
+	SumTotalPrice(ctx context.Context, filter LedgerFilter) (int64, error)
+	// Create inserts the ledger with its attachments
+	Create(ctx context.Context, ledger *model.Ledger) error
+	// UpdateReceipt replaces the receipt shown in the ledger lists
+	UpdateReceipt(ctx context.Context, id int64, receipt model.LedgerReceipt) error
+	// Cancel flips a ledger in the status that isn't canceled yet, it returns ErrNotFound otherwise
+	Cancel(ctx context.Context, id int64, status model.LedgerStatus, updatedBy int64) error
+	// Approve posts a pending ledger with its creation time, balances and review fields
+	Approve(ctx context.Context, ledger model.Ledger) error
+	// Reject rejects a pending ledger of the project expenditure with its reason and review fields
+	Reject(ctx context.Context, ledger model.Ledger) error
+	UpdateBalance(ctx context.Context, correction model.LedgerBalanceCorrection) error
+	// ListObjectKeys returns the receipt and thumbnail keys of every ledger, the deleted ones included
+	ListObjectKeys(ctx context.Context) ([]string, error)
+}
+
+type LedgerAttachmentFilter struct {
+	IDs             []int64
+	ExcludeLedgerID int64
+	ContentHash     string
+	// SimilarTo matches the images whose perceptual hash differs from it by at most MaxDistance bits,
+	// pdfs and the receipts uploaded before the hashes have none
+	SimilarTo   *int64
+	MaxDistance int
+	IsDuplicate bool
+	Limit       int64
+}
+
+type LedgerAttachmentRepository interface {
+	// List returns the attachments of the ledgers, the oldest first
+	List(ctx context.Context, ledgerIDs ...int64) ([]model.LedgerAttachment, error)
+	// ListOfActiveLedgers returns the attachments matching the filter whose ledger isn't canceled or rejected,
+	// the oldest first
+	ListOfActiveLedgers(ctx context.Context, filter LedgerAttachmentFilter) ([]model.LedgerAttachment, error)
+	Create(ctx context.Context, attachments ...*model.LedgerAttachment) error
+	// Delete returns ErrNotFound when the ledger doesn't have the attachment
+	Delete(ctx context.Context, ledgerID int64, id int64, deletedBy int64) error
+	// ListObjectKeys returns the object and thumbnail keys of every attachment, the deleted ones included
+	ListObjectKeys(ctx context.Context) ([]string, error)
+}
+
+type FundTransferFilter struct {
+	ID          int64
+	ProjectID   int64
+	InspectorID int64
+	Status      model.TransferStatus
+	Limit       int64
+	Offset      int64
+}
+
+type FundTransferRepository interface {
+	// Get returns the transfer with its project, sender and inspector
+	Get(ctx context.Context, filter FundTransferFilter) (model.FundTransfer, error)
+	List(ctx context.Context, filter FundTransferFilter) ([]model.FundTransfer, error)
+	Count(ctx context.Context, filter FundTransferFilter) (int64, error)
+	Create(ctx context.Context, transfer *model.FundTransfer) error
+	// UpdateStatus moves a transfer of the project out of the from status, it returns ErrNotFound
+	// when the transfer isn't in that status anymore
+	UpdateStatus(ctx context.Context, transfer model.FundTransfer, from model.TransferStatus) error
+	// ListObjectKeys returns the receipt keys of every transfer, the deleted ones included
+	ListObjectKeys(ctx context.Context) ([]string, error)
+}
+
+// StatisticsFilter matches the posted ledgers or the projects created since CreatedFrom,
+// a zero field matches everything. The projects of an inspector are the projects they are a member of.
+type StatisticsFilter struct {
+	CreatedFrom int64
+	LedgerType  model.LedgerType
+	ProjectType string
+	InspectorID int64
+	ProjectID   int64
+}
+
+type StatisticsRepository interface {
+	CountProject(ctx context.Context, filter StatisticsFilter) (int64, error)
+	SumLedger(ctx context.Context, filter StatisticsFilter) (int64, error)
+	GetInspectorStats(ctx context.Context, inspectorID int64, intervalMonth int64) (model.MqtInspectorStats, error)
+	ReplaceInspectorStats(ctx context.Context, stats []model.MqtInspectorStats) error
+}
+
+type IdempotencyKeyRepository interface {
+	Get(ctx context.Context, userID int64, key string) (model.IdempotencyKey, error)
+	// Create returns false without an error when the key of the user already exists
+	Create(ctx context.Context, idempotencyKey *model.IdempotencyKey) (bool, error)
+	Complete(ctx context.Context, idempotencyKey model.IdempotencyKey) error
+	Delete(ctx context.Context, id int64) error
+	// DeleteExpired also deletes the key when it is still in progress since before staleAt,
+	// its request died without releasing it
+	DeleteExpired(ctx context.Context, userID int64, key string, now int64, staleAt int64) error
+}
+
+type UserSessionRepository interface {
+	Get(ctx context.Context, id int64) (model.UserSession, error)
+	// GetByTokenHash returns the session whose current or previous refresh token has the hash
+	GetByTokenHash(ctx context.Context, tokenHash string) (model.UserSession, error)
+	Create(ctx context.Context, session *model.UserSession) error
+	// Rotate replaces the refresh token of an active session, it returns ErrNotFound
+	// when the token was rotated by another request or the session was revoked
+	Rotate(ctx context.Context, id int64, tokenHash string, newTokenHash string, expiresAt int64) error
+	Revoke(ctx context.Context, id int64) error
+	RevokeByUser(ctx context.Context, userID int64) error
+	// RevokeOthers revokes every session of the user except the one kept
+	RevokeOthers(ctx context.Context, userID int64, keptID int64) error
+}
+
+type PasswordHistoryRepository interface {
+	// List returns the latest passwords of the user first
+	List(ctx context.Context, userID int64, limit int64) ([]model.PasswordHistory, error)
+	Create(ctx context.Context, history *model.PasswordHistory) error
+}
+
+type LoginAttemptRepository interface {
+	Create(ctx context.Context, attempt *model.LoginAttempt) error
+}
+
+type LoginThrottleRepository interface {
+	Get(ctx context.Context, key string) (model.LoginThrottle, error)
+	// Fail counts a failed login of the key, the count starts over when the last failure is before windowStart
+	Fail(ctx context.Context, key string, now int64, windowStart int64) (model.LoginThrottle, error)
+	Reset(ctx context.Context, key string) error
+}
+
+type LoginChallengeRepository interface {
+	GetByTokenHash(ctx context.Context, tokenHash string) (model.LoginChallenge, error)
+	Create(ctx context.Context, challenge *model.LoginChallenge) error
+	// Use returns ErrNotFound when the challenge was already used
+	Use(ctx context.Context, id int64) error
+}
+
+type RecoveryCodeRepository interface {
+	// Replace deletes the recovery codes of the user before creating the new ones
+	Replace(ctx context.Context, userID int64, codes []model.RecoveryCode) error
+	// Use returns ErrNotFound when the user has no unused recovery code with the hash
+	Use(ctx context.Context, userID int64, codeHash string) error
+	DeleteByUser(ctx context.Context, userID int64) error
+}
+
+type RolePermissionRepository interface {
+	// List returns the permissions of the role ordered by the role, an empty role lists every role
+	List(ctx context.Context, role model.Role) ([]model.RolePermission, error)
+	// Replace deletes the permissions of the role before creating the new ones
+	Replace(ctx context.Context, role model.Role, permissions []model.RolePermission) error
+}
+
+// AuditLogFilter matches the audit logs created between CreatedFrom and CreatedTo, a zero field matches everything
+type AuditLogFilter struct {
+	Entity      string
+	EntityID    int64
+	UserID      int64
+	CreatedFrom int64
+	CreatedTo   int64
+	Limit       int64
+	Offset      int64
+}
+
+// AuditLogRepository reads the audit logs written by the callbacks of the database, there is no way to change them
+type AuditLogRepository interface {
+	// List returns the latest audit logs first
+	List(ctx context.Context, filter AuditLogFilter) ([]model.AuditLog, error)
+	Count(ctx context.Context, filter AuditLogFilter) (int64, error)
+}
+
+type ProjectAssignmentRepository interface {
+	// List returns the assignments of the project with their inspectors, the first one first
+	List(ctx context.Context, projectID int64) ([]model.ProjectAssignment, error)
+	Create(ctx context.Context, assignment *model.ProjectAssignment) error
+}
+
+// ProjectMemberFilter matches the current members, WithRemoved matches the removed members too
+type ProjectMemberFilter struct {
+	ProjectID   int64
+	WithRemoved bool
+}
+
+type ProjectMemberRepository interface {
+	// Get returns an active member of the project
+	Get(ctx context.Context, projectID int64, inspectorID int64) (model.ProjectMember, error)
+	// List returns the members with their inspectors, the first added first
+	List(ctx context.Context, filter ProjectMemberFilter) ([]model.ProjectMember, error)
+	// Create returns ErrDuplicate when the inspector is already a member of the project
+	Create(ctx context.Context, member *model.ProjectMember) error
+	UpdateRole(ctx context.Context, projectID int64, inspectorID int64, role model.ProjectMemberRole) error
+	Delete(ctx context.Context, projectID int64, inspectorID int64) error
+}
+
+func IsNotFound(err error) bool {
+	return errors.Is(err, ErrNotFound)
+}
+
+func IsDuplicate(err error) bool {
+	return errors.Is(err, ErrDuplicate)
+}
+
+func IsLockTimeout(err error) bool {
+	return errors.Is(err, ErrLockTimeout)
+}
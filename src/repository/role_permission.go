@@ -0,0 +1,39 @@
+package repository
+
+import (
+	"context"
+
+	"tigaputera-backend/src/model"
+
+	"gorm.io/gorm"
+)
+
+type rolePermissionRepository struct {
+	db *gorm.DB
+}
+
+func (r *rolePermissionRepository) List(ctx context.Context, role model.Role) ([]model.RolePermission, error) {
+	query := r.db.WithContext(ctx)
+	if role != "" {
+		query = query.Where("role = ?", role)
+	}
+
+	var permissions []model.RolePermission
+	err := query.Order("role ASC, id ASC").Find(&permissions).Error
+
+	return permissions, translateError(err)
+}
+
+func (r *rolePermissionRepository) Replace(
+	ctx context.Context,
+	role model.Role,
+	permissions []model.RolePermission,
+) error {
+	return translateError(r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("role = ?", role).Delete(&model.RolePermission{}).Error; err != nil {
+			return err
+		}
+
+		return tx.Create(&permissions).Error
+	}))
+}
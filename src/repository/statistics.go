@@ -0,0 +1,96 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"tigaputera-backend/src/model"
+
+	"gorm.io/gorm"
+)
+
+type statisticsRepository struct {
+	db *gorm.DB
+}
+
+func (r *statisticsRepository) CountProject(ctx context.Context, filter StatisticsFilter) (int64, error) {
+	query := r.db.WithContext(ctx).
+		Model(&model.Project{}).
+		Where("created_at >= ?", filter.CreatedFrom)
+	if filter.ProjectType != "" {
+		query = query.Where("type = ?", filter.ProjectType)
+	}
+	if filter.InspectorID != 0 {
+		query = query.Where(fmt.Sprintf(projectMemberQuery, "projects.id"), filter.InspectorID)
+	}
+
+	var total int64
+	err := query.Count(&total).Error
+
+	return total, translateError(err)
+}
+
+func (r *statisticsRepository) SumLedger(ctx context.Context, filter StatisticsFilter) (int64, error) {
+	whereQuery := "IL.deleted_at IS NULL AND IL.created_at >= ? AND IL.status = ?"
+	whereQueryArgs := []interface{}{filter.CreatedFrom, model.Posted}
+	if filter.LedgerType != "" {
+		whereQuery += " AND IL.ledger_type = ?"
+		whereQueryArgs = append(whereQueryArgs, filter.LedgerType)
+	}
+	if filter.InspectorID != 0 {
+		whereQuery += " AND IL.inspector_id = ?"
+		whereQueryArgs = append(whereQueryArgs, filter.InspectorID)
+	}
+	if filter.ProjectID != 0 {
+		whereQuery += " AND IL.project_id = ?"
+		whereQueryArgs = append(whereQueryArgs, filter.ProjectID)
+	}
+
+	joinQuery := "INNER JOIN projects P ON P.id = IL.project_id AND 1=?"
+	joinQueryArgs := []interface{}{1}
+	if filter.ProjectType != "" {
+		joinQuery += " AND P.type = ?"
+		joinQueryArgs = append(joinQueryArgs, filter.ProjectType)
+	}
+
+	var total int64
+	err := r.db.WithContext(ctx).
+		Table("ledgers IL").
+		Select("COALESCE(SUM(IL.total_price), 0) AS total").
+		Joins(joinQuery, joinQueryArgs...).
+		Where(whereQuery, whereQueryArgs...).
+		Scan(&total).Error
+
+	return total, translateError(err)
+}
+
+func (r *statisticsRepository) GetInspectorStats(
+	ctx context.Context,
+	inspectorID int64,
+	intervalMonth int64,
+) (model.MqtInspectorStats, error) {
+	var stats model.MqtInspectorStats
+	err := r.db.WithContext(ctx).
+		Where("inspector_id = ? AND interval_month = ?", inspectorID, intervalMonth).
+		Take(&stats).Error
+
+	return stats, translateError(err)
+}
+
+// ReplaceInspectorStats deletes every materialized statistics before inserting the new ones
+func (r *statisticsRepository) ReplaceInspectorStats(ctx context.Context, stats []model.MqtInspectorStats) error {
+	db := r.db.WithContext(ctx)
+	if err := db.Unscoped().Where("1 = 1").Delete(&model.MqtInspectorStats{}).Error; err != nil {
+		return translateError(err)
+	}
+
+	if err := db.Unscoped().Where("1 = 1").Delete(&model.MqtProjectStats{}).Error; err != nil {
+		return translateError(err)
+	}
+
+	if len(stats) == 0 {
+		return nil
+	}
+
+	return translateError(db.Create(&stats).Error)
+}
@@ -0,0 +1,57 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"tigaputera-backend/src/model"
+
+	"gorm.io/gorm"
+)
+
+type loginChallengeRepository struct {
+	db *gorm.DB
+}
+
+func (r *loginChallengeRepository) GetByTokenHash(ctx context.Context, tokenHash string) (model.LoginChallenge, error) {
+	var challenge model.LoginChallenge
+	err := r.db.WithContext(ctx).Where("token_hash = ?", tokenHash).Take(&challenge).Error
+
+	return challenge, translateError(err)
+}
+
+func (r *loginChallengeRepository) Create(ctx context.Context, challenge *model.LoginChallenge) error {
+	return translateError(r.db.WithContext(ctx).Create(challenge).Error)
+}
+
+func (r *loginChallengeRepository) Use(ctx context.Context, id int64) error {
+	return updateResult(r.db.WithContext(ctx).
+		Model(&model.LoginChallenge{}).
+		Where("id = ? AND used_at IS NULL", id).
+		Update("used_at", time.Now().Unix()))
+}
+
+type recoveryCodeRepository struct {
+	db *gorm.DB
+}
+
+func (r *recoveryCodeRepository) Replace(ctx context.Context, userID int64, codes []model.RecoveryCode) error {
+	return translateError(r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ?", userID).Delete(&model.RecoveryCode{}).Error; err != nil {
+			return err
+		}
+
+		return tx.Create(&codes).Error
+	}))
+}
+
+func (r *recoveryCodeRepository) Use(ctx context.Context, userID int64, codeHash string) error {
+	return updateResult(r.db.WithContext(ctx).
+		Model(&model.RecoveryCode{}).
+		Where("user_id = ? AND code_hash = ? AND used_at IS NULL", userID, codeHash).
+		Update("used_at", time.Now().Unix()))
+}
+
+func (r *recoveryCodeRepository) DeleteByUser(ctx context.Context, userID int64) error {
+	return translateError(r.db.WithContext(ctx).Where("user_id = ?", userID).Delete(&model.RecoveryCode{}).Error)
+}
@@ -0,0 +1,157 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"tigaputera-backend/src/model"
+
+	"gorm.io/gorm"
+)
+
+type userRepository struct {
+	db *gorm.DB
+}
+
+func (r *userRepository) Get(ctx context.Context, id int64) (model.User, error) {
+	var user model.User
+	err := r.db.WithContext(ctx).First(&user, id).Error
+
+	return user, translateError(err)
+}
+
+func (r *userRepository) GetByUsername(ctx context.Context, username string) (model.User, error) {
+	var user model.User
+	err := r.db.WithContext(ctx).
+		Where("username = ?", username).
+		First(&user).Error
+
+	return user, translateError(err)
+}
+
+func (r *userRepository) List(ctx context.Context, filter UserFilter) ([]model.User, error) {
+	users := []model.User{}
+	query := r.filter(ctx, filter).Order("id")
+	if filter.Limit > 0 {
+		query = query.Limit(int(filter.Limit)).Offset(int(filter.Offset))
+	}
+
+	err := query.Find(&users).Error
+
+	return users, translateError(err)
+}
+
+func (r *userRepository) Count(ctx context.Context, filter UserFilter) (int64, error) {
+	var count int64
+	err := r.filter(ctx, filter).Count(&count).Error
+
+	return count, translateError(err)
+}
+
+func (r *userRepository) filter(ctx context.Context, filter UserFilter) *gorm.DB {
+	query := r.db.WithContext(ctx).Model(&model.User{})
+	if filter.Role != "" {
+		query = query.Where("role = ?", filter.Role)
+	}
+	if filter.ExcludeRole != "" {
+		query = query.Where("role <> ?", filter.ExcludeRole)
+	}
+	if filter.IsDeleted {
+		query = query.Unscoped().Where("deleted_at IS NOT NULL")
+	}
+
+	return query
+}
+
+func (r *userRepository) Create(ctx context.Context, user *model.User) error {
+	return translateError(r.db.WithContext(ctx).Create(user).Error)
+}
+
+func (r *userRepository) UpdatePassword(ctx context.Context, id int64, password string) error {
+	return updateResult(r.db.WithContext(ctx).
+		Model(&model.User{}).
+		Where("id = ?", id).
+		Updates(map[string]interface{}{
+			"password":            password,
+			"is_first_login":      false,
+			"password_changed_at": time.Now().Unix(),
+		}))
+}
+
+func (r *userRepository) UpdateTotpSecret(ctx context.Context, id int64, secret string) error {
+	return updateResult(r.db.WithContext(ctx).
+		Model(&model.User{}).
+		Where("id = ? AND totp_enabled_at IS NULL", id).
+		Updates(map[string]interface{}{
+			"totp_secret":         secret,
+			"totp_last_used_step": 0,
+		}))
+}
+
+func (r *userRepository) EnableTotp(ctx context.Context, id int64, step int64) error {
+	return updateResult(r.db.WithContext(ctx).
+		Model(&model.User{}).
+		Where("id = ? AND totp_enabled_at IS NULL AND totp_secret <> ''", id).
+		Updates(map[string]interface{}{
+			"totp_enabled_at":     time.Now().Unix(),
+			"totp_last_used_step": step,
+		}))
+}
+
+func (r *userRepository) UseTotpStep(ctx context.Context, id int64, step int64) error {
+	return updateResult(r.db.WithContext(ctx).
+		Model(&model.User{}).
+		Where("id = ? AND totp_last_used_step < ?", id, step).
+		Update("totp_last_used_step", step))
+}
+
+func (r *userRepository) DisableTotp(ctx context.Context, id int64) error {
+	return updateResult(r.db.WithContext(ctx).
+		Model(&model.User{}).
+		Where("id = ?", id).
+		Updates(map[string]interface{}{
+			"totp_secret":         "",
+			"totp_enabled_at":     nil,
+			"totp_last_used_step": 0,
+		}))
+}
+
+func (r *userRepository) UpdateRole(ctx context.Context, id int64, role model.Role) error {
+	return updateResult(r.db.WithContext(ctx).
+		Model(&model.User{}).
+		Where("id = ?", id).
+		Update("role", role))
+}
+
+func (r *userRepository) UpdateName(ctx context.Context, id int64, name string) error {
+	return updateResult(r.db.WithContext(ctx).
+		Model(&model.User{}).
+		Where("id = ?", id).
+		Update("name", name))
+}
+
+func (r *userRepository) ResetPassword(ctx context.Context, id int64, password string) error {
+	return updateResult(r.db.WithContext(ctx).
+		Model(&model.User{}).
+		Where("id = ?", id).
+		Updates(map[string]interface{}{
+			"password":            password,
+			"is_first_login":      true,
+			"password_changed_at": time.Now().Unix(),
+		}))
+}
+
+func (r *userRepository) Delete(ctx context.Context, id int64) error {
+	return updateResult(r.db.WithContext(ctx).Delete(&model.User{}, id))
+}
+
+func (r *userRepository) Restore(ctx context.Context, id int64) error {
+	return updateResult(r.db.WithContext(ctx).
+		Unscoped().
+		Model(&model.User{}).
+		Where("id = ? AND deleted_at IS NOT NULL", id).
+		Updates(map[string]interface{}{
+			"deleted_at": nil,
+			"deleted_by": nil,
+		}))
+}
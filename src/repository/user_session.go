@@ -0,0 +1,72 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"tigaputera-backend/src/model"
+
+	"gorm.io/gorm"
+)
+
+type userSessionRepository struct {
+	db *gorm.DB
+}
+
+func (r *userSessionRepository) Get(ctx context.Context, id int64) (model.UserSession, error) {
+	var session model.UserSession
+	err := r.db.WithContext(ctx).Where("id = ?", id).Take(&session).Error
+
+	return session, translateError(err)
+}
+
+func (r *userSessionRepository) GetByTokenHash(ctx context.Context, tokenHash string) (model.UserSession, error) {
+	var session model.UserSession
+	err := r.db.WithContext(ctx).
+		Where("refresh_token_hash = ? OR previous_token_hash = ?", tokenHash, tokenHash).
+		Take(&session).Error
+
+	return session, translateError(err)
+}
+
+func (r *userSessionRepository) Create(ctx context.Context, session *model.UserSession) error {
+	return translateError(r.db.WithContext(ctx).Create(session).Error)
+}
+
+func (r *userSessionRepository) Rotate(
+	ctx context.Context,
+	id int64,
+	tokenHash string,
+	newTokenHash string,
+	expiresAt int64,
+) error {
+	return updateResult(r.db.WithContext(ctx).
+		Model(&model.UserSession{}).
+		Where("id = ? AND refresh_token_hash = ? AND revoked_at IS NULL", id, tokenHash).
+		Updates(map[string]interface{}{
+			"refresh_token_hash":  newTokenHash,
+			"previous_token_hash": tokenHash,
+			"expires_at":          expiresAt,
+		}))
+}
+
+func (r *userSessionRepository) Revoke(ctx context.Context, id int64) error {
+	return translateError(r.db.WithContext(ctx).
+		Model(&model.UserSession{}).
+		Where("id = ? AND revoked_at IS NULL", id).
+		Update("revoked_at", time.Now().Unix()).Error)
+}
+
+func (r *userSessionRepository) RevokeByUser(ctx context.Context, userID int64) error {
+	return translateError(r.db.WithContext(ctx).
+		Model(&model.UserSession{}).
+		Where("user_id = ? AND revoked_at IS NULL", userID).
+		Update("revoked_at", time.Now().Unix()).Error)
+}
+
+func (r *userSessionRepository) RevokeOthers(ctx context.Context, userID int64, keptID int64) error {
+	return translateError(r.db.WithContext(ctx).
+		Model(&model.UserSession{}).
+		Where("user_id = ? AND id <> ? AND revoked_at IS NULL", userID, keptID).
+		Update("revoked_at", time.Now().Unix()).Error)
+}
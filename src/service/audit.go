@@ -0,0 +1,55 @@
+package service
+
+import (
+	"context"
+
+	errors "tigaputera-backend/sdk/error"
+	"tigaputera-backend/src/model"
+	"tigaputera-backend/src/repository"
+)
+
+type AuditService interface {
+	// GetListAuditLog returns the audit logs of the writes, the latest first
+	GetListAuditLog(ctx context.Context, param *model.AuditLogParam) ([]model.AuditLog, error)
+}
+
+type auditService struct {
+	repo repository.Interface
+}
+
+func NewAuditService(repo repository.Interface) AuditService {
+	return &auditService{repo: repo}
+}
+
+func (s *auditService) GetListAuditLog(ctx context.Context, param *model.AuditLogParam) ([]model.AuditLog, error) {
+	logs := []model.AuditLog{}
+	if param.CreatedFrom != 0 && param.CreatedTo != 0 && param.CreatedFrom > param.CreatedTo {
+		return logs, errors.BadRequest("Tanggal awal harus sebelum tanggal akhir")
+	}
+
+	param.SetDefaultPagination()
+
+	filter := repository.AuditLogFilter{
+		Entity:      param.Entity,
+		EntityID:    param.EntityID,
+		UserID:      param.UserID,
+		CreatedFrom: param.CreatedFrom,
+		CreatedTo:   param.CreatedTo,
+		Limit:       param.Limit,
+		Offset:      param.Offset,
+	}
+
+	logs, err := s.repo.AuditLog().List(ctx, filter)
+	if err != nil {
+		return logs, errors.InternalServerError(err.Error())
+	}
+
+	param.TotalElement, err = s.repo.AuditLog().Count(ctx, filter)
+	if err != nil {
+		return logs, errors.InternalServerError(err.Error())
+	}
+
+	param.ProcessPagination(int64(len(logs)))
+
+	return logs, nil
+}
@@ -0,0 +1,217 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"tigaputera-backend/sdk/auth"
+	errors "tigaputera-backend/sdk/error"
+	"tigaputera-backend/sdk/number"
+	"tigaputera-backend/src/model"
+	"tigaputera-backend/src/repository"
+)
+
+func (s *ledgerService) GetListExpenditureApproval(
+	ctx context.Context,
+	user auth.User,
+	param *model.ExpenditureApprovalParam,
+) ([]model.ExpenditureApprovalResponse, error) {
+	approvals := []model.ExpenditureApprovalResponse{}
+
+	if param.Status == "" {
+		param.Status = string(model.Pending)
+	}
+
+	if !model.IsLedgerStatusCorrect(param.Status) {
+		return approvals, errors.BadRequest("Status harus Diposting, Menunggu Persetujuan, atau Ditolak")
+	}
+
+	param.SetDefaultPagination()
+
+	filter := repository.LedgerFilter{
+		ProjectID:  param.ProjectID,
+		LedgerType: model.Credit,
+		Status:     model.LedgerStatus(param.Status),
+		IsCanceled: boolPtr(false),
+		Limit:      param.Limit,
+		Offset:     param.Offset,
+	}
+	if !hasPermission(user, model.LedgerReadAll) {
+		filter.InspectorID = user.ID
+	}
+
+	ledgers, err := s.repo.Ledger().List(ctx, filter)
+	if err != nil {
+		return approvals, errors.InternalServerError(err.Error())
+	}
+
+	param.TotalElement, err = s.repo.Ledger().Count(ctx, filter)
+	if err != nil {
+		return approvals, errors.InternalServerError(err.Error())
+	}
+
+	for _, ledger := range ledgers {
+		approvals = append(approvals, s.getExpenditureApprovalResponse(ledger))
+	}
+
+	param.ProcessPagination(int64(len(approvals)))
+
+	return approvals, nil
+}
+
+func (s *ledgerService) getExpenditureApprovalResponse(ledger model.Ledger) model.ExpenditureApprovalResponse {
+	return model.ExpenditureApprovalResponse{
+		ID:              ledger.ID,
+		Timestamp:       ledger.CreatedAt,
+		ExpenditureID:   getInt64(ledger.RefID),
+		ExpenditureName: ledger.Ref,
+		InspectorName:   ledger.Inspector.Name,
+		Name:            *ledger.Description,
+		Price:           number.ConvertToRupiah(ledger.Price),
+		Amount:          ledger.Amount,
+		TotalPrice:      number.ConvertToRupiah(ledger.TotalPrice),
+		ReceiptURL:      s.signedURL.Sign(ledger.ReceiptKey),
+		ThumbnailURL:    s.signedURL.Sign(ledger.ThumbnailKey),
+		Status:          model.GetLedgerStatusStyle(ledger.Status),
+		RejectionReason: ledger.RejectionReason,
+	}
+}
+
+func (s *ledgerService) ApproveExpenditureTransaction(
+	ctx context.Context,
+	user auth.User,
+	param model.ExpenditureApprovalParam,
+) error {
+	pendingLedger, err := s.repo.Ledger().Get(ctx, repository.LedgerFilter{
+		ID:         param.ID,
+		ProjectID:  param.ProjectID,
+		RefID:      param.ExpenditureID,
+		LedgerType: model.Credit,
+		Status:     model.Pending,
+		IsCanceled: boolPtr(false),
+	})
+	if repository.IsNotFound(err) {
+		return errors.NotFound("transaksi pengeluaran proyek tidak ditemukan")
+	} else if err != nil {
+		return errors.InternalServerError(err.Error())
+	}
+
+	// a role with both the ledger write and the approval can't approve its own expenditures
+	if pendingLedger.InspectorID == user.ID || getInt64(pendingLedger.CreatedBy) == user.ID {
+		return errors.BadRequest("Pengeluaran tidak dapat disetujui oleh pengajunya sendiri")
+	}
+
+	projectExpenditure, err := s.getProjectExpenditure(ctx, param.ProjectID, param.ExpenditureID)
+	if err != nil {
+		return err
+	}
+
+	if err := s.checkApprovalProject(ctx, s.repo, pendingLedger); err != nil {
+		return err
+	}
+
+	inspectorID := pendingLedger.InspectorID
+	return s.postLedger(ctx, inspectorID, param.ProjectID, func(tx repository.Interface, latestLedger model.Ledger) error {
+		if err := s.checkApprovalProject(ctx, tx, pendingLedger); err != nil {
+			return err
+		}
+
+		// the other pending expenditures of the inspector keep their hold on the balance
+		pendingHold, err := s.getPendingHold(ctx, tx, inspectorID, param.ProjectID)
+		if err != nil {
+			return errors.InternalServerError(err.Error())
+		}
+
+		totalPrice := -pendingLedger.TotalPrice
+		prevProjectBalance := *latestLedger.FinalProjectBalance
+		if prevProjectBalance-(pendingHold-totalPrice) < totalPrice {
+			return errors.BadRequest("Saldo pengawas tidak mencukupi")
+		}
+
+		// the ledger chain is ordered by posting time then id, the approved expenditure has an older id
+		// than the latest ledger so it is posted a second later when both fall in the same second
+		now := time.Now().Unix()
+		postedAt := getPostedAt(latestLedger)
+		if postedAt == latestLedger.PostedAt && latestLedger.ID > pendingLedger.ID {
+			postedAt++
+		}
+
+		prevInspectorBalance := *latestLedger.FinalInspectorBalance
+		finalInspectorBalance := prevInspectorBalance - totalPrice
+		finalProjectBalance := prevProjectBalance - totalPrice
+		err = tx.Ledger().Approve(ctx, model.Ledger{
+			ID:                      pendingLedger.ID,
+			PostedAt:                postedAt,
+			CurrentInspectorBalance: &prevInspectorBalance,
+			FinalInspectorBalance:   &finalInspectorBalance,
+			CurrentProjectBalance:   &prevProjectBalance,
+			FinalProjectBalance:     &finalProjectBalance,
+			ReviewedBy:              &user.ID,
+			ReviewedAt:              &now,
+			UpdatedBy:               &user.ID,
+		})
+		if repository.IsNotFound(err) {
+			// canceled or reviewed by another request while waiting for the lock
+			return errors.NotFound("transaksi pengeluaran proyek tidak ditemukan")
+		} else if err != nil {
+			return errors.InternalServerError(err.Error())
+		}
+
+		if err := s.addExpenditureTotal(ctx, tx, projectExpenditure, pendingLedger.TotalPrice, user.ID); err != nil {
+			return errors.InternalServerError(err.Error())
+		}
+
+		return nil
+	})
+}
+
+// checkApprovalProject refuses the approval when the project was finished or postponed, or the inspector removed
+// from it, since the expenditure was submitted, it is checked again under the ledger lock
+func (s *ledgerService) checkApprovalProject(
+	ctx context.Context,
+	tx repository.Interface,
+	pendingLedger model.Ledger,
+) error {
+	project, err := tx.Project().Get(ctx, pendingLedger.ProjectID)
+	if repository.IsNotFound(err) {
+		return errors.NotFound("proyek tidak ditemukan")
+	} else if err != nil {
+		return errors.InternalServerError(err.Error())
+	} else if !project.IsRunning() {
+		return errors.BadRequest("Pengeluaran hanya dapat disetujui pada proyek yang sedang berjalan")
+	}
+
+	isMember, err := s.isProjectMember(ctx, tx, pendingLedger.ProjectID, pendingLedger.InspectorID)
+	if err != nil {
+		return errors.InternalServerError(err.Error())
+	} else if !isMember {
+		return errors.BadRequest("Pengawas bukan lagi anggota proyek, pengeluaran tidak dapat disetujui")
+	}
+
+	return nil
+}
+
+func (s *ledgerService) RejectExpenditureTransaction(
+	ctx context.Context,
+	user auth.User,
+	param model.ExpenditureApprovalParam,
+	body model.RejectExpenditureBody,
+) error {
+	now := time.Now().Unix()
+	err := s.repo.Ledger().Reject(ctx, model.Ledger{
+		ID:              param.ID,
+		ProjectID:       param.ProjectID,
+		RefID:           &param.ExpenditureID,
+		RejectionReason: &body.Reason,
+		ReviewedBy:      &user.ID,
+		ReviewedAt:      &now,
+		UpdatedBy:       &user.ID,
+	})
+	if repository.IsNotFound(err) {
+		return errors.NotFound("transaksi pengeluaran proyek tidak ditemukan")
+	} else if err != nil {
+		return errors.InternalServerError(err.Error())
+	}
+
+	return nil
+}
@@ -0,0 +1,300 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"tigaputera-backend/sdk/auth"
+	errors "tigaputera-backend/sdk/error"
+	"tigaputera-backend/sdk/file"
+	"tigaputera-backend/sdk/number"
+	"tigaputera-backend/src/model"
+	"tigaputera-backend/src/repository"
+)
+
+func (s *ledgerService) CreateFundTransfer(
+	ctx context.Context,
+	user auth.User,
+	param model.FundTransferParam,
+	body model.CreateFundTransferBody,
+) error {
+	project, err := s.repo.Project().Get(ctx, param.ProjectID)
+	if repository.IsNotFound(err) {
+		return errors.NotFound("proyek tidak ditemukan")
+	} else if err != nil {
+		return errors.InternalServerError(err.Error())
+	} else if !project.IsRunning() {
+		return errors.BadRequest("Dana hanya dapat dikirim ke proyek yang sedang berjalan")
+	}
+
+	// the funds go to the lead unless another member is chosen
+	inspectorID := project.InspectorID
+	if body.InspectorID != 0 {
+		if !project.IsMember(body.InspectorID) {
+			return errors.BadRequest("Pengawas bukan anggota proyek")
+		}
+		inspectorID = body.InspectorID
+	}
+
+	transfer := model.FundTransfer{
+		ProjectID:   project.ID,
+		SenderID:    user.ID,
+		InspectorID: inspectorID,
+		Amount:      body.Amount,
+		Note:        body.Note,
+		Status:      model.TransferPending,
+		CreatedBy:   &user.ID,
+		UpdatedBy:   &user.ID,
+	}
+
+	if err := s.repo.FundTransfer().Create(ctx, &transfer); err != nil {
+		return errors.InternalServerError(err.Error())
+	}
+
+	return nil
+}
+
+func (s *ledgerService) GetListFundTransfer(
+	ctx context.Context,
+	user auth.User,
+	param *model.FundTransferParam,
+) ([]model.FundTransferResponse, error) {
+	transferResponses := []model.FundTransferResponse{}
+
+	param.SetDefaultPagination()
+
+	filter := repository.FundTransferFilter{
+		ProjectID: param.ProjectID,
+		Limit:     param.Limit,
+		Offset:    param.Offset,
+	}
+	if !hasPermission(user, model.LedgerReadAll) {
+		filter.InspectorID = user.ID
+	}
+
+	transfers, err := s.repo.FundTransfer().List(ctx, filter)
+	if err != nil {
+		return transferResponses, errors.InternalServerError(err.Error())
+	}
+
+	param.TotalElement, err = s.repo.FundTransfer().Count(ctx, filter)
+	if err != nil {
+		return transferResponses, errors.InternalServerError(err.Error())
+	}
+
+	for _, transfer := range transfers {
+		transferResponses = append(transferResponses, s.getFundTransferResponse(transfer))
+	}
+
+	param.ProcessPagination(int64(len(transferResponses)))
+
+	return transferResponses, nil
+}
+
+func (s *ledgerService) getFundTransferResponse(transfer model.FundTransfer) model.FundTransferResponse {
+	return model.FundTransferResponse{
+		ID:            transfer.ID,
+		Timestamp:     transfer.CreatedAt,
+		ProjectName:   transfer.Project.Name,
+		SenderName:    transfer.Sender.Name,
+		InspectorName: transfer.Inspector.Name,
+		Amount:        number.ConvertToRupiah(transfer.Amount),
+		Note:          transfer.Note,
+		Status:        model.GetTransferStatusStyle(transfer.Status),
+		ReceiptURL:    s.signedURL.Sign(transfer.ReceiptKey),
+		ConfirmedAt:   transfer.ConfirmedAt,
+	}
+}
+
+func (s *ledgerService) ConfirmFundTransfer(
+	ctx context.Context,
+	user auth.User,
+	param model.FundTransferParam,
+	attachmentFiles []*file.File,
+) error {
+	transfer, err := s.repo.FundTransfer().Get(ctx, repository.FundTransferFilter{
+		ID:        param.ID,
+		ProjectID: param.ProjectID,
+		Status:    model.TransferPending,
+	})
+	if repository.IsNotFound(err) {
+		return errors.NotFound("transfer dana tidak ditemukan")
+	} else if err != nil {
+		return errors.InternalServerError(err.Error())
+	} else if transfer.InspectorID != user.ID {
+		return errors.NotFound("transfer dana tidak ditemukan")
+	}
+
+	if err := s.checkTransferInspector(ctx, s.repo, transfer); err != nil {
+		return err
+	}
+
+	attachments, err := s.uploadAttachments(ctx, attachmentFiles, 0, fmt.Sprintf(
+		"%s_%d_transfer_%d_%d", // username_projectId_transfer_transferId_timestamp
+		user.Username,
+		transfer.ProjectID,
+		transfer.ID,
+		time.Now().Unix(),
+	), "incomes")
+	if err != nil {
+		return err
+	}
+
+	return s.postFundTransfer(ctx, transfer, attachments)
+}
+
+// checkTransferInspector refuses the transfer when the project was reassigned or the inspector removed from it
+// since the transfer was sent, it is checked again under the ledger lock
+func (s *ledgerService) checkTransferInspector(
+	ctx context.Context,
+	tx repository.Interface,
+	transfer model.FundTransfer,
+) error {
+	isMember, err := s.isProjectMember(ctx, tx, transfer.ProjectID, transfer.InspectorID)
+	if err != nil {
+		return errors.InternalServerError(err.Error())
+	} else if !isMember {
+		return errors.BadRequest("Pengawas bukan lagi anggota proyek, transfer dana tidak dapat dikonfirmasi")
+	}
+
+	return nil
+}
+
+// postFundTransfer posts the outgoing leg of the sender and the income of the inspector
+// in the same transaction, both legs refer to the transfer
+func (s *ledgerService) postFundTransfer(
+	ctx context.Context,
+	transfer model.FundTransfer,
+	attachments []model.LedgerAttachment,
+) error {
+	return s.repo.Transaction(ctx, func(tx repository.Interface) error {
+		if err := s.lockLedger(ctx, tx, transfer.ProjectID, transfer.SenderID, transfer.InspectorID); err != nil {
+			return err
+		}
+
+		if err := s.checkTransferInspector(ctx, tx, transfer); err != nil {
+			return err
+		}
+
+		now := time.Now().Unix()
+		err := tx.FundTransfer().UpdateStatus(ctx, model.FundTransfer{
+			ID:          transfer.ID,
+			ProjectID:   transfer.ProjectID,
+			Status:      model.TransferConfirmed,
+			ReceiptKey:  attachments[0].ObjectKey,
+			ConfirmedAt: &now,
+			UpdatedBy:   &transfer.InspectorID,
+		}, model.TransferPending)
+		if repository.IsNotFound(err) {
+			// confirmed or canceled by another request while waiting for the lock
+			return errors.NotFound("transfer dana tidak ditemukan")
+		} else if err != nil {
+			return errors.InternalServerError(err.Error())
+		}
+
+		if err := s.insertTransferLegs(ctx, tx, transfer, attachments); err != nil {
+			return errors.InternalServerError(err.Error())
+		}
+
+		return nil
+	})
+}
+
+func (s *ledgerService) insertTransferLegs(
+	ctx context.Context,
+	tx repository.Interface,
+	transfer model.FundTransfer,
+	attachments []model.LedgerAttachment,
+) error {
+	senderLedger, err := s.getLatestLedger(ctx, tx, transfer.SenderID, transfer.ProjectID)
+	if err != nil {
+		return err
+	}
+
+	inspectorLedger, err := s.getLatestLedger(ctx, tx, transfer.InspectorID, transfer.ProjectID)
+	if err != nil {
+		return err
+	}
+
+	senderDesc := "Transfer ke " + transfer.Inspector.Name
+	prevSenderBalance := *senderLedger.FinalInspectorBalance
+	finalSenderBalance := prevSenderBalance - transfer.Amount
+	prevSenderProjectBalance := *senderLedger.FinalProjectBalance
+	finalSenderProjectBalance := prevSenderProjectBalance - transfer.Amount
+	senderLeg := model.Ledger{
+		InspectorID:             transfer.SenderID,
+		ProjectID:               transfer.ProjectID,
+		LedgerType:              model.Transfer,
+		RefID:                   &transfer.ID,
+		Ref:                     transfer.Inspector.Name,
+		Description:             &senderDesc,
+		Amount:                  1,
+		Price:                   -transfer.Amount,
+		TotalPrice:              -transfer.Amount,
+		CurrentInspectorBalance: &prevSenderBalance,
+		FinalInspectorBalance:   &finalSenderBalance,
+		CurrentProjectBalance:   &prevSenderProjectBalance,
+		FinalProjectBalance:     &finalSenderProjectBalance,
+		PostedAt:                getPostedAt(senderLedger),
+		TransferID:              &transfer.ID,
+		CreatedBy:               &transfer.InspectorID,
+	}
+
+	senderLeg.SetAttachments(attachments)
+
+	if err := tx.Ledger().Create(ctx, &senderLeg); err != nil {
+		return err
+	}
+
+	inspectorDesc := "Pemasukan"
+	if transfer.Note != "" {
+		inspectorDesc = transfer.Note
+	}
+	prevInspectorBalance := *inspectorLedger.FinalInspectorBalance
+	finalInspectorBalance := prevInspectorBalance + transfer.Amount
+	prevProjectBalance := *inspectorLedger.FinalProjectBalance
+	finalProjectBalance := prevProjectBalance + transfer.Amount
+	inspectorLeg := model.Ledger{
+		InspectorID:             transfer.InspectorID,
+		ProjectID:               transfer.ProjectID,
+		LedgerType:              model.Debit,
+		RefID:                   &transfer.ID,
+		Ref:                     transfer.Sender.Name,
+		Description:             &inspectorDesc,
+		Amount:                  1,
+		Price:                   transfer.Amount,
+		TotalPrice:              transfer.Amount,
+		CurrentInspectorBalance: &prevInspectorBalance,
+		FinalInspectorBalance:   &finalInspectorBalance,
+		CurrentProjectBalance:   &prevProjectBalance,
+		FinalProjectBalance:     &finalProjectBalance,
+		PostedAt:                getPostedAt(inspectorLedger),
+		TransferID:              &transfer.ID,
+		CreatedBy:               &transfer.InspectorID,
+	}
+
+	inspectorLeg.SetAttachments(attachments)
+
+	return s.insertIncome(ctx, tx, inspectorLeg)
+}
+
+func (s *ledgerService) CancelFundTransfer(
+	ctx context.Context,
+	user auth.User,
+	param model.FundTransferParam,
+) error {
+	err := s.repo.FundTransfer().UpdateStatus(ctx, model.FundTransfer{
+		ID:        param.ID,
+		ProjectID: param.ProjectID,
+		Status:    model.TransferCanceled,
+		UpdatedBy: &user.ID,
+	}, model.TransferPending)
+	if repository.IsNotFound(err) {
+		return errors.NotFound("transfer dana tidak ditemukan")
+	} else if err != nil {
+		return errors.InternalServerError(err.Error())
+	}
+
+	return nil
+}
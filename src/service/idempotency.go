@@ -0,0 +1,87 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	errors "tigaputera-backend/sdk/error"
+	"tigaputera-backend/src/model"
+	"tigaputera-backend/src/repository"
+)
+
+// how long the response of an idempotent request is replayed for its retries
+const idempotencyKeyTTL = 24 * time.Hour
+
+// how long a key stays in progress before a retry takes it over, longer than the request timeout
+const idempotencyKeyStaleTimeout = 10 * time.Minute
+
+type IdempotencyService interface {
+	// Acquire reserves the key of the user for the request. When the key is already used,
+	// it returns the completed key whose response has to be replayed.
+	Acquire(ctx context.Context, idempotencyKey *model.IdempotencyKey) (*model.IdempotencyKey, error)
+	// Release frees the key, so a retry of the request is processed again
+	Release(ctx context.Context, idempotencyKey model.IdempotencyKey) error
+	// Complete stores the response replayed to the retries of the request
+	Complete(ctx context.Context, idempotencyKey model.IdempotencyKey) error
+}
+
+type idempotencyService struct {
+	repo repository.Interface
+}
+
+func NewIdempotencyService(repo repository.Interface) IdempotencyService {
+	return &idempotencyService{repo: repo}
+}
+
+func (s *idempotencyService) Acquire(
+	ctx context.Context,
+	idempotencyKey *model.IdempotencyKey,
+) (*model.IdempotencyKey, error) {
+	now := time.Now()
+	idempotencyKey.ExpiresAt = now.Add(idempotencyKeyTTL).Unix()
+
+	if err := s.repo.IdempotencyKey().DeleteExpired(
+		ctx,
+		idempotencyKey.UserID,
+		idempotencyKey.Key,
+		now.Unix(),
+		now.Add(-idempotencyKeyStaleTimeout).Unix(),
+	); err != nil {
+		return nil, errors.InternalServerError(err.Error())
+	}
+
+	isCreated, err := s.repo.IdempotencyKey().Create(ctx, idempotencyKey)
+	if err != nil {
+		return nil, errors.InternalServerError(err.Error())
+	} else if isCreated {
+		return nil, nil
+	}
+
+	storedKey, err := s.repo.IdempotencyKey().Get(ctx, idempotencyKey.UserID, idempotencyKey.Key)
+	if repository.IsNotFound(err) {
+		// the first request failed and released the key in the meantime
+		return nil, errors.Conflict("Request sebelumnya gagal diproses, silakan coba lagi")
+	} else if err != nil {
+		return nil, errors.InternalServerError(err.Error())
+	}
+
+	if storedKey.Method != idempotencyKey.Method || storedKey.Path != idempotencyKey.Path {
+		return nil, errors.BadRequest("Idempotency-Key sudah digunakan untuk request lain")
+	} else if storedKey.RequestHash != idempotencyKey.RequestHash {
+		return nil, errors.UnprocessableEntity("Idempotency-Key sudah digunakan dengan isi request yang berbeda")
+	}
+
+	if !storedKey.IsCompleted {
+		return nil, errors.Conflict("Request dengan Idempotency-Key yang sama sedang diproses")
+	}
+
+	return &storedKey, nil
+}
+
+func (s *idempotencyService) Release(ctx context.Context, idempotencyKey model.IdempotencyKey) error {
+	return s.repo.IdempotencyKey().Delete(ctx, idempotencyKey.ID)
+}
+
+func (s *idempotencyService) Complete(ctx context.Context, idempotencyKey model.IdempotencyKey) error {
+	return s.repo.IdempotencyKey().Complete(ctx, idempotencyKey)
+}
@@ -0,0 +1,878 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"tigaputera-backend/sdk/auth"
+	errors "tigaputera-backend/sdk/error"
+	"tigaputera-backend/sdk/file"
+	"tigaputera-backend/sdk/imaging"
+	"tigaputera-backend/sdk/number"
+	"tigaputera-backend/sdk/signedurl"
+	"tigaputera-backend/sdk/storage"
+	"tigaputera-backend/src/model"
+	"tigaputera-backend/src/repository"
+)
+
+type LedgerService interface {
+	CreateIncomeTransaction(
+		ctx context.Context,
+		user auth.User,
+		param model.LedgerParam,
+		body model.CreateProjectIncomeBody,
+		attachmentFiles []*file.File,
+	) error
+	CancelIncomeTransaction(ctx context.Context, user auth.User, param model.LedgerTransactionParam) error
+	// CreateExpenditureTransaction returns true when the expenditure waits for a director approval
+	CreateExpenditureTransaction(
+		ctx context.Context,
+		user auth.User,
+		param model.ExpenditureDetailParam,
+		body model.CreateExpenditureDetailBody,
+		attachmentFiles []*file.File,
+	) (bool, error)
+	GetExpenditureTransactionList(
+		ctx context.Context,
+		user auth.User,
+		param model.ExpenditureDetailParam,
+	) (model.ExpenditureDetailListResponse, error)
+	DeleteExpenditureTransaction(ctx context.Context, user auth.User, param model.ExpenditureDetailParam) error
+	CorrectTransaction(
+		ctx context.Context,
+		user auth.User,
+		param model.LedgerTransactionParam,
+		body model.CorrectLedgerBody,
+	) error
+	GetProjectLedger(
+		ctx context.Context,
+		user auth.User,
+		param *model.LedgerParam,
+	) (model.ProjectLedgerResponse, error)
+	GetInspectorLedger(
+		ctx context.Context,
+		user auth.User,
+		param *model.LedgerParam,
+	) (model.InspectorLedgerResponse, error)
+	GetListExpenditureApproval(
+		ctx context.Context,
+		user auth.User,
+		param *model.ExpenditureApprovalParam,
+	) ([]model.ExpenditureApprovalResponse, error)
+	ApproveExpenditureTransaction(ctx context.Context, user auth.User, param model.ExpenditureApprovalParam) error
+	RejectExpenditureTransaction(
+		ctx context.Context,
+		user auth.User,
+		param model.ExpenditureApprovalParam,
+		body model.RejectExpenditureBody,
+	) error
+	CreateFundTransfer(
+		ctx context.Context,
+		user auth.User,
+		param model.FundTransferParam,
+		body model.CreateFundTransferBody,
+	) error
+	GetListFundTransfer(
+		ctx context.Context,
+		user auth.User,
+		param *model.FundTransferParam,
+	) ([]model.FundTransferResponse, error)
+	ConfirmFundTransfer(
+		ctx context.Context,
+		user auth.User,
+		param model.FundTransferParam,
+		attachmentFiles []*file.File,
+	) error
+	CancelFundTransfer(ctx context.Context, user auth.User, param model.FundTransferParam) error
+	// ReassignProject moves the project to another inspector with its balance, the ledger of the project
+	// keeps showing the transactions of the previous inspectors
+	ReassignProject(
+		ctx context.Context,
+		user auth.User,
+		param model.ProjectParam,
+		body model.ReassignProjectBody,
+	) error
+	GetListProjectAssignment(
+		ctx context.Context,
+		user auth.User,
+		param model.ProjectParam,
+	) ([]model.ProjectAssignmentResponse, error)
+	// the members of a project record their transactions on their own project balance,
+	// the balance of the project is the sum of them
+	GetListProjectMember(
+		ctx context.Context,
+		user auth.User,
+		param model.ProjectParam,
+	) ([]model.ProjectMemberResponse, error)
+	AddProjectMember(
+		ctx context.Context,
+		user auth.User,
+		param model.ProjectParam,
+		body model.AddProjectMemberBody,
+	) error
+	RemoveProjectMember(ctx context.Context, user auth.User, param model.ProjectMemberParam) error
+	GetLedgerAttachments(
+		ctx context.Context,
+		user auth.User,
+		param model.LedgerAttachmentParam,
+	) ([]model.LedgerAttachmentResponse, error)
+	AddLedgerAttachments(
+		ctx context.Context,
+		user auth.User,
+		param model.LedgerAttachmentParam,
+		attachmentFiles []*file.File,
+	) error
+	// DeleteLedgerAttachment removes an attachment of a transaction, the last one can't be removed
+	DeleteLedgerAttachment(ctx context.Context, user auth.User, param model.LedgerAttachmentParam) error
+	GetDuplicateReceiptReport(ctx context.Context) ([]model.DuplicateReceiptResponse, error)
+	// CheckLedgerIntegrity reports the broken balance chains and denormalized totals.
+	// When fix is true, the reported corrections are written in the same transaction.
+	CheckLedgerIntegrity(ctx context.Context, fix bool) (model.LedgerIntegrityReport, error)
+}
+
+type ledgerService struct {
+	repo      repository.Interface
+	storage   storage.Interface
+	signedURL signedurl.Interface
+	imaging   imaging.Interface
+}
+
+func NewLedgerService(
+	repo repository.Interface,
+	storage storage.Interface,
+	signedURL signedurl.Interface,
+	imaging imaging.Interface,
+) LedgerService {
+	return &ledgerService{
+		repo:      repo,
+		storage:   storage,
+		signedURL: signedURL,
+		imaging:   imaging,
+	}
+}
+
+type postLedgerFunc func(tx repository.Interface, latestLedger model.Ledger) error
+
+// postLedger runs post inside a transaction holding the ledger locks of the inspector and the project.
+// The latest ledger given to post is read after the locks are taken, so the balances computed from it
+// can't be based on a row that another request is about to chain from.
+func (s *ledgerService) postLedger(
+	ctx context.Context,
+	inspectorID int64,
+	projectID int64,
+	post postLedgerFunc,
+) error {
+	return s.repo.Transaction(ctx, func(tx repository.Interface) error {
+		if err := s.lockLedger(ctx, tx, projectID, inspectorID); err != nil {
+			return err
+		}
+
+		latestLedger, err := s.getLatestLedger(ctx, tx, inspectorID, projectID)
+		if err != nil {
+			return errors.InternalServerError(err.Error())
+		}
+
+		return post(tx, latestLedger)
+	})
+}
+
+func (s *ledgerService) lockLedger(
+	ctx context.Context,
+	tx repository.Interface,
+	projectID int64,
+	inspectorIDs ...int64,
+) error {
+	err := tx.Ledger().Lock(ctx, projectID, inspectorIDs...)
+	if repository.IsLockTimeout(err) {
+		return errors.Conflict("Transaksi lain sedang diproses, silakan coba lagi")
+	} else if err != nil {
+		return errors.InternalServerError(err.Error())
+	}
+
+	return nil
+}
+
+// getLatestLedger gets the balances the next ledger of the inspector in the project chains from,
+// the inspector balance comes from any project while the project balance only from this one
+func (s *ledgerService) getLatestLedger(
+	ctx context.Context,
+	tx repository.Interface,
+	inspectorID int64,
+	projectID int64,
+) (model.Ledger, error) {
+	latestLedger := model.Ledger{
+		CurrentInspectorBalance: new(int64),
+		FinalInspectorBalance:   new(int64),
+		CurrentProjectBalance:   new(int64),
+		FinalProjectBalance:     new(int64),
+		Project: model.Project{
+			ID:     projectID,
+			Income: new(int64),
+		},
+	}
+
+	inspectorLedger, err := tx.Ledger().Get(ctx, repository.LedgerFilter{
+		InspectorID: inspectorID,
+		Status:      model.Posted,
+	})
+	if repository.IsNotFound(err) {
+		return latestLedger, nil
+	} else if err != nil {
+		return latestLedger, err
+	}
+
+	latestLedger = inspectorLedger
+	latestLedger.CurrentProjectBalance = new(int64)
+	latestLedger.FinalProjectBalance = new(int64)
+
+	projectLedger, err := tx.Ledger().Get(ctx, repository.LedgerFilter{
+		InspectorID: inspectorID,
+		ProjectID:   projectID,
+		Status:      model.Posted,
+	})
+	if repository.IsNotFound(err) {
+		return latestLedger, nil
+	} else if err != nil {
+		return latestLedger, err
+	}
+
+	latestLedger.CurrentProjectBalance = projectLedger.CurrentProjectBalance
+	latestLedger.FinalProjectBalance = projectLedger.FinalProjectBalance
+
+	return latestLedger, nil
+}
+
+// getPostedAt gets the posting time of a ledger chained from latestLedger, an approved expenditure
+// can be posted ahead of the clock and the ledgers chained from it must not be ordered before it
+func getPostedAt(latestLedger model.Ledger) int64 {
+	now := time.Now().Unix()
+	if latestLedger.PostedAt > now {
+		return latestLedger.PostedAt
+	}
+
+	return now
+}
+
+func (s *ledgerService) CreateIncomeTransaction(
+	ctx context.Context,
+	user auth.User,
+	param model.LedgerParam,
+	body model.CreateProjectIncomeBody,
+	attachmentFiles []*file.File,
+) error {
+	projectID := param.ProjectID
+
+	// the income is recorded by a member of the project on their own project balance
+	project, err := s.repo.Project().Get(ctx, projectID)
+	if repository.IsNotFound(err) || (err == nil && !project.IsMember(user.ID)) {
+		return errors.NotFound("proyek tidak ditemukan")
+	} else if err != nil {
+		return errors.InternalServerError(err.Error())
+	}
+
+	attachments, err := s.uploadAttachments(ctx, attachmentFiles, 0, fmt.Sprintf(
+		"%s_%d_%d", // username_projectId_timestamp
+		user.Username,
+		projectID,
+		time.Now().Unix(),
+	), "incomes")
+	if err != nil {
+		return err
+	}
+
+	return s.postLedger(ctx, user.ID, projectID, func(tx repository.Interface, latestLedger model.Ledger) error {
+		ledgerDesc := "Pemasukan"
+		prevInspectorBalance := *latestLedger.FinalInspectorBalance
+		prevProjectBalance := *latestLedger.FinalProjectBalance
+		finalInspectorBalance := prevInspectorBalance + body.Amount
+		finalProjectBalance := prevProjectBalance + body.Amount
+		newLedger := model.Ledger{
+			InspectorID:             user.ID,
+			ProjectID:               projectID,
+			LedgerType:              model.Debit,
+			Ref:                     body.Ref,
+			Amount:                  1,
+			Price:                   body.Amount,
+			TotalPrice:              body.Amount,
+			Description:             &ledgerDesc,
+			CurrentInspectorBalance: &prevInspectorBalance,
+			FinalInspectorBalance:   &finalInspectorBalance,
+			CurrentProjectBalance:   &prevProjectBalance,
+			FinalProjectBalance:     &finalProjectBalance,
+			PostedAt:                getPostedAt(latestLedger),
+		}
+		newLedger.SetAttachments(attachments)
+
+		if err := s.insertIncome(ctx, tx, newLedger); err != nil {
+			return errors.InternalServerError(err.Error())
+		}
+
+		return nil
+	})
+}
+
+func (s *ledgerService) insertIncome(
+	ctx context.Context,
+	tx repository.Interface,
+	incomeTrans model.Ledger,
+) error {
+	if err := tx.Ledger().Create(ctx, &incomeTrans); err != nil {
+		return err
+	}
+
+	return tx.Project().AddIncome(ctx, incomeTrans.ProjectID, incomeTrans.TotalPrice, incomeTrans.InspectorID)
+}
+
+func (s *ledgerService) CreateExpenditureTransaction(
+	ctx context.Context,
+	user auth.User,
+	param model.ExpenditureDetailParam,
+	body model.CreateExpenditureDetailBody,
+	attachmentFiles []*file.File,
+) (bool, error) {
+	projectExpenditure, err := s.repo.ProjectExpenditure().GetOfInspector(ctx, user.ID, param.ExpenditureID)
+	if repository.IsNotFound(err) {
+		return false, errors.NotFound("pengeluaran proyek tidak ditemukan")
+	} else if err != nil {
+		return false, errors.InternalServerError(err.Error())
+	}
+
+	projectID := projectExpenditure.ProjectID
+	totalPrice := body.Price * body.Amount
+
+	// the balance is checked before the receipts are uploaded so a refused expenditure leaves no object behind,
+	// it is checked again under the ledger lock
+	latestLedger, err := s.getLatestLedger(ctx, s.repo, user.ID, projectID)
+	if err != nil {
+		return false, errors.InternalServerError(err.Error())
+	}
+	if err := s.checkExpenditureBalance(ctx, s.repo, user.ID, projectID, latestLedger, totalPrice); err != nil {
+		return false, err
+	}
+
+	attachments, err := s.uploadAttachments(ctx, attachmentFiles, 0, fmt.Sprintf(
+		"%s_%d_%d_%d", // username_projectId_expenditureId_timestamp
+		user.Username,
+		projectExpenditure.Project.ID,
+		projectExpenditure.ID,
+		time.Now().Unix(),
+	), "expenditures")
+	if err != nil {
+		return false, err
+	}
+
+	isPending := projectExpenditure.IsApprovalRequired(totalPrice)
+	err = s.postLedger(ctx, user.ID, projectID, func(tx repository.Interface, latestLedger model.Ledger) error {
+		if err := s.checkExpenditureBalance(ctx, tx, user.ID, projectID, latestLedger, totalPrice); err != nil {
+			return err
+		}
+
+		prevProjectBalance := *latestLedger.FinalProjectBalance
+
+		expenditureTransaction := model.Ledger{
+			InspectorID: user.ID,
+			ProjectID:   projectID,
+			LedgerType:  model.Credit,
+			RefID:       &projectExpenditure.ID,
+			Ref:         projectExpenditure.Name,
+			Description: &body.Name,
+			Amount:      body.Amount,
+			Price:       -body.Price,
+			TotalPrice:  -totalPrice,
+		}
+		expenditureTransaction.SetAttachments(attachments)
+
+		// pending expenditures are held from the balance but only posted once approved
+		if isPending {
+			expenditureTransaction.Status = model.Pending
+			if err := tx.Ledger().Create(ctx, &expenditureTransaction); err != nil {
+				return errors.InternalServerError(err.Error())
+			}
+
+			return nil
+		}
+
+		prevInspectorBalance := *latestLedger.FinalInspectorBalance
+		finalInspectorBalance := prevInspectorBalance - totalPrice
+		finalProjectBalance := prevProjectBalance - totalPrice
+		expenditureTransaction.CurrentInspectorBalance = &prevInspectorBalance
+		expenditureTransaction.FinalInspectorBalance = &finalInspectorBalance
+		expenditureTransaction.CurrentProjectBalance = &prevProjectBalance
+		expenditureTransaction.FinalProjectBalance = &finalProjectBalance
+		expenditureTransaction.PostedAt = getPostedAt(latestLedger)
+
+		if err := s.insertExpenditure(ctx, tx, expenditureTransaction, projectExpenditure); err != nil {
+			return errors.InternalServerError(err.Error())
+		}
+
+		return nil
+	})
+	if err != nil {
+		s.deleteAttachments(ctx, attachments)
+		return false, err
+	}
+
+	return isPending, nil
+}
+
+// checkExpenditureBalance refuses an expenditure above the project balance of the inspector
+// left by the expenditures waiting for approval
+func (s *ledgerService) checkExpenditureBalance(
+	ctx context.Context,
+	tx repository.Interface,
+	inspectorID int64,
+	projectID int64,
+	latestLedger model.Ledger,
+	totalPrice int64,
+) error {
+	pendingHold, err := s.getPendingHold(ctx, tx, inspectorID, projectID)
+	if err != nil {
+		return errors.InternalServerError(err.Error())
+	}
+
+	if *latestLedger.FinalProjectBalance-pendingHold < totalPrice {
+		return errors.BadRequest("Saldo anda tidak mencukupi")
+	}
+
+	return nil
+}
+
+// getPendingHold sums the expenditures of the inspector in the project that are waiting for approval
+func (s *ledgerService) getPendingHold(
+	ctx context.Context,
+	tx repository.Interface,
+	inspectorID int64,
+	projectID int64,
+) (int64, error) {
+	total, err := tx.Ledger().SumTotalPrice(ctx, repository.LedgerFilter{
+		InspectorID: inspectorID,
+		ProjectID:   projectID,
+		Status:      model.Pending,
+		IsCanceled:  boolPtr(false),
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return -total, nil
+}
+
+func (s *ledgerService) insertExpenditure(
+	ctx context.Context,
+	tx repository.Interface,
+	expenditureTrans model.Ledger,
+	projectExpenditure model.ProjectExpenditure,
+) error {
+	if err := tx.Ledger().Create(ctx, &expenditureTrans); err != nil {
+		return err
+	}
+
+	return s.addExpenditureTotal(
+		ctx,
+		tx,
+		projectExpenditure,
+		expenditureTrans.TotalPrice,
+		expenditureTrans.InspectorID,
+	)
+}
+
+// addExpenditureTotal adds a posted expenditure ledger to the expenditure total,
+// ledger total price is negative for an expenditure
+func (s *ledgerService) addExpenditureTotal(
+	ctx context.Context,
+	tx repository.Interface,
+	projectExpenditure model.ProjectExpenditure,
+	ledgerTotalPrice int64,
+	updatedBy int64,
+) error {
+	if err := tx.ProjectExpenditure().AddTotalPrice(
+		ctx,
+		projectExpenditure.ID,
+		-ledgerTotalPrice,
+		updatedBy,
+	); err != nil {
+		return err
+	}
+
+	return tx.Project().SetUpdatedBy(ctx, projectExpenditure.ProjectID, updatedBy)
+}
+
+func (s *ledgerService) getProjectExpenditure(
+	ctx context.Context,
+	projectID int64,
+	expenditureID int64,
+) (model.ProjectExpenditure, error) {
+	projectExpenditure, err := s.repo.ProjectExpenditure().Get(ctx, projectID, expenditureID)
+	if repository.IsNotFound(err) {
+		return projectExpenditure, errors.NotFound("pengeluaran proyek tidak ditemukan")
+	} else if err != nil {
+		return projectExpenditure, errors.InternalServerError(err.Error())
+	}
+
+	return projectExpenditure, nil
+}
+
+func (s *ledgerService) GetExpenditureTransactionList(
+	ctx context.Context,
+	user auth.User,
+	param model.ExpenditureDetailParam,
+) (model.ExpenditureDetailListResponse, error) {
+	var res model.ExpenditureDetailListResponse
+
+	projectExpenditure, err := s.getProjectExpenditure(ctx, param.ProjectID, param.ExpenditureID)
+	if err != nil {
+		return res, err
+	}
+
+	if canSee, err := s.canSeeProjectLedgerOf(ctx, user, projectExpenditure.ProjectID); err != nil {
+		return res, errors.InternalServerError(err.Error())
+	} else if !canSee {
+		return res, errors.NotFound("pengeluaran proyek tidak ditemukan")
+	}
+
+	inspector, err := s.repo.User().Get(ctx, projectExpenditure.Project.InspectorID)
+	if err != nil {
+		return res, errors.InternalServerError(err.Error())
+	}
+
+	inspectorIDs, err := s.getProjectInspectorIDs(ctx, projectExpenditure.Project)
+	if err != nil {
+		return res, errors.InternalServerError(err.Error())
+	}
+
+	ledgers, err := s.repo.Ledger().List(ctx, repository.LedgerFilter{
+		InspectorIDs: inspectorIDs,
+		ProjectID:    projectExpenditure.ProjectID,
+		RefID:        projectExpenditure.ID,
+		LedgerType:   model.Credit,
+		IsCanceled:   boolPtr(false),
+		Status:       model.Posted,
+	})
+	if err != nil {
+		return res, errors.InternalServerError(err.Error())
+	}
+
+	ledgerIDs := make([]int64, len(ledgers))
+	for i, ledger := range ledgers {
+		ledgerIDs[i] = ledger.ID
+	}
+
+	attachments, err := s.repo.LedgerAttachment().List(ctx, ledgerIDs...)
+	if err != nil {
+		return res, errors.InternalServerError(err.Error())
+	}
+
+	ledgerAttachments := map[int64][]model.LedgerAttachment{}
+	for _, attachment := range attachments {
+		ledgerAttachments[attachment.LedgerID] = append(ledgerAttachments[attachment.LedgerID], attachment)
+	}
+
+	expenditureTrans := []model.ExpenditureDetailList{}
+	var sumTotal int64
+	for _, expenditure := range ledgers {
+		expenditure.TotalPrice = -expenditure.TotalPrice
+		expenditure.Price = -expenditure.Price
+		expenditureTrans = append(
+			expenditureTrans,
+			s.getExpenditureDetailList(expenditure, ledgerAttachments[expenditure.ID]),
+		)
+		sumTotal += expenditure.TotalPrice
+	}
+
+	res = model.ExpenditureDetailListResponse{
+		ExpenditureName: projectExpenditure.Name,
+		ProjectName:     projectExpenditure.Project.Name,
+		InspectorName:   inspector.Name,
+		Details:         expenditureTrans,
+		SumTotal:        number.ConvertToRupiah(sumTotal),
+	}
+
+	return res, nil
+}
+
+func (s *ledgerService) getExpenditureDetailList(
+	expenditureTrans model.Ledger,
+	attachments []model.LedgerAttachment,
+) model.ExpenditureDetailList {
+	return model.ExpenditureDetailList{
+		ID:           expenditureTrans.ID,
+		Name:         *expenditureTrans.Description,
+		Price:        number.ConvertToRupiah(expenditureTrans.Price),
+		Amount:       expenditureTrans.Amount,
+		TotalPrice:   number.ConvertToRupiah(expenditureTrans.TotalPrice),
+		ReceiptURL:   s.signedURL.Sign(expenditureTrans.ReceiptKey),
+		ThumbnailURL: s.signedURL.Sign(expenditureTrans.ThumbnailKey),
+		Attachments:  s.getAttachmentResponses(attachments),
+	}
+}
+
+func (s *ledgerService) DeleteExpenditureTransaction(
+	ctx context.Context,
+	user auth.User,
+	param model.ExpenditureDetailParam,
+) error {
+	expenditureDetail, err := s.repo.Ledger().Get(ctx, repository.LedgerFilter{
+		ID:          param.ID,
+		InspectorID: user.ID,
+		ProjectID:   param.ProjectID,
+	})
+	if repository.IsNotFound(err) {
+		return errors.NotFound("transaksi pengeluaran proyek tidak ditemukan")
+	} else if err != nil {
+		return errors.InternalServerError(err.Error())
+	} else if *expenditureDetail.IsCanceled ||
+		expenditureDetail.LedgerType == model.Debit ||
+		expenditureDetail.Status == model.Rejected {
+		return errors.NotFound("transaksi pengeluaran proyek tidak ditemukan")
+	}
+
+	// a member removed from the project can't change its transactions anymore
+	if isMember, err := s.isProjectMember(ctx, s.repo, param.ProjectID, user.ID); err != nil {
+		return errors.InternalServerError(err.Error())
+	} else if !isMember {
+		return errors.NotFound("transaksi pengeluaran proyek tidak ditemukan")
+	}
+
+	// a pending expenditure hasn't moved any balance, so there is nothing to reverse
+	if expenditureDetail.Status == model.Pending {
+		err := s.repo.Ledger().Cancel(ctx, expenditureDetail.ID, model.Pending, user.ID)
+		if repository.IsNotFound(err) {
+			return errors.NotFound("transaksi pengeluaran proyek tidak ditemukan")
+		} else if err != nil {
+			return errors.InternalServerError(err.Error())
+		}
+
+		return nil
+	}
+
+	if _, err := s.getProjectExpenditure(ctx, param.ProjectID, param.ExpenditureID); err != nil {
+		return err
+	}
+
+	return s.postLedger(ctx, user.ID, param.ProjectID, func(tx repository.Interface, latestLedger model.Ledger) error {
+		_, err := s.postLedgerReversal(ctx, tx, expenditureDetail, latestLedger, user.ID)
+		return err
+	})
+}
+
+func (s *ledgerService) GetProjectLedger(
+	ctx context.Context,
+	user auth.User,
+	param *model.LedgerParam,
+) (model.ProjectLedgerResponse, error) {
+	var res model.ProjectLedgerResponse
+
+	if param.IntervalMonth <= 0 {
+		param.IntervalMonth = 1
+	}
+
+	project, err := s.repo.Project().Get(ctx, param.ProjectID)
+	if repository.IsNotFound(err) {
+		return res, errors.NotFound("proyek tidak ditemukan")
+	} else if err != nil {
+		return res, errors.InternalServerError(err.Error())
+	}
+
+	if !canSeeProjectLedger(user, project) {
+		return res, errors.NotFound("proyek tidak ditemukan")
+	}
+
+	param.PaginationParam.SetDefaultPagination()
+
+	// the transfers of the director are left out, only the inspectors who held the project are shown
+	inspectorIDs, err := s.getProjectInspectorIDs(ctx, project)
+	if err != nil {
+		return res, errors.InternalServerError(err.Error())
+	}
+
+	filter := repository.LedgerFilter{
+		ProjectID:    project.ID,
+		InspectorIDs: inspectorIDs,
+		CreatedFrom:  getStartTime(param.IntervalMonth),
+		Status:       model.Posted,
+		Limit:        param.Limit,
+		Offset:       param.Offset,
+	}
+
+	ledgers, err := s.repo.Ledger().List(ctx, filter)
+	if err != nil {
+		return res, errors.InternalServerError(err.Error())
+	}
+
+	transactions := []model.InspectorLedgerTransaction{}
+	for _, ledger := range ledgers {
+		transactions = append(transactions, s.getTransaction(ledger))
+	}
+
+	param.TotalElement, err = s.repo.Ledger().Count(ctx, filter)
+	if err != nil {
+		return res, errors.InternalServerError(err.Error())
+	}
+	param.ProcessPagination(int64(len(transactions)))
+
+	members, balance, err := s.getProjectMemberBalances(ctx, project.ID)
+	if err != nil {
+		return res, errors.InternalServerError(err.Error())
+	}
+
+	res = model.ProjectLedgerResponse{
+		Account: model.ProjectLedgerAccount{
+			ProjectID:      project.ID,
+			ProjectName:    project.Name,
+			InspectorName:  project.Inspector.Name,
+			CurrentBalance: number.ConvertToRupiah(balance),
+			Members:        members,
+		},
+		Transactions: transactions,
+	}
+
+	return res, nil
+}
+
+func (s *ledgerService) GetInspectorLedger(
+	ctx context.Context,
+	user auth.User,
+	param *model.LedgerParam,
+) (model.InspectorLedgerResponse, error) {
+	var res model.InspectorLedgerResponse
+
+	if !hasPermission(user, model.LedgerReadAll) {
+		param.InspectorID = user.ID
+	}
+
+	if param.IntervalMonth == 0 {
+		param.IntervalMonth = 1
+	}
+
+	param.SetDefaultPagination()
+
+	// the transfer legs of the director aren't an inspector balance
+	filter := repository.LedgerFilter{
+		InspectorID:          param.InspectorID,
+		ExcludeInspectorRole: model.Admin,
+		CreatedFrom:          getStartTime(param.IntervalMonth),
+		Status:               model.Posted,
+		Limit:                param.Limit,
+		Offset:               param.Offset,
+	}
+
+	ledgers, err := s.repo.Ledger().List(ctx, filter)
+	if err != nil {
+		return res, errors.InternalServerError(err.Error())
+	}
+
+	transactions := []model.InspectorLedgerTransaction{}
+	for _, ledger := range ledgers {
+		transactions = append(transactions, s.getTransaction(ledger))
+	}
+
+	param.TotalElement, err = s.repo.Ledger().Count(ctx, filter)
+	if err != nil {
+		return res, errors.InternalServerError(err.Error())
+	}
+
+	param.ProcessPagination(int64(len(transactions)))
+
+	account, err := s.getInspectorLedgerAccount(ctx, param.InspectorID)
+	if err != nil {
+		return res, errors.InternalServerError(err.Error())
+	}
+
+	res = model.InspectorLedgerResponse{
+		Account:      account,
+		Transactions: transactions,
+	}
+
+	return res, nil
+}
+
+// getInspectorLedgerAccount gets the balance of an inspector, a zero inspectorID sums every inspector
+func (s *ledgerService) getInspectorLedgerAccount(
+	ctx context.Context,
+	inspectorID int64,
+) (model.InspectorLedgerAccount, error) {
+	var account model.InspectorLedgerAccount
+
+	if inspectorID == 0 {
+		var finalBalance int64
+		stats, err := s.repo.Statistics().GetInspectorStats(ctx, 0, 1)
+		if err == nil {
+			finalBalance = getInt64(stats.Margin)
+		} else if !repository.IsNotFound(err) {
+			return account, err
+		}
+
+		account = model.InspectorLedgerAccount{
+			InspectorName:  "Semua Pengawas",
+			CurrentBalance: number.ConvertToRupiah(finalBalance),
+		}
+
+		return account, nil
+	}
+
+	latestLedger, err := s.repo.Ledger().Get(ctx, repository.LedgerFilter{
+		InspectorID:          inspectorID,
+		ExcludeInspectorRole: model.Admin,
+		Status:               model.Posted,
+	})
+	if repository.IsNotFound(err) {
+		latestLedger = model.Ledger{FinalInspectorBalance: new(int64)}
+	} else if err != nil {
+		return account, err
+	}
+
+	account = model.InspectorLedgerAccount{
+		InspectorName:  latestLedger.Inspector.Name,
+		CurrentBalance: number.ConvertToRupiah(*latestLedger.FinalInspectorBalance),
+	}
+
+	return account, nil
+}
+
+// isProjectMember reports whether the inspector is a current member of the project
+func (s *ledgerService) isProjectMember(
+	ctx context.Context,
+	tx repository.Interface,
+	projectID int64,
+	inspectorID int64,
+) (bool, error) {
+	_, err := tx.ProjectMember().Get(ctx, projectID, inspectorID)
+	if repository.IsNotFound(err) {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// canSeeProjectLedgerOf is canSeeProjectLedger for the project joined to a ledger or an expenditure,
+// it has no members loaded
+func (s *ledgerService) canSeeProjectLedgerOf(ctx context.Context, user auth.User, projectID int64) (bool, error) {
+	if hasPermission(user, model.LedgerReadAll) {
+		return true, nil
+	}
+
+	return s.isProjectMember(ctx, s.repo, projectID, user.ID)
+}
+
+// canSeeProjectLedger reports whether the user may see the ledger and the receipts of the project,
+// a user without ledger:read:all only sees the projects assigned to them
+func canSeeProjectLedger(user auth.User, project model.Project) bool {
+	return hasPermission(user, model.LedgerReadAll) || project.IsMember(user.ID)
+}
+
+func (s *ledgerService) getTransaction(ledger model.Ledger) model.InspectorLedgerTransaction {
+	return model.InspectorLedgerTransaction{
+		ID:            ledger.ID,
+		Timestamp:     ledger.CreatedAt,
+		Type:          string(ledger.LedgerType),
+		RefName:       ledger.Ref,
+		ProjectName:   ledger.Project.Name,
+		Amount:        number.ConvertToRupiah(ledger.TotalPrice),
+		InspectorName: ledger.Inspector.Name,
+		RecieptURL:    s.signedURL.Sign(ledger.ReceiptKey),
+		ThumbnailURL:  s.signedURL.Sign(ledger.ThumbnailKey),
+	}
+}
@@ -0,0 +1,474 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"tigaputera-backend/sdk/auth"
+	errors "tigaputera-backend/sdk/error"
+	"tigaputera-backend/sdk/file"
+	"tigaputera-backend/sdk/imaging"
+	"tigaputera-backend/src/model"
+	"tigaputera-backend/src/repository"
+)
+
+const (
+	// a pdf is uploaded as is, it has the same size limit as the images
+	maxPDFSize = imaging.MaxFileSize
+	// a re-photographed receipt usually differs by a few bits of the perceptual hash
+	maxDuplicateDistance = 10
+)
+
+func (s *ledgerService) GetLedgerAttachments(
+	ctx context.Context,
+	user auth.User,
+	param model.LedgerAttachmentParam,
+) ([]model.LedgerAttachmentResponse, error) {
+	ledger, err := s.repo.Ledger().Get(ctx, repository.LedgerFilter{
+		ID:        param.LedgerID,
+		ProjectID: param.ProjectID,
+	})
+	if repository.IsNotFound(err) {
+		return nil, errors.NotFound("transaksi proyek tidak ditemukan")
+	} else if err != nil {
+		return nil, errors.InternalServerError(err.Error())
+	}
+
+	if canSee, err := s.canSeeProjectLedgerOf(ctx, user, ledger.ProjectID); err != nil {
+		return nil, errors.InternalServerError(err.Error())
+	} else if !canSee {
+		return nil, errors.NotFound("transaksi proyek tidak ditemukan")
+	}
+
+	attachments, err := s.repo.LedgerAttachment().List(ctx, ledger.ID)
+	if err != nil {
+		return nil, errors.InternalServerError(err.Error())
+	}
+
+	return s.getAttachmentResponses(attachments), nil
+}
+
+func (s *ledgerService) AddLedgerAttachments(
+	ctx context.Context,
+	user auth.User,
+	param model.LedgerAttachmentParam,
+	files []*file.File,
+) error {
+	ledger, err := s.getAttachableLedger(ctx, user, param)
+	if err != nil {
+		return err
+	}
+
+	// checked again under the lock, this one avoids uploading files that would be rejected
+	attachments, err := s.repo.LedgerAttachment().List(ctx, ledger.ID)
+	if err != nil {
+		return errors.InternalServerError(err.Error())
+	} else if len(attachments)+len(files) > model.MaxLedgerAttachments {
+		return getMaxAttachmentsError()
+	}
+
+	path := "incomes"
+	if ledger.IsExpenditure() {
+		path = "expenditures"
+	}
+
+	newAttachments, err := s.uploadAttachments(ctx, files, ledger.ID, fmt.Sprintf(
+		"%s_%d_%d_attachment_%d", // username_projectId_ledgerId_attachment_timestamp
+		user.Username,
+		ledger.ProjectID,
+		ledger.ID,
+		time.Now().Unix(),
+	), path)
+	if err != nil {
+		return err
+	}
+
+	// the attachments of a ledger are changed under the ledger locks, so the limits can't be raced
+	return s.repo.Transaction(ctx, func(tx repository.Interface) error {
+		if err := s.lockLedger(ctx, tx, ledger.ProjectID, ledger.InspectorID); err != nil {
+			return err
+		}
+
+		attachments, err := tx.LedgerAttachment().List(ctx, ledger.ID)
+		if err != nil {
+			return errors.InternalServerError(err.Error())
+		} else if len(attachments)+len(newAttachments) > model.MaxLedgerAttachments {
+			return getMaxAttachmentsError()
+		}
+
+		created := make([]*model.LedgerAttachment, len(newAttachments))
+		for i := range newAttachments {
+			newAttachments[i].LedgerID = ledger.ID
+			newAttachments[i].CreatedBy = &user.ID
+			created[i] = &newAttachments[i]
+		}
+
+		if err := tx.LedgerAttachment().Create(ctx, created...); err != nil {
+			return errors.InternalServerError(err.Error())
+		}
+
+		if len(attachments) == 0 {
+			if err := tx.Ledger().UpdateReceipt(ctx, ledger.ID, newAttachments[0].GetReceipt()); err != nil {
+				return errors.InternalServerError(err.Error())
+			}
+		}
+
+		return nil
+	})
+}
+
+func (s *ledgerService) DeleteLedgerAttachment(
+	ctx context.Context,
+	user auth.User,
+	param model.LedgerAttachmentParam,
+) error {
+	ledger, err := s.getAttachableLedger(ctx, user, param)
+	if err != nil {
+		return err
+	}
+
+	return s.repo.Transaction(ctx, func(tx repository.Interface) error {
+		if err := s.lockLedger(ctx, tx, ledger.ProjectID, ledger.InspectorID); err != nil {
+			return err
+		}
+
+		attachments, err := tx.LedgerAttachment().List(ctx, ledger.ID)
+		if err != nil {
+			return errors.InternalServerError(err.Error())
+		}
+
+		remaining := []model.LedgerAttachment{}
+		for _, attachment := range attachments {
+			if attachment.ID != param.ID {
+				remaining = append(remaining, attachment)
+			}
+		}
+
+		if len(remaining) == len(attachments) {
+			return errors.NotFound("lampiran transaksi tidak ditemukan")
+		} else if len(remaining) == 0 {
+			return errors.BadRequest("Transaksi harus memiliki minimal satu bukti")
+		}
+
+		if err := tx.LedgerAttachment().Delete(ctx, ledger.ID, param.ID, user.ID); err != nil {
+			return errors.InternalServerError(err.Error())
+		}
+
+		// the first attachment left is shown in the ledger lists
+		if err := tx.Ledger().UpdateReceipt(ctx, ledger.ID, remaining[0].GetReceipt()); err != nil {
+			return errors.InternalServerError(err.Error())
+		}
+
+		return nil
+	})
+}
+
+// getAttachableLedger gets an income or expenditure of the inspector that isn't canceled or rejected,
+// the receipts of the reversals and the fund transfers can't be changed
+func (s *ledgerService) getAttachableLedger(
+	ctx context.Context,
+	user auth.User,
+	param model.LedgerAttachmentParam,
+) (model.Ledger, error) {
+	ledger, err := s.repo.Ledger().Get(ctx, repository.LedgerFilter{
+		ID:          param.LedgerID,
+		InspectorID: user.ID,
+		ProjectID:   param.ProjectID,
+		IsCanceled:  boolPtr(false),
+	})
+	if repository.IsNotFound(err) {
+		return ledger, errors.NotFound("transaksi proyek tidak ditemukan")
+	} else if err != nil {
+		return ledger, errors.InternalServerError(err.Error())
+	}
+
+	if ledger.Status == model.Rejected ||
+		ledger.ReversalOfID != nil ||
+		ledger.TransferID != nil ||
+		ledger.LedgerType == model.Transfer {
+		return ledger, errors.NotFound("transaksi proyek tidak ditemukan")
+	}
+
+	return ledger, nil
+}
+
+// GetDuplicateReceiptReport lists the receipts flagged as near-identical to the receipt of another transaction,
+// the pairs whose transactions were canceled or rejected since are left out
+func (s *ledgerService) GetDuplicateReceiptReport(ctx context.Context) ([]model.DuplicateReceiptResponse, error) {
+	report := []model.DuplicateReceiptResponse{}
+
+	duplicates, err := s.repo.LedgerAttachment().ListOfActiveLedgers(ctx, repository.LedgerAttachmentFilter{
+		IsDuplicate: true,
+	})
+	if err != nil {
+		return report, errors.InternalServerError(err.Error())
+	} else if len(duplicates) == 0 {
+		return report, nil
+	}
+
+	originalIDs := []int64{}
+	for _, duplicate := range duplicates {
+		originalIDs = append(originalIDs, *duplicate.DuplicateOfID)
+	}
+
+	originals, err := s.repo.LedgerAttachment().ListOfActiveLedgers(ctx, repository.LedgerAttachmentFilter{
+		IDs: originalIDs,
+	})
+	if err != nil {
+		return report, errors.InternalServerError(err.Error())
+	}
+
+	attachments := map[int64]model.LedgerAttachment{}
+	ledgerIDs := []int64{}
+	for _, attachment := range append(duplicates, originals...) {
+		attachments[attachment.ID] = attachment
+		ledgerIDs = append(ledgerIDs, attachment.LedgerID)
+	}
+
+	ledgerList, err := s.repo.Ledger().List(ctx, repository.LedgerFilter{IDs: ledgerIDs})
+	if err != nil {
+		return report, errors.InternalServerError(err.Error())
+	}
+
+	ledgers := map[int64]model.Ledger{}
+	for _, ledger := range ledgerList {
+		ledgers[ledger.ID] = ledger
+	}
+
+	getDuplicateReceipt := func(attachment model.LedgerAttachment) model.DuplicateReceipt {
+		return model.DuplicateReceipt{
+			Attachment:  s.getAttachmentResponses([]model.LedgerAttachment{attachment})[0],
+			Transaction: s.getTransaction(ledgers[attachment.LedgerID]),
+		}
+	}
+
+	for _, duplicate := range duplicates {
+		original, ok := attachments[*duplicate.DuplicateOfID]
+		if !ok {
+			continue
+		}
+
+		report = append(report, model.DuplicateReceiptResponse{
+			Distance:    getDuplicateDistance(*duplicate.PerceptualHash, *original.PerceptualHash),
+			Receipt:     getDuplicateReceipt(duplicate),
+			DuplicateOf: getDuplicateReceipt(original),
+		})
+	}
+
+	return report, nil
+}
+
+func getMaxAttachmentsError() error {
+	return errors.BadRequest(fmt.Sprintf("Bukti transaksi maksimal %d file", model.MaxLedgerAttachments))
+}
+
+func (s *ledgerService) getAttachmentResponses(
+	attachments []model.LedgerAttachment,
+) []model.LedgerAttachmentResponse {
+	attachmentResponses := []model.LedgerAttachmentResponse{}
+	for _, attachment := range attachments {
+		attachmentResponses = append(attachmentResponses, model.LedgerAttachmentResponse{
+			ID:            attachment.ID,
+			FileType:      attachment.FileType,
+			URL:           s.signedURL.Sign(attachment.ObjectKey),
+			ThumbnailURL:  s.signedURL.Sign(attachment.ThumbnailKey),
+			CapturedAt:    attachment.CapturedAt,
+			Latitude:      attachment.Latitude,
+			Longitude:     attachment.Longitude,
+			DuplicateOfID: attachment.DuplicateOfID,
+		})
+	}
+
+	return attachmentResponses
+}
+
+// uploadAttachments uploads the files of a transaction, the files after the first are numbered.
+// ledgerID is the transaction the files are added to, 0 for a new one.
+func (s *ledgerService) uploadAttachments(
+	ctx context.Context,
+	files []*file.File,
+	ledgerID int64,
+	fileName string,
+	path string,
+) ([]model.LedgerAttachment, error) {
+	attachments := []model.LedgerAttachment{}
+	for i, file := range files {
+		attachmentName := fileName
+		if i > 0 {
+			attachmentName = fmt.Sprintf("%s_%d", fileName, i+1)
+		}
+
+		var attachment model.LedgerAttachment
+		var err error
+		if file.IsPDF() {
+			attachment, err = s.uploadPDF(ctx, file, ledgerID, attachmentName, path)
+		} else {
+			attachment, err = s.uploadImage(ctx, file, ledgerID, attachmentName, path)
+		}
+		if err != nil {
+			s.deleteAttachments(ctx, attachments)
+			return nil, err
+		}
+
+		attachments = append(attachments, attachment)
+	}
+
+	return attachments, nil
+}
+
+// deleteAttachments removes the uploaded files of a ledger that wasn't saved, a file that can't be deleted
+// is left to the storage garbage collection
+func (s *ledgerService) deleteAttachments(ctx context.Context, attachments []model.LedgerAttachment) {
+	for _, attachment := range attachments {
+		for _, key := range []string{attachment.ObjectKey, attachment.ThumbnailKey} {
+			// the keys are path/file_name, see storage.Object
+			i := strings.LastIndex(key, "/")
+			if i < 0 {
+				continue
+			}
+
+			_ = s.storage.Delete(ctx, key[i+1:], key[:i])
+		}
+	}
+}
+
+// uploadImage processes the receipt image, then uploads it and its thumbnail as jpeg
+func (s *ledgerService) uploadImage(
+	ctx context.Context,
+	receiptImage *file.File,
+	ledgerID int64,
+	fileName string,
+	path string,
+) (model.LedgerAttachment, error) {
+	attachment := model.LedgerAttachment{FileType: model.ImageAttachment}
+
+	processedImage, err := s.imaging.Process(receiptImage.Content)
+	if err != nil {
+		return attachment, getReceiptImageError(err)
+	}
+
+	// the hash is stored in a signed column, only its bits matter
+	perceptualHash := int64(processedImage.PerceptualHash)
+	attachment.ContentHash = processedImage.ContentHash
+	attachment.PerceptualHash = &perceptualHash
+	if err := s.checkDuplicateReceipt(ctx, &attachment, ledgerID); err != nil {
+		return attachment, err
+	}
+
+	fileName += ".jpg"
+	attachment.ObjectKey, err = s.storage.UploadFromBytes(ctx, bytes.NewReader(processedImage.Content), fileName, path)
+	if err != nil {
+		return attachment, errors.InternalServerError(err.Error())
+	}
+
+	attachment.ThumbnailKey, err = s.storage.UploadFromBytes(
+		ctx,
+		bytes.NewReader(processedImage.Thumbnail),
+		fileName,
+		getThumbnailPath(path),
+	)
+	if err != nil {
+		return attachment, errors.InternalServerError(err.Error())
+	}
+
+	attachment.CapturedAt = processedImage.CapturedAt
+	attachment.Latitude = processedImage.Latitude
+	attachment.Longitude = processedImage.Longitude
+
+	return attachment, nil
+}
+
+func (s *ledgerService) uploadPDF(
+	ctx context.Context,
+	receiptPDF *file.File,
+	ledgerID int64,
+	fileName string,
+	path string,
+) (model.LedgerAttachment, error) {
+	attachment := model.LedgerAttachment{FileType: model.PDFAttachment}
+
+	content, err := io.ReadAll(io.LimitReader(receiptPDF.Content, maxPDFSize+1))
+	if err != nil {
+		return attachment, errors.InternalServerError(err.Error())
+	} else if len(content) > maxPDFSize {
+		return attachment, errors.BadRequest(fmt.Sprintf("Ukuran file PDF maksimal %d MB", maxPDFSize>>20))
+	}
+
+	attachment.ContentHash = imaging.HashContent(content)
+	if err := s.checkDuplicateReceipt(ctx, &attachment, ledgerID); err != nil {
+		return attachment, err
+	}
+
+	attachment.ObjectKey, err = s.storage.UploadFromBytes(ctx, bytes.NewReader(content), fileName+".pdf", path)
+	if err != nil {
+		return attachment, errors.InternalServerError(err.Error())
+	}
+
+	return attachment, nil
+}
+
+// checkDuplicateReceipt rejects a file already backing another active transaction,
+// a near-identical image is accepted but flagged with the attachment it looks like
+func (s *ledgerService) checkDuplicateReceipt(
+	ctx context.Context,
+	attachment *model.LedgerAttachment,
+	ledgerID int64,
+) error {
+	duplicates, err := s.repo.LedgerAttachment().ListOfActiveLedgers(ctx, repository.LedgerAttachmentFilter{
+		ExcludeLedgerID: ledgerID,
+		ContentHash:     attachment.ContentHash,
+		Limit:           1,
+	})
+	if err != nil {
+		return errors.InternalServerError(err.Error())
+	} else if len(duplicates) > 0 {
+		return errors.BadRequest("Bukti transaksi sudah digunakan pada transaksi lain")
+	}
+
+	if attachment.PerceptualHash == nil {
+		return nil
+	}
+
+	// the oldest near-identical image is the one flagged
+	images, err := s.repo.LedgerAttachment().ListOfActiveLedgers(ctx, repository.LedgerAttachmentFilter{
+		ExcludeLedgerID: ledgerID,
+		SimilarTo:       attachment.PerceptualHash,
+		MaxDistance:     maxDuplicateDistance,
+		Limit:           1,
+	})
+	if err != nil {
+		return errors.InternalServerError(err.Error())
+	} else if len(images) > 0 {
+		attachment.DuplicateOfID = &images[0].ID
+	}
+
+	return nil
+}
+
+func getDuplicateDistance(a int64, b int64) int {
+	return imaging.Distance(uint64(a), uint64(b))
+}
+
+func getReceiptImageError(err error) error {
+	switch err {
+	case imaging.ErrFileTooLarge:
+		return errors.BadRequest(fmt.Sprintf("Ukuran gambar bukti maksimal %d MB", imaging.MaxFileSize>>20))
+	case imaging.ErrUnsupportedFormat:
+		return errors.BadRequest("Gambar bukti harus berupa png, jpg, atau jpeg")
+	case imaging.ErrResolutionTooLarge:
+		return errors.BadRequest("Resolusi gambar bukti terlalu besar")
+	case imaging.ErrInvalidImage:
+		return errors.BadRequest("Gambar bukti rusak atau tidak dapat dibaca")
+	}
+
+	return errors.InternalServerError(err.Error())
+}
+
+// getThumbnailPath returns the folder the thumbnails of the receipts in path are uploaded to
+func getThumbnailPath(path string) string {
+	return path + "_thumbnail"
+}
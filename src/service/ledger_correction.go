@@ -0,0 +1,248 @@
+package service
+
+import (
+	"context"
+
+	"tigaputera-backend/sdk/auth"
+	errors "tigaputera-backend/sdk/error"
+	"tigaputera-backend/src/model"
+	"tigaputera-backend/src/repository"
+)
+
+func (s *ledgerService) CancelIncomeTransaction(
+	ctx context.Context,
+	user auth.User,
+	param model.LedgerTransactionParam,
+) error {
+	income, err := s.getCorrectableLedger(ctx, param, user.ID)
+	if err != nil {
+		return err
+	} else if !income.IsIncome() {
+		return errors.NotFound("transaksi pemasukan proyek tidak ditemukan")
+	}
+
+	return s.postLedger(ctx, user.ID, param.ProjectID, func(tx repository.Interface, latestLedger model.Ledger) error {
+		pendingHold, err := s.getPendingHold(ctx, tx, user.ID, param.ProjectID)
+		if err != nil {
+			return errors.InternalServerError(err.Error())
+		}
+
+		if *latestLedger.FinalProjectBalance-pendingHold < income.TotalPrice {
+			return errors.BadRequest("Saldo proyek tidak mencukupi untuk membatalkan pemasukan")
+		}
+
+		_, err = s.postLedgerReversal(ctx, tx, income, latestLedger, user.ID)
+		return err
+	})
+}
+
+func (s *ledgerService) CorrectTransaction(
+	ctx context.Context,
+	user auth.User,
+	param model.LedgerTransactionParam,
+	body model.CorrectLedgerBody,
+) error {
+	original, err := s.getCorrectableLedger(ctx, param, user.ID)
+	if err != nil {
+		return err
+	}
+
+	replacement := s.getReplacementLedger(original, body)
+
+	// the replacement keeps the receipts of the corrected transaction
+	attachments, err := s.repo.LedgerAttachment().List(ctx, original.ID)
+	if err != nil {
+		return errors.InternalServerError(err.Error())
+	}
+	replacement.SetAttachments(attachments)
+
+	// a corrected expenditure above the approval threshold has to go through the approval
+	if original.IsExpenditure() {
+		projectExpenditure, err := s.getProjectExpenditure(ctx, original.ProjectID, *original.RefID)
+		if err != nil {
+			return err
+		}
+
+		if projectExpenditure.IsApprovalRequired(-replacement.TotalPrice) {
+			return errors.BadRequest(
+				"Koreksi melebihi batas persetujuan, batalkan transaksi dan buat pengeluaran baru",
+			)
+		}
+	}
+
+	return s.postLedger(ctx, user.ID, param.ProjectID, func(tx repository.Interface, latestLedger model.Ledger) error {
+		pendingHold, err := s.getPendingHold(ctx, tx, user.ID, param.ProjectID)
+		if err != nil {
+			return errors.InternalServerError(err.Error())
+		}
+
+		finalProjectBalance := *latestLedger.FinalProjectBalance - original.TotalPrice + replacement.TotalPrice
+		if finalProjectBalance-pendingHold < 0 {
+			return errors.BadRequest("Saldo proyek tidak mencukupi untuk koreksi transaksi")
+		}
+
+		reversal, err := s.postLedgerReversal(ctx, tx, original, latestLedger, user.ID)
+		if err != nil {
+			return err
+		}
+
+		prevInspectorBalance := *reversal.FinalInspectorBalance
+		finalInspectorBalance := prevInspectorBalance + replacement.TotalPrice
+		prevProjectBalance := *reversal.FinalProjectBalance
+		replacement.CurrentInspectorBalance = &prevInspectorBalance
+		replacement.FinalInspectorBalance = &finalInspectorBalance
+		replacement.CurrentProjectBalance = &prevProjectBalance
+		replacement.FinalProjectBalance = &finalProjectBalance
+
+		if original.IsIncome() {
+			err = s.insertIncome(ctx, tx, replacement)
+		} else {
+			err = s.insertExpenditure(ctx, tx, replacement, model.ProjectExpenditure{
+				ID:        *original.RefID,
+				ProjectID: original.ProjectID,
+			})
+		}
+		if err != nil {
+			return errors.InternalServerError(err.Error())
+		}
+
+		return nil
+	})
+}
+
+// getCorrectableLedger gets a posted income or expenditure of the inspector that isn't canceled,
+// reversals and the legs of a fund transfer can't be canceled or corrected
+func (s *ledgerService) getCorrectableLedger(
+	ctx context.Context,
+	param model.LedgerTransactionParam,
+	inspectorID int64,
+) (model.Ledger, error) {
+	ledger, err := s.repo.Ledger().Get(ctx, repository.LedgerFilter{
+		ID:          param.ID,
+		InspectorID: inspectorID,
+		ProjectID:   param.ProjectID,
+		Status:      model.Posted,
+		IsCanceled:  boolPtr(false),
+	})
+	if repository.IsNotFound(err) {
+		return ledger, errors.NotFound("transaksi proyek tidak ditemukan")
+	} else if err != nil {
+		return ledger, errors.InternalServerError(err.Error())
+	}
+
+	isIncome := ledger.IsIncome() && ledger.TransferID == nil
+	isExpenditure := ledger.IsExpenditure() && ledger.LedgerType == model.Credit
+	if ledger.ReversalOfID != nil || (!isIncome && !isExpenditure) {
+		return ledger, errors.NotFound("transaksi proyek tidak ditemukan")
+	}
+
+	// the transactions of a project handed over or left can't be changed by the previous member
+	if isMember, err := s.isProjectMember(ctx, s.repo, ledger.ProjectID, inspectorID); err != nil {
+		return ledger, errors.InternalServerError(err.Error())
+	} else if !isMember {
+		return ledger, errors.NotFound("transaksi proyek tidak ditemukan")
+	}
+
+	return ledger, nil
+}
+
+func (s *ledgerService) getReplacementLedger(
+	original model.Ledger,
+	body model.CorrectLedgerBody,
+) model.Ledger {
+	replacement := model.Ledger{
+		InspectorID:    original.InspectorID,
+		ProjectID:      original.ProjectID,
+		LedgerType:     original.LedgerType,
+		RefID:          original.RefID,
+		Ref:            original.Ref,
+		Description:    original.Description,
+		Amount:         original.Amount,
+		Price:          body.Price,
+		LedgerReceipt:  original.LedgerReceipt,
+		CorrectionOfID: &original.ID,
+	}
+
+	if original.IsIncome() {
+		if body.Ref != "" {
+			replacement.Ref = body.Ref
+		}
+		replacement.TotalPrice = replacement.Price
+
+		return replacement
+	}
+
+	if body.Amount != 0 {
+		replacement.Amount = body.Amount
+	}
+	if body.Name != "" {
+		replacement.Description = &body.Name
+	}
+	replacement.Price = -body.Price
+	replacement.TotalPrice = replacement.Price * replacement.Amount
+
+	return replacement
+}
+
+// postLedgerReversal cancels a posted ledger and posts the entry reversing it after latestLedger,
+// the denormalized project income or expenditure total is reversed with it
+func (s *ledgerService) postLedgerReversal(
+	ctx context.Context,
+	tx repository.Interface,
+	original model.Ledger,
+	latestLedger model.Ledger,
+	userID int64,
+) (model.Ledger, error) {
+	// the ledger could have been canceled by another request while waiting for the lock
+	err := tx.Ledger().Cancel(ctx, original.ID, model.Posted, userID)
+	if repository.IsNotFound(err) {
+		return model.Ledger{}, errors.NotFound("transaksi proyek tidak ditemukan")
+	} else if err != nil {
+		return model.Ledger{}, errors.InternalServerError(err.Error())
+	}
+
+	reversalDesc := "Pembatalan " + *original.Description
+	prevInspectorBalance := *latestLedger.FinalInspectorBalance
+	finalInspectorBalance := prevInspectorBalance - original.TotalPrice
+	prevProjectBalance := *latestLedger.FinalProjectBalance
+	finalProjectBalance := prevProjectBalance - original.TotalPrice
+	reversal := model.Ledger{
+		InspectorID:             original.InspectorID,
+		ProjectID:               original.ProjectID,
+		LedgerType:              model.Debit,
+		RefID:                   original.RefID,
+		Ref:                     original.Ref,
+		Description:             &reversalDesc,
+		Amount:                  original.Amount,
+		Price:                   -original.Price,
+		TotalPrice:              -original.TotalPrice,
+		CurrentInspectorBalance: &prevInspectorBalance,
+		FinalInspectorBalance:   &finalInspectorBalance,
+		CurrentProjectBalance:   &prevProjectBalance,
+		FinalProjectBalance:     &finalProjectBalance,
+		ReversalOfID:            &original.ID,
+		PostedAt:                getPostedAt(latestLedger),
+	}
+
+	if original.IsIncome() {
+		if err := s.insertIncome(ctx, tx, reversal); err != nil {
+			return reversal, errors.InternalServerError(err.Error())
+		}
+
+		return reversal, nil
+	}
+
+	if err := tx.Ledger().Create(ctx, &reversal); err != nil {
+		return reversal, errors.InternalServerError(err.Error())
+	}
+
+	projectExpenditure := model.ProjectExpenditure{
+		ID:        *original.RefID,
+		ProjectID: original.ProjectID,
+	}
+	if err := s.addExpenditureTotal(ctx, tx, projectExpenditure, reversal.TotalPrice, userID); err != nil {
+		return reversal, errors.InternalServerError(err.Error())
+	}
+
+	return reversal, nil
+}
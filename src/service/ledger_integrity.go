@@ -0,0 +1,247 @@
+package service
+
+import (
+	"context"
+	"sort"
+
+	errors "tigaputera-backend/sdk/error"
+	"tigaputera-backend/src/model"
+	"tigaputera-backend/src/repository"
+)
+
+type balanceChain struct {
+	prevLedgerID *int64
+	storedFinal  int64
+	rebuiltFinal int64
+}
+
+type expenditureTotalKey struct {
+	expenditureID int64
+	projectID     int64
+}
+
+func (s *ledgerService) CheckLedgerIntegrity(
+	ctx context.Context,
+	fix bool,
+) (model.LedgerIntegrityReport, error) {
+	var report model.LedgerIntegrityReport
+
+	err := s.repo.Transaction(ctx, func(tx repository.Interface) error {
+		if fix {
+			// block new postings, so the rebuilt balances are based on every ledger
+			if err := tx.Ledger().LockAll(ctx); err != nil {
+				return errors.InternalServerError(err.Error())
+			}
+		}
+
+		var err error
+		report, err = s.getLedgerIntegrityReport(ctx, tx)
+		if err != nil {
+			return errors.InternalServerError(err.Error())
+		}
+
+		if !fix || report.IsConsistent() {
+			return nil
+		}
+
+		if err := s.applyLedgerIntegrityReport(ctx, tx, report); err != nil {
+			return errors.InternalServerError(err.Error())
+		}
+
+		report.IsFixed = true
+
+		return nil
+	})
+	if err != nil {
+		report.IsFixed = false
+		return report, err
+	}
+
+	return report, nil
+}
+
+func (s *ledgerService) getLedgerIntegrityReport(
+	ctx context.Context,
+	tx repository.Interface,
+) (model.LedgerIntegrityReport, error) {
+	report := model.LedgerIntegrityReport{
+		BalanceBreaks:              []model.LedgerBalanceBreak{},
+		BalanceCorrections:         []model.LedgerBalanceCorrection{},
+		ProjectIncomeMismatches:    []model.LedgerTotalMismatch{},
+		ExpenditureTotalMismatches: []model.LedgerTotalMismatch{},
+	}
+
+	ledgers, err := tx.Ledger().List(ctx, repository.LedgerFilter{
+		Status:    model.Posted,
+		Ascending: true,
+	})
+	if err != nil {
+		return report, err
+	}
+
+	inspectorChains := map[int64]*balanceChain{}
+	projectChains := map[[2]int64]*balanceChain{}
+	projectIncomes := map[int64]int64{}
+	expenditureTotals := map[expenditureTotalKey]int64{}
+
+	for _, ledger := range ledgers {
+		inspectorChain, ok := inspectorChains[ledger.InspectorID]
+		if !ok {
+			inspectorChain = &balanceChain{}
+			inspectorChains[ledger.InspectorID] = inspectorChain
+		}
+
+		s.checkBalanceChain(
+			&report,
+			inspectorChain,
+			ledger,
+			model.InspectorBalance,
+			getInt64(ledger.CurrentInspectorBalance),
+			getInt64(ledger.FinalInspectorBalance),
+		)
+
+		projectChainKey := [2]int64{ledger.InspectorID, ledger.ProjectID}
+		projectChain, ok := projectChains[projectChainKey]
+		if !ok {
+			projectChain = &balanceChain{}
+			projectChains[projectChainKey] = projectChain
+		}
+
+		s.checkBalanceChain(
+			&report,
+			projectChain,
+			ledger,
+			model.ProjectBalance,
+			getInt64(ledger.CurrentProjectBalance),
+			getInt64(ledger.FinalProjectBalance),
+		)
+
+		if ledger.IsIncome() {
+			projectIncomes[ledger.ProjectID] += ledger.TotalPrice
+		} else if ledger.IsExpenditure() {
+			key := expenditureTotalKey{expenditureID: *ledger.RefID, projectID: ledger.ProjectID}
+			expenditureTotals[key] -= ledger.TotalPrice
+		}
+	}
+
+	report.TotalLedger = int64(len(ledgers))
+
+	projects, err := tx.Project().List(ctx, repository.ProjectFilter{})
+	if err != nil {
+		return report, err
+	}
+	sort.Slice(projects, func(i, j int) bool {
+		return projects[i].ID < projects[j].ID
+	})
+
+	for _, project := range projects {
+		income := getInt64(project.Income)
+		if income != projectIncomes[project.ID] {
+			report.ProjectIncomeMismatches = append(report.ProjectIncomeMismatches, model.LedgerTotalMismatch{
+				ID:      project.ID,
+				Name:    project.Name,
+				Stored:  income,
+				Rebuilt: projectIncomes[project.ID],
+			})
+		}
+	}
+
+	expenditures, err := tx.ProjectExpenditure().List(ctx, 0)
+	if err != nil {
+		return report, err
+	}
+
+	for _, expenditure := range expenditures {
+		key := expenditureTotalKey{expenditureID: expenditure.ID, projectID: expenditure.ProjectID}
+		totalPrice := getInt64(expenditure.TotalPrice)
+		if totalPrice != expenditureTotals[key] {
+			report.ExpenditureTotalMismatches = append(report.ExpenditureTotalMismatches, model.LedgerTotalMismatch{
+				ID:      expenditure.ID,
+				Name:    expenditure.Name,
+				Stored:  totalPrice,
+				Rebuilt: expenditureTotals[key],
+			})
+		}
+	}
+
+	return report, nil
+}
+
+func (s *ledgerService) checkBalanceChain(
+	report *model.LedgerIntegrityReport,
+	chain *balanceChain,
+	ledger model.Ledger,
+	balanceType model.BalanceType,
+	current int64,
+	final int64,
+) {
+	if current != chain.storedFinal {
+		report.BalanceBreaks = append(report.BalanceBreaks, model.LedgerBalanceBreak{
+			LedgerID:     ledger.ID,
+			PrevLedgerID: chain.prevLedgerID,
+			InspectorID:  ledger.InspectorID,
+			ProjectID:    ledger.ProjectID,
+			BalanceType:  balanceType,
+			Reason:       "Saldo awal tidak sama dengan saldo akhir transaksi sebelumnya",
+			Expected:     chain.storedFinal,
+			Actual:       current,
+		})
+	}
+
+	if final != current+ledger.TotalPrice {
+		report.BalanceBreaks = append(report.BalanceBreaks, model.LedgerBalanceBreak{
+			LedgerID:     ledger.ID,
+			PrevLedgerID: chain.prevLedgerID,
+			InspectorID:  ledger.InspectorID,
+			ProjectID:    ledger.ProjectID,
+			BalanceType:  balanceType,
+			Reason:       "Saldo akhir tidak sama dengan saldo awal ditambah total transaksi",
+			Expected:     current + ledger.TotalPrice,
+			Actual:       final,
+		})
+	}
+
+	rebuiltStart := chain.rebuiltFinal
+	rebuiltFinal := rebuiltStart + ledger.TotalPrice
+	if current != rebuiltStart || final != rebuiltFinal {
+		report.BalanceCorrections = append(report.BalanceCorrections, model.LedgerBalanceCorrection{
+			LedgerID:     ledger.ID,
+			BalanceType:  balanceType,
+			StoredStart:  current,
+			StoredFinal:  final,
+			RebuiltStart: rebuiltStart,
+			RebuiltFinal: rebuiltFinal,
+		})
+	}
+
+	ledgerID := ledger.ID
+	chain.prevLedgerID = &ledgerID
+	chain.storedFinal = final
+	chain.rebuiltFinal = rebuiltFinal
+}
+
+func (s *ledgerService) applyLedgerIntegrityReport(
+	ctx context.Context,
+	tx repository.Interface,
+	report model.LedgerIntegrityReport,
+) error {
+	for _, correction := range report.BalanceCorrections {
+		if err := tx.Ledger().UpdateBalance(ctx, correction); err != nil {
+			return err
+		}
+	}
+
+	for _, mismatch := range report.ProjectIncomeMismatches {
+		if err := tx.Project().SetIncome(ctx, mismatch.ID, mismatch.Rebuilt); err != nil {
+			return err
+		}
+	}
+
+	for _, mismatch := range report.ExpenditureTotalMismatches {
+		if err := tx.ProjectExpenditure().SetTotalPrice(ctx, mismatch.ID, mismatch.Rebuilt); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
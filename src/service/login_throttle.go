@@ -0,0 +1,155 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"tigaputera-backend/sdk/appcontext"
+	errors "tigaputera-backend/sdk/error"
+	"tigaputera-backend/src/model"
+	"tigaputera-backend/src/repository"
+)
+
+const (
+	// a username is locked after this many consecutive failures, whether or not a user has it
+	maxUsernameLoginFailures = 5
+	// an ip address is shared by the users behind the same network, so it has more failures to spare
+	maxIPLoginFailures = 20
+	// the first lockout, every failure after it doubles the lockout up to maxLoginLockoutTime
+	loginLockoutTime    = time.Minute
+	maxLoginLockoutTime = time.Hour
+	// the failures are counted from the first one after a quiet period this long
+	loginFailureWindow = 24 * time.Hour
+)
+
+// loginThrottleKey is a key the failed logins are counted by, the username key always comes first
+type loginThrottleKey struct {
+	key         string
+	maxFailures int64
+}
+
+func getLoginThrottleKeys(ctx context.Context, username string) []loginThrottleKey {
+	keys := []loginThrottleKey{{key: getUsernameThrottleKey(username), maxFailures: maxUsernameLoginFailures}}
+	if ip := appcontext.GetClientIP(ctx); ip != "" {
+		keys = append(keys, loginThrottleKey{key: "ip:" + ip, maxFailures: maxIPLoginFailures})
+	}
+
+	return keys
+}
+
+func getUsernameThrottleKey(username string) string {
+	return "username:" + strings.ToLower(username)
+}
+
+// getLoginLockedUntil returns when the key is unlocked, the lockout doubles with every failure past the limit
+func getLoginLockedUntil(throttle model.LoginThrottle, maxFailures int64) time.Time {
+	if throttle.FailedCount < maxFailures {
+		return time.Time{}
+	}
+
+	lockout := maxLoginLockoutTime
+	if exponent := throttle.FailedCount - maxFailures; exponent < 16 && loginLockoutTime<<exponent < lockout {
+		lockout = loginLockoutTime << exponent
+	}
+
+	return time.Unix(throttle.LastFailedAt, 0).Add(lockout)
+}
+
+func (s *userService) checkLoginLockout(ctx context.Context, username string, keys []loginThrottleKey) error {
+	now := time.Now()
+	lockedUntil := now
+	for _, key := range keys {
+		throttle, err := s.repo.LoginThrottle().Get(ctx, key.key)
+		if repository.IsNotFound(err) {
+			continue
+		} else if err != nil {
+			return errors.InternalServerError(err.Error())
+		}
+
+		if keyLockedUntil := getLoginLockedUntil(throttle, key.maxFailures); keyLockedUntil.After(lockedUntil) {
+			lockedUntil = keyLockedUntil
+		}
+	}
+
+	if !lockedUntil.After(now) {
+		return nil
+	}
+
+	// the attempts while locked are recorded but not counted, the lockout would never end otherwise
+	if err := s.recordLoginFailure(ctx, username, nil, model.LockedOut); err != nil {
+		return errors.InternalServerError(err.Error())
+	}
+
+	return errors.TooManyRequests(fmt.Sprintf(
+		"Terlalu banyak percobaan login, silakan coba lagi dalam %d menit",
+		int64(math.Ceil(lockedUntil.Sub(now).Minutes())),
+	))
+}
+
+// failLogin counts the failed login and returns the same error whether the username or the password is wrong
+func (s *userService) failLogin(
+	ctx context.Context,
+	username string,
+	userID *int64,
+	reason model.LoginFailureReason,
+	keys []loginThrottleKey,
+) error {
+	if err := s.countLoginFailure(ctx, username, userID, reason, keys); err != nil {
+		return errors.InternalServerError(err.Error())
+	}
+
+	return errors.Unauthorized("Username atau password salah")
+}
+
+func (s *userService) countLoginFailure(
+	ctx context.Context,
+	username string,
+	userID *int64,
+	reason model.LoginFailureReason,
+	keys []loginThrottleKey,
+) error {
+	if err := s.recordLoginFailure(ctx, username, userID, reason); err != nil {
+		return err
+	}
+
+	now := time.Now()
+	for _, key := range keys {
+		if _, err := s.repo.LoginThrottle().Fail(
+			ctx,
+			key.key,
+			now.Unix(),
+			now.Add(-loginFailureWindow).Unix(),
+		); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (s *userService) recordLoginFailure(
+	ctx context.Context,
+	username string,
+	userID *int64,
+	reason model.LoginFailureReason,
+) error {
+	return s.repo.LoginAttempt().Create(ctx, &model.LoginAttempt{
+		Username:  username,
+		UserID:    userID,
+		IPAddress: appcontext.GetClientIP(ctx),
+		UserAgent: appcontext.GetUserAgent(ctx),
+		Reason:    reason,
+	})
+}
+
+// getDummyPassword returns a hash to compare the passwords of the unknown usernames with
+func (s *userService) getDummyPassword() string {
+	s.dummyPasswordOnce.Do(func() {
+		s.dummyPassword, _ = s.password.Hash("dummy-password")
+	})
+
+	return s.dummyPassword
+}
@@ -0,0 +1,475 @@
+package service
+
+import (
+	"context"
+
+	"tigaputera-backend/sdk/auth"
+	errors "tigaputera-backend/sdk/error"
+	"tigaputera-backend/sdk/number"
+	"tigaputera-backend/src/model"
+	"tigaputera-backend/src/repository"
+)
+
+type ProjectService interface {
+	CreateProject(ctx context.Context, body model.CreateProjectBody) error
+	GetListProject(ctx context.Context, user auth.User, param *model.ProjectParam) ([]model.ProjectListResponse, error)
+	GetListProjectName(ctx context.Context, user auth.User) ([]model.ProjectNameResponse, error)
+	// GetProject returns the project when the user may see it, see canSeeProject
+	GetProject(ctx context.Context, user auth.User, projectID int64) (model.Project, error)
+	GetProjectDetail(ctx context.Context, user auth.User, projectID int64) (model.ProjectDetailResponse, error)
+	UpdateProject(ctx context.Context, user auth.User, projectID int64, body model.UpdateProjectBody) error
+	UpdateProjectBudget(ctx context.Context, projectID int64, body model.UpdateProjectBudgetBody) error
+	UpdateProjectStatus(ctx context.Context, projectID int64, body model.UpdateProjectStatusBody) error
+	UpdateProjectApprovalThreshold(
+		ctx context.Context,
+		user auth.User,
+		projectID int64,
+		body model.UpdateApprovalThresholdBody,
+	) error
+	CreateProjectExpenditure(
+		ctx context.Context,
+		projectID int64,
+		body model.CreateProjectExpenditureBody,
+	) error
+	UpdateExpenditureApprovalThreshold(
+		ctx context.Context,
+		user auth.User,
+		param model.ExpenditureDetailParam,
+		body model.UpdateApprovalThresholdBody,
+	) error
+}
+
+type projectService struct {
+	repo repository.Interface
+}
+
+func NewProjectService(repo repository.Interface) ProjectService {
+	return &projectService{repo: repo}
+}
+
+func (s *projectService) CreateProject(ctx context.Context, body model.CreateProjectBody) error {
+	if !model.IsProjectTypeCorrect(body.Type) {
+		return errors.BadRequest("Tipe proyek harus Drainase, Beton, Hotmix, atau Bangunan")
+	}
+
+	project := model.Project{
+		Name:        body.Name,
+		Description: body.Description,
+		Type:        body.Type,
+		DeptName:    body.DeptName,
+		CompanyName: body.CompanyName,
+		Status:      string(model.Running),
+		Volume:      body.Volume,
+		Length:      body.Length,
+		Width:       body.Width,
+		InspectorID: body.InspectorID,
+		StartDate:   body.StartDate,
+		FinalDate:   body.FinalDate,
+	}
+
+	return s.repo.Transaction(ctx, func(tx repository.Interface) error {
+		err := tx.Project().Create(ctx, &project)
+		if repository.IsDuplicate(err) {
+			return errors.BadRequest("Nama proyek sudah ada")
+		} else if err != nil {
+			return errors.InternalServerError(err.Error())
+		}
+
+		initialProjectExpenditures := make([]*model.ProjectExpenditure, len(model.InitialProjectExpenditures))
+		for i := range model.InitialProjectExpenditures {
+			projectExpenditure := model.InitialProjectExpenditures[i]
+			projectExpenditure.ProjectID = project.ID
+			initialProjectExpenditures[i] = &projectExpenditure
+		}
+
+		if err := tx.ProjectExpenditure().Create(ctx, initialProjectExpenditures...); err != nil {
+			return errors.InternalServerError(err.Error())
+		}
+
+		if err := tx.ProjectAssignment().Create(ctx, &model.ProjectAssignment{
+			ProjectID:   project.ID,
+			InspectorID: project.InspectorID,
+		}); err != nil {
+			return errors.InternalServerError(err.Error())
+		}
+
+		if err := tx.ProjectMember().Create(ctx, &model.ProjectMember{
+			ProjectID:   project.ID,
+			InspectorID: project.InspectorID,
+			Role:        model.LeadInspector,
+		}); err != nil {
+			return errors.InternalServerError(err.Error())
+		}
+
+		return nil
+	})
+}
+
+func (s *projectService) GetListProject(
+	ctx context.Context,
+	user auth.User,
+	param *model.ProjectParam,
+) ([]model.ProjectListResponse, error) {
+	param.SetDefaultPagination()
+
+	filter := repository.ProjectFilter{
+		Keyword: param.Keyword,
+		Limit:   param.Limit,
+		Offset:  param.Offset,
+	}
+	if !hasPermission(user, model.ProjectReadAll) {
+		filter.InspectorID = user.ID
+	}
+
+	projectListResponses := []model.ProjectListResponse{}
+	projects, err := s.repo.Project().List(ctx, filter)
+	if err != nil {
+		return projectListResponses, errors.InternalServerError(err.Error())
+	}
+
+	for _, project := range projects {
+		projectListResponses = append(projectListResponses, model.ProjectListResponse{
+			ID:            project.ID,
+			Name:          project.Name,
+			Type:          model.GetProjectTypeStyle(project.Type),
+			Status:        model.GetProjectStatusStyle(project.Status),
+			UpdatedAt:     project.UpdatedAt,
+			InspectorName: project.Inspector.Name,
+		})
+	}
+
+	param.TotalElement, err = s.repo.Project().Count(ctx, filter)
+	if err != nil {
+		return projectListResponses, errors.InternalServerError(err.Error())
+	}
+
+	param.ProcessPagination(int64(len(projectListResponses)))
+
+	return projectListResponses, nil
+}
+
+func (s *projectService) GetListProjectName(
+	ctx context.Context,
+	user auth.User,
+) ([]model.ProjectNameResponse, error) {
+	var filter repository.ProjectFilter
+	if !hasPermission(user, model.ProjectReadAll) {
+		filter.InspectorID = user.ID
+	}
+
+	projectNames := []model.ProjectNameResponse{}
+	projects, err := s.repo.Project().List(ctx, filter)
+	if err != nil {
+		return projectNames, errors.InternalServerError(err.Error())
+	}
+
+	for _, project := range projects {
+		projectNames = append(projectNames, model.ProjectNameResponse{
+			ID:   project.ID,
+			Name: project.Name,
+		})
+	}
+
+	return projectNames, nil
+}
+
+func (s *projectService) GetProject(ctx context.Context, user auth.User, projectID int64) (model.Project, error) {
+	project, err := s.repo.Project().Get(ctx, projectID)
+	if repository.IsNotFound(err) {
+		return project, errors.NotFound("proyek tidak ditemukan")
+	} else if err != nil {
+		return project, errors.InternalServerError(err.Error())
+	}
+
+	if !canSeeProject(user, project) {
+		return model.Project{}, errors.NotFound("proyek tidak ditemukan")
+	}
+
+	return project, nil
+}
+
+// canSeeProject reports whether the user may see the project,
+// a user without project:read:all only sees the projects assigned to them
+func canSeeProject(user auth.User, project model.Project) bool {
+	return hasPermission(user, model.ProjectReadAll) || project.IsMember(user.ID)
+}
+
+func (s *projectService) GetProjectDetail(
+	ctx context.Context,
+	user auth.User,
+	projectID int64,
+) (model.ProjectDetailResponse, error) {
+	var res model.ProjectDetailResponse
+
+	project, err := s.GetProject(ctx, user, projectID)
+	if err != nil {
+		return res, err
+	}
+
+	ppnPrice := -int64(float64(*project.Budget) * project.PPN)
+	pphPrice := -int64(float64(*project.Budget) * project.PPH)
+	totalBudget := *project.Budget + ppnPrice + pphPrice
+
+	projectBudget := model.ProjectBudget{
+		Budgets: []model.Budget{
+			{
+				Name:  "Pagu Pekerjaan",
+				Price: number.ConvertToRupiah(*project.Budget),
+			},
+			{
+				Name:  "PPN",
+				Price: number.ConvertToRupiah(ppnPrice),
+			},
+			{
+				Name:  "PPh",
+				Price: number.ConvertToRupiah(pphPrice),
+			},
+		},
+		PPNPercentage: project.PPN * 100,
+		PPHPercentage: project.PPH * 100,
+		Total:         number.ConvertToRupiah(totalBudget),
+	}
+
+	projectExpenditure, totalExpenditure, err := s.getProjectExpenditure(ctx, project.ID)
+	if err != nil {
+		return res, errors.InternalServerError(err.Error())
+	}
+
+	projectStats := model.ProjectStatistics{
+		TotalIncome:          number.ConvertToRupiah(*project.Income),
+		PercentageFromBudget: number.GetPercentage(*project.Income, totalBudget),
+	}
+
+	res = model.ProjectDetailResponse{
+		ID:                 project.ID,
+		Name:               project.Name,
+		Description:        project.Description,
+		Type:               model.GetProjectTypeStyle(project.Type),
+		Status:             model.GetProjectStatusStyle(project.Status),
+		DeptName:           project.DeptName,
+		CompanyName:        project.CompanyName,
+		Volume:             project.Volume,
+		Length:             project.Length,
+		Width:              project.Width,
+		InspectorName:      project.Inspector.Name,
+		StartDate:          project.StartDate,
+		FinalDate:          project.FinalDate,
+		ProjectBudget:      projectBudget,
+		ProjectExpenditure: projectExpenditure,
+		ProjectStatistics:  projectStats,
+		Margin:             number.ConvertToRupiah(totalBudget - totalExpenditure),
+	}
+
+	return res, nil
+}
+
+func (s *projectService) getProjectExpenditure(
+	ctx context.Context,
+	projectID int64,
+) (model.ProjectExpenditureResponse, int64, error) {
+	var res model.ProjectExpenditureResponse
+	var totalExpenditure int64
+
+	projectExpenditures, err := s.repo.ProjectExpenditure().List(ctx, projectID)
+	if err != nil {
+		return res, totalExpenditure, err
+	}
+
+	expenditures := []model.ProjectExpenditureList{}
+	for _, expenditure := range projectExpenditures {
+		expenditures = append(expenditures, model.ProjectExpenditureList{
+			ID:          expenditure.ID,
+			Sequence:    expenditure.Sequence,
+			Name:        expenditure.Name,
+			TotalPrice:  number.ConvertToRupiah(*expenditure.TotalPrice),
+			IsFixedCost: *expenditure.IsFixedCost,
+		})
+
+		totalExpenditure += *expenditure.TotalPrice
+	}
+
+	res.Expenditures = expenditures
+	res.SumTotal = number.ConvertToRupiah(totalExpenditure)
+
+	return res, totalExpenditure, nil
+}
+
+// UpdateProject fixes the master data of the project, the type can't change once the project has a transaction
+func (s *projectService) UpdateProject(
+	ctx context.Context,
+	user auth.User,
+	projectID int64,
+	body model.UpdateProjectBody,
+) error {
+	if body.Type != nil && !model.IsProjectTypeCorrect(*body.Type) {
+		return errors.BadRequest("Tipe proyek harus Drainase, Beton, Hotmix, atau Bangunan")
+	}
+
+	return s.repo.Transaction(ctx, func(tx repository.Interface) error {
+		// every posting locks the project ledger, no first transaction is posted between the check and the update
+		err := tx.Ledger().Lock(ctx, projectID)
+		if repository.IsLockTimeout(err) {
+			return errors.Conflict("Transaksi lain sedang diproses, silakan coba lagi")
+		} else if err != nil {
+			return errors.InternalServerError(err.Error())
+		}
+
+		project, err := tx.Project().Get(ctx, projectID)
+		if repository.IsNotFound(err) {
+			return errors.NotFound("Proyek tidak ditemukan")
+		} else if err != nil {
+			return errors.InternalServerError(err.Error())
+		}
+
+		if body.Type != nil && *body.Type != project.Type {
+			count, err := tx.Ledger().Count(ctx, repository.LedgerFilter{ProjectID: project.ID})
+			if err != nil {
+				return errors.InternalServerError(err.Error())
+			} else if count > 0 {
+				return errors.BadRequest("Tipe proyek tidak dapat diubah karena proyek sudah memiliki transaksi")
+			}
+			project.Type = *body.Type
+		}
+
+		if body.Name != nil {
+			project.Name = *body.Name
+		}
+		if body.Description != nil {
+			project.Description = *body.Description
+		}
+		if body.DeptName != nil {
+			project.DeptName = *body.DeptName
+		}
+		if body.CompanyName != nil {
+			project.CompanyName = *body.CompanyName
+		}
+		if body.StartDate != nil {
+			project.StartDate = *body.StartDate
+		}
+		if body.FinalDate != nil {
+			project.FinalDate = *body.FinalDate
+		}
+		if body.Volume != nil {
+			project.Volume = body.Volume
+		}
+		if body.Length != nil {
+			project.Length = body.Length
+		}
+		if body.Width != nil {
+			project.Width = body.Width
+		}
+
+		if project.FinalDate <= project.StartDate {
+			return errors.BadRequest("Tanggal selesai proyek harus setelah tanggal mulai")
+		}
+
+		err = tx.Project().Update(ctx, project, user.ID)
+		if repository.IsDuplicate(err) {
+			return errors.BadRequest("Nama proyek sudah ada")
+		} else if repository.IsNotFound(err) {
+			return errors.NotFound("Proyek tidak ditemukan")
+		} else if err != nil {
+			return errors.InternalServerError(err.Error())
+		}
+
+		return nil
+	})
+}
+
+func (s *projectService) UpdateProjectBudget(
+	ctx context.Context,
+	projectID int64,
+	body model.UpdateProjectBudgetBody,
+) error {
+	err := s.repo.Project().UpdateBudget(ctx, projectID, body.Budget, body.PPN, body.PPH)
+	if repository.IsNotFound(err) {
+		return errors.NotFound("Proyek tidak ditemukan")
+	} else if err != nil {
+		return errors.InternalServerError(err.Error())
+	}
+
+	return nil
+}
+
+func (s *projectService) UpdateProjectStatus(
+	ctx context.Context,
+	projectID int64,
+	body model.UpdateProjectStatusBody,
+) error {
+	if !model.IsProjectStatusCorrect(body.Status) {
+		return errors.BadRequest("Status proyek harus Sedang Berjalan, Selesai, Ditunda, atau Dibatalkan")
+	}
+
+	err := s.repo.Project().UpdateStatus(ctx, projectID, body.Status)
+	if repository.IsNotFound(err) {
+		return errors.NotFound("Proyek tidak ditemukan")
+	} else if err != nil {
+		return errors.InternalServerError(err.Error())
+	}
+
+	return nil
+}
+
+func (s *projectService) UpdateProjectApprovalThreshold(
+	ctx context.Context,
+	user auth.User,
+	projectID int64,
+	body model.UpdateApprovalThresholdBody,
+) error {
+	err := s.repo.Project().UpdateApprovalThreshold(ctx, projectID, body.Threshold, user.ID)
+	if repository.IsNotFound(err) {
+		return errors.NotFound("Proyek tidak ditemukan")
+	} else if err != nil {
+		return errors.InternalServerError(err.Error())
+	}
+
+	return nil
+}
+
+func (s *projectService) CreateProjectExpenditure(
+	ctx context.Context,
+	projectID int64,
+	body model.CreateProjectExpenditureBody,
+) error {
+	latestProjectExpenditure, err := s.repo.ProjectExpenditure().GetLastSequence(ctx, projectID)
+	if repository.IsNotFound(err) {
+		return errors.NotFound("Proyek tidak ditemukan")
+	} else if err != nil {
+		return errors.InternalServerError(err.Error())
+	}
+
+	projectExpenditure := model.ProjectExpenditure{
+		ProjectID:   projectID,
+		Sequence:    latestProjectExpenditure.Sequence + 1,
+		Name:        body.Name,
+		IsFixedCost: body.IsFixedCost,
+	}
+
+	if err := s.repo.ProjectExpenditure().Create(ctx, &projectExpenditure); err != nil {
+		return errors.InternalServerError(err.Error())
+	}
+
+	return nil
+}
+
+func (s *projectService) UpdateExpenditureApprovalThreshold(
+	ctx context.Context,
+	user auth.User,
+	param model.ExpenditureDetailParam,
+	body model.UpdateApprovalThresholdBody,
+) error {
+	err := s.repo.ProjectExpenditure().UpdateApprovalThreshold(
+		ctx,
+		param.ProjectID,
+		param.ExpenditureID,
+		body.Threshold,
+		user.ID,
+	)
+	if repository.IsNotFound(err) {
+		return errors.NotFound("pengeluaran proyek tidak ditemukan")
+	} else if err != nil {
+		return errors.InternalServerError(err.Error())
+	}
+
+	return nil
+}
@@ -0,0 +1,287 @@
+package service
+
+import (
+	"context"
+
+	"tigaputera-backend/sdk/auth"
+	errors "tigaputera-backend/sdk/error"
+	"tigaputera-backend/sdk/number"
+	"tigaputera-backend/src/model"
+	"tigaputera-backend/src/repository"
+)
+
+// ReassignProject moves the project to another inspector. The project balance of the previous inspector
+// is closed by a transfer out of it and opened by a transfer into the new inspector, both refer to the assignment.
+func (s *ledgerService) ReassignProject(
+	ctx context.Context,
+	user auth.User,
+	param model.ProjectParam,
+	body model.ReassignProjectBody,
+) error {
+	project, err := s.repo.Project().Get(ctx, param.ID)
+	if repository.IsNotFound(err) {
+		return errors.NotFound("proyek tidak ditemukan")
+	} else if err != nil {
+		return errors.InternalServerError(err.Error())
+	} else if project.InspectorID == body.InspectorID {
+		return errors.BadRequest("Proyek sudah ditangani oleh pengawas tersebut")
+	}
+
+	inspector, err := getProjectInspector(ctx, s.repo, body.InspectorID)
+	if err != nil {
+		return err
+	}
+
+	previousInspector := project.Inspector
+	previousInspector.ID = project.InspectorID
+
+	return s.repo.Transaction(ctx, func(tx repository.Interface) error {
+		if err := s.lockLedger(ctx, tx, project.ID, previousInspector.ID, inspector.ID); err != nil {
+			return err
+		}
+
+		if err := s.checkProjectHandover(ctx, tx, project.ID, previousInspector.ID); err != nil {
+			return err
+		}
+
+		err := tx.Project().UpdateInspector(ctx, project.ID, inspector.ID, previousInspector.ID)
+		if repository.IsNotFound(err) {
+			// reassigned by another request while waiting for the lock
+			return errors.Conflict("Proyek sedang dipindahkan, silakan coba lagi")
+		} else if err != nil {
+			return errors.InternalServerError(err.Error())
+		}
+
+		previousLedger, err := s.getLatestLedger(ctx, tx, previousInspector.ID, project.ID)
+		if err != nil {
+			return errors.InternalServerError(err.Error())
+		}
+
+		assignment := model.ProjectAssignment{
+			ProjectID:           project.ID,
+			InspectorID:         inspector.ID,
+			PreviousInspectorID: &previousInspector.ID,
+			HandoverAmount:      *previousLedger.FinalProjectBalance,
+			Note:                body.Note,
+			CreatedBy:           &user.ID,
+		}
+		if err := tx.ProjectAssignment().Create(ctx, &assignment); err != nil {
+			return errors.InternalServerError(err.Error())
+		}
+
+		if err := s.updateLeadMember(ctx, tx, project.ID, previousInspector.ID, inspector.ID); err != nil {
+			return errors.InternalServerError(err.Error())
+		}
+
+		if assignment.HandoverAmount == 0 {
+			return nil
+		}
+
+		err = s.insertHandoverLegs(ctx, tx, user, project.ID, &assignment.ID, previousInspector, inspector, previousLedger)
+		if err != nil {
+			return errors.InternalServerError(err.Error())
+		}
+
+		return nil
+	})
+}
+
+// updateLeadMember makes the new inspector the lead of the project, an assistant is promoted,
+// and removes the previous lead from the members
+func (s *ledgerService) updateLeadMember(
+	ctx context.Context,
+	tx repository.Interface,
+	projectID int64,
+	previousInspectorID int64,
+	inspectorID int64,
+) error {
+	err := tx.ProjectMember().UpdateRole(ctx, projectID, inspectorID, model.LeadInspector)
+	if repository.IsNotFound(err) {
+		err = tx.ProjectMember().Create(ctx, &model.ProjectMember{
+			ProjectID:   projectID,
+			InspectorID: inspectorID,
+			Role:        model.LeadInspector,
+		})
+	}
+	if err != nil {
+		return err
+	}
+
+	if err := tx.ProjectMember().Delete(ctx, projectID, previousInspectorID); err != nil && !repository.IsNotFound(err) {
+		return err
+	}
+
+	return nil
+}
+
+// checkProjectHandover refuses the handover while the previous inspector has expenditures waiting for
+// an approval or transfers waiting for a confirmation, they would be posted to a project they no longer hold
+func (s *ledgerService) checkProjectHandover(
+	ctx context.Context,
+	tx repository.Interface,
+	projectID int64,
+	inspectorID int64,
+) error {
+	pendingExpenditures, err := tx.Ledger().Count(ctx, repository.LedgerFilter{
+		InspectorID: inspectorID,
+		ProjectID:   projectID,
+		Status:      model.Pending,
+	})
+	if err != nil {
+		return errors.InternalServerError(err.Error())
+	} else if pendingExpenditures > 0 {
+		return errors.BadRequest("Masih ada pengeluaran yang menunggu persetujuan, selesaikan sebelum memindahkan proyek")
+	}
+
+	pendingTransfers, err := tx.FundTransfer().Count(ctx, repository.FundTransferFilter{
+		InspectorID: inspectorID,
+		ProjectID:   projectID,
+		Status:      model.TransferPending,
+	})
+	if err != nil {
+		return errors.InternalServerError(err.Error())
+	} else if pendingTransfers > 0 {
+		return errors.BadRequest("Masih ada transfer dana yang menunggu konfirmasi, selesaikan sebelum memindahkan proyek")
+	}
+
+	return nil
+}
+
+// insertHandoverLegs moves the whole project balance of the previous inspector to the inspector,
+// refID is the assignment of a reassigned project and nil for a member leaving it
+func (s *ledgerService) insertHandoverLegs(
+	ctx context.Context,
+	tx repository.Interface,
+	user auth.User,
+	projectID int64,
+	refID *int64,
+	previousInspector model.User,
+	inspector model.User,
+	previousLedger model.Ledger,
+) error {
+	inspectorLedger, err := s.getLatestLedger(ctx, tx, inspector.ID, projectID)
+	if err != nil {
+		return err
+	}
+
+	amount := *previousLedger.FinalProjectBalance
+
+	previousDesc := "Serah terima proyek ke " + inspector.Name
+	prevPreviousBalance := *previousLedger.FinalInspectorBalance
+	finalPreviousBalance := prevPreviousBalance - amount
+	prevPreviousProjectBalance := *previousLedger.FinalProjectBalance
+	finalPreviousProjectBalance := prevPreviousProjectBalance - amount
+	closingLeg := model.Ledger{
+		InspectorID:             previousInspector.ID,
+		ProjectID:               projectID,
+		LedgerType:              model.Transfer,
+		RefID:                   refID,
+		Ref:                     inspector.Name,
+		Description:             &previousDesc,
+		Amount:                  1,
+		Price:                   -amount,
+		TotalPrice:              -amount,
+		CurrentInspectorBalance: &prevPreviousBalance,
+		FinalInspectorBalance:   &finalPreviousBalance,
+		CurrentProjectBalance:   &prevPreviousProjectBalance,
+		FinalProjectBalance:     &finalPreviousProjectBalance,
+		PostedAt:                getPostedAt(previousLedger),
+		CreatedBy:               &user.ID,
+	}
+
+	if err := tx.Ledger().Create(ctx, &closingLeg); err != nil {
+		return err
+	}
+
+	inspectorDesc := "Serah terima proyek dari " + previousInspector.Name
+	prevInspectorBalance := *inspectorLedger.FinalInspectorBalance
+	finalInspectorBalance := prevInspectorBalance + amount
+	prevProjectBalance := *inspectorLedger.FinalProjectBalance
+	finalProjectBalance := prevProjectBalance + amount
+	openingLeg := model.Ledger{
+		InspectorID:             inspector.ID,
+		ProjectID:               projectID,
+		LedgerType:              model.Transfer,
+		RefID:                   refID,
+		Ref:                     previousInspector.Name,
+		Description:             &inspectorDesc,
+		Amount:                  1,
+		Price:                   amount,
+		TotalPrice:              amount,
+		CurrentInspectorBalance: &prevInspectorBalance,
+		FinalInspectorBalance:   &finalInspectorBalance,
+		CurrentProjectBalance:   &prevProjectBalance,
+		FinalProjectBalance:     &finalProjectBalance,
+		PostedAt:                getPostedAt(inspectorLedger),
+		CreatedBy:               &user.ID,
+	}
+
+	return tx.Ledger().Create(ctx, &openingLeg)
+}
+
+func (s *ledgerService) GetListProjectAssignment(
+	ctx context.Context,
+	user auth.User,
+	param model.ProjectParam,
+) ([]model.ProjectAssignmentResponse, error) {
+	assignmentResponses := []model.ProjectAssignmentResponse{}
+
+	project, err := s.repo.Project().Get(ctx, param.ID)
+	if repository.IsNotFound(err) || (err == nil && !canSeeProject(user, project)) {
+		return assignmentResponses, errors.NotFound("proyek tidak ditemukan")
+	} else if err != nil {
+		return assignmentResponses, errors.InternalServerError(err.Error())
+	}
+
+	assignments, err := s.repo.ProjectAssignment().List(ctx, project.ID)
+	if err != nil {
+		return assignmentResponses, errors.InternalServerError(err.Error())
+	}
+
+	for _, assignment := range assignments {
+		assignmentResponses = append(assignmentResponses, model.ProjectAssignmentResponse{
+			ID:                    assignment.ID,
+			Timestamp:             assignment.CreatedAt,
+			InspectorName:         assignment.Inspector.Name,
+			PreviousInspectorName: assignment.PreviousInspector.Name,
+			HandoverAmount:        number.ConvertToRupiah(assignment.HandoverAmount),
+			Note:                  assignment.Note,
+		})
+	}
+
+	return assignmentResponses, nil
+}
+
+// getProjectInspectorIDs returns every inspector who held or worked on the project, the ledger of the project
+// is made of their ledgers in it
+func (s *ledgerService) getProjectInspectorIDs(ctx context.Context, project model.Project) ([]int64, error) {
+	assignments, err := s.repo.ProjectAssignment().List(ctx, project.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	members, err := s.repo.ProjectMember().List(ctx, repository.ProjectMemberFilter{
+		ProjectID:   project.ID,
+		WithRemoved: true,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	inspectorIDs := []int64{project.InspectorID}
+	isAdded := map[int64]bool{project.InspectorID: true}
+	for _, assignment := range assignments {
+		if !isAdded[assignment.InspectorID] {
+			inspectorIDs = append(inspectorIDs, assignment.InspectorID)
+			isAdded[assignment.InspectorID] = true
+		}
+	}
+	for _, member := range members {
+		if !isAdded[member.InspectorID] {
+			inspectorIDs = append(inspectorIDs, member.InspectorID)
+			isAdded[member.InspectorID] = true
+		}
+	}
+
+	return inspectorIDs, nil
+}
@@ -0,0 +1,165 @@
+package service
+
+import (
+	"context"
+
+	"tigaputera-backend/sdk/auth"
+	errors "tigaputera-backend/sdk/error"
+	"tigaputera-backend/sdk/number"
+	"tigaputera-backend/src/model"
+	"tigaputera-backend/src/repository"
+)
+
+func (s *ledgerService) GetListProjectMember(
+	ctx context.Context,
+	user auth.User,
+	param model.ProjectParam,
+) ([]model.ProjectMemberResponse, error) {
+	project, err := s.repo.Project().Get(ctx, param.ID)
+	if repository.IsNotFound(err) || (err == nil && !canSeeProject(user, project)) {
+		return []model.ProjectMemberResponse{}, errors.NotFound("proyek tidak ditemukan")
+	} else if err != nil {
+		return []model.ProjectMemberResponse{}, errors.InternalServerError(err.Error())
+	}
+
+	memberResponses, _, err := s.getProjectMemberBalances(ctx, project.ID)
+	if err != nil {
+		return memberResponses, errors.InternalServerError(err.Error())
+	}
+
+	return memberResponses, nil
+}
+
+// getProjectMemberBalances returns the members with their project balance and the balance of the project,
+// the balances of the members who left were handed over so only the current members are summed
+func (s *ledgerService) getProjectMemberBalances(
+	ctx context.Context,
+	projectID int64,
+) ([]model.ProjectMemberResponse, int64, error) {
+	memberResponses := []model.ProjectMemberResponse{}
+
+	members, err := s.repo.ProjectMember().List(ctx, repository.ProjectMemberFilter{ProjectID: projectID})
+	if err != nil {
+		return memberResponses, 0, err
+	}
+
+	var balance int64
+	for _, member := range members {
+		latestLedger, err := s.getLatestLedger(ctx, s.repo, member.InspectorID, projectID)
+		if err != nil {
+			return memberResponses, 0, err
+		}
+
+		memberResponses = append(memberResponses, model.ProjectMemberResponse{
+			InspectorID:    member.InspectorID,
+			InspectorName:  member.Inspector.Name,
+			Role:           member.Role,
+			CurrentBalance: number.ConvertToRupiah(*latestLedger.FinalProjectBalance),
+		})
+		balance += *latestLedger.FinalProjectBalance
+	}
+
+	return memberResponses, balance, nil
+}
+
+// AddProjectMember adds an inspector to the project as an assistant of the lead
+func (s *ledgerService) AddProjectMember(
+	ctx context.Context,
+	user auth.User,
+	param model.ProjectParam,
+	body model.AddProjectMemberBody,
+) error {
+	project, err := s.repo.Project().Get(ctx, param.ID)
+	if repository.IsNotFound(err) {
+		return errors.NotFound("proyek tidak ditemukan")
+	} else if err != nil {
+		return errors.InternalServerError(err.Error())
+	} else if !project.IsRunning() {
+		return errors.BadRequest("Anggota hanya dapat ditambahkan ke proyek yang sedang berjalan")
+	}
+
+	inspector, err := getProjectInspector(ctx, s.repo, body.InspectorID)
+	if err != nil {
+		return err
+	}
+
+	err = s.repo.ProjectMember().Create(ctx, &model.ProjectMember{
+		ProjectID:   project.ID,
+		InspectorID: inspector.ID,
+		Role:        model.AssistantInspector,
+		CreatedBy:   &user.ID,
+	})
+	if repository.IsDuplicate(err) {
+		return errors.BadRequest("Pengawas sudah menjadi anggota proyek")
+	} else if err != nil {
+		return errors.InternalServerError(err.Error())
+	}
+
+	return nil
+}
+
+// RemoveProjectMember removes an assistant from the project, their project balance is handed over to the lead.
+// The lead can't be removed, the project is reassigned instead.
+func (s *ledgerService) RemoveProjectMember(
+	ctx context.Context,
+	user auth.User,
+	param model.ProjectMemberParam,
+) error {
+	project, err := s.repo.Project().Get(ctx, param.ProjectID)
+	if repository.IsNotFound(err) {
+		return errors.NotFound("proyek tidak ditemukan")
+	} else if err != nil {
+		return errors.InternalServerError(err.Error())
+	}
+
+	member, err := s.repo.ProjectMember().Get(ctx, project.ID, param.InspectorID)
+	if repository.IsNotFound(err) {
+		return errors.NotFound("Anggota proyek tidak ditemukan")
+	} else if err != nil {
+		return errors.InternalServerError(err.Error())
+	} else if member.Role == model.LeadInspector || member.InspectorID == project.InspectorID {
+		return errors.BadRequest("Pengawas utama tidak dapat dihapus, pindahkan proyek ke pengawas lain terlebih dahulu")
+	}
+
+	lead := project.Inspector
+	lead.ID = project.InspectorID
+
+	return s.repo.Transaction(ctx, func(tx repository.Interface) error {
+		if err := s.lockLedger(ctx, tx, project.ID, member.InspectorID, lead.ID); err != nil {
+			return err
+		}
+
+		// the balance goes to the lead locked above, not to one who took over the project meanwhile
+		current, err := tx.Project().Get(ctx, project.ID)
+		if err != nil {
+			return errors.InternalServerError(err.Error())
+		} else if current.InspectorID != lead.ID {
+			return errors.Conflict("Proyek sedang dipindahkan, silakan coba lagi")
+		}
+
+		if err := s.checkProjectHandover(ctx, tx, project.ID, member.InspectorID); err != nil {
+			return err
+		}
+
+		err = tx.ProjectMember().Delete(ctx, project.ID, member.InspectorID)
+		if repository.IsNotFound(err) {
+			return errors.NotFound("Anggota proyek tidak ditemukan")
+		} else if err != nil {
+			return errors.InternalServerError(err.Error())
+		}
+
+		memberLedger, err := s.getLatestLedger(ctx, tx, member.InspectorID, project.ID)
+		if err != nil {
+			return errors.InternalServerError(err.Error())
+		} else if *memberLedger.FinalProjectBalance == 0 {
+			return nil
+		}
+
+		err = s.insertHandoverLegs(ctx, tx, user, project.ID, nil, member.Inspector, lead, memberLedger)
+		if err != nil {
+			return errors.InternalServerError(err.Error())
+		}
+
+		return nil
+	})
+}
@@ -0,0 +1,146 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"tigaputera-backend/sdk/auth"
+	errors "tigaputera-backend/sdk/error"
+	"tigaputera-backend/src/model"
+	"tigaputera-backend/src/repository"
+)
+
+// getPermissions returns the permissions of the role
+func (s *userService) getPermissions(ctx context.Context, role model.Role) ([]model.Permission, error) {
+	if role == model.Admin {
+		return model.AdminPermissions, nil
+	}
+
+	rolePermissions, err := s.repo.RolePermission().List(ctx, role)
+	if err != nil {
+		return nil, err
+	}
+
+	permissions := []model.Permission{}
+	for _, rolePermission := range rolePermissions {
+		permissions = append(permissions, rolePermission.Permission)
+	}
+
+	return permissions, nil
+}
+
+func (s *userService) GetListRole(ctx context.Context) ([]model.RoleResponse, error) {
+	roles := []model.RoleResponse{{Role: model.Admin, Permissions: model.AdminPermissions}}
+
+	rolePermissions, err := s.repo.RolePermission().List(ctx, "")
+	if err != nil {
+		return roles, errors.InternalServerError(err.Error())
+	}
+
+	// the permissions are ordered by the role, so the permissions of a role are next to each other
+	for _, rolePermission := range rolePermissions {
+		last := &roles[len(roles)-1]
+		if last.Role != rolePermission.Role {
+			roles = append(roles, model.RoleResponse{Role: rolePermission.Role})
+			last = &roles[len(roles)-1]
+		}
+		last.Permissions = append(last.Permissions, rolePermission.Permission)
+	}
+
+	return roles, nil
+}
+
+func (s *userService) UpdateRole(ctx context.Context, param model.RoleParam, body model.UpdateRoleBody) error {
+	role := model.Role(strings.TrimSpace(param.Role))
+	if role == "" || len(role) > 255 {
+		return errors.BadRequest("Nama peran maksimal 255 karakter")
+	} else if role == model.Admin {
+		return errors.BadRequest("Hak akses direktur tidak dapat diubah")
+	}
+
+	granted := map[model.Permission]bool{}
+	rolePermissions := []model.RolePermission{}
+	for _, permission := range body.Permissions {
+		if !model.IsPermissionCorrect(permission) {
+			return errors.BadRequest(fmt.Sprintf("Hak akses %s tidak dikenal", permission))
+		} else if granted[permission] {
+			continue
+		}
+
+		granted[permission] = true
+		rolePermissions = append(rolePermissions, model.RolePermission{Role: role, Permission: permission})
+	}
+
+	if err := s.repo.RolePermission().Replace(ctx, role, rolePermissions); err != nil {
+		return errors.InternalServerError(err.Error())
+	}
+
+	return nil
+}
+
+// UpdateUserRole moves the user to another role, the role of a director can't be given or taken
+func (s *userService) UpdateUserRole(
+	ctx context.Context,
+	param model.UserParam,
+	body model.UpdateUserRoleBody,
+) error {
+	role := model.Role(strings.TrimSpace(body.Role))
+	if role == model.Admin {
+		return errors.BadRequest("Peran direktur tidak dapat diberikan")
+	}
+
+	rolePermissions, err := s.repo.RolePermission().List(ctx, role)
+	if err != nil {
+		return errors.InternalServerError(err.Error())
+	} else if len(rolePermissions) == 0 {
+		return errors.NotFound("Peran tidak ditemukan")
+	}
+
+	target, err := s.repo.User().Get(ctx, param.ID)
+	if repository.IsNotFound(err) {
+		return errors.NotFound("Pengguna tidak ditemukan")
+	} else if err != nil {
+		return errors.InternalServerError(err.Error())
+	} else if target.Role == model.Admin {
+		return errors.BadRequest("Peran direktur tidak dapat diubah")
+	}
+
+	err = s.repo.User().UpdateRole(ctx, target.ID, role)
+	if repository.IsNotFound(err) {
+		return errors.NotFound("Pengguna tidak ditemukan")
+	} else if err != nil {
+		return errors.InternalServerError(err.Error())
+	}
+
+	return nil
+}
+
+// hasPermission reports whether the role of the user grants the permission
+func hasPermission(user auth.User, permission model.Permission) bool {
+	return user.HasPermission(string(permission))
+}
+
+// getProjectInspector gets an active user whose role records the transactions of the projects,
+// the directors and the read-only roles can't be given a project
+func getProjectInspector(ctx context.Context, repo repository.Interface, inspectorID int64) (model.User, error) {
+	inspector, err := repo.User().Get(ctx, inspectorID)
+	if repository.IsNotFound(err) {
+		return inspector, errors.NotFound("Pengawas tidak ditemukan")
+	} else if err != nil {
+		return inspector, errors.InternalServerError(err.Error())
+	}
+
+	rolePermissions, err := repo.RolePermission().List(ctx, inspector.Role)
+	if err != nil {
+		return inspector, errors.InternalServerError(err.Error())
+	}
+
+	for _, rolePermission := range rolePermissions {
+		if rolePermission.Permission == model.LedgerWrite {
+			return inspector, nil
+		}
+	}
+
+	return inspector, errors.NotFound("Pengawas tidak ditemukan")
+}
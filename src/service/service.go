@@ -0,0 +1,73 @@
+package service
+
+import (
+	"time"
+
+	"tigaputera-backend/sdk/imaging"
+	"tigaputera-backend/sdk/jwt"
+	"tigaputera-backend/sdk/password"
+	"tigaputera-backend/sdk/signedurl"
+	"tigaputera-backend/sdk/storage"
+	"tigaputera-backend/sdk/totp"
+	"tigaputera-backend/src/repository"
+)
+
+// Service holds the business logic of the api, the handlers only bind the requests and render the results
+type Service struct {
+	User        UserService
+	Project     ProjectService
+	Ledger      LedgerService
+	Statistics  StatisticsService
+	Idempotency IdempotencyService
+	Storage     StorageService
+	Audit       AuditService
+}
+
+func Init(
+	repo repository.Interface,
+	jwt jwt.Interface,
+	password password.Interface,
+	storage storage.Interface,
+	signedURL signedurl.Interface,
+	imaging imaging.Interface,
+	totp totp.Interface,
+) *Service {
+	return &Service{
+		User:        NewUserService(repo, jwt, password, totp),
+		Project:     NewProjectService(repo),
+		Ledger:      NewLedgerService(repo, storage, signedURL, imaging),
+		Statistics:  NewStatisticsService(repo, storage, signedURL),
+		Idempotency: NewIdempotencyService(repo),
+		Storage:     NewStorageService(repo, storage, signedURL),
+		Audit:       NewAuditService(repo),
+	}
+}
+
+func getInt64(value *int64) int64 {
+	if value == nil {
+		return 0
+	}
+
+	return *value
+}
+
+func boolPtr(value bool) *bool {
+	return &value
+}
+
+// getStartTime returns the start of the day intervalMonth months ago in UTC
+func getStartTime(intervalMonth int64) int64 {
+	beginMonth := time.Now().UTC().AddDate(0, -int(intervalMonth), 0)
+	startTime := time.Date(
+		beginMonth.Year(),
+		beginMonth.Month(),
+		beginMonth.Day(),
+		0,
+		0,
+		0,
+		0,
+		beginMonth.Location(),
+	).Unix()
+
+	return startTime
+}
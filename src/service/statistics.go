@@ -0,0 +1,624 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"time"
+
+	"tigaputera-backend/sdk/auth"
+	errors "tigaputera-backend/sdk/error"
+	"tigaputera-backend/sdk/number"
+	"tigaputera-backend/sdk/signedurl"
+	"tigaputera-backend/sdk/storage"
+	"tigaputera-backend/src/model"
+	"tigaputera-backend/src/repository"
+
+	"github.com/xuri/excelize/v2"
+)
+
+var statsIntervalMonths = []int64{1, 3, 6, 12}
+
+// the folder the ledger reports are uploaded to
+const ledgerReportPath = "ledger_report"
+
+type StatisticsService interface {
+	RefreshStatistics(ctx context.Context) error
+	GetUserStats(ctx context.Context, user auth.User) (model.InspectorStatsResponse, error)
+	GetUserStatsDetail(
+		ctx context.Context,
+		user auth.User,
+		param *model.InspectorStatsParam,
+	) (model.InspectorStatsDetailResponse, error)
+	CreateLedgerReport(ctx context.Context) error
+	// GetLedgerReportURL signs the url of the report created for the interval, the url expires shortly
+	GetLedgerReportURL(ctx context.Context, param model.LedgerReportParam) (model.LedgerReportResponse, error)
+}
+
+type statisticsService struct {
+	repo      repository.Interface
+	storage   storage.Interface
+	signedURL signedurl.Interface
+}
+
+func NewStatisticsService(
+	repo repository.Interface,
+	storage storage.Interface,
+	signedURL signedurl.Interface,
+) StatisticsService {
+	return &statisticsService{
+		repo:      repo,
+		storage:   storage,
+		signedURL: signedURL,
+	}
+}
+
+func (s *statisticsService) RefreshStatistics(ctx context.Context) error {
+	return s.repo.Transaction(ctx, func(tx repository.Interface) error {
+		users, err := tx.User().List(ctx, repository.UserFilter{ExcludeRole: model.Admin})
+		if err != nil {
+			return errors.InternalServerError(err.Error())
+		}
+
+		inspectorStats, err := s.getInspectorStats(ctx, tx, users)
+		if err != nil {
+			return errors.InternalServerError(err.Error())
+		}
+
+		if err := tx.Statistics().ReplaceInspectorStats(ctx, inspectorStats); err != nil {
+			return errors.InternalServerError(err.Error())
+		}
+
+		return nil
+	})
+}
+
+// getInspectorStats computes the stats of every interval for all inspectors together,
+// stored with inspector id 0, and for each inspector
+func (s *statisticsService) getInspectorStats(
+	ctx context.Context,
+	tx repository.Interface,
+	users []model.User,
+) ([]model.MqtInspectorStats, error) {
+	var inspectorStats []model.MqtInspectorStats
+
+	allInspector := model.User{Username: "All"}
+	endDateUnix := getStartTime(0)
+
+	for _, intervalMonth := range statsIntervalMonths {
+		startDateUnix := getStartTime(intervalMonth)
+
+		for _, user := range append([]model.User{allInspector}, users...) {
+			inspectorID := user.ID
+			filter := repository.StatisticsFilter{
+				CreatedFrom: startDateUnix,
+				InspectorID: inspectorID,
+			}
+
+			projectCountData, err := s.getInspectorProjectCount(ctx, tx, filter)
+			if err != nil {
+				return inspectorStats, err
+			}
+
+			totalExpenditureData, err := s.getInspectorExpenditureCount(ctx, tx, filter)
+			if err != nil {
+				return inspectorStats, err
+			}
+
+			filter.LedgerType = model.Debit
+			totalIncomeData, err := tx.Statistics().SumLedger(ctx, filter)
+			if err != nil {
+				return inspectorStats, err
+			}
+
+			margin := totalIncomeData - totalExpenditureData.Total
+
+			inspectorStats = append(inspectorStats, model.MqtInspectorStats{
+				StartTime:                startDateUnix,
+				EndTime:                  endDateUnix,
+				IntervalMonth:            intervalMonth,
+				InspectorID:              &inspectorID,
+				InspectorUsername:        user.Username,
+				TotalDrainageProject:     &projectCountData.Drainage,
+				TotalAshpaltProject:      &projectCountData.Ashpalt,
+				TotalConcreteProject:     &projectCountData.Concrete,
+				TotalBuildingProject:     &projectCountData.Building,
+				TotalProject:             &projectCountData.Total,
+				TotalDrainageExpenditure: &totalExpenditureData.Drainage,
+				TotalAshpaltExpenditure:  &totalExpenditureData.Ashpalt,
+				TotalConcreteExpenditure: &totalExpenditureData.Concrete,
+				TotalBuildingExpenditure: &totalExpenditureData.Building,
+				TotalExpenditure:         &totalExpenditureData.Total,
+				TotalIncome:              &totalIncomeData,
+				Margin:                   &margin,
+			})
+		}
+	}
+
+	return inspectorStats, nil
+}
+
+func (s *statisticsService) getInspectorProjectCount(
+	ctx context.Context,
+	tx repository.Interface,
+	filter repository.StatisticsFilter,
+) (model.ProjectData, error) {
+	var totalProjectStats model.ProjectData
+
+	projectCounts := map[string]*int64{
+		"Drainase": &totalProjectStats.Drainage,
+		"Hotmix":   &totalProjectStats.Ashpalt,
+		"Beton":    &totalProjectStats.Concrete,
+		"Bangunan": &totalProjectStats.Building,
+	}
+
+	for projectType, total := range projectCounts {
+		filter.ProjectType = projectType
+
+		count, err := tx.Statistics().CountProject(ctx, filter)
+		if err != nil {
+			return totalProjectStats, err
+		}
+
+		*total = count
+		totalProjectStats.Total += count
+	}
+
+	return totalProjectStats, nil
+}
+
+func (s *statisticsService) getInspectorExpenditureCount(
+	ctx context.Context,
+	tx repository.Interface,
+	filter repository.StatisticsFilter,
+) (model.ProjectData, error) {
+	var totalExpenditureStats model.ProjectData
+
+	expenditureSums := map[string]*int64{
+		"Drainase": &totalExpenditureStats.Drainage,
+		"Hotmix":   &totalExpenditureStats.Ashpalt,
+		"Beton":    &totalExpenditureStats.Concrete,
+		"Bangunan": &totalExpenditureStats.Building,
+	}
+
+	filter.LedgerType = model.Credit
+	for projectType, total := range expenditureSums {
+		filter.ProjectType = projectType
+
+		// the total price of an expenditure is negative
+		sum, err := tx.Statistics().SumLedger(ctx, filter)
+		if err != nil {
+			return totalExpenditureStats, err
+		}
+
+		*total = -sum
+		totalExpenditureStats.Total += -sum
+	}
+
+	return totalExpenditureStats, nil
+}
+
+func (s *statisticsService) GetUserStats(ctx context.Context, user auth.User) (model.InspectorStatsResponse, error) {
+	var res model.InspectorStatsResponse
+
+	var inspectorID int64
+	if !hasPermission(user, model.LedgerReadAll) {
+		inspectorID = user.ID
+	}
+
+	userStats, err := s.repo.Statistics().GetInspectorStats(ctx, inspectorID, 1)
+	if repository.IsNotFound(err) {
+		res = model.InspectorStatsResponse{
+			TotalExpenditure: number.ConvertToRupiah(0),
+			TotalIncome:      number.ConvertToRupiah(0),
+			Margin:           number.ConvertToRupiah(0),
+		}
+
+		return res, nil
+	} else if err != nil {
+		return res, errors.InternalServerError(err.Error())
+	}
+
+	res = model.InspectorStatsResponse{
+		TotalProject:     *userStats.TotalProject,
+		TotalExpenditure: number.ConvertToRupiah(*userStats.TotalExpenditure),
+		TotalIncome:      number.ConvertToRupiah(*userStats.TotalIncome),
+		Margin:           number.ConvertToRupiah(*userStats.Margin),
+	}
+
+	return res, nil
+}
+
+func (s *statisticsService) GetUserStatsDetail(
+	ctx context.Context,
+	user auth.User,
+	param *model.InspectorStatsParam,
+) (model.InspectorStatsDetailResponse, error) {
+	if !hasPermission(user, model.LedgerReadAll) {
+		param.InspectorID = user.ID
+	}
+
+	if param.IntervalMonth == 0 {
+		param.IntervalMonth = 1
+	}
+
+	userStats, err := s.repo.Statistics().GetInspectorStats(ctx, param.InspectorID, param.IntervalMonth)
+	if repository.IsNotFound(err) {
+		userStats = model.MqtInspectorStats{
+			StartTime:                param.StartTime,
+			EndTime:                  time.Time{}.Unix(),
+			IntervalMonth:            param.IntervalMonth,
+			InspectorID:              &param.InspectorID,
+			InspectorUsername:        user.Username,
+			TotalDrainageProject:     new(int64), // 0
+			TotalAshpaltProject:      new(int64), // 0
+			TotalConcreteProject:     new(int64), // 0
+			TotalBuildingProject:     new(int64), // 0
+			TotalProject:             new(int64), // 0
+			TotalDrainageExpenditure: new(int64), // 0
+			TotalAshpaltExpenditure:  new(int64), // 0
+			TotalConcreteExpenditure: new(int64), // 0
+			TotalBuildingExpenditure: new(int64), // 0
+			TotalExpenditure:         new(int64), // 0
+			TotalIncome:              new(int64), // 0
+			Margin:                   new(int64), // 0
+		}
+	} else if err != nil {
+		return model.InspectorStatsDetailResponse{}, errors.InternalServerError(err.Error())
+	}
+
+	return s.getUserStatsDetailResponse(userStats), nil
+}
+
+func (s *statisticsService) getUserStatsDetailResponse(
+	userStats model.MqtInspectorStats,
+) model.InspectorStatsDetailResponse {
+	projectCount := model.TotalProjectStats{
+		TotalProject: *userStats.TotalProject,
+		Drainage: model.Stats{
+			Name:  "Drainase",
+			Total: *userStats.TotalDrainageProject,
+			Percentage: number.GetPercentage(
+				*userStats.TotalDrainageProject,
+				*userStats.TotalProject,
+			),
+		},
+		Ashpalt: model.Stats{
+			Name:  "Hotmix",
+			Total: *userStats.TotalAshpaltProject,
+			Percentage: number.GetPercentage(
+				*userStats.TotalAshpaltProject,
+				*userStats.TotalProject,
+			),
+		},
+		Concrete: model.Stats{
+			Name:  "Beton",
+			Total: *userStats.TotalConcreteProject,
+			Percentage: number.GetPercentage(
+				*userStats.TotalConcreteProject,
+				*userStats.TotalProject,
+			),
+		},
+		Building: model.Stats{
+			Name:  "Bangunan",
+			Total: *userStats.TotalBuildingProject,
+			Percentage: number.GetPercentage(
+				*userStats.TotalBuildingProject,
+				*userStats.TotalProject,
+			),
+		},
+	}
+
+	expenditure := model.TotalExpenditureStats{
+		TotalExpenditure: number.ConvertToRupiah(*userStats.TotalExpenditure),
+		Drainage: model.StatsString{
+			Name:  "Drainase",
+			Total: number.ConvertToRupiah(*userStats.TotalDrainageExpenditure),
+			Percentage: number.GetPercentage(
+				*userStats.TotalDrainageExpenditure,
+				*userStats.TotalExpenditure,
+			),
+		},
+		Ashpalt: model.StatsString{
+			Name:  "Hotmix",
+			Total: number.ConvertToRupiah(*userStats.TotalAshpaltExpenditure),
+			Percentage: number.GetPercentage(
+				*userStats.TotalAshpaltExpenditure,
+				*userStats.TotalExpenditure,
+			),
+		},
+		Concrete: model.StatsString{
+			Name:  "Beton",
+			Total: number.ConvertToRupiah(*userStats.TotalConcreteExpenditure),
+			Percentage: number.GetPercentage(
+				*userStats.TotalConcreteExpenditure,
+				*userStats.TotalExpenditure,
+			),
+		},
+		Building: model.StatsString{
+			Name:  "Bangunan",
+			Total: number.ConvertToRupiah(*userStats.TotalBuildingExpenditure),
+			Percentage: number.GetPercentage(
+				*userStats.TotalBuildingExpenditure,
+				*userStats.TotalExpenditure,
+			),
+		},
+	}
+
+	return model.InspectorStatsDetailResponse{
+		LastUpdated:       userStats.EndTime,
+		InspectorID:       *userStats.InspectorID,
+		InspectorUsername: userStats.InspectorUsername,
+		IntervalMonth:     userStats.IntervalMonth,
+		ProjectCount:      projectCount,
+		Expenditure:       expenditure,
+		Income:            number.ConvertToRupiah(*userStats.TotalIncome),
+		Margin:            number.ConvertToRupiah(*userStats.Margin),
+	}
+}
+
+// CreateLedgerReport overwrites the report of each interval, the reports no longer written are left
+// to the storage garbage collection
+func (s *statisticsService) CreateLedgerReport(ctx context.Context) error {
+	projects, err := s.repo.Project().List(ctx, repository.ProjectFilter{})
+	if err != nil {
+		return errors.InternalServerError(err.Error())
+	}
+
+	currentDateUnix := getStartTime(0)
+
+	for _, intervalMonth := range statsIntervalMonths {
+		startDateUnix := getStartTime(intervalMonth)
+
+		f := excelize.NewFile()
+
+		for _, project := range projects {
+			ledgers, err := s.repo.Ledger().List(ctx, repository.LedgerFilter{
+				ProjectID:   project.ID,
+				CreatedFrom: startDateUnix,
+				ExcludeType: model.Transfer,
+				Status:      model.Posted,
+				Ascending:   true,
+			})
+			if err != nil {
+				return errors.InternalServerError(err.Error())
+			}
+
+			if err := s.createNewSheetsExcel(f, project, ledgers, intervalMonth, currentDateUnix); err != nil {
+				return errors.InternalServerError(err.Error())
+			}
+		}
+
+		f.DeleteSheet("Sheet1")
+
+		excelBytes, err := f.WriteToBuffer()
+		if err != nil {
+			return errors.InternalServerError(err.Error())
+		}
+
+		if _, err := s.storage.UploadFromBytes(
+			ctx,
+			bytes.NewReader(excelBytes.Bytes()),
+			getLedgerReportFileName(intervalMonth),
+			ledgerReportPath,
+		); err != nil {
+			return errors.InternalServerError(err.Error())
+		}
+	}
+
+	return nil
+}
+
+func (s *statisticsService) GetLedgerReportURL(
+	ctx context.Context,
+	param model.LedgerReportParam,
+) (model.LedgerReportResponse, error) {
+	var res model.LedgerReportResponse
+
+	if param.IntervalMonth == 0 {
+		param.IntervalMonth = 1
+	}
+
+	if !isStatsIntervalMonth(param.IntervalMonth) {
+		return res, errors.BadRequest("interval_month harus 1, 3, 6, atau 12")
+	}
+
+	res = model.LedgerReportResponse{
+		IntervalMonth: param.IntervalMonth,
+		URL:           s.signedURL.Sign(ledgerReportPath + "/" + getLedgerReportFileName(param.IntervalMonth)),
+	}
+
+	return res, nil
+}
+
+func getLedgerReportFileName(intervalMonth int64) string {
+	return fmt.Sprintf("%d_month_ledger.xlsx", intervalMonth)
+}
+
+func isStatsIntervalMonth(intervalMonth int64) bool {
+	for _, statsIntervalMonth := range statsIntervalMonths {
+		if intervalMonth == statsIntervalMonth {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (s *statisticsService) createNewSheetsExcel(
+	f *excelize.File,
+	project model.Project,
+	projectLedgers []model.Ledger,
+	intervalMonth int64,
+	currentTime int64,
+) error {
+	// Create a new sheet.
+	_, err := f.NewSheet(project.Name)
+	if err != nil {
+		return err
+	}
+
+	for i := 1; i <= 9; i++ {
+		col1 := fmt.Sprintf("A%d", i)
+		col2 := fmt.Sprintf("B%d", i)
+		col3 := fmt.Sprintf("C%d", i)
+		col4 := fmt.Sprintf("D%d", i)
+		err := f.MergeCell(project.Name, col1, col2)
+		if err != nil {
+			return err
+		}
+		err = f.MergeCell(project.Name, col3, col4)
+		if err != nil {
+			return err
+		}
+	}
+
+	f.SetCellValue(project.Name, "A1", "Nama Proyek")
+	f.SetCellValue(project.Name, "A2", "Nama Pekerjaan")
+	f.SetCellValue(project.Name, "A3", "Nama Pengawas")
+	f.SetCellValue(project.Name, "A4", "Kategori Proyek")
+	f.SetCellValue(project.Name, "A5", "Status Proyek")
+	f.SetCellValue(project.Name, "A6", "Nama Dinas")
+	f.SetCellValue(project.Name, "A7", "Tanggal Mulai Proyek")
+	f.SetCellValue(project.Name, "A8", "Tanggal Selesai Proyek")
+	f.SetCellValue(project.Name, "A9", "Terakhir Diperbarui")
+
+	f.SetCellValue(project.Name, "C1", project.Name)
+	f.SetCellValue(project.Name, "C2", project.Description)
+	f.SetCellValue(project.Name, "C3", project.Inspector.Username)
+	f.SetCellValue(project.Name, "C4", project.Type)
+	f.SetCellValue(project.Name, "C5", project.Status)
+	f.SetCellValue(project.Name, "C6", project.DeptName)
+	f.SetCellValue(project.Name, "C7", convertDateToString(project.StartDate))
+	f.SetCellValue(project.Name, "C8", convertDateToString(project.FinalDate))
+	f.SetCellValue(project.Name, "C9", convertDateToString(currentTime))
+
+	projectInfostyle, err := f.NewStyle(&excelize.Style{
+		Font: &excelize.Font{
+			Bold: true,
+			Size: 12,
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	err = f.SetCellStyle(project.Name, "A1", "D9", projectInfostyle)
+	if err != nil {
+		return err
+	}
+
+	ledgerHeaderStyle, err := f.NewStyle(&excelize.Style{
+		Font: &excelize.Font{
+			Bold: true,
+			Size: 12,
+		},
+		Alignment: &excelize.Alignment{
+			Horizontal: "center",
+		},
+		Border: []excelize.Border{
+			{
+				Type:  "left",
+				Color: "#000000",
+			},
+			{
+				Type:  "top",
+				Color: "#000000",
+			},
+			{
+				Type:  "right",
+				Color: "#000000",
+			},
+			{
+				Type:  "bottom",
+				Color: "#000000",
+			},
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	f.MergeCell(project.Name, "A11", "F11")
+	f.SetCellValue(project.Name, "A11", fmt.Sprintf("BUKU KAS %d BULAN TERAKHIR", intervalMonth))
+	f.SetCellStyle(project.Name, "A11", "F11", ledgerHeaderStyle)
+
+	f.SetCellValue(project.Name, "A12", "No")
+	f.SetCellValue(project.Name, "B12", "Tanggal")
+	f.SetCellValue(project.Name, "C12", "Keterangan")
+	f.SetCellValue(project.Name, "D12", "Debit")
+	f.SetCellValue(project.Name, "E12", "Kredit")
+	f.SetCellValue(project.Name, "F12", "Saldo")
+
+	err = f.SetCellStyle(project.Name, "A12", "F12", ledgerHeaderStyle)
+	if err != nil {
+		return err
+	}
+
+	row := 13
+	for i, ledger := range projectLedgers {
+		row = i + 13
+		var description string
+		if ledger.LedgerType == model.Debit {
+			description = "Pemasukan dari direktur"
+		} else {
+			description = *ledger.Description + " - " + ledger.Ref
+		}
+		f.SetCellValue(project.Name, fmt.Sprintf("A%d", row), i+1)                                        // No
+		f.SetCellValue(project.Name, fmt.Sprintf("B%d", row), convertLocalDateToString(ledger.CreatedAt)) // Tanggal
+		f.SetCellValue(project.Name, fmt.Sprintf("C%d", row), description)                                // Keterangan
+		if ledger.LedgerType == model.Debit {
+			f.SetCellValue(project.Name, fmt.Sprintf("D%d", row), number.ConvertToRupiah(ledger.TotalPrice))
+		} else {
+			f.SetCellValue(project.Name, fmt.Sprintf("E%d", row), number.ConvertToRupiah(-ledger.TotalPrice))
+		}
+		f.SetCellValue(project.Name, fmt.Sprintf("F%d", row), number.ConvertToRupiah(*ledger.FinalProjectBalance))
+	}
+
+	ledgerBodyStyle, err := f.NewStyle(&excelize.Style{
+		Font: &excelize.Font{
+			Size: 12,
+		},
+		Alignment: &excelize.Alignment{
+			Horizontal: "center",
+		},
+		Border: []excelize.Border{
+			{
+				Type:  "left",
+				Color: "#000000",
+			},
+			{
+				Type:  "top",
+				Color: "#000000",
+			},
+			{
+				Type:  "right",
+				Color: "#000000",
+			},
+			{
+				Type:  "bottom",
+				Color: "#000000",
+			},
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	err = f.SetCellStyle(project.Name, "A12", fmt.Sprintf("F%d", row), ledgerBodyStyle)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func convertDateToString(date int64) string {
+	return time.Unix(date, 0).Format("02-01-2006 15:04:05")
+}
+
+func convertLocalDateToString(date int64) string {
+	loc, _ := time.LoadLocation("Asia/Jakarta")
+	jakartaDate := time.Unix(date, 0).In(loc)
+	return jakartaDate.Format("02-01-2006 15:04:05")
+}
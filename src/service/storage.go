@@ -0,0 +1,211 @@
+package service
+
+import (
+	"context"
+	"io"
+	"time"
+
+	errors "tigaputera-backend/sdk/error"
+	"tigaputera-backend/sdk/signedurl"
+	"tigaputera-backend/sdk/storage"
+	"tigaputera-backend/src/model"
+	"tigaputera-backend/src/repository"
+)
+
+const (
+	// the orphaned objects are moved under this folder, it isn't served by the signed urls
+	quarantinePath = "quarantine"
+	// an object younger than this may belong to a transaction that isn't committed yet
+	orphanMinAge = time.Hour
+	// a quarantined object can still be moved back by hand until it is deleted
+	quarantineGracePeriod = 7 * 24 * time.Hour
+)
+
+// the folders the receipts, their thumbnails and the reports are uploaded to
+var storagePaths = []string{
+	"incomes",
+	"expenditures",
+	getThumbnailPath("incomes"),
+	getThumbnailPath("expenditures"),
+	ledgerReportPath,
+}
+
+type StorageService interface {
+	// Download opens an object through its signed url, the reader must be closed
+	Download(ctx context.Context, param model.StorageObjectParam) (io.ReadCloser, error)
+	// CollectGarbage quarantines the objects no ledger, transfer, or report refers to,
+	// and deletes the ones quarantined for longer than the grace period
+	CollectGarbage(ctx context.Context, param model.StorageGarbageParam) (model.StorageGarbageReport, error)
+}
+
+type storageService struct {
+	repo      repository.Interface
+	storage   storage.Interface
+	signedURL signedurl.Interface
+}
+
+func NewStorageService(
+	repo repository.Interface,
+	storage storage.Interface,
+	signedURL signedurl.Interface,
+) StorageService {
+	return &storageService{
+		repo:      repo,
+		storage:   storage,
+		signedURL: signedURL,
+	}
+}
+
+func (s *storageService) Download(ctx context.Context, param model.StorageObjectParam) (io.ReadCloser, error) {
+	if !s.signedURL.Verify(param.Path+"/"+param.FileName, param.Expires, param.Signature) {
+		return nil, errors.Unauthorized("tautan file tidak valid atau sudah kedaluwarsa")
+	}
+
+	if !isStoragePath(param.Path) {
+		return nil, errors.NotFound("file tidak ditemukan")
+	}
+
+	reader, err := s.storage.Download(ctx, param.FileName, param.Path)
+	if err == storage.ErrObjectNotFound {
+		return nil, errors.NotFound("file tidak ditemukan")
+	} else if err != nil {
+		return nil, errors.InternalServerError(err.Error())
+	}
+
+	return reader, nil
+}
+
+func (s *storageService) CollectGarbage(
+	ctx context.Context,
+	param model.StorageGarbageParam,
+) (model.StorageGarbageReport, error) {
+	report := model.StorageGarbageReport{
+		DryRun:      param.DryRun,
+		Quarantined: []string{},
+		Restored:    []string{},
+		Deleted:     []string{},
+	}
+	now := time.Now()
+
+	// the objects are listed before the keys, so an upload committed in between is still referenced
+	objects := []storage.Object{}
+	quarantinedObjects := []storage.Object{}
+	for _, path := range storagePaths {
+		pathObjects, err := s.storage.List(ctx, path)
+		if err != nil {
+			return report, errors.InternalServerError(err.Error())
+		}
+		objects = append(objects, pathObjects...)
+
+		pathObjects, err = s.storage.List(ctx, getQuarantinePath(path))
+		if err != nil {
+			return report, errors.InternalServerError(err.Error())
+		}
+		quarantinedObjects = append(quarantinedObjects, pathObjects...)
+	}
+
+	isReferenced, err := s.getReferencedKeys(ctx)
+	if err != nil {
+		return report, errors.InternalServerError(err.Error())
+	}
+
+	for _, object := range objects {
+		report.CheckedObjects++
+		if isReferenced[object.Key()] || now.Sub(object.UpdatedAt) < orphanMinAge {
+			continue
+		}
+
+		report.Quarantined = append(report.Quarantined, object.Key())
+		if param.DryRun {
+			continue
+		}
+
+		if err := s.moveObject(ctx, object, getQuarantinePath(object.Path)); err != nil {
+			return report, errors.InternalServerError(err.Error())
+		}
+	}
+
+	// a quarantined object is written when it is moved, so its update time is the start of the grace period
+	for _, object := range quarantinedObjects {
+		originalPath := object.Path[len(quarantinePath)+1:]
+		originalKey := storage.Object{FileName: object.FileName, Path: originalPath}.Key()
+		if isReferenced[originalKey] {
+			report.Restored = append(report.Restored, originalKey)
+			if param.DryRun {
+				continue
+			}
+
+			if err := s.moveObject(ctx, object, originalPath); err != nil {
+				return report, errors.InternalServerError(err.Error())
+			}
+		} else if now.Sub(object.UpdatedAt) >= quarantineGracePeriod {
+			report.Deleted = append(report.Deleted, originalKey)
+			if param.DryRun {
+				continue
+			}
+
+			if err := s.storage.Delete(ctx, object.FileName, object.Path); err != nil {
+				return report, errors.InternalServerError(err.Error())
+			}
+		}
+	}
+
+	return report, nil
+}
+
+// getReferencedKeys returns the keys of the receipts of every ledger, attachment, and transfer,
+// the canceled and deleted ones included, and the keys of the current ledger reports
+func (s *storageService) getReferencedKeys(ctx context.Context) (map[string]bool, error) {
+	isReferenced := map[string]bool{}
+	for _, listObjectKeys := range []func(ctx context.Context) ([]string, error){
+		s.repo.Ledger().ListObjectKeys,
+		s.repo.LedgerAttachment().ListObjectKeys,
+		s.repo.FundTransfer().ListObjectKeys,
+	} {
+		keys, err := listObjectKeys(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, key := range keys {
+			isReferenced[key] = true
+		}
+	}
+
+	for _, intervalMonth := range statsIntervalMonths {
+		isReferenced[ledgerReportPath+"/"+getLedgerReportFileName(intervalMonth)] = true
+	}
+
+	return isReferenced, nil
+}
+
+// moveObject copies the object to the path before deleting it, the storage drivers can't rename
+func (s *storageService) moveObject(ctx context.Context, object storage.Object, path string) error {
+	reader, err := s.storage.Download(ctx, object.FileName, object.Path)
+	if err != nil {
+		return err
+	}
+
+	// the object is streamed to its new path, the reports can be too big to be kept in memory
+	_, err = s.storage.UploadFromReader(ctx, reader, object.FileName, path)
+	reader.Close()
+	if err != nil {
+		return err
+	}
+
+	return s.storage.Delete(ctx, object.FileName, object.Path)
+}
+
+func getQuarantinePath(path string) string {
+	return quarantinePath + "/" + path
+}
+
+func isStoragePath(path string) bool {
+	for _, storagePath := range storagePaths {
+		if path == storagePath {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,252 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"time"
+
+	"tigaputera-backend/sdk/auth"
+	errors "tigaputera-backend/sdk/error"
+	"tigaputera-backend/sdk/totp"
+	"tigaputera-backend/src/model"
+	"tigaputera-backend/src/repository"
+)
+
+const (
+	// the second step of the login has to be finished within this time
+	loginChallengeExpiredTime = 5 * time.Minute
+	recoveryCodeCount         = 10
+)
+
+func (s *userService) createLoginChallenge(ctx context.Context, user model.User) (model.UserLoginResponse, error) {
+	var res model.UserLoginResponse
+
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return res, errors.InternalServerError(err.Error())
+	}
+	token := base64.RawURLEncoding.EncodeToString(raw)
+
+	if err := s.repo.LoginChallenge().Create(ctx, &model.LoginChallenge{
+		UserID:    user.ID,
+		TokenHash: hashChallengeToken(token),
+		ExpiresAt: time.Now().Add(loginChallengeExpiredTime).Unix(),
+	}); err != nil {
+		return res, errors.InternalServerError(err.Error())
+	}
+
+	res = model.UserLoginResponse{
+		User:           user,
+		IsTotpRequired: true,
+		ChallengeToken: token,
+	}
+
+	return res, nil
+}
+
+func (s *userService) VerifyLoginTotp(ctx context.Context, body model.TotpLoginBody) (model.UserLoginResponse, error) {
+	var res model.UserLoginResponse
+
+	challenge, err := s.repo.LoginChallenge().GetByTokenHash(ctx, hashChallengeToken(body.ChallengeToken))
+	if repository.IsNotFound(err) {
+		return res, errors.Unauthorized("Sesi login telah berakhir, silakan login kembali")
+	} else if err != nil {
+		return res, errors.InternalServerError(err.Error())
+	}
+
+	if challenge.UsedAt != nil || challenge.ExpiresAt < time.Now().Unix() {
+		return res, errors.Unauthorized("Sesi login telah berakhir, silakan login kembali")
+	}
+
+	user, err := s.repo.User().Get(ctx, challenge.UserID)
+	if repository.IsNotFound(err) {
+		return res, errors.Unauthorized("Sesi login telah berakhir, silakan login kembali")
+	} else if err != nil {
+		return res, errors.InternalServerError(err.Error())
+	}
+
+	throttleKeys := getLoginThrottleKeys(ctx, user.Username)
+	if err := s.checkLoginLockout(ctx, user.Username, throttleKeys); err != nil {
+		return res, err
+	}
+
+	if ok, err := s.verifyTotpCode(ctx, user, body.Code, true); err != nil {
+		return res, err
+	} else if !ok {
+		if err := s.countLoginFailure(ctx, user.Username, &user.ID, model.WrongTotp, throttleKeys); err != nil {
+			return res, errors.InternalServerError(err.Error())
+		}
+
+		return res, errors.Unauthorized("Kode autentikator salah")
+	}
+
+	// a challenge finishes a single login, e.g. the same request sent twice
+	if err := s.repo.LoginChallenge().Use(ctx, challenge.ID); repository.IsNotFound(err) {
+		return res, errors.Unauthorized("Sesi login telah berakhir, silakan login kembali")
+	} else if err != nil {
+		return res, errors.InternalServerError(err.Error())
+	}
+
+	if err := s.repo.LoginThrottle().Reset(ctx, throttleKeys[0].key); err != nil {
+		return res, errors.InternalServerError(err.Error())
+	}
+
+	return s.createSession(ctx, user)
+}
+
+// verifyTotpCode checks the code of the authenticator, a code is only accepted once.
+// A recovery code is accepted instead when allowRecoveryCode is set, it is used up as well.
+func (s *userService) verifyTotpCode(
+	ctx context.Context,
+	user model.User,
+	code string,
+	allowRecoveryCode bool,
+) (bool, error) {
+	if step, ok := s.totp.Verify(user.TotpSecret, code); ok {
+		err := s.repo.User().UseTotpStep(ctx, user.ID, step)
+		if repository.IsNotFound(err) {
+			return false, nil
+		} else if err != nil {
+			return false, errors.InternalServerError(err.Error())
+		}
+
+		return true, nil
+	}
+
+	if !allowRecoveryCode {
+		return false, nil
+	}
+
+	err := s.repo.RecoveryCode().Use(ctx, user.ID, totp.HashRecoveryCode(code))
+	if repository.IsNotFound(err) {
+		return false, nil
+	} else if err != nil {
+		return false, errors.InternalServerError(err.Error())
+	}
+
+	return true, nil
+}
+
+func (s *userService) EnrollTotp(ctx context.Context, user auth.User) (model.TotpEnrollmentResponse, error) {
+	var res model.TotpEnrollmentResponse
+
+	secret, err := s.totp.GenerateSecret()
+	if err != nil {
+		return res, errors.InternalServerError(err.Error())
+	}
+
+	err = s.repo.User().UpdateTotpSecret(ctx, user.ID, secret.Encrypted)
+	if repository.IsNotFound(err) {
+		return res, errors.BadRequest("Autentikasi dua langkah sudah aktif")
+	} else if err != nil {
+		return res, errors.InternalServerError(err.Error())
+	}
+
+	res = model.TotpEnrollmentResponse{
+		Secret:          secret.Plain,
+		ProvisioningURI: s.totp.GetProvisioningURI(secret.Plain, user.Username),
+	}
+
+	return res, nil
+}
+
+func (s *userService) EnableTotp(
+	ctx context.Context,
+	user auth.User,
+	body model.TotpCodeBody,
+) (model.TotpRecoveryCodesResponse, error) {
+	var res model.TotpRecoveryCodesResponse
+
+	storedUser, err := s.repo.User().Get(ctx, user.ID)
+	if repository.IsNotFound(err) {
+		return res, errors.NotFound("Pengguna tidak ditemukan")
+	} else if err != nil {
+		return res, errors.InternalServerError(err.Error())
+	}
+
+	if storedUser.TotpEnabledAt != nil {
+		return res, errors.BadRequest("Autentikasi dua langkah sudah aktif")
+	} else if storedUser.TotpSecret == "" {
+		return res, errors.BadRequest("Silakan daftarkan autentikator terlebih dahulu")
+	}
+
+	step, ok := s.totp.Verify(storedUser.TotpSecret, body.Code)
+	if !ok {
+		return res, errors.BadRequest("Kode autentikator salah")
+	}
+
+	recoveryCodes := []string{}
+	codes := []model.RecoveryCode{}
+	for i := 0; i < recoveryCodeCount; i++ {
+		code, err := totp.GenerateRecoveryCode()
+		if err != nil {
+			return res, errors.InternalServerError(err.Error())
+		}
+
+		recoveryCodes = append(recoveryCodes, code)
+		codes = append(codes, model.RecoveryCode{UserID: user.ID, CodeHash: totp.HashRecoveryCode(code)})
+	}
+
+	err = s.repo.Transaction(ctx, func(tx repository.Interface) error {
+		if err := tx.User().EnableTotp(ctx, user.ID, step); err != nil {
+			return err
+		}
+
+		return tx.RecoveryCode().Replace(ctx, user.ID, codes)
+	})
+	if repository.IsNotFound(err) {
+		// enabled or enrolled again by another request
+		return res, errors.BadRequest("Autentikasi dua langkah sudah aktif")
+	} else if err != nil {
+		return res, errors.InternalServerError(err.Error())
+	}
+
+	res = model.TotpRecoveryCodesResponse{RecoveryCodes: recoveryCodes}
+
+	return res, nil
+}
+
+func (s *userService) DisableTotp(ctx context.Context, user auth.User, body model.TotpCodeBody) error {
+	storedUser, err := s.repo.User().Get(ctx, user.ID)
+	if repository.IsNotFound(err) {
+		return errors.NotFound("Pengguna tidak ditemukan")
+	} else if err != nil {
+		return errors.InternalServerError(err.Error())
+	}
+
+	// the role of the token could be older than a role change
+	if storedUser.Role == model.Admin {
+		return errors.BadRequest("Autentikasi dua langkah wajib untuk direktur")
+	}
+
+	if storedUser.TotpEnabledAt == nil {
+		return errors.BadRequest("Autentikasi dua langkah belum aktif")
+	}
+
+	if ok, err := s.verifyTotpCode(ctx, storedUser, body.Code, false); err != nil {
+		return err
+	} else if !ok {
+		return errors.BadRequest("Kode autentikator salah")
+	}
+
+	err = s.repo.Transaction(ctx, func(tx repository.Interface) error {
+		if err := tx.User().DisableTotp(ctx, user.ID); err != nil {
+			return err
+		}
+
+		return tx.RecoveryCode().DeleteByUser(ctx, user.ID)
+	})
+	if err != nil {
+		return errors.InternalServerError(err.Error())
+	}
+
+	return nil
+}
+
+func hashChallengeToken(token string) string {
+	hash := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(hash[:])
+}
@@ -0,0 +1,631 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"tigaputera-backend/sdk/appcontext"
+	"tigaputera-backend/sdk/auth"
+	errors "tigaputera-backend/sdk/error"
+	"tigaputera-backend/sdk/jwt"
+	"tigaputera-backend/sdk/number"
+	"tigaputera-backend/sdk/password"
+	"tigaputera-backend/sdk/totp"
+	"tigaputera-backend/src/model"
+	"tigaputera-backend/src/repository"
+)
+
+type UserService interface {
+	Login(ctx context.Context, body model.UserLoginBody) (model.UserLoginResponse, error)
+	// RefreshToken exchanges a refresh token for new tokens, a rotated token used again revokes its session
+	RefreshToken(ctx context.Context, body model.RefreshTokenBody) (model.UserLoginResponse, error)
+	Logout(ctx context.Context, user auth.User) error
+	// CheckSession rejects the access tokens of a revoked or expired session and of a deleted user,
+	// it returns the stored user with the password expiry and the permissions of the role
+	CheckSession(ctx context.Context, user auth.User) (model.User, error)
+	// ResetPassword changes the password of the user, the new password must follow the password policy
+	ResetPassword(ctx context.Context, user auth.User, body model.ResetPasswordBody) error
+	CreateInspector(ctx context.Context, user auth.User, body model.CreateInspectorBody) error
+	// GetListInspector lists the active inspectors, or the deactivated ones with IsDeleted
+	GetListInspector(ctx context.Context, param *model.UserParam) ([]model.User, error)
+	UpdateInspector(ctx context.Context, param model.UserParam, body model.UpdateInspectorBody) error
+	// ResetInspectorPassword sets a new password for an inspector who forgot it, the inspector
+	// changes it on the next login and the sessions are revoked
+	ResetInspectorPassword(ctx context.Context, param model.UserParam, body model.ResetPasswordBody) error
+	// DeactivateInspector refuses an inspector who still holds a balance or has running projects,
+	// they are handed over first
+	DeactivateInspector(ctx context.Context, inspectorID int64) error
+	ReactivateInspector(ctx context.Context, inspectorID int64) error
+	// VerifyLoginTotp finishes the login of a user with an authenticator
+	VerifyLoginTotp(ctx context.Context, body model.TotpLoginBody) (model.UserLoginResponse, error)
+	// EnrollTotp generates a new secret, the authenticator is enabled once a code of it is verified
+	EnrollTotp(ctx context.Context, user auth.User) (model.TotpEnrollmentResponse, error)
+	EnableTotp(ctx context.Context, user auth.User, body model.TotpCodeBody) (model.TotpRecoveryCodesResponse, error)
+	DisableTotp(ctx context.Context, user auth.User, body model.TotpCodeBody) error
+	// UnlockUser clears the failed logins of the user, the failures of the ip addresses stay counted
+	UnlockUser(ctx context.Context, userID int64) error
+	// GetListRole returns the roles with their permissions, a role exists as long as it has a permission
+	GetListRole(ctx context.Context) ([]model.RoleResponse, error)
+	// UpdateRole replaces the permissions of the role, the role is created when it doesn't exist
+	UpdateRole(ctx context.Context, param model.RoleParam, body model.UpdateRoleBody) error
+	UpdateUserRole(ctx context.Context, param model.UserParam, body model.UpdateUserRoleBody) error
+}
+
+type userService struct {
+	repo     repository.Interface
+	jwt      jwt.Interface
+	password password.Interface
+	totp     totp.Interface
+
+	dummyPasswordOnce sync.Once
+	dummyPassword     string
+}
+
+func NewUserService(
+	repo repository.Interface,
+	jwt jwt.Interface,
+	password password.Interface,
+	totp totp.Interface,
+) UserService {
+	return &userService{
+		repo:     repo,
+		jwt:      jwt,
+		password: password,
+		totp:     totp,
+	}
+}
+
+func (s *userService) Login(ctx context.Context, body model.UserLoginBody) (model.UserLoginResponse, error) {
+	var res model.UserLoginResponse
+
+	throttleKeys := getLoginThrottleKeys(ctx, body.Username)
+	if err := s.checkLoginLockout(ctx, body.Username, throttleKeys); err != nil {
+		return res, err
+	}
+
+	user, err := s.repo.User().GetByUsername(ctx, body.Username)
+	if repository.IsNotFound(err) {
+		// the password is still compared, so the response time doesn't tell the username doesn't exist
+		s.password.Compare(s.getDummyPassword(), body.Password)
+		return res, s.failLogin(ctx, body.Username, nil, model.UnknownUser, throttleKeys)
+	} else if err != nil {
+		return res, errors.InternalServerError(err.Error())
+	}
+
+	if !s.password.Compare(user.Password, body.Password) {
+		return res, s.failLogin(ctx, body.Username, &user.ID, model.WrongPassword, throttleKeys)
+	}
+
+	// the failures of the username are counted until the code of the authenticator is right as well
+	if user.TotpEnabledAt != nil {
+		return s.createLoginChallenge(ctx, user)
+	}
+
+	if err := s.repo.LoginThrottle().Reset(ctx, throttleKeys[0].key); err != nil {
+		return res, errors.InternalServerError(err.Error())
+	}
+
+	return s.createSession(ctx, user)
+}
+
+func (s *userService) createSession(ctx context.Context, user model.User) (model.UserLoginResponse, error) {
+	var res model.UserLoginResponse
+
+	refreshToken, err := s.jwt.GenerateRefreshToken()
+	if err != nil {
+		return res, errors.InternalServerError(err.Error())
+	}
+
+	session := model.UserSession{
+		UserID:           user.ID,
+		RefreshTokenHash: refreshToken.Hash,
+		UserAgent:        appcontext.GetUserAgent(ctx),
+		ExpiresAt:        refreshToken.ExpiresAt,
+	}
+	if err := s.repo.UserSession().Create(ctx, &session); err != nil {
+		return res, errors.InternalServerError(err.Error())
+	}
+
+	return s.getLoginResponse(user, session.ID, refreshToken.Token)
+}
+
+func (s *userService) RefreshToken(ctx context.Context, body model.RefreshTokenBody) (model.UserLoginResponse, error) {
+	var res model.UserLoginResponse
+
+	tokenHash := jwt.HashRefreshToken(body.RefreshToken)
+	session, err := s.repo.UserSession().GetByTokenHash(ctx, tokenHash)
+	if repository.IsNotFound(err) {
+		return res, errors.Unauthorized("Sesi anda telah berakhir, silakan login kembali")
+	} else if err != nil {
+		return res, errors.InternalServerError(err.Error())
+	}
+
+	// the token was already rotated, whoever holds the newer one may have stolen it
+	if session.RefreshTokenHash != tokenHash {
+		if err := s.repo.UserSession().Revoke(ctx, session.ID); err != nil {
+			return res, errors.InternalServerError(err.Error())
+		}
+
+		return res, errors.Unauthorized("Sesi anda telah berakhir, silakan login kembali")
+	}
+
+	if !session.IsActive(time.Now().Unix()) {
+		return res, errors.Unauthorized("Sesi anda telah berakhir, silakan login kembali")
+	}
+
+	user, err := s.repo.User().Get(ctx, session.UserID)
+	if repository.IsNotFound(err) {
+		return res, errors.Unauthorized("Sesi anda telah berakhir, silakan login kembali")
+	} else if err != nil {
+		return res, errors.InternalServerError(err.Error())
+	}
+
+	refreshToken, err := s.jwt.GenerateRefreshToken()
+	if err != nil {
+		return res, errors.InternalServerError(err.Error())
+	}
+
+	err = s.repo.UserSession().Rotate(ctx, session.ID, tokenHash, refreshToken.Hash, refreshToken.ExpiresAt)
+	if repository.IsNotFound(err) {
+		// rotated by a concurrent refresh or revoked in between
+		return res, errors.Unauthorized("Sesi anda telah berakhir, silakan login kembali")
+	} else if err != nil {
+		return res, errors.InternalServerError(err.Error())
+	}
+
+	return s.getLoginResponse(user, session.ID, refreshToken.Token)
+}
+
+func (s *userService) getLoginResponse(
+	user model.User,
+	sessionID int64,
+	refreshToken string,
+) (model.UserLoginResponse, error) {
+	var res model.UserLoginResponse
+
+	user.IsPasswordExpired = s.isPasswordExpired(user)
+	token, err := s.jwt.GenerateToken(auth.User{
+		ID:           user.ID,
+		Username:     user.Username,
+		Name:         user.Name,
+		IsFirstLogin: user.IsFirstLogin != nil && *user.IsFirstLogin,
+		Role:         string(user.Role),
+		SessionID:    sessionID,
+	})
+	if err != nil {
+		return res, errors.InternalServerError(err.Error())
+	}
+
+	res = model.UserLoginResponse{
+		User:         user,
+		Token:        token,
+		RefreshToken: refreshToken,
+	}
+
+	return res, nil
+}
+
+func (s *userService) Logout(ctx context.Context, user auth.User) error {
+	if err := s.repo.UserSession().Revoke(ctx, user.SessionID); err != nil {
+		return errors.InternalServerError(err.Error())
+	}
+
+	return nil
+}
+
+func (s *userService) CheckSession(ctx context.Context, user auth.User) (model.User, error) {
+	session, err := s.repo.UserSession().Get(ctx, user.SessionID)
+	if repository.IsNotFound(err) {
+		return model.User{}, errors.Unauthorized("Sesi anda telah berakhir, silakan login kembali")
+	} else if err != nil {
+		return model.User{}, errors.InternalServerError(err.Error())
+	}
+
+	if session.UserID != user.ID || !session.IsActive(time.Now().Unix()) {
+		return model.User{}, errors.Unauthorized("Sesi anda telah berakhir, silakan login kembali")
+	}
+
+	// the sessions of a deactivated inspector are revoked, this also covers the sessions created before
+	storedUser, err := s.repo.User().Get(ctx, user.ID)
+	if repository.IsNotFound(err) {
+		return storedUser, errors.Unauthorized("Sesi anda telah berakhir, silakan login kembali")
+	} else if err != nil {
+		return storedUser, errors.InternalServerError(err.Error())
+	}
+	storedUser.IsPasswordExpired = s.isPasswordExpired(storedUser)
+
+	storedUser.Permissions, err = s.getPermissions(ctx, storedUser.Role)
+	if err != nil {
+		return storedUser, errors.InternalServerError(err.Error())
+	}
+
+	return storedUser, nil
+}
+
+func (s *userService) isPasswordExpired(user model.User) bool {
+	expiredTime := s.password.Policy().ExpiredTime
+	if expiredTime <= 0 {
+		return false
+	}
+
+	return time.Unix(user.PasswordChangedAt, 0).Add(expiredTime).Before(time.Now())
+}
+
+// validatePassword checks the new password of the user against the password policy,
+// a new user has no previous passwords
+func (s *userService) validatePassword(ctx context.Context, user model.User, password string) error {
+	policy := s.password.Policy()
+	if len([]rune(password)) < policy.MinLength {
+		return errors.BadRequest(fmt.Sprintf("Password minimal %d karakter", policy.MinLength))
+	}
+
+	if strings.EqualFold(password, user.Username) {
+		return errors.BadRequest("Password tidak boleh sama dengan username")
+	}
+
+	if user.ID == 0 || policy.HistoryCount <= 0 {
+		return nil
+	}
+
+	histories, err := s.repo.PasswordHistory().List(ctx, user.ID, int64(policy.HistoryCount))
+	if err != nil {
+		return errors.InternalServerError(err.Error())
+	}
+
+	// the users created before the history only have their current password
+	previousPasswords := []string{user.Password}
+	for _, history := range histories {
+		previousPasswords = append(previousPasswords, history.Password)
+	}
+
+	for _, previousPassword := range previousPasswords {
+		if s.password.Compare(previousPassword, password) {
+			return errors.BadRequest(fmt.Sprintf(
+				"Password tidak boleh sama dengan %d password terakhir",
+				policy.HistoryCount,
+			))
+		}
+	}
+
+	return nil
+}
+
+func (s *userService) ResetPassword(ctx context.Context, user auth.User, body model.ResetPasswordBody) error {
+	storedUser, err := s.repo.User().Get(ctx, user.ID)
+	if repository.IsNotFound(err) {
+		return errors.NotFound("Pengguna tidak ditemukan")
+	} else if err != nil {
+		return errors.InternalServerError(err.Error())
+	}
+
+	// a stolen token alone can't change the password, the users who must change it are limited to this route
+	storedUser.IsPasswordExpired = s.isPasswordExpired(storedUser)
+	if !storedUser.MustChangePassword() && !s.password.Compare(storedUser.Password, body.CurrentPassword) {
+		return errors.BadRequest("Password lama salah")
+	}
+
+	if err := s.validatePassword(ctx, storedUser, body.NewPassword); err != nil {
+		return err
+	}
+
+	newPassword, err := s.password.Hash(body.NewPassword)
+	if err != nil {
+		return errors.InternalServerError(err.Error())
+	}
+
+	// the other sessions are logged out, the session changing the password is kept
+	err = s.repo.Transaction(ctx, func(tx repository.Interface) error {
+		if err := tx.User().UpdatePassword(ctx, storedUser.ID, newPassword); err != nil {
+			return err
+		}
+
+		if err := tx.UserSession().RevokeOthers(ctx, storedUser.ID, user.SessionID); err != nil {
+			return err
+		}
+
+		return tx.PasswordHistory().Create(ctx, &model.PasswordHistory{UserID: storedUser.ID, Password: newPassword})
+	})
+	if repository.IsNotFound(err) {
+		return errors.NotFound("Pengguna tidak ditemukan")
+	} else if err != nil {
+		return errors.InternalServerError(err.Error())
+	}
+
+	return nil
+}
+
+func (s *userService) CreateInspector(ctx context.Context, user auth.User, body model.CreateInspectorBody) error {
+	if err := s.validatePassword(ctx, model.User{Username: body.Username}, body.Password); err != nil {
+		return err
+	}
+
+	hashedPassword, err := s.password.Hash(body.Password)
+	if err != nil {
+		return errors.InternalServerError(err.Error())
+	}
+
+	// the inspector changes the password the director typed on the first login
+	newUser := model.User{
+		Username:          body.Username,
+		Name:              body.Name,
+		Password:          hashedPassword,
+		Role:              model.Inspector,
+		PasswordChangedAt: time.Now().Unix(),
+		CreatedBy:         &user.ID,
+		UpdatedBy:         &user.ID,
+	}
+
+	err = s.repo.Transaction(ctx, func(tx repository.Interface) error {
+		if err := tx.User().Create(ctx, &newUser); err != nil {
+			return err
+		}
+
+		return tx.PasswordHistory().Create(ctx, &model.PasswordHistory{UserID: newUser.ID, Password: hashedPassword})
+	})
+	if repository.IsDuplicate(err) {
+		return errors.BadRequest("Username sudah digunakan")
+	} else if err != nil {
+		return errors.InternalServerError(err.Error())
+	}
+
+	return nil
+}
+
+func (s *userService) GetListInspector(ctx context.Context, param *model.UserParam) ([]model.User, error) {
+	param.SetDefaultPagination()
+
+	// the users of the custom roles are managed like the inspectors
+	filter := repository.UserFilter{
+		ExcludeRole: model.Admin,
+		IsDeleted:   param.IsDeleted,
+		Limit:       param.Limit,
+		Offset:      param.Offset,
+	}
+
+	users, err := s.repo.User().List(ctx, filter)
+	if err != nil {
+		return users, errors.InternalServerError(err.Error())
+	}
+
+	param.TotalElement, err = s.repo.User().Count(ctx, filter)
+	if err != nil {
+		return users, errors.InternalServerError(err.Error())
+	}
+
+	param.ProcessPagination(int64(len(users)))
+
+	return users, nil
+}
+
+func (s *userService) UnlockUser(ctx context.Context, userID int64) error {
+	user, err := s.repo.User().Get(ctx, userID)
+	if repository.IsNotFound(err) {
+		return errors.NotFound("Pengguna tidak ditemukan")
+	} else if err != nil {
+		return errors.InternalServerError(err.Error())
+	}
+
+	if err := s.repo.LoginThrottle().Reset(ctx, getUsernameThrottleKey(user.Username)); err != nil {
+		return errors.InternalServerError(err.Error())
+	}
+
+	return nil
+}
+
+// getInspector gets an active user managed by the directors, whatever the role, the directors aren't found
+func (s *userService) getInspector(ctx context.Context, inspectorID int64) (model.User, error) {
+	inspector, err := s.repo.User().Get(ctx, inspectorID)
+	if repository.IsNotFound(err) {
+		return inspector, errors.NotFound("Pengawas tidak ditemukan")
+	} else if err != nil {
+		return inspector, errors.InternalServerError(err.Error())
+	} else if inspector.Role == model.Admin {
+		return inspector, errors.NotFound("Pengawas tidak ditemukan")
+	}
+
+	return inspector, nil
+}
+
+func (s *userService) UpdateInspector(
+	ctx context.Context,
+	param model.UserParam,
+	body model.UpdateInspectorBody,
+) error {
+	inspector, err := s.getInspector(ctx, param.ID)
+	if err != nil {
+		return err
+	}
+
+	err = s.repo.User().UpdateName(ctx, inspector.ID, strings.TrimSpace(body.Name))
+	if repository.IsNotFound(err) {
+		return errors.NotFound("Pengawas tidak ditemukan")
+	} else if err != nil {
+		return errors.InternalServerError(err.Error())
+	}
+
+	return nil
+}
+
+func (s *userService) ResetInspectorPassword(
+	ctx context.Context,
+	param model.UserParam,
+	body model.ResetPasswordBody,
+) error {
+	inspector, err := s.getInspector(ctx, param.ID)
+	if err != nil {
+		return err
+	}
+
+	if err := s.validatePassword(ctx, inspector, body.NewPassword); err != nil {
+		return err
+	}
+
+	newPassword, err := s.password.Hash(body.NewPassword)
+	if err != nil {
+		return errors.InternalServerError(err.Error())
+	}
+
+	err = s.repo.Transaction(ctx, func(tx repository.Interface) error {
+		if err := tx.User().ResetPassword(ctx, inspector.ID, newPassword); err != nil {
+			return err
+		}
+
+		if err := tx.PasswordHistory().Create(ctx, &model.PasswordHistory{
+			UserID:   inspector.ID,
+			Password: newPassword,
+		}); err != nil {
+			return err
+		}
+
+		if err := tx.UserSession().RevokeByUser(ctx, inspector.ID); err != nil {
+			return err
+		}
+
+		// the inspector who forgot the password is usually locked out by the failed logins
+		return tx.LoginThrottle().Reset(ctx, getUsernameThrottleKey(inspector.Username))
+	})
+	if repository.IsNotFound(err) {
+		return errors.NotFound("Pengawas tidak ditemukan")
+	} else if err != nil {
+		return errors.InternalServerError(err.Error())
+	}
+
+	return nil
+}
+
+func (s *userService) DeactivateInspector(ctx context.Context, inspectorID int64) error {
+	inspector, err := s.getInspector(ctx, inspectorID)
+	if err != nil {
+		return err
+	}
+
+	return s.repo.Transaction(ctx, func(tx repository.Interface) error {
+		// the ledger lock of the inspector keeps the postings, approvals and transfers out until the deactivation is done
+		err := tx.Ledger().Lock(ctx, 0, inspector.ID)
+		if repository.IsLockTimeout(err) {
+			return errors.Conflict("Transaksi lain sedang diproses, silakan coba lagi")
+		} else if err != nil {
+			return errors.InternalServerError(err.Error())
+		}
+
+		if err := s.checkInspectorDeactivation(ctx, tx, inspector.ID); err != nil {
+			return err
+		}
+
+		err = tx.User().Delete(ctx, inspector.ID)
+		if repository.IsNotFound(err) {
+			return errors.NotFound("Pengawas tidak ditemukan")
+		} else if err != nil {
+			return errors.InternalServerError(err.Error())
+		}
+
+		if err := tx.UserSession().RevokeByUser(ctx, inspector.ID); err != nil {
+			return errors.InternalServerError(err.Error())
+		}
+
+		return nil
+	})
+}
+
+// checkInspectorDeactivation refuses to deactivate an inspector who still has something to be settled,
+// the pending expenditures and transfers come first because they change the balance
+func (s *userService) checkInspectorDeactivation(ctx context.Context, tx repository.Interface, inspectorID int64) error {
+	pendingExpenditures, err := tx.Ledger().Count(ctx, repository.LedgerFilter{
+		InspectorID: inspectorID,
+		Status:      model.Pending,
+		IsCanceled:  boolPtr(false),
+	})
+	if err != nil {
+		return errors.InternalServerError(err.Error())
+	} else if pendingExpenditures > 0 {
+		return errors.BadRequest(fmt.Sprintf(
+			"Pengawas masih memiliki %d pengeluaran yang menunggu persetujuan, "+
+				"setujui atau tolak pengeluaran sebelum menonaktifkan pengawas",
+			pendingExpenditures,
+		))
+	}
+
+	pendingTransfers, err := tx.FundTransfer().Count(ctx, repository.FundTransferFilter{
+		InspectorID: inspectorID,
+		Status:      model.TransferPending,
+	})
+	if err != nil {
+		return errors.InternalServerError(err.Error())
+	} else if pendingTransfers > 0 {
+		return errors.BadRequest(fmt.Sprintf(
+			"Pengawas masih memiliki %d transfer dana yang belum dikonfirmasi, "+
+				"batalkan transfer sebelum menonaktifkan pengawas",
+			pendingTransfers,
+		))
+	}
+
+	latestLedger, err := tx.Ledger().Get(ctx, repository.LedgerFilter{
+		InspectorID: inspectorID,
+		Status:      model.Posted,
+	})
+	if err != nil && !repository.IsNotFound(err) {
+		return errors.InternalServerError(err.Error())
+	} else if err == nil && getInt64(latestLedger.FinalInspectorBalance) != 0 {
+		return errors.BadRequest(fmt.Sprintf(
+			"Pengawas masih memiliki saldo %s, serahkan saldo sebelum menonaktifkan pengawas",
+			number.ConvertToRupiah(getInt64(latestLedger.FinalInspectorBalance)),
+		))
+	}
+
+	// the postponed projects are resumed later, the inspector has to be out of them too
+	var projects int64
+	for _, status := range []model.ProjectStatus{model.Running, model.Postponed} {
+		count, err := tx.Project().Count(ctx, repository.ProjectFilter{
+			InspectorID: inspectorID,
+			Status:      status,
+		})
+		if err != nil {
+			return errors.InternalServerError(err.Error())
+		}
+		projects += count
+	}
+
+	if projects > 0 {
+		return errors.BadRequest(fmt.Sprintf(
+			"Pengawas masih menjadi anggota %d proyek yang sedang berjalan atau ditunda, "+
+				"pindahkan proyek atau keluarkan pengawas dari proyek sebelum menonaktifkan pengawas",
+			projects,
+		))
+	}
+
+	return nil
+}
+
+func (s *userService) ReactivateInspector(ctx context.Context, inspectorID int64) error {
+	if _, err := s.repo.User().Get(ctx, inspectorID); err == nil {
+		return errors.BadRequest("Pengawas masih aktif")
+	} else if !repository.IsNotFound(err) {
+		return errors.InternalServerError(err.Error())
+	}
+
+	// the directors aren't deactivated, the role is checked once the user is readable again
+	err := s.repo.Transaction(ctx, func(tx repository.Interface) error {
+		if err := tx.User().Restore(ctx, inspectorID); err != nil {
+			return err
+		}
+
+		inspector, err := tx.User().Get(ctx, inspectorID)
+		if err != nil {
+			return err
+		} else if inspector.Role == model.Admin {
+			return repository.ErrNotFound
+		}
+
+		return nil
+	})
+	if repository.IsNotFound(err) {
+		return errors.NotFound("Pengawas tidak ditemukan")
+	} else if err != nil {
+		return errors.InternalServerError(err.Error())
+	}
+
+	return nil
+}